@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -258,38 +259,46 @@ func main() {
 
 	for _, evt := range events {
 		_, err = tx.Exec(context.Background(),
-			`INSERT INTO events (tenant_id, title, description, starts_at, ends_at, location, capacity) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			tenantID, evt.title, evt.description, evt.startsAt, evt.endsAt, evt.location, evt.capacity)
+			`INSERT INTO events (tenant_id, title, description, starts_at, ends_at, location, capacity, slug) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			tenantID, evt.title, evt.description, evt.startsAt, evt.endsAt, evt.location, evt.capacity,
+			strings.ReplaceAll(strings.ToLower(evt.title), " ", "-"))
 		if err != nil {
 			log.Fatalf("Error inserting event: %v\n", err)
 		}
 	}
 	fmt.Printf("✓ Created 4 sample events\n")
 
-	// Create sample facilities and slots
+	// Create sample facilities and their weekly availability. The server
+	// generates slots from the templates when it starts.
 	facilities := []struct {
-		name    string
-		ftype   string
-		address string
-		rules   string
+		name        string
+		ftype       string
+		address     string
+		rules       string
+		weekdays    []int32
+		open, close string
+		slotMinutes int
 	}{
 		{
 			"Main Gymnasium",
 			"gym",
 			"123 Recreation St",
 			"No outside shoes. Must be member.",
+			[]int32{1, 2, 3, 4, 5}, "06:00", "22:00", 60,
 		},
 		{
 			"Tennis Court",
 			"court",
 			"123 Recreation St",
 			"Reservations required. 30-minute slots.",
+			[]int32{0, 1, 2, 3, 4, 5, 6}, "07:00", "21:00", 30,
 		},
 		{
 			"Community Pool",
 			"facility",
 			"456 Water Ave",
 			"Swimsuits required. Supervision for children under 12.",
+			[]int32{0, 6}, "09:00", "17:00", 120,
 		},
 	}
 
@@ -302,21 +311,15 @@ func main() {
 			log.Fatalf("Error inserting facility: %v\n", err)
 		}
 
-		// Create 4 time slots for each facility
-		baseTime := now.Add(24 * time.Hour)
-		for i := 0; i < 4; i++ {
-			startTime := baseTime.Add(time.Duration(i*2) * time.Hour)
-			endTime := startTime.Add(1 * time.Hour)
-
-			_, err = tx.Exec(context.Background(),
-				`INSERT INTO facility_slots (facility_id, starts_at, ends_at, status) VALUES ($1, $2, $3, $4)`,
-				facilityID, startTime, endTime, "open")
-			if err != nil {
-				log.Fatalf("Error inserting facility slot: %v\n", err)
-			}
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO facility_availability_templates (tenant_id, facility_id, weekdays, open_time, close_time, slot_minutes)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			tenantID, facilityID, fac.weekdays, fac.open, fac.close, fac.slotMinutes)
+		if err != nil {
+			log.Fatalf("Error inserting availability template: %v\n", err)
 		}
 	}
-	fmt.Printf("✓ Created 3 facilities with 12 time slots\n")
+	fmt.Printf("✓ Created 3 facilities with weekly availability templates\n")
 
 	// Commit transaction
 	err = tx.Commit(context.Background())
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rec-hub/backend/pkg/config"
 	"github.com/rec-hub/backend/pkg/db"
 	"github.com/rec-hub/backend/pkg/handlers"
+	"github.com/rec-hub/backend/pkg/mail"
 	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/payments"
 )
 
 func main() {
@@ -34,6 +39,12 @@ func main() {
 	// Create Gin router
 	router := gin.Default()
 
+	// Client IPs are taken from X-Forwarded-For only when a trusted proxy
+	// sent the request; otherwise it is the connection's address
+	if err := router.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -57,6 +68,47 @@ func main() {
 		TenantID:  "", // Will be set by middleware
 	}
 
+	// Online payments provider (disabled when unset)
+	switch cfg.PaymentsProvider {
+	case "stripe":
+		h.Payments = payments.NewStripeProvider(cfg.StripeSecretKey, cfg.StripeWebhookSecret)
+	case "fake":
+		// Anyone with a session id can complete a fake checkout
+		if cfg.GinMode == gin.ReleaseMode {
+			log.Fatalf("The fake payments provider is for development and cannot run with GIN_MODE=release")
+		}
+		h.Payments = payments.NewFakeProvider(cfg.FakePaymentsSecret, cfg.APIBaseURL)
+	case "":
+	default:
+		log.Fatalf("Unknown payments provider: %s", cfg.PaymentsProvider)
+	}
+
+	// Deliver queued notification emails
+	smtpPort, err := strconv.Atoi(cfg.SMTPPort)
+	if err != nil {
+		log.Fatalf("Invalid SMTP_PORT: %v", err)
+	}
+	outbox := &mail.Outbox{
+		DB:     pgPool,
+		Mailer: mail.NewMailer(cfg.SMTPHost, smtpPort, cfg.FromEmail),
+	}
+	go outbox.Run(context.Background(), 30*time.Second)
+
+	// Retry refunds the payment provider has not completed yet
+	go h.RunRefundSettlement(context.Background(), 5*time.Minute)
+
+	// Expire lottery offers so places roll down the waitlist
+	go h.RunOfferExpiry(context.Background(), time.Minute)
+
+	// Queue reminders ahead of events, programs and bookings
+	go h.RunReminders(context.Background(), time.Minute)
+
+	// Extend facility slots from availability templates over their horizon
+	go h.RunSlotGeneration(context.Background(), time.Hour)
+
+	// Cancel unpaid bookings whose slot hold lapsed, reopening the slots
+	go h.RunHoldExpiry(context.Background(), time.Minute)
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -77,6 +129,15 @@ func main() {
 		// Boot endpoint (with tenant resolver)
 		api.GET("/boot", middleware.TenantResolver(cfg), h.BootHandler)
 
+		// Payment provider callbacks (verified by signature, not auth)
+		api.POST("/payments/webhooks/:provider", h.HandlePaymentWebhook)
+		if cfg.PaymentsProvider == "fake" {
+			api.GET("/payments/fake/checkout/:session_id", h.CompleteFakeCheckout)
+		}
+
+		// Private calendar feeds (authenticated by the token in the URL)
+		api.GET("/calendar/:token", h.GetCalendarFeed)
+
 		// Protected routes (with auth and tenant resolver)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware(cfg))
@@ -112,6 +173,39 @@ func main() {
 			{
 				settings.GET("", h.GetTenantSettings)
 				settings.PUT("", h.UpdateTenantSettings)
+				settings.GET("/residency-areas", h.GetResidencyAreas)
+				settings.PUT("/residency-areas", h.UpdateResidencyAreas)
+			}
+
+			// Current user
+			me := protected.Group("/me")
+			{
+				me.PUT("/profile", h.UpdateMyProfile)
+				me.GET("/ledger", h.GetMyLedger)
+				me.GET("/teaching", h.GetMyTeaching)
+				me.GET("/registrations", h.ListMyRegistrations)
+				me.PUT("/registrations/:id", h.UpdateMyRegistration)
+				me.GET("/registrations/:id/cancellation", h.GetMyRegistrationCancellation)
+				me.POST("/registrations/:id/cancel", h.CancelMyRegistration)
+				me.POST("/registrations/:id/accept", h.AcceptMyRegistrationOffer)
+				me.POST("/registrations/:id/waivers", h.SignMyRegistrationWaivers)
+				me.GET("/waivers", h.ListMyWaivers)
+				me.GET("/event-registrations", h.ListMyEventRSVPs)
+				me.POST("/event-registrations", h.CreateMyEventRSVP)
+				me.PUT("/event-registrations/:id", h.UpdateMyEventRSVP)
+				me.POST("/event-registrations/:id/cancel", h.CancelMyEventRSVP)
+				me.GET("/event-registrations/:id/ticket", h.GetMyEventTicket)
+				me.GET("/scholarship-applications", h.ListMyScholarshipApplications)
+				me.POST("/scholarship-applications", h.ApplyForScholarship)
+				me.POST("/scholarship-applications/:id/withdraw", h.WithdrawMyScholarshipApplication)
+			}
+
+			// Residents
+			residents := protected.Group("/residents")
+			{
+				residents.PUT("/:user_id/membership", h.UpdateResidentMembership)
+				residents.GET("/:user_id/ledger", h.GetResidentLedger)
+				residents.POST("/:user_id/ledger", h.PostResidentLedger)
 			}
 
 			// Programs
@@ -119,17 +213,121 @@ func main() {
 			{
 				programs.GET("", h.ListPrograms)
 				programs.POST("", h.CreateProgram)
+				programs.POST("/import", h.ImportPrograms)
 				programs.PUT("/:id", h.UpdateProgram)
 				programs.DELETE("/:id", h.DeleteProgram)
+				programs.GET("/:id/eligibility", h.GetProgramEligibility)
+				programs.PUT("/:id/eligibility", h.UpdateProgramEligibility)
+				programs.GET("/:id/form", h.GetProgramForm)
+				programs.PUT("/:id/form", h.UpdateProgramForm)
+				programs.GET("/:id/price-rules", h.GetProgramPriceRules)
+				programs.PUT("/:id/price-rules", h.UpdateProgramPriceRules)
+				programs.GET("/:id/schedules", h.ListProgramSchedules)
+				programs.POST("/:id/schedules", h.CreateProgramSchedule)
+				programs.PUT("/:id/schedules/:schedule_id", h.UpdateProgramSchedule)
+				programs.DELETE("/:id/schedules/:schedule_id", h.DeleteProgramSchedule)
+				programs.POST("/:id/schedules/:schedule_id/generate", h.GenerateProgramSessions)
+				programs.GET("/:id/sessions", h.ListProgramSessions)
+				programs.GET("/:id/attendance/summary", h.GetProgramAttendanceSummary)
+				programs.GET("/:id/roster", h.ExportProgramRoster)
+				programs.GET("/:id/instructors", h.ListProgramInstructors)
+				programs.POST("/:id/instructors", h.AssignProgramInstructor)
+				programs.DELETE("/:id/instructors/:instructor_id", h.RemoveProgramInstructor)
+				programs.PUT("/:id/required-certifications", h.UpdateProgramRequiredCertifications)
+				programs.GET("/:id/lottery", h.GetProgramLottery)
+				programs.PUT("/:id/lottery", h.UpdateProgramLottery)
+				programs.DELETE("/:id/lottery", h.DeleteProgramLottery)
+				programs.POST("/:id/lottery/draw", h.DrawProgramLottery)
+			}
+
+			// Program sessions
+			sessions := protected.Group("/program-sessions")
+			{
+				sessions.GET("/:id/attendance", h.GetSessionAttendance)
+				sessions.PUT("/:id/attendance", h.MarkSessionAttendance)
+				sessions.POST("/:id/instructors", h.AssignSessionInstructor)
+				sessions.DELETE("/:id/instructors/:instructor_id", h.RemoveSessionInstructor)
+			}
+
+			// Instructors
+			instructors := protected.Group("/instructors")
+			{
+				instructors.GET("", h.ListInstructors)
+				instructors.POST("", h.CreateInstructor)
+				instructors.GET("/conflicts", h.GetInstructorConflicts)
+				instructors.GET("/:id", h.GetInstructor)
+				instructors.PUT("/:id", h.UpdateInstructor)
+				instructors.DELETE("/:id", h.DeleteInstructor)
+				instructors.POST("/:id/certifications", h.AddInstructorCertification)
+				instructors.PUT("/:id/certifications/:cert_id", h.UpdateInstructorCertification)
+				instructors.DELETE("/:id/certifications/:cert_id", h.DeleteInstructorCertification)
+			}
+
+			// Calendar feed subscriptions
+			calendarFeeds := protected.Group("/calendar-feeds")
+			{
+				calendarFeeds.GET("", h.ListCalendarFeeds)
+				calendarFeeds.POST("", h.CreateCalendarFeed)
+				calendarFeeds.DELETE("/:id", h.RevokeCalendarFeed)
+			}
+
+			// Seasons
+			seasons := protected.Group("/seasons")
+			{
+				seasons.GET("", h.ListSeasons)
+				seasons.POST("", h.CreateSeason)
+				seasons.PUT("/:id", h.UpdateSeason)
+				seasons.DELETE("/:id", h.DeleteSeason)
+				seasons.POST("/:id/rollover", h.RolloverSeason)
+			}
+
+			// Coupons
+			coupons := protected.Group("/coupons")
+			{
+				coupons.GET("", h.ListCoupons)
+				coupons.POST("", h.CreateCoupon)
+				coupons.PUT("/:id", h.UpdateCoupon)
+				coupons.DELETE("/:id", h.DeleteCoupon)
+			}
+
+			// Scholarships
+			scholarships := protected.Group("/scholarships")
+			{
+				scholarships.GET("", h.GetScholarshipFund)
+				scholarships.PUT("", h.UpdateScholarshipFund)
+				scholarships.GET("/applications", h.ListScholarshipApplications)
+				scholarships.GET("/applications/:id", h.GetScholarshipApplication)
+				scholarships.PUT("/applications/:id/status", h.DecideScholarshipApplication)
+			}
+
+			// Waivers
+			waivers := protected.Group("/waivers")
+			{
+				waivers.GET("", h.ListWaivers)
+				waivers.POST("", h.CreateWaiver)
+				waivers.GET("/:id", h.GetWaiver)
+				waivers.PUT("/:id", h.UpdateWaiver)
+				waivers.DELETE("/:id", h.DeleteWaiver)
+				waivers.POST("/:id/versions", h.PublishWaiverVersion)
+				waivers.PUT("/:id/attachments", h.UpdateWaiverAttachments)
+				waivers.GET("/:id/signatures", h.ListWaiverSignatures)
+				waivers.GET("/:id/unsigned", h.ListUnsignedWaiver)
 			}
+			protected.GET("/waiver-signatures/:id/pdf", h.DownloadWaiverSignature)
 
 			// Events
 			events := protected.Group("/events")
 			{
 				events.GET("", h.ListEvents)
 				events.POST("", h.CreateEvent)
+				events.POST("/import", h.ImportEvents)
 				events.PUT("/:id", h.UpdateEvent)
 				events.DELETE("/:id", h.DeleteEvent)
+				events.PUT("/:id/occurrences", h.UpdateEventOccurrence)
+				events.GET("/:id/attendees", h.ListEventAttendees)
+				events.GET("/:id/check-in", h.GetEventCheckIn)
+				events.POST("/:id/check-in", h.CheckInEventTicket)
+				events.POST("/:id/check-in/sync", h.SyncEventCheckIns)
 			}
 
 			// Facilities
@@ -139,12 +337,18 @@ func main() {
 				facilities.POST("", h.CreateFacility)
 				facilities.PUT("/:id", h.UpdateFacility)
 				facilities.DELETE("/:id", h.DeleteFacility)
+				facilities.GET("/:id/availability-templates", h.ListAvailabilityTemplates)
+				facilities.POST("/:id/availability-templates", h.CreateAvailabilityTemplate)
+				facilities.PUT("/:id/availability-templates/:template_id", h.UpdateAvailabilityTemplate)
+				facilities.DELETE("/:id/availability-templates/:template_id", h.DeleteAvailabilityTemplate)
+				facilities.POST("/:id/availability-templates/:template_id/generate", h.GenerateTemplateSlots)
 			}
 
 			// Facility slots
 			slots := protected.Group("/facility-slots")
 			{
 				slots.GET("", h.ListFacilitySlots)
+				slots.GET("/overlaps", h.SlotOverlapReport)
 				slots.POST("", h.CreateFacilitySlot)
 				slots.PUT("/:id", h.UpdateFacilitySlot)
 				slots.DELETE("/:id", h.DeleteFacilitySlot)
@@ -162,9 +366,19 @@ func main() {
 			{
 				registrations.POST("", h.CreateProgramRegistration)
 				registrations.GET("", h.ListProgramRegistrations)
+				registrations.GET("/export", h.ExportProgramRegistrations)
+				registrations.POST("/quote", h.QuoteProgramRegistration)
 				registrations.PUT("/:id/status", h.UpdateProgramRegistrationStatus)
 			}
 
+			// Payments
+			paymentsGroup := protected.Group("/payments")
+			{
+				paymentsGroup.GET("", h.ListPayments)
+				paymentsGroup.POST("/checkout", h.CreateCheckout)
+				paymentsGroup.POST("/:id/refund", h.RefundPayment)
+			}
+
 			// Dashboard (admin only)
 			dashboard := protected.Group("/admin/dashboard")
 			{
@@ -201,15 +415,29 @@ func main() {
 
 			// Public programs
 			public.GET("/programs", h.GetPublicPrograms)
+			public.GET("/seasons", h.GetPublicSeasons)
+			public.GET("/programs/:id", h.GetPublicProgram)
+			public.GET("/programs/:id/form", h.GetPublicProgramForm)
+			public.GET("/programs/:id/calendar.ics", h.GetPublicProgramCalendar)
 
 			// Public events
+			public.GET("/events", h.GetPublicEvents)
 			public.GET("/events/upcoming", h.GetUpcomingEvents)
+			public.GET("/events/:id", h.GetPublicEvent)
+			public.GET("/calendar.ics", h.GetPublicEventCalendar)
+			public.POST("/events/:id/rsvp", h.CreatePublicEventRSVP)
+			public.POST("/event-registrations/:id/cancel", h.CancelPublicEventRSVP)
+			public.GET("/tickets/:code/qr.png", h.GetPublicTicketQR)
 
 			// Public facilities
 			public.GET("/facilities", h.GetPublicFacilities)
 
+			// Waivers required to register or book
+			public.GET("/waivers", h.GetPublicWaivers)
+
 			// Public bookings
 			public.POST("/bookings", h.CreatePublicBooking)
+			public.POST("/bookings/:id/checkout", h.CreatePublicBookingCheckout)
 
 			// Public auth (for residents to create accounts)
 			public.POST("/register", h.PublicRegister)
@@ -0,0 +1,42 @@
+-- Migration 008: Structured eligibility rules for program registration
+
+-- One rule set per program; NULL/empty columns mean "no restriction"
+CREATE TABLE IF NOT EXISTS program_eligibility_rules (
+  program_id uuid PRIMARY KEY REFERENCES programs(id) ON DELETE CASCADE,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  min_age int CHECK (min_age IS NULL OR min_age >= 0),
+  max_age int CHECK (max_age IS NULL OR max_age >= 0),
+  age_cutoff_date date,
+  residents_only bool NOT NULL DEFAULT false,
+  allowed_genders text[] NOT NULL DEFAULT '{}',
+  membership_required bool NOT NULL DEFAULT false,
+  prerequisite_program_ids uuid[] NOT NULL DEFAULT '{}',
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CHECK (min_age IS NULL OR max_age IS NULL OR min_age <= max_age)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_eligibility_rules_tenant_id ON program_eligibility_rules(tenant_id);
+
+-- Streets and ZIP codes that count as "in district" for residency rules
+CREATE TABLE IF NOT EXISTS tenant_residency_areas (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('street', 'zip')),
+  value text NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, kind, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_residency_areas_tenant_id ON tenant_residency_areas(tenant_id);
+
+-- Resident address used for residency matching
+ALTER TABLE users ADD COLUMN IF NOT EXISTS street_address text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS zip_code text;
+
+-- Membership is tracked per tenant; NULL means not a member
+ALTER TABLE tenant_users ADD COLUMN IF NOT EXISTS member_until date;
+
+-- Participant details evaluated against the rules
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS participant_date_of_birth date;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS participant_gender text;
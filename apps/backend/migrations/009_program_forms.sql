@@ -0,0 +1,15 @@
+-- Migration 009: Per-program custom registration questions
+
+-- Form definition per program; fields is an ordered array of typed questions
+CREATE TABLE IF NOT EXISTS program_forms (
+  program_id uuid PRIMARY KEY REFERENCES programs(id) ON DELETE CASCADE,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  fields jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_forms_tenant_id ON program_forms(tenant_id);
+
+-- Validated answers keyed by field key
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS answers jsonb NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,47 @@
+-- Migration 010: Tiered program pricing and coupon codes
+
+-- Price rules attached to a program (non-resident surcharge, early-bird, sibling)
+CREATE TABLE IF NOT EXISTS program_price_rules (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  program_id uuid NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('non_resident_surcharge', 'early_bird', 'sibling_discount')),
+  label text,
+  amount_cents int NOT NULL DEFAULT 0 CHECK (amount_cents >= 0),
+  percent numeric(5,2) NOT NULL DEFAULT 0 CHECK (percent >= 0 AND percent <= 100),
+  ends_at timestamptz,
+  min_sibling_index int NOT NULL DEFAULT 2,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_price_rules_program_id ON program_price_rules(program_id);
+
+-- Tenant-level coupon codes
+CREATE TABLE IF NOT EXISTS coupons (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  code text NOT NULL,
+  description text,
+  percent_off numeric(5,2) NOT NULL DEFAULT 0 CHECK (percent_off >= 0 AND percent_off <= 100),
+  amount_off_cents int NOT NULL DEFAULT 0 CHECK (amount_off_cents >= 0),
+  starts_at timestamptz,
+  ends_at timestamptz,
+  max_redemptions int CHECK (max_redemptions IS NULL OR max_redemptions > 0),
+  redemption_count int NOT NULL DEFAULT 0,
+  active bool NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupons_tenant_id ON coupons(tenant_id);
+
+-- Computed price stored with each registration
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS price_cents int NOT NULL DEFAULT 0;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS price_quote jsonb NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS coupon_code text;
+
+-- Households register siblings into the same program, so uniqueness is per participant
+ALTER TABLE program_registrations DROP CONSTRAINT IF EXISTS program_registrations_program_id_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_program_registrations_participant
+  ON program_registrations(program_id, user_id, lower(participant_name));
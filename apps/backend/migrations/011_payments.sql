@@ -0,0 +1,37 @@
+-- Migration 011: Online payments
+
+CREATE TABLE IF NOT EXISTS payments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  subject_type text NOT NULL CHECK (subject_type IN ('program_registration', 'booking')),
+  subject_id uuid NOT NULL,
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  payer_email text,
+  amount_cents int NOT NULL CHECK (amount_cents >= 0),
+  currency text NOT NULL DEFAULT 'usd',
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'failed', 'cancelled')),
+  provider text NOT NULL,
+  provider_session_id text,
+  provider_payment_ref text,
+  failure_reason text,
+  paid_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_tenant_id ON payments(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_payments_subject ON payments(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments(tenant_id, paid_at) WHERE status = 'paid';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_session ON payments(provider, provider_session_id)
+  WHERE provider_session_id IS NOT NULL;
+
+-- Processed webhook events, so provider retries are applied once
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+  provider text NOT NULL,
+  event_id text NOT NULL,
+  received_at timestamptz DEFAULT now(),
+  PRIMARY KEY (provider, event_id)
+);
+
+-- Facility rental rate used to price bookings
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS hourly_rate_cents int NOT NULL DEFAULT 0 CHECK (hourly_rate_cents >= 0);
@@ -0,0 +1,69 @@
+-- Migration 012: Household ledger, account credit and refunds
+--
+-- Each resident account (the person who registers their household's
+-- participants) has a double-entry ledger. Every transaction posts entries
+-- that sum to zero across four accounts:
+--   receivable  amounts the household owes (debit positive)
+--   cash        money received through the payment provider
+--   revenue     program and facility income (credit negative)
+--   credit      account credit owed back to the household (credit negative)
+
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('charge', 'payment', 'refund', 'credit', 'adjustment')),
+  description text NOT NULL,
+  subject_type text,
+  subject_id uuid,
+  payment_id uuid,
+  refund_id uuid,
+  created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_transactions_account ON ledger_transactions(tenant_id, user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ledger_transactions_subject ON ledger_transactions(subject_type, subject_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  transaction_id uuid NOT NULL REFERENCES ledger_transactions(id) ON DELETE CASCADE,
+  account text NOT NULL CHECK (account IN ('receivable', 'cash', 'revenue', 'credit')),
+  amount_cents int NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+
+-- Reject unbalanced transactions at commit time
+CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+BEGIN
+  IF (SELECT COALESCE(SUM(amount_cents), 0) FROM ledger_entries WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+    RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+  AFTER INSERT OR UPDATE ON ledger_entries
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- Credit reserved by a checkout is applied when the payment completes
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS credit_applied_cents int NOT NULL DEFAULT 0 CHECK (credit_applied_cents >= 0);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_cents int NOT NULL DEFAULT 0 CHECK (refunded_cents >= 0);
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  payment_id uuid NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+  amount_cents int NOT NULL CHECK (amount_cents > 0),
+  method text NOT NULL CHECK (method IN ('original_payment', 'account_credit')),
+  reason text,
+  provider_refund_ref text,
+  created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_payment_id ON refunds(payment_id);
@@ -0,0 +1,41 @@
+-- Migration 013: Registration seasons
+--
+-- Seasons group programs and control when registration is open. Residents or
+-- members may register from priority_opens_at; everyone else from
+-- registration_opens_at. Times are stored in UTC and entered in the tenant's
+-- timezone (tenant_settings.config->>'timezone').
+
+CREATE TABLE IF NOT EXISTS seasons (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  slug text NOT NULL,
+  starts_on date,
+  ends_on date,
+  registration_opens_at timestamptz,
+  registration_closes_at timestamptz,
+  priority_opens_at timestamptz,
+  priority_audience text NOT NULL DEFAULT 'residents_or_members'
+    CHECK (priority_audience IN ('residents', 'members', 'residents_or_members')),
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_seasons_tenant_id ON seasons(tenant_id);
+
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS season_id uuid REFERENCES seasons(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_programs_season_id ON programs(season_id);
+
+-- Turn existing free-text seasons into season records
+INSERT INTO seasons (tenant_id, name, slug)
+SELECT DISTINCT tenant_id, trim(season), trim(both '-' from regexp_replace(lower(trim(season)), '[^a-z0-9]+', '-', 'g'))
+FROM programs
+WHERE season IS NOT NULL AND trim(season) <> ''
+ON CONFLICT (tenant_id, slug) DO NOTHING;
+
+UPDATE programs p SET season_id = s.id
+FROM seasons s
+WHERE p.season_id IS NULL
+  AND s.tenant_id = p.tenant_id
+  AND s.slug = trim(both '-' from regexp_replace(lower(trim(p.season)), '[^a-z0-9]+', '-', 'g'));
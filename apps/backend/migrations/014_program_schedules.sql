@@ -0,0 +1,45 @@
+-- Migration 014: Recurring program schedules and sessions
+
+-- Weekly meeting pattern for a program, e.g. Tue/Thu 18:00-19:00 at a facility
+CREATE TABLE IF NOT EXISTS program_schedules (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  program_id uuid NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  facility_id uuid REFERENCES facilities(id) ON DELETE SET NULL,
+  weekdays int[] NOT NULL,
+  start_time time NOT NULL,
+  end_time time NOT NULL,
+  starts_on date NOT NULL,
+  ends_on date NOT NULL,
+  interval_weeks int NOT NULL DEFAULT 1 CHECK (interval_weeks >= 1),
+  exception_dates date[] NOT NULL DEFAULT '{}',
+  location_note text,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CHECK (end_time > start_time),
+  CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_schedules_program_id ON program_schedules(program_id);
+
+-- Generated occurrences. Sessions at a facility hold a reserved facility slot.
+CREATE TABLE IF NOT EXISTS program_sessions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  program_id uuid NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  schedule_id uuid REFERENCES program_schedules(id) ON DELETE SET NULL,
+  facility_id uuid REFERENCES facilities(id) ON DELETE SET NULL,
+  facility_slot_id uuid REFERENCES facility_slots(id) ON DELETE SET NULL,
+  -- false when the session took over an existing open slot
+  owns_slot bool NOT NULL DEFAULT true,
+  starts_at timestamptz NOT NULL,
+  ends_at timestamptz NOT NULL,
+  status text NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'cancelled')),
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(schedule_id, starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_sessions_program_id ON program_sessions(program_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_program_sessions_facility_slot_id ON program_sessions(facility_slot_id);
+
+CREATE INDEX IF NOT EXISTS idx_facility_slots_facility_range ON facility_slots(facility_id, starts_at, ends_at);
@@ -0,0 +1,15 @@
+-- Migration 015: Attendance records per program session
+
+CREATE TABLE IF NOT EXISTS attendance_records (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  session_id uuid NOT NULL REFERENCES program_sessions(id) ON DELETE CASCADE,
+  registration_id uuid NOT NULL REFERENCES program_registrations(id) ON DELETE CASCADE,
+  status text NOT NULL CHECK (status IN ('present', 'absent', 'late', 'excused')),
+  note text,
+  marked_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  marked_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE(session_id, registration_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_records_registration_id ON attendance_records(registration_id);
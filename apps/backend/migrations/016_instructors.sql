@@ -0,0 +1,63 @@
+-- Migration 016: Instructor profiles, certifications and assignments
+
+-- Staff or contractor who teaches programs. user_id links a STAFF login so
+-- the instructor can see their own rosters.
+CREATE TABLE IF NOT EXISTS instructors (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  name text NOT NULL,
+  email text,
+  phone text,
+  kind text NOT NULL DEFAULT 'staff' CHECK (kind IN ('staff', 'contractor')),
+  bio text,
+  active bool NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_instructors_tenant_id ON instructors(tenant_id);
+
+CREATE TABLE IF NOT EXISTS instructor_certifications (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  instructor_id uuid NOT NULL REFERENCES instructors(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  issuer text,
+  credential_number text,
+  issued_on date,
+  -- NULL when the certification does not expire
+  expires_on date,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_instructor_certifications_instructor_id ON instructor_certifications(instructor_id);
+
+-- Certifications an instructor must hold to teach the program, matched by name
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS required_certifications text[] NOT NULL DEFAULT '{}';
+
+-- Instructors teaching every session of a program
+CREATE TABLE IF NOT EXISTS program_instructors (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  program_id uuid NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  instructor_id uuid NOT NULL REFERENCES instructors(id) ON DELETE CASCADE,
+  role text NOT NULL DEFAULT 'lead' CHECK (role IN ('lead', 'assistant')),
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(program_id, instructor_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_instructors_instructor_id ON program_instructors(instructor_id);
+
+-- Instructors added to single sessions, e.g. substitutes
+CREATE TABLE IF NOT EXISTS session_instructors (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  session_id uuid NOT NULL REFERENCES program_sessions(id) ON DELETE CASCADE,
+  instructor_id uuid NOT NULL REFERENCES instructors(id) ON DELETE CASCADE,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(session_id, instructor_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_instructors_instructor_id ON session_instructors(instructor_id);
@@ -0,0 +1,13 @@
+-- Migration 017: Self-service registration cancellations
+
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS cancelled_at timestamptz;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS cancelled_by uuid REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS cancellation_reason text;
+
+CREATE INDEX IF NOT EXISTS idx_program_registrations_waitlist
+  ON program_registrations(program_id, registered_at) WHERE status = 'waitlisted';
+
+-- A cancelled participant may register again
+DROP INDEX IF EXISTS idx_program_registrations_participant;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_program_registrations_active_participant
+  ON program_registrations(program_id, user_id, lower(participant_name)) WHERE status <> 'cancelled';
@@ -0,0 +1,62 @@
+-- Migration 018: Registration and booking status transitions
+
+-- Program capacity. enrolled_count tracks registrations holding a place
+-- (pending, approved or completed); NULL capacity means unlimited.
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS capacity int CHECK (capacity IS NULL OR capacity >= 0);
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS enrolled_count int NOT NULL DEFAULT 0;
+
+UPDATE programs p SET enrolled_count = (
+  SELECT count(*) FROM program_registrations pr
+  WHERE pr.program_id = p.id AND pr.status IN ('pending', 'approved', 'completed')
+);
+
+-- Bookings only ever take these statuses. The status used to be free text,
+-- so known spellings are mapped first; any other value stops the migration
+-- for an operator to resolve rather than being guessed at.
+DO $$
+DECLARE
+  unknown text;
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'bookings_status_check') THEN
+    UPDATE bookings SET status = CASE lower(btrim(status))
+        WHEN 'confirmed' THEN 'approved'
+        WHEN 'accepted' THEN 'approved'
+        WHEN 'booked' THEN 'approved'
+        WHEN 'rejected' THEN 'declined'
+        WHEN 'denied' THEN 'declined'
+        WHEN 'canceled' THEN 'cancelled'
+        ELSE lower(btrim(status))
+      END
+    WHERE status NOT IN ('pending', 'approved', 'declined', 'cancelled');
+
+    SELECT string_agg(DISTINCT quote_literal(status), ', ') INTO unknown
+    FROM bookings WHERE status NOT IN ('pending', 'approved', 'declined', 'cancelled');
+    IF unknown IS NOT NULL THEN
+      RAISE EXCEPTION 'bookings have unrecognised statuses: %', unknown
+        USING HINT = 'Update them to pending, approved, declined or cancelled, then run the migration again.';
+    END IF;
+
+    ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+      CHECK (status IN ('pending', 'approved', 'declined', 'cancelled'));
+  END IF;
+END $$;
+
+-- Notification emails are queued in the same transaction as the change that
+-- triggers them and delivered by a background worker
+CREATE TABLE IF NOT EXISTS email_outbox (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  to_email text NOT NULL,
+  subject text NOT NULL,
+  body_html text NOT NULL,
+  dedupe_key text UNIQUE,
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+  attempts int NOT NULL DEFAULT 0,
+  last_error text,
+  send_after timestamptz NOT NULL DEFAULT now(),
+  sent_at timestamptz,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox(send_after) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_tenant_id ON email_outbox(tenant_id);
@@ -0,0 +1,42 @@
+-- Migration 019: Lottery registration
+
+-- Lottery mode for a program. Entries are taken between opens_at and
+-- closes_at, then a seeded draw assigns offers and waitlist positions.
+CREATE TABLE IF NOT EXISTS program_lotteries (
+  program_id uuid PRIMARY KEY REFERENCES programs(id) ON DELETE CASCADE,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  opens_at timestamptz NOT NULL,
+  closes_at timestamptz NOT NULL,
+  offer_hours int NOT NULL DEFAULT 48 CHECK (offer_hours > 0),
+  resident_weight numeric(6,2) NOT NULL DEFAULT 1 CHECK (resident_weight > 0),
+  sibling_weight numeric(6,2) NOT NULL DEFAULT 1 CHECK (sibling_weight > 0),
+  seed text,
+  algorithm text,
+  results jsonb,
+  drawn_at timestamptz,
+  drawn_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CHECK (closes_at > opens_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_lotteries_tenant_id ON program_lotteries(tenant_id);
+
+-- Lottery entries are registrations in the 'entered' status; winners are
+-- 'offered' a place until offer_expires_at
+ALTER TABLE program_registrations DROP CONSTRAINT IF EXISTS program_registrations_status_check;
+ALTER TABLE program_registrations ADD CONSTRAINT program_registrations_status_check
+  CHECK (status IN ('entered', 'offered', 'pending', 'approved', 'waitlisted', 'cancelled', 'completed'));
+
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS lottery_rank int;
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS lottery_weight numeric(8,2);
+ALTER TABLE program_registrations ADD COLUMN IF NOT EXISTS offer_expires_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_program_registrations_offers
+  ON program_registrations(offer_expires_at) WHERE status = 'offered';
+
+-- Offers hold a place against capacity
+UPDATE programs p SET enrolled_count = (
+  SELECT count(*) FROM program_registrations pr
+  WHERE pr.program_id = p.id AND pr.status IN ('offered', 'pending', 'approved', 'completed')
+);
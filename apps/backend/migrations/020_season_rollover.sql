@@ -0,0 +1,9 @@
+-- Migration 020: Season rollover
+
+-- Programs and events copied into a new season remember where they came
+-- from, so a rollover is not repeated into the same season
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS rolled_over_from uuid REFERENCES programs(id) ON DELETE SET NULL;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS rolled_over_from uuid REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_programs_rolled_over_from ON programs(rolled_over_from);
+CREATE INDEX IF NOT EXISTS idx_events_rolled_over_from ON events(rolled_over_from);
@@ -0,0 +1,68 @@
+-- Migration 021: Digital waivers and e-signatures
+
+-- Files generated by the server, such as signed waiver PDFs, are kept with
+-- their metadata
+ALTER TABLE media_assets ADD COLUMN IF NOT EXISTS data bytea;
+
+CREATE TABLE IF NOT EXISTS waivers (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  active bool NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_waivers_tenant_id ON waivers(tenant_id);
+
+-- Waiver text is never edited in place; each change is a new version.
+-- body_hash is the SHA-256 of the title and body as presented to signers.
+CREATE TABLE IF NOT EXISTS waiver_versions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  waiver_id uuid NOT NULL REFERENCES waivers(id) ON DELETE CASCADE,
+  version int NOT NULL,
+  title text NOT NULL,
+  body text NOT NULL,
+  body_hash text NOT NULL,
+  created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(waiver_id, version)
+);
+
+-- What a waiver applies to. subject_key is a program or event id, or a
+-- facility type such as 'pavilion'.
+CREATE TABLE IF NOT EXISTS waiver_attachments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  waiver_id uuid NOT NULL REFERENCES waivers(id) ON DELETE CASCADE,
+  subject_type text NOT NULL CHECK (subject_type IN ('program', 'event', 'facility_type')),
+  subject_key text NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(waiver_id, subject_type, subject_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waiver_attachments_subject ON waiver_attachments(tenant_id, subject_type, subject_key);
+
+-- A signature on one version, for a registration or booking
+CREATE TABLE IF NOT EXISTS waiver_signatures (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  waiver_id uuid NOT NULL REFERENCES waivers(id) ON DELETE CASCADE,
+  waiver_version_id uuid NOT NULL REFERENCES waiver_versions(id) ON DELETE CASCADE,
+  subject_type text NOT NULL CHECK (subject_type IN ('program_registration', 'event_registration', 'booking')),
+  subject_id uuid NOT NULL,
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  signer_name text NOT NULL,
+  signer_email text,
+  participant_name text,
+  ip_address text,
+  user_agent text,
+  document_hash text NOT NULL,
+  media_id uuid REFERENCES media_assets(id) ON DELETE SET NULL,
+  signed_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE(waiver_version_id, subject_type, subject_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waiver_signatures_subject ON waiver_signatures(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_waiver_signatures_user_id ON waiver_signatures(user_id);
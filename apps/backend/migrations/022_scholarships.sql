@@ -0,0 +1,56 @@
+-- Migration 022: Financial assistance and scholarships
+
+-- One scholarship fund per tenant. The budget resets each fiscal year, which
+-- starts on the first day of fiscal_year_start_month.
+CREATE TABLE IF NOT EXISTS scholarship_funds (
+  tenant_id uuid PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  name text NOT NULL DEFAULT 'Financial Assistance',
+  description text,
+  annual_budget_cents int NOT NULL DEFAULT 0 CHECK (annual_budget_cents >= 0),
+  fiscal_year_start_month int NOT NULL DEFAULT 1 CHECK (fiscal_year_start_month BETWEEN 1 AND 12),
+  accepting_applications bool NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+-- A household's application. Approved applications carry the award: a
+-- percentage off program prices (optionally capped) or a fixed amount.
+CREATE TABLE IF NOT EXISTS scholarship_applications (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status text NOT NULL DEFAULT 'submitted'
+    CHECK (status IN ('submitted', 'approved', 'denied', 'withdrawn', 'revoked')),
+  household_size int CHECK (household_size IS NULL OR household_size > 0),
+  annual_income_cents int CHECK (annual_income_cents IS NULL OR annual_income_cents >= 0),
+  statement text,
+  document_ids uuid[] NOT NULL DEFAULT '{}',
+  award_kind text CHECK (award_kind IN ('percent', 'fixed')),
+  award_percent numeric(5,2) CHECK (award_percent IS NULL OR (award_percent > 0 AND award_percent <= 100)),
+  award_amount_cents int CHECK (award_amount_cents IS NULL OR award_amount_cents > 0),
+  award_expires_on date,
+  decision_note text,
+  decided_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  decided_at timestamptz,
+  submitted_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scholarship_applications_tenant ON scholarship_applications(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_scholarship_applications_user ON scholarship_applications(user_id);
+
+-- Each registration discounted by an award. Cancelling the registration
+-- releases the amount back to the award and the fund.
+CREATE TABLE IF NOT EXISTS scholarship_redemptions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  application_id uuid NOT NULL REFERENCES scholarship_applications(id) ON DELETE CASCADE,
+  registration_id uuid NOT NULL UNIQUE REFERENCES program_registrations(id) ON DELETE CASCADE,
+  amount_cents int NOT NULL CHECK (amount_cents > 0),
+  fiscal_year int NOT NULL,
+  released_at timestamptz,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scholarship_redemptions_fund ON scholarship_redemptions(tenant_id, fiscal_year) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_scholarship_redemptions_application ON scholarship_redemptions(application_id);
@@ -0,0 +1,36 @@
+-- Migration 023: Event RSVPs
+
+-- Guests can RSVP without an account; they cancel with the token they were sent
+ALTER TABLE event_registrations ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS guest_name text;
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS guest_email text;
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS cancel_token text;
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS cancelled_at timestamptz;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'event_registrations_party_check') THEN
+    ALTER TABLE event_registrations ADD CONSTRAINT event_registrations_party_check CHECK (attendee_count > 0);
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'event_registrations_registrant_check') THEN
+    ALTER TABLE event_registrations ADD CONSTRAINT event_registrations_registrant_check
+      CHECK (user_id IS NOT NULL OR guest_email IS NOT NULL);
+  END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_registrations_cancel_token
+  ON event_registrations(cancel_token) WHERE cancel_token IS NOT NULL;
+
+-- One active RSVP per account or guest email per event
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_registrations_active_user
+  ON event_registrations(event_id, user_id) WHERE status <> 'cancelled' AND user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_registrations_active_guest
+  ON event_registrations(event_id, lower(guest_email)) WHERE status <> 'cancelled' AND user_id IS NULL;
+
+-- registered_count is the number of people in active RSVPs
+UPDATE events e SET registered_count = COALESCE((
+  SELECT SUM(er.attendee_count) FROM event_registrations er
+  WHERE er.event_id = e.id AND er.status IN ('registered', 'attended')
+), 0);
+
+ALTER TABLE events ALTER COLUMN registered_count SET NOT NULL;
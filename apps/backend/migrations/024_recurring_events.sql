@@ -0,0 +1,48 @@
+-- Migration 024: Recurring events
+
+-- A recurring event is a series: starts_at/ends_at are the first
+-- occurrence, rrule is an RFC 5545 RRULE value and exdates are the
+-- occurrence starts that are skipped. Splitting a series ("this and
+-- following") creates a new event that points at the original's series.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS rrule text;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS exdates timestamptz[] NOT NULL DEFAULT '{}';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS series_id uuid REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_series_id ON events(series_id);
+
+-- One row per occurrence that differs from its series or has RSVPs. The
+-- occurrence is identified by the start the rule generates for it
+-- (RECURRENCE-ID); NULL columns inherit from the series.
+CREATE TABLE IF NOT EXISTS event_occurrences (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  event_id uuid NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+  occurrence_start timestamptz NOT NULL,
+  starts_at timestamptz,
+  ends_at timestamptz,
+  title text,
+  description text,
+  location text,
+  capacity int CHECK (capacity IS NULL OR capacity >= 0),
+  cancelled bool NOT NULL DEFAULT false,
+  registered_count int NOT NULL DEFAULT 0 CHECK (registered_count >= 0),
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE (event_id, occurrence_start),
+  CHECK ((starts_at IS NULL) = (ends_at IS NULL)),
+  CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_occurrences_tenant ON event_occurrences(tenant_id);
+
+-- RSVPs to a recurring event name the occurrence
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS occurrence_start timestamptz;
+
+DROP INDEX IF EXISTS idx_event_registrations_active_user;
+DROP INDEX IF EXISTS idx_event_registrations_active_guest;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_registrations_active_user
+  ON event_registrations(event_id, COALESCE(occurrence_start, '-infinity'), user_id)
+  WHERE status <> 'cancelled' AND user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_registrations_active_guest
+  ON event_registrations(event_id, COALESCE(occurrence_start, '-infinity'), lower(guest_email))
+  WHERE status <> 'cancelled' AND user_id IS NULL;
@@ -0,0 +1,22 @@
+-- Migration 025: Calendar feed subscriptions
+
+-- A private .ics feed of one facility's schedule or one staff member's
+-- teaching schedule. Subscribers authenticate with the token in the URL;
+-- only its SHA-256 hash is stored, so a lost token is revoked and replaced.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('facility', 'staff')),
+  facility_id uuid REFERENCES facilities(id) ON DELETE CASCADE,
+  instructor_id uuid REFERENCES instructors(id) ON DELETE CASCADE,
+  label text,
+  token_hash text NOT NULL UNIQUE,
+  created_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz DEFAULT now(),
+  last_accessed_at timestamptz,
+  revoked_at timestamptz,
+  CHECK ((kind = 'facility') = (facility_id IS NOT NULL)),
+  CHECK ((kind = 'staff') = (instructor_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_feeds_tenant ON calendar_feeds(tenant_id);
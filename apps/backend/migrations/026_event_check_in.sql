@@ -0,0 +1,41 @@
+-- Migration 026: Event check-in
+
+-- How many of the party have been checked in; an RSVP becomes attended at
+-- its first check-in
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS checked_in_count int NOT NULL DEFAULT 0;
+ALTER TABLE event_registrations ADD COLUMN IF NOT EXISTS checked_in_at timestamptz;
+
+-- RSVPs marked attended before check-in existed had everyone there
+UPDATE event_registrations
+SET checked_in_count = attendee_count, checked_in_at = COALESCE(checked_in_at, updated_at)
+WHERE status = 'attended' AND checked_in_count = 0;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'event_registrations_checked_in_check') THEN
+    ALTER TABLE event_registrations ADD CONSTRAINT event_registrations_checked_in_check
+      CHECK (checked_in_count >= 0 AND checked_in_count <= attendee_count);
+  END IF;
+END $$;
+
+-- Every accepted scan, including duplicates. Scans synced from a device
+-- that was offline carry the device's id for the scan, so a retried sync
+-- does not check anyone in twice.
+CREATE TABLE IF NOT EXISTS event_check_ins (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  registration_id uuid NOT NULL REFERENCES event_registrations(id) ON DELETE CASCADE,
+  event_id uuid NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+  occurrence_start timestamptz,
+  count int NOT NULL CHECK (count >= 0),
+  result text NOT NULL CHECK (result IN ('checked_in', 'partial', 'duplicate')),
+  client_scan_id text,
+  scanned_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  scanned_at timestamptz NOT NULL DEFAULT now(),
+  recorded_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_check_ins_event ON event_check_ins(event_id, occurrence_start);
+CREATE INDEX IF NOT EXISTS idx_event_check_ins_registration_id ON event_check_ins(registration_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_check_ins_client_scan
+  ON event_check_ins(tenant_id, client_scan_id) WHERE client_scan_id IS NOT NULL;
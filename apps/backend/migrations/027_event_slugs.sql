@@ -0,0 +1,30 @@
+-- Migration 027: Every event has a slug, unique per tenant, for shareable URLs
+
+-- Events without one get their title's slug, or the title's slug and the
+-- start of their id where that is taken
+WITH missing AS (
+  SELECT id, tenant_id,
+         COALESCE(NULLIF(trim(both '-' from regexp_replace(lower(title), '[^a-z0-9]+', '-', 'g')), ''), 'event') AS base
+  FROM events
+  WHERE slug IS NULL OR slug = ''
+), numbered AS (
+  SELECT id, tenant_id, base, row_number() OVER (PARTITION BY tenant_id, base ORDER BY id) AS n
+  FROM missing
+)
+UPDATE events e
+SET slug = CASE
+  WHEN m.n = 1 AND NOT EXISTS (SELECT 1 FROM events x WHERE x.tenant_id = m.tenant_id AND x.slug = m.base)
+    THEN m.base
+  ELSE m.base || '-' || left(e.id::text, 8)
+END
+FROM numbered m
+WHERE e.id = m.id;
+
+ALTER TABLE events ALTER COLUMN slug SET NOT NULL;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'events_slug_check') THEN
+    ALTER TABLE events ADD CONSTRAINT events_slug_check CHECK (slug <> '');
+  END IF;
+END $$;
@@ -0,0 +1,13 @@
+-- Migration 028: Scheduled reminders
+
+-- Reminders are queued in the outbox ahead of time. reminder_for names what
+-- the reminder is about (e.g. "booking:<id>") so pending reminders can be
+-- cancelled when it is cancelled or moves; cancelled rows are never sent.
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS reminder_for text;
+
+ALTER TABLE email_outbox DROP CONSTRAINT IF EXISTS email_outbox_status_check;
+ALTER TABLE email_outbox ADD CONSTRAINT email_outbox_status_check
+  CHECK (status IN ('pending', 'sent', 'failed', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_reminder_for
+  ON email_outbox(tenant_id, reminder_for) WHERE status = 'pending' AND reminder_for IS NOT NULL;
@@ -0,0 +1,35 @@
+-- Migration 029: Weekly availability templates that generate facility slots
+
+-- Operating hours for a facility, e.g. Mon-Fri 06:00-22:00 in 60 minute
+-- slots with 15 minutes between them. valid_from and valid_until limit a
+-- template to a season; either may be open-ended.
+CREATE TABLE IF NOT EXISTS facility_availability_templates (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  facility_id uuid NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+  name text,
+  weekdays int[] NOT NULL,
+  open_time time NOT NULL,
+  close_time time NOT NULL,
+  slot_minutes int NOT NULL CHECK (slot_minutes BETWEEN 5 AND 1440),
+  buffer_minutes int NOT NULL DEFAULT 0 CHECK (buffer_minutes BETWEEN 0 AND 1440),
+  valid_from date,
+  valid_until date,
+  exception_dates date[] NOT NULL DEFAULT '{}',
+  -- how many days ahead slots are kept generated
+  horizon_days int NOT NULL DEFAULT 60 CHECK (horizon_days BETWEEN 1 AND 180),
+  active bool NOT NULL DEFAULT true,
+  generated_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CHECK (close_time > open_time),
+  CHECK (valid_until IS NULL OR valid_from IS NULL OR valid_until >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_facility_availability_templates_facility_id ON facility_availability_templates(facility_id);
+
+-- Slots generated from a template. Booked and reserved slots keep their
+-- template_id but are never changed by regeneration.
+ALTER TABLE facility_slots ADD COLUMN IF NOT EXISTS template_id uuid REFERENCES facility_availability_templates(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_facility_slots_template_id ON facility_slots(template_id, starts_at) WHERE template_id IS NOT NULL;
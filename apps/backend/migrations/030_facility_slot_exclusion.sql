@@ -0,0 +1,29 @@
+-- Migration 030: Slots at a facility cannot overlap
+
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Existing overlapping or empty slots are exempt until they are cleaned up;
+-- GET /api/facility-slots/overlaps lists them. Moving an exempt slot, or
+-- removing what it overlaps, brings it under the constraint.
+ALTER TABLE facility_slots ADD COLUMN IF NOT EXISTS legacy_overlap bool NOT NULL DEFAULT false;
+
+UPDATE facility_slots a SET legacy_overlap = true
+WHERE a.ends_at <= a.starts_at
+   OR EXISTS (
+     SELECT 1 FROM facility_slots b
+     WHERE b.facility_id = a.facility_id AND b.id <> a.id
+       AND b.starts_at < a.ends_at AND b.ends_at > a.starts_at
+   );
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'facility_slots_range_check') THEN
+    ALTER TABLE facility_slots ADD CONSTRAINT facility_slots_range_check
+      CHECK (legacy_overlap OR ends_at > starts_at);
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'facility_slots_no_overlap') THEN
+    ALTER TABLE facility_slots ADD CONSTRAINT facility_slots_no_overlap
+      EXCLUDE USING gist (facility_id WITH =, tstzrange(starts_at, ends_at) WITH &&)
+      WHERE (NOT legacy_overlap);
+  END IF;
+END $$;
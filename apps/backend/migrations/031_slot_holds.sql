@@ -0,0 +1,23 @@
+-- Migration 031: Bookings claim a concrete slot, held during checkout
+
+-- A held slot is claimed by a booking in checkout until held_until; a
+-- booked slot belongs to booking_id. Slots booked before this migration
+-- are linked to their approved booking where there is one.
+ALTER TABLE facility_slots ADD COLUMN IF NOT EXISTS booking_id uuid REFERENCES bookings(id) ON DELETE SET NULL;
+ALTER TABLE facility_slots ADD COLUMN IF NOT EXISTS held_until timestamptz;
+
+UPDATE facility_slots fs SET booking_id = b.id
+FROM bookings b
+WHERE fs.status = 'booked' AND fs.booking_id IS NULL
+  AND b.resource_type = 'facility_slot' AND b.resource_id = fs.id AND b.status = 'approved';
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'facility_slots_hold_check') THEN
+    ALTER TABLE facility_slots ADD CONSTRAINT facility_slots_hold_check
+      CHECK (status <> 'held' OR (held_until IS NOT NULL AND booking_id IS NOT NULL));
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_facility_slots_held_until ON facility_slots(held_until) WHERE status = 'held';
+CREATE INDEX IF NOT EXISTS idx_facility_slots_booking_id ON facility_slots(booking_id) WHERE booking_id IS NOT NULL;
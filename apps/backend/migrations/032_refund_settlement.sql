@@ -0,0 +1,14 @@
+-- Migration 032: Refunds to the payment provider settle after they are recorded
+
+-- A refund to the original payment is committed as pending before the
+-- provider is called, and the refund's id is the provider's idempotency
+-- key, so a failure on either side is retried without refunding twice.
+-- Pending refunds reserve their amount in payments.refunded_cents; failed
+-- refunds release it.
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'succeeded'
+  CHECK (status IN ('pending', 'succeeded', 'failed'));
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS attempts int NOT NULL DEFAULT 0;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS last_error text;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS settled_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_refunds_pending ON refunds(created_at) WHERE status = 'pending';
@@ -0,0 +1,9 @@
+-- Migration 033: Payments that cannot simply be settled
+
+-- refund_due: the provider collected money for a checkout that had already
+-- been cancelled or had failed. It is refunded automatically and becomes
+-- refunded. review: the provider reported a different amount than was
+-- charged; staff decide what to do. Neither is posted to the ledger.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+  CHECK (status IN ('pending', 'paid', 'failed', 'cancelled', 'refund_due', 'refunded', 'review'));
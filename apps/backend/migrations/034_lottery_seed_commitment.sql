@@ -0,0 +1,10 @@
+-- Migration 034: Lottery seeds are committed to before the draw
+
+-- A seed chosen by staff is stored when the lottery is set up, before
+-- entries open, and only seed_hash (hex SHA-256 of the seed) is shown until
+-- the draw reveals the seed. Lotteries without one get a seed generated at
+-- the draw.
+ALTER TABLE program_lotteries ADD COLUMN IF NOT EXISTS seed_hash text;
+
+UPDATE program_lotteries SET seed_hash = encode(sha256(convert_to(seed, 'UTF8')), 'hex')
+WHERE seed IS NOT NULL AND seed_hash IS NULL;
@@ -0,0 +1,9 @@
+-- Migration 035: Lottery seeds are derived from the closed entry set
+
+-- The draw's seed is now the HMAC, keyed by LOTTERY_SECRET, of the program
+-- and entries_digest (SHA-256 of the sorted entry ids), so it is unknown
+-- until the draw and tied to the entries drawn. Seeds set by staff before a
+-- draw are no longer used.
+ALTER TABLE program_lotteries ADD COLUMN IF NOT EXISTS entries_digest text;
+UPDATE program_lotteries SET seed = NULL WHERE drawn_at IS NULL;
+ALTER TABLE program_lotteries DROP COLUMN IF EXISTS seed_hash;
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -25,10 +26,25 @@ type Config struct {
 	SMTPPort  string
 	FromEmail string
 
+	// Payments
+	PaymentsProvider    string
+	PaymentsCurrency    string
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	FakePaymentsSecret  string
+
+	// Tickets
+	TicketSecret string
+
+	// Lotteries
+	LotterySecret string
+
 	// Server
 	Port             string
+	APIBaseURL       string
 	PublicBaseDomain string
 	GinMode          string
+	TrustedProxies   string
 
 	// Demo (for seeding)
 	DemoAdminEmail    string
@@ -49,9 +65,18 @@ func Load() *Config {
 		SMTPHost:          getEnv("SMTP_HOST", "localhost"),
 		SMTPPort:          getEnv("SMTP_PORT", "1025"),
 		FromEmail:         getEnv("FROM_EMAIL", "no-reply@rechub.app"),
+		PaymentsProvider:    getEnv("PAYMENTS_PROVIDER", ""),
+		PaymentsCurrency:    getEnv("PAYMENTS_CURRENCY", "usd"),
+		StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		FakePaymentsSecret:  getEnv("FAKE_PAYMENTS_SECRET", "fake-webhook-secret"),
+		TicketSecret:      getEnv("TICKET_SECRET", "dev-ticket-secret-change-in-production"),
+		LotterySecret:     getEnv("LOTTERY_SECRET", "dev-lottery-secret-change-in-production"),
 		Port:              getEnv("PORT", "8000"),
+		APIBaseURL:        getEnv("API_BASE_URL", "http://localhost:8000"),
 		PublicBaseDomain:  getEnv("PUBLIC_BASE_DOMAIN", "local.rechub"),
 		GinMode:           getEnv("GIN_MODE", "debug"),
+		TrustedProxies:    getEnv("TRUSTED_PROXIES", ""),
 		DemoAdminEmail:    getEnv("DEMO_ADMIN_EMAIL", "admin@demo.local"),
 		DemoAdminPassword: getEnv("DEMO_ADMIN_PASSWORD", "DemoPass123!"),
 	}
@@ -71,3 +96,15 @@ func (c *Config) DatabaseURL() string {
 func (c *Config) ServerAddress() string {
 	return fmt.Sprintf(":%s", c.Port)
 }
+
+// TrustedProxyList returns the comma-separated TRUSTED_PROXIES addresses
+// and CIDRs. With none, forwarded client IP headers are ignored.
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
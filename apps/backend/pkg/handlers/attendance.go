@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/pdf"
+)
+
+// ============ Attendance & Rosters ============
+
+// Attendance statuses for a participant at one session
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// rosterStatuses are the registration statuses that put a participant on a
+// program's roster; waitlisted and cancelled registrations are left off
+var rosterStatuses = []string{"pending", "approved", "completed"}
+
+func validAttendanceStatus(s string) bool {
+	switch s {
+	case AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+type AttendanceMark struct {
+	RegistrationID string  `json:"registration_id" binding:"required"`
+	Status         string  `json:"status" binding:"required"`
+	Note           *string `json:"note"`
+}
+
+// AttendanceRequest marks attendance for a session. DefaultStatus, when set,
+// is applied to every roster participant not listed in Records and not
+// already marked, e.g. "absent" after ticking off who showed up.
+type AttendanceRequest struct {
+	Records       []AttendanceMark `json:"records"`
+	DefaultStatus string           `json:"default_status"`
+}
+
+type rosterEntry struct {
+	RegistrationID        string     `json:"registration_id"`
+	ParticipantName       string     `json:"participant_name"`
+	ParticipantAge        *int       `json:"participant_age"`
+	ContactEmail          string     `json:"contact_email"`
+	ContactPhone          *string    `json:"contact_phone"`
+	EmergencyContactName  *string    `json:"emergency_contact_name"`
+	EmergencyContactPhone *string    `json:"emergency_contact_phone"`
+	Notes                 *string    `json:"notes"`
+	RegistrationStatus    string     `json:"registration_status"`
+	Attendance            *string    `json:"attendance"`
+	AttendanceNote        *string    `json:"attendance_note"`
+	MarkedAt              *time.Time `json:"marked_at"`
+}
+
+type programSession struct {
+	ID           string
+	ProgramID    string
+	ProgramTitle string
+	StartsAt     time.Time
+	EndsAt       time.Time
+	Status       string
+	FacilityName *string
+}
+
+func canTakeAttendance(role string) bool {
+	return role == "OWNER" || role == "ADMIN" || role == "STAFF"
+}
+
+// loadSession returns a tenant's program session with its program title
+func (h *Handler) loadSession(ctx context.Context, q dbtx, tenantID, sessionID string) (*programSession, error) {
+	var s programSession
+	err := q.QueryRow(ctx,
+		`SELECT s.id, s.program_id, p.title, s.starts_at, s.ends_at, s.status, f.name
+		 FROM program_sessions s
+		 JOIN programs p ON s.program_id = p.id
+		 LEFT JOIN facilities f ON s.facility_id = f.id
+		 WHERE s.id = $1 AND s.tenant_id = $2`,
+		sessionID, tenantID).Scan(&s.ID, &s.ProgramID, &s.ProgramTitle, &s.StartsAt, &s.EndsAt, &s.Status, &s.FacilityName)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// loadRoster returns the program's roster, with attendance for sessionID
+// when it is not nil
+func (h *Handler) loadRoster(ctx context.Context, q dbtx, tenantID, programID string, sessionID *string) ([]rosterEntry, error) {
+	rows, err := q.Query(ctx,
+		`SELECT pr.id, pr.participant_name, pr.participant_age, u.email, u.phone,
+		        pr.emergency_contact_name, pr.emergency_contact_phone, pr.notes, pr.status,
+		        a.status, a.note, a.marked_at
+		 FROM program_registrations pr
+		 JOIN users u ON pr.user_id = u.id
+		 LEFT JOIN attendance_records a ON a.registration_id = pr.id AND a.session_id = $3
+		 WHERE pr.tenant_id = $1 AND pr.program_id = $2 AND pr.status = ANY($4)
+		 ORDER BY lower(pr.participant_name), pr.registered_at`,
+		tenantID, programID, sessionID, rosterStatuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roster := []rosterEntry{}
+	for rows.Next() {
+		var e rosterEntry
+		if err := rows.Scan(&e.RegistrationID, &e.ParticipantName, &e.ParticipantAge, &e.ContactEmail, &e.ContactPhone,
+			&e.EmergencyContactName, &e.EmergencyContactPhone, &e.Notes, &e.RegistrationStatus,
+			&e.Attendance, &e.AttendanceNote, &e.MarkedAt); err != nil {
+			return nil, err
+		}
+		roster = append(roster, e)
+	}
+	return roster, rows.Err()
+}
+
+func sessionResponse(s *programSession) gin.H {
+	return gin.H{
+		"id":            s.ID,
+		"program_id":    s.ProgramID,
+		"program_title": s.ProgramTitle,
+		"starts_at":     s.StartsAt,
+		"ends_at":       s.EndsAt,
+		"status":        s.Status,
+		"facility_name": s.FacilityName,
+	}
+}
+
+// respondSessionAttendance writes the session and its roster with attendance
+func (h *Handler) respondSessionAttendance(c *gin.Context, ctx context.Context, tenantID string, s *programSession) {
+	roster, err := h.loadRoster(ctx, h.DB, tenantID, s.ProgramID, &s.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	counts := map[string]int{AttendancePresent: 0, AttendanceAbsent: 0, AttendanceLate: 0, AttendanceExcused: 0}
+	unmarked := 0
+	for _, e := range roster {
+		if e.Attendance == nil {
+			unmarked++
+			continue
+		}
+		counts[*e.Attendance]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":  sessionResponse(s),
+		"roster":   roster,
+		"counts":   counts,
+		"unmarked": unmarked,
+	})
+}
+
+func (h *Handler) GetSessionAttendance(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	session, err := h.loadSession(ctx, h.DB, tenantID, c.Param("id"))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !h.canViewRoster(ctx, claims, session.ProgramID, &session.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not assigned to this session"})
+		return
+	}
+
+	h.respondSessionAttendance(c, ctx, tenantID, session)
+}
+
+// MarkSessionAttendance records attendance for several participants at once
+func (h *Handler) MarkSessionAttendance(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req AttendanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Records) == 0 && req.DefaultStatus == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "records or default_status is required"})
+		return
+	}
+	if req.DefaultStatus != "" && !validAttendanceStatus(req.DefaultStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_status must be present, absent, late or excused"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	session, err := h.loadSession(ctx, tx, tenantID, c.Param("id"))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !h.canViewRoster(ctx, claims, session.ProgramID, &session.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not assigned to this session"})
+		return
+	}
+	if session.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "session is cancelled"})
+		return
+	}
+
+	roster, err := h.loadRoster(ctx, tx, tenantID, session.ProgramID, &session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	onRoster := make(map[string]rosterEntry, len(roster))
+	for _, e := range roster {
+		onRoster[e.RegistrationID] = e
+	}
+
+	marks := map[string]AttendanceMark{}
+	errs := []FormFieldError{}
+	for i, m := range req.Records {
+		field := fmt.Sprintf("records[%d]", i)
+		if _, ok := onRoster[m.RegistrationID]; !ok {
+			errs = append(errs, FormFieldError{field, "registration is not on this program's roster"})
+			continue
+		}
+		if !validAttendanceStatus(m.Status) {
+			errs = append(errs, FormFieldError{field, "status must be present, absent, late or excused"})
+			continue
+		}
+		marks[m.RegistrationID] = m
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attendance records", "fields": errs})
+		return
+	}
+
+	if req.DefaultStatus != "" {
+		for _, e := range roster {
+			if _, listed := marks[e.RegistrationID]; !listed && e.Attendance == nil {
+				marks[e.RegistrationID] = AttendanceMark{RegistrationID: e.RegistrationID, Status: req.DefaultStatus}
+			}
+		}
+	}
+
+	for _, m := range marks {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO attendance_records (tenant_id, session_id, registration_id, status, note, marked_by)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 ON CONFLICT (session_id, registration_id)
+			 DO UPDATE SET status = EXCLUDED.status, note = EXCLUDED.note,
+			               marked_by = EXCLUDED.marked_by, marked_at = now()`,
+			tenantID, session.ID, m.RegistrationID, m.Status, m.Note, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save attendance"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	h.respondSessionAttendance(c, ctx, tenantID, session)
+}
+
+type attendanceCounts struct {
+	Present  int      `json:"present"`
+	Absent   int      `json:"absent"`
+	Late     int      `json:"late"`
+	Excused  int      `json:"excused"`
+	Unmarked int      `json:"unmarked"`
+	Rate     *float64 `json:"attendance_rate"`
+}
+
+// finish derives unmarked sessions and the attendance rate: sessions
+// attended (present or late) over sessions held, not counting excused ones
+func (a *attendanceCounts) finish(held int) {
+	a.Unmarked = held - a.Present - a.Absent - a.Late - a.Excused
+	if a.Unmarked < 0 {
+		a.Unmarked = 0
+	}
+	if expected := held - a.Excused; expected > 0 {
+		rate := float64(a.Present+a.Late) / float64(expected)
+		a.Rate = &rate
+	}
+}
+
+// GetProgramAttendanceSummary reports attendance per participant and for the
+// program as a whole over sessions held in a date range
+func (h *Handler) GetProgramAttendanceSummary(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+	if !h.canViewRoster(ctx, claims, programID, nil) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not assigned to this program"})
+		return
+	}
+
+	// Defaults to every session held so far
+	from := time.Time{}
+	to := time.Now()
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339"})
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339"})
+			return
+		}
+		to = t
+	}
+
+	var held int
+	err := h.DB.QueryRow(ctx,
+		`SELECT count(*) FROM program_sessions
+		 WHERE tenant_id = $1 AND program_id = $2 AND status = 'scheduled' AND starts_at >= $3 AND starts_at < $4`,
+		tenantID, programID, from, to).Scan(&held)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	// Participants who later left the program still appear if they have
+	// attendance in the range
+	rows, err := h.DB.Query(ctx,
+		`SELECT pr.id, pr.participant_name, pr.status,
+		        count(a.id) FILTER (WHERE a.status = 'present'),
+		        count(a.id) FILTER (WHERE a.status = 'absent'),
+		        count(a.id) FILTER (WHERE a.status = 'late'),
+		        count(a.id) FILTER (WHERE a.status = 'excused')
+		 FROM program_registrations pr
+		 LEFT JOIN (attendance_records a
+		            JOIN program_sessions s ON a.session_id = s.id
+		                 AND s.status = 'scheduled' AND s.starts_at >= $3 AND s.starts_at < $4)
+		        ON a.registration_id = pr.id
+		 WHERE pr.tenant_id = $1 AND pr.program_id = $2
+		 GROUP BY pr.id, pr.participant_name, pr.status
+		 HAVING pr.status = ANY($5) OR count(a.id) > 0
+		 ORDER BY lower(pr.participant_name)`,
+		tenantID, programID, from, to, rosterStatuses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	var totals attendanceCounts
+	participants := []gin.H{}
+	for rows.Next() {
+		var id, name, status string
+		var a attendanceCounts
+		if err := rows.Scan(&id, &name, &status, &a.Present, &a.Absent, &a.Late, &a.Excused); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		a.finish(held)
+
+		totals.Present += a.Present
+		totals.Absent += a.Absent
+		totals.Late += a.Late
+		totals.Excused += a.Excused
+		totals.Unmarked += a.Unmarked
+
+		participants = append(participants, gin.H{
+			"registration_id":     id,
+			"participant_name":    name,
+			"registration_status": status,
+			"attendance":          a,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	// Program rate is over participant-sessions rather than sessions
+	if expected := totals.Present + totals.Absent + totals.Late + totals.Unmarked; expected > 0 {
+		rate := float64(totals.Present+totals.Late) / float64(expected)
+		totals.Rate = &rate
+	}
+
+	response := gin.H{
+		"program_id":    programID,
+		"sessions_held": held,
+		"participants":  participants,
+		"totals":        totals,
+		"to":            to,
+	}
+	if !from.IsZero() {
+		response["from"] = from
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportProgramRoster downloads a printable roster as CSV or PDF, with
+// emergency contacts. With session_id the roster carries that session's
+// attendance; otherwise the attendance column is left blank to fill in.
+func (h *Handler) ExportProgramRoster(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or pdf"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+
+	var programTitle string
+	err := h.DB.QueryRow(ctx,
+		`SELECT title FROM programs WHERE id = $1 AND tenant_id = $2`,
+		programID, tenantID).Scan(&programTitle)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+
+	var session *programSession
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		session, err = h.loadSession(ctx, h.DB, tenantID, sessionID)
+		if err != nil || session.ProgramID != programID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+	}
+
+	var sessionID *string
+	if session != nil {
+		sessionID = &session.ID
+	}
+	if !h.canViewRoster(ctx, claims, programID, sessionID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not assigned to this program"})
+		return
+	}
+	roster, err := h.loadRoster(ctx, h.DB, tenantID, programID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	filename := "roster-" + slugify(programTitle)
+	if session != nil {
+		filename += "-" + session.StartsAt.In(loc).Format("2006-01-02")
+	}
+
+	header := []string{
+		"participant_name", "participant_age", "contact_email", "contact_phone",
+		"emergency_contact_name", "emergency_contact_phone", "notes", "registration_status", "attendance",
+	}
+	records := make([][]string, 0, len(roster))
+	for _, e := range roster {
+		records = append(records, []string{
+			e.ParticipantName, formatOptionalInt(e.ParticipantAge), e.ContactEmail, derefString(e.ContactPhone),
+			derefString(e.EmergencyContactName), derefString(e.EmergencyContactPhone), derefString(e.Notes),
+			e.RegistrationStatus, derefString(e.Attendance),
+		})
+	}
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write(header)
+		for _, r := range records {
+			_ = w.Write(r)
+		}
+		w.Flush()
+		return
+	}
+
+	subtitle := []string{fmt.Sprintf("%d participants", len(roster))}
+	if session != nil {
+		line := session.StartsAt.In(loc).Format("Monday, January 2, 2006 3:04 PM") + "–" + session.EndsAt.In(loc).Format("3:04 PM")
+		if session.FacilityName != nil {
+			line += " at " + *session.FacilityName
+		}
+		if session.Status == "cancelled" {
+			line += " (cancelled)"
+		}
+		subtitle = append([]string{line}, subtitle...)
+	}
+
+	doc := pdf.Table{
+		Title:    programTitle + " — Roster",
+		Subtitle: subtitle,
+		Columns: []pdf.Column{
+			{Header: "Participant", Width: 130},
+			{Header: "Age", Width: 30},
+			{Header: "Contact email", Width: 130},
+			{Header: "Contact phone", Width: 80},
+			{Header: "Emergency contact", Width: 110},
+			{Header: "Emergency phone", Width: 80},
+			{Header: "Notes", Width: 90},
+			{Header: "Attendance", Width: 50},
+		},
+		Footer: "Generated " + time.Now().In(loc).Format("Jan 2, 2006 3:04 PM"),
+	}
+	for _, r := range records {
+		// Drop the registration status column; the printed roster is for instructors
+		doc.Rows = append(doc.Rows, append(append([]string{}, r[:7]...), r[8]))
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+	c.Data(http.StatusOK, "application/pdf", doc.Render())
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// writeAuditLog records an action in audit_logs. before and after are
+// marshalled to JSON; pass nil to leave them empty.
+func writeAuditLog(ctx context.Context, q dbtx, tenantID, actorID uuid.UUID, action, entity string, entityID *uuid.UUID, before, after interface{}) error {
+	var beforeJSON, afterJSON []byte
+	if before != nil {
+		beforeJSON, _ = json.Marshal(before)
+	}
+	if after != nil {
+		afterJSON, _ = json.Marshal(after)
+	}
+
+	var actor *uuid.UUID
+	if actorID != uuid.Nil {
+		actor = &actorID
+	}
+
+	_, err := q.Exec(ctx,
+		`INSERT INTO audit_logs (tenant_id, actor_id, action, entity, entity_id, before, after)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		tenantID, actor, action, entity, entityID, beforeJSON, afterJSON)
+	return err
+}
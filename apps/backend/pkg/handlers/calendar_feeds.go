@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/ical"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Calendar Feeds ============
+
+const (
+	calendarProductID = "-//Rec Hub//Calendar Feeds//EN"
+	calendarRefresh   = time.Hour
+
+	// Dated feeds cover recent history and the year ahead
+	calendarPast  = 30 * 24 * time.Hour
+	calendarAhead = 366 * 24 * time.Hour
+)
+
+// CalendarFeedRequest creates a private feed of a facility's schedule or a
+// staff member's teaching schedule
+type CalendarFeedRequest struct {
+	Kind         string  `json:"kind" binding:"required,oneof=facility staff"`
+	FacilityID   *string `json:"facility_id"`
+	InstructorID *string `json:"instructor_id"`
+	Label        *string `json:"label"`
+}
+
+// CalendarFeed is a private feed subscription. Token and URL are only
+// returned when the feed is created.
+type CalendarFeed struct {
+	ID             string     `json:"id"`
+	Kind           string     `json:"kind"`
+	FacilityID     *string    `json:"facility_id"`
+	InstructorID   *string    `json:"instructor_id"`
+	SubjectName    string     `json:"subject_name"`
+	Label          *string    `json:"label"`
+	CreatedBy      *string    `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	Token          string     `json:"token,omitempty"`
+	URL            string     `json:"url,omitempty"`
+}
+
+const calendarFeedColumns = `cf.id::text, cf.kind, cf.facility_id::text, cf.instructor_id::text,
+	COALESCE(f.name, i.name, ''), cf.label, cf.created_by::text, cf.created_at, cf.last_accessed_at, cf.revoked_at`
+
+const calendarFeedJoins = `calendar_feeds cf
+	LEFT JOIN facilities f ON f.id = cf.facility_id
+	LEFT JOIN instructors i ON i.id = cf.instructor_id`
+
+func scanCalendarFeed(row pgx.Row) (*CalendarFeed, error) {
+	var f CalendarFeed
+	err := row.Scan(&f.ID, &f.Kind, &f.FacilityID, &f.InstructorID, &f.SubjectName, &f.Label,
+		&f.CreatedBy, &f.CreatedAt, &f.LastAccessedAt, &f.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// hashFeedToken is the stored form of a feed token
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarUID is the stable UID of a feed entry, so a refreshed feed
+// updates entries instead of duplicating them
+func calendarUID(kind, id, domain string) string {
+	return fmt.Sprintf("%s-%s@%s", kind, id, domain)
+}
+
+// writeCalendar sends a rendered feed
+func writeCalendar(c *gin.Context, filename string, cal *ical.Calendar) {
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Bytes(time.Now()))
+}
+
+func (h *Handler) tenantName(ctx context.Context, tenantID string) string {
+	var name string
+	if err := h.DB.QueryRow(ctx, `SELECT name FROM tenants WHERE id = $1`, tenantID).Scan(&name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// eventCalendarEntries returns the tenant's public events as feed entries.
+// A recurring event is one entry with its rule; cancelled occurrences
+// become exceptions and edited ones are entries with a RECURRENCE-ID.
+func (h *Handler) eventCalendarEntries(ctx context.Context, tenantID, domain, category string, loc *time.Location) ([]ical.Event, error) {
+	from := time.Now().Add(-calendarPast)
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+eventSeriesColumns+` FROM events e
+		 WHERE e.tenant_id = $1 AND e.status = 'active' AND e.visibility = true
+		   AND (e.rrule IS NOT NULL OR e.ends_at >= $2)
+		   AND ($3 = '' OR lower(e.category) = lower($3))
+		 ORDER BY e.starts_at`,
+		tenantID, from, category)
+	if err != nil {
+		return nil, err
+	}
+	var series []*eventSeries
+	var recurringIDs []string
+	for rows.Next() {
+		s, err := scanEventSeries(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		series = append(series, s)
+		if s.RRule != nil {
+			recurringIDs = append(recurringIDs, s.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := loadOccurrenceOverrides(ctx, h.DB, recurringIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ical.Event
+	for _, s := range series {
+		rec, err := s.recurrence(loc)
+		if err != nil {
+			rec = nil
+		}
+		if rec != nil {
+			if last, ok := rec.Last(); ok && last.Before(from) {
+				continue
+			}
+		}
+		base := occurrenceCalendarEntry(s.occurrence(s.StartsAt, nil), domain)
+		base.LastModified = s.UpdatedAt
+		if rec == nil {
+			entries = append(entries, base)
+			continue
+		}
+		base.RRule = *s.RRule
+		base.ExDates = append(base.ExDates, s.Exdates...)
+
+		var edits []ical.Event
+		for _, o := range overrides[s.ID] {
+			if !rec.Includes(o.OccurrenceStart) {
+				continue
+			}
+			if o.Cancelled {
+				base.ExDates = append(base.ExDates, o.OccurrenceStart)
+				continue
+			}
+			if o.StartsAt == nil && o.Title == nil && o.Description == nil && o.Location == nil {
+				// Only RSVP bookkeeping; the occurrence is unchanged
+				continue
+			}
+			edit := occurrenceCalendarEntry(s.occurrence(o.OccurrenceStart, o), domain)
+			recurrenceID := o.OccurrenceStart
+			edit.RecurrenceID = &recurrenceID
+			edit.LastModified = s.UpdatedAt
+			edits = append(edits, edit)
+		}
+		entries = append(entries, base)
+		entries = append(entries, edits...)
+	}
+	return entries, nil
+}
+
+func occurrenceCalendarEntry(o EventOccurrence, domain string) ical.Event {
+	e := ical.Event{
+		UID:         calendarUID("event", o.EventID, domain),
+		Start:       o.StartsAt,
+		End:         o.EndsAt,
+		Summary:     o.Title,
+		Description: derefString(o.Description),
+		Location:    derefString(o.Location),
+		Status:      ical.StatusConfirmed,
+	}
+	if o.Category != nil && *o.Category != "" {
+		e.Categories = []string{*o.Category}
+	}
+	return e
+}
+
+// sessionCalendarEntries returns program sessions in the feed window as
+// feed entries. where filters program_sessions s; its arguments start at $4.
+func (h *Handler) sessionCalendarEntries(ctx context.Context, tenantID, domain, where string, args ...interface{}) ([]ical.Event, error) {
+	from := time.Now().Add(-calendarPast)
+	to := time.Now().Add(calendarAhead)
+	rows, err := h.DB.Query(ctx,
+		`SELECT s.id::text, s.starts_at, s.ends_at, p.title, p.description, f.name, f.address
+		 FROM program_sessions s
+		 JOIN programs p ON p.id = s.program_id
+		 LEFT JOIN facilities f ON f.id = s.facility_id
+		 WHERE s.tenant_id = $1 AND s.status = 'scheduled' AND s.ends_at >= $2 AND s.starts_at < $3
+		   AND `+where+`
+		 ORDER BY s.starts_at`,
+		append([]interface{}{tenantID, from, to}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ical.Event
+	for rows.Next() {
+		var id, title string
+		var startsAt, endsAt time.Time
+		var description, facility, address *string
+		if err := rows.Scan(&id, &startsAt, &endsAt, &title, &description, &facility, &address); err != nil {
+			return nil, err
+		}
+		location := derefString(facility)
+		if address != nil && *address != "" {
+			location = strings.TrimPrefix(location+", "+*address, ", ")
+		}
+		entries = append(entries, ical.Event{
+			UID:         calendarUID("session", id, domain),
+			Start:       startsAt,
+			End:         endsAt,
+			Summary:     title,
+			Description: derefString(description),
+			Location:    location,
+			Status:      ical.StatusConfirmed,
+		})
+	}
+	return entries, rows.Err()
+}
+
+// bookingCalendarEntries returns the facility's pending and approved slot
+// bookings in the feed window. Pending requests are tentative.
+func (h *Handler) bookingCalendarEntries(ctx context.Context, tenantID, domain, facilityID string) ([]ical.Event, error) {
+	from := time.Now().Add(-calendarPast)
+	to := time.Now().Add(calendarAhead)
+	rows, err := h.DB.Query(ctx,
+		`SELECT b.id::text, b.status, COALESCE(NULLIF(b.requester_name, ''), b.requester_email), b.notes,
+		        b.updated_at, fs.starts_at, fs.ends_at, f.name
+		 FROM bookings b
+		 JOIN facility_slots fs ON fs.id = b.resource_id
+		 JOIN facilities f ON f.id = fs.facility_id
+		 WHERE b.tenant_id = $1 AND b.resource_type = 'facility_slot' AND fs.facility_id = $2
+		   AND b.status IN ('pending', 'approved')
+		   AND fs.ends_at >= $3 AND fs.starts_at < $4
+		 ORDER BY fs.starts_at`,
+		tenantID, facilityID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ical.Event
+	for rows.Next() {
+		var id, status, requester, facility string
+		var notes *string
+		var updatedAt, startsAt, endsAt time.Time
+		if err := rows.Scan(&id, &status, &requester, &notes, &updatedAt, &startsAt, &endsAt, &facility); err != nil {
+			return nil, err
+		}
+		entry := ical.Event{
+			UID:          calendarUID("booking", id, domain),
+			Start:        startsAt,
+			End:          endsAt,
+			Summary:      "Booking: " + requester,
+			Description:  derefString(notes),
+			Location:     facility,
+			Status:       ical.StatusConfirmed,
+			LastModified: updatedAt,
+		}
+		if status == "pending" {
+			entry.Summary += " (pending)"
+			entry.Status = ical.StatusTentative
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetPublicEventCalendar serves the tenant's public events as an .ics
+// feed, optionally limited to one category
+func (h *Handler) GetPublicEventCalendar(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	category := strings.TrimSpace(c.Query("category"))
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	domain := h.tenantPrimaryDomain(ctx, tenantID)
+	entries, err := h.eventCalendarEntries(ctx, tenantID, domain, category, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	name := strings.TrimSpace(h.tenantName(ctx, tenantID) + " Events")
+	if category != "" {
+		name += " - " + category
+	}
+	writeCalendar(c, "events.ics", &ical.Calendar{
+		ProductID: calendarProductID,
+		Name:      name,
+		Location:  loc,
+		Refresh:   calendarRefresh,
+		Events:    entries,
+	})
+}
+
+// GetPublicProgramCalendar serves an active program's scheduled sessions
+// as an .ics feed
+func (h *Handler) GetPublicProgramCalendar(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	var programID, title string
+	err = h.DB.QueryRow(ctx,
+		`SELECT id::text, title FROM programs
+		 WHERE tenant_id = $1 AND status = 'active' AND id::text = $2`,
+		tenantID, c.Param("id")).Scan(&programID, &title)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	domain := h.tenantPrimaryDomain(ctx, tenantID)
+	entries, err := h.sessionCalendarEntries(ctx, tenantID, domain, `s.program_id = $4`, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	writeCalendar(c, "program.ics", &ical.Calendar{
+		ProductID: calendarProductID,
+		Name:      title,
+		Location:  h.tenantLocation(ctx, h.DB, tenantID),
+		Refresh:   calendarRefresh,
+		Events:    entries,
+	})
+}
+
+// GetCalendarFeed serves a private feed to whoever holds its token. The
+// token may carry an .ics suffix for calendar apps that expect one.
+func (h *Handler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	ctx := context.Background()
+
+	var feedID, tenantID, kind string
+	var facilityID, instructorID *string
+	err := h.DB.QueryRow(ctx,
+		`UPDATE calendar_feeds SET last_accessed_at = now()
+		 WHERE token_hash = $1 AND revoked_at IS NULL
+		 RETURNING id::text, tenant_id::text, kind, facility_id::text, instructor_id::text`,
+		hashFeedToken(token)).Scan(&feedID, &tenantID, &kind, &facilityID, &instructorID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	domain := h.tenantPrimaryDomain(ctx, tenantID)
+	var name string
+	var entries []ical.Event
+	switch kind {
+	case "facility":
+		if err = h.DB.QueryRow(ctx, `SELECT name FROM facilities WHERE id = $1`, *facilityID).Scan(&name); err != nil {
+			break
+		}
+		entries, err = h.sessionCalendarEntries(ctx, tenantID, domain, `s.facility_id = $4`, *facilityID)
+		if err != nil {
+			break
+		}
+		var bookings []ical.Event
+		bookings, err = h.bookingCalendarEntries(ctx, tenantID, domain, *facilityID)
+		entries = append(entries, bookings...)
+	case "staff":
+		if err = h.DB.QueryRow(ctx, `SELECT name FROM instructors WHERE id = $1`, *instructorID).Scan(&name); err != nil {
+			break
+		}
+		name += " - Teaching Schedule"
+		entries, err = h.sessionCalendarEntries(ctx, tenantID, domain,
+			`(EXISTS (SELECT 1 FROM program_instructors pi WHERE pi.program_id = s.program_id AND pi.instructor_id = $4)
+			  OR EXISTS (SELECT 1 FROM session_instructors si WHERE si.session_id = s.id AND si.instructor_id = $4))`,
+			*instructorID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	writeCalendar(c, "schedule.ics", &ical.Calendar{
+		ProductID: calendarProductID,
+		Name:      name,
+		Location:  h.tenantLocation(ctx, h.DB, tenantID),
+		Refresh:   calendarRefresh,
+		Events:    entries,
+	})
+}
+
+// ListCalendarFeeds lists private feeds. Staff see the feeds they created.
+func (h *Handler) ListCalendarFeeds(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+calendarFeedColumns+` FROM `+calendarFeedJoins+`
+		 WHERE cf.tenant_id = $1 AND ($2 OR cf.created_by = $3)
+		   AND ($4 OR cf.revoked_at IS NULL)
+		 ORDER BY cf.created_at DESC`,
+		claims.TenantID, claims.Role != "STAFF", claims.UserID, c.Query("include_revoked") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	feeds := []CalendarFeed{}
+	for rows.Next() {
+		f, err := scanCalendarFeed(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		feeds = append(feeds, *f)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feeds": feeds})
+}
+
+// CreateCalendarFeed issues a private feed token. Admins can subscribe to
+// any facility or staff member; staff only to their own teaching schedule.
+func (h *Handler) CreateCalendarFeed(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	var facilityID, instructorID *string
+	switch req.Kind {
+	case "facility":
+		if claims.Role == "STAFF" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		if req.FacilityID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "facility_id is required"})
+			return
+		}
+		var exists bool
+		h.DB.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM facilities WHERE id::text = $1 AND tenant_id = $2)`,
+			*req.FacilityID, tenantID).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "facility not found"})
+			return
+		}
+		facilityID = req.FacilityID
+	case "staff":
+		if req.InstructorID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "instructor_id is required"})
+			return
+		}
+		var userID *string
+		err := h.DB.QueryRow(ctx,
+			`SELECT user_id::text FROM instructors WHERE id::text = $1 AND tenant_id = $2`,
+			*req.InstructorID, tenantID).Scan(&userID)
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "instructor not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if claims.Role == "STAFF" && (userID == nil || *userID != claims.UserID.String()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		instructorID = req.InstructorID
+	}
+
+	token, err := newCancelToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+
+	var feedID string
+	err = h.DB.QueryRow(ctx,
+		`INSERT INTO calendar_feeds (tenant_id, kind, facility_id, instructor_id, label, token_hash, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id::text`,
+		tenantID, req.Kind, facilityID, instructorID, nullIfEmpty(derefString(req.Label)),
+		hashFeedToken(token), claims.UserID).Scan(&feedID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+
+	feed, err := scanCalendarFeed(h.DB.QueryRow(ctx,
+		`SELECT `+calendarFeedColumns+` FROM `+calendarFeedJoins+` WHERE cf.id = $1`, feedID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	feed.Token = token
+	feed.URL = fmt.Sprintf("https://%s/api/calendar/%s.ics", h.tenantPrimaryDomain(ctx, tenantID), token)
+
+	c.JSON(http.StatusCreated, feed)
+}
+
+// RevokeCalendarFeed stops a feed's token from working. Staff can revoke
+// the feeds they created.
+func (h *Handler) RevokeCalendarFeed(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`UPDATE calendar_feeds SET revoked_at = COALESCE(revoked_at, now())
+		 WHERE id::text = $1 AND tenant_id = $2 AND ($3 OR created_by = $4)`,
+		c.Param("id"), claims.TenantID, claims.Role != "STAFF", claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
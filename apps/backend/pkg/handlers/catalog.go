@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/rec-hub/backend/pkg/middleware"
 	"github.com/rec-hub/backend/pkg/models"
+	"github.com/rec-hub/backend/pkg/schedule"
 )
 
 // ============ Programs ============
@@ -18,10 +21,12 @@ type ProgramRequest struct {
 	Title       string  `json:"title" binding:"required"`
 	Description *string `json:"description"`
 	Season      *string `json:"season"`
+	SeasonID    *string `json:"season_id"`
 	Category    *string `json:"category"`
 	StartDate   *string `json:"start_date"`
 	EndDate     *string `json:"end_date"`
 	PriceCents  int     `json:"price_cents"`
+	Capacity    *int    `json:"capacity" binding:"omitempty,min=0"`
 	Status      *string `json:"status"`
 	ImageURL    *string `json:"image_url"`
 	Slug        *string `json:"slug"`
@@ -32,10 +37,13 @@ type ProgramResponse struct {
 	Title       string  `json:"title"`
 	Description *string `json:"description"`
 	Season      *string `json:"season"`
+	SeasonID    *string `json:"season_id"`
 	Category    *string `json:"category"`
 	StartDate   *string `json:"start_date"`
 	EndDate     *string `json:"end_date"`
 	PriceCents  int     `json:"price_cents"`
+	Capacity    *int    `json:"capacity"`
+	Enrolled    int     `json:"enrolled_count"`
 	Status      string  `json:"status"`
 	ImageURL    *string `json:"image_url"`
 	Slug        *string `json:"slug"`
@@ -52,7 +60,7 @@ func (h *Handler) ListPrograms(c *gin.Context) {
 
 	ctx := context.Background()
 	rows, err := h.DB.Query(ctx,
-		`SELECT id, title, description, season, category, start_date, end_date, price_cents, status, image_url, slug, created_at, updated_at
+		`SELECT id, title, description, season, season_id::text, category, start_date::text, end_date::text, price_cents, capacity, enrolled_count, status, image_url, slug, created_at, updated_at
 		 FROM programs WHERE tenant_id = $1 ORDER BY created_at DESC`,
 		claims.TenantID.String())
 	if err != nil {
@@ -64,13 +72,14 @@ func (h *Handler) ListPrograms(c *gin.Context) {
 	var programs []ProgramResponse
 	for rows.Next() {
 		var p ProgramResponse
-		var desc, season, category, startDate, endDate, imageURL, slug *string
+		var desc, season, seasonID, category, startDate, endDate, imageURL, slug *string
 		var id string
-		var priceCents int
+		var priceCents, enrolled int
+		var capacity *int
 		var status string
 		var createdAt, updatedAt time.Time
 
-		if err := rows.Scan(&id, &p.Title, &desc, &season, &category, &startDate, &endDate, &priceCents, &status, &imageURL, &slug, &createdAt, &updatedAt); err != nil {
+		if err := rows.Scan(&id, &p.Title, &desc, &season, &seasonID, &category, &startDate, &endDate, &priceCents, &capacity, &enrolled, &status, &imageURL, &slug, &createdAt, &updatedAt); err != nil {
 			continue
 		}
 
@@ -79,10 +88,13 @@ func (h *Handler) ListPrograms(c *gin.Context) {
 			Title:       p.Title,
 			Description: desc,
 			Season:      season,
+			SeasonID:    seasonID,
 			Category:    category,
 			StartDate:   startDate,
 			EndDate:     endDate,
 			PriceCents:  priceCents,
+			Capacity:    capacity,
+			Enrolled:    enrolled,
 			Status:      status,
 			ImageURL:    imageURL,
 			Slug:        slug,
@@ -110,17 +122,18 @@ func (h *Handler) CreateProgram(c *gin.Context) {
 	ctx := context.Background()
 	programID := uuid.New()
 
+	if err := h.resolveProgramSeason(ctx, claims.TenantID.String(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Default status to 'active' if not provided
 	status := "active"
 	if req.Status != nil {
 		status = *req.Status
 	}
 
-	_, err := h.DB.Exec(ctx,
-		`INSERT INTO programs (id, tenant_id, title, description, season, category, start_date, end_date, price_cents, status, image_url, slug)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
-		programID, claims.TenantID, req.Title, req.Description, req.Season, req.Category, req.StartDate, req.EndDate, req.PriceCents, status, req.ImageURL, req.Slug)
-	if err != nil {
+	if err := insertProgram(ctx, h.DB, programID, claims.TenantID, req, status); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create program"})
 		return
 	}
@@ -128,6 +141,15 @@ func (h *Handler) CreateProgram(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"id": programID.String()})
 }
 
+// insertProgram stores a new program; imports share it with CreateProgram
+func insertProgram(ctx context.Context, q dbtx, programID, tenantID uuid.UUID, req ProgramRequest, status string) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO programs (id, tenant_id, title, description, season, season_id, category, start_date, end_date, price_cents, capacity, status, image_url, slug)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		programID, tenantID, req.Title, req.Description, req.Season, req.SeasonID, req.Category, req.StartDate, req.EndDate, req.PriceCents, req.Capacity, status, req.ImageURL, req.Slug)
+	return err
+}
+
 func (h *Handler) UpdateProgram(c *gin.Context) {
 	claims := middleware.GetClaimsFromContext(c)
 	if claims == nil {
@@ -156,6 +178,11 @@ func (h *Handler) UpdateProgram(c *gin.Context) {
 		return
 	}
 
+	if err := h.resolveProgramSeason(ctx, tenantID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get current status if not provided
 	status := "active"
 	if req.Status != nil {
@@ -163,10 +190,10 @@ func (h *Handler) UpdateProgram(c *gin.Context) {
 	}
 
 	_, err = h.DB.Exec(ctx,
-		`UPDATE programs SET title = $1, description = $2, season = $3, category = $4, start_date = $5, end_date = $6,
-		                     price_cents = $7, status = $8, image_url = $9, slug = $10, updated_at = now()
-		 WHERE id = $11`,
-		req.Title, req.Description, req.Season, req.Category, req.StartDate, req.EndDate, req.PriceCents, status, req.ImageURL, req.Slug, programID)
+		`UPDATE programs SET title = $1, description = $2, season = $3, season_id = $4, category = $5, start_date = $6, end_date = $7,
+		                     price_cents = $8, capacity = $9, status = $10, image_url = $11, slug = $12, updated_at = now()
+		 WHERE id = $13`,
+		req.Title, req.Description, req.Season, req.SeasonID, req.Category, req.StartDate, req.EndDate, req.PriceCents, req.Capacity, status, req.ImageURL, req.Slug, programID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
@@ -209,38 +236,102 @@ func (h *Handler) DeleteProgram(c *gin.Context) {
 // ============ Events ============
 
 type EventRequest struct {
-	Title           string    `json:"title" binding:"required"`
-	Description     *string   `json:"description"`
-	StartsAt        time.Time `json:"starts_at" binding:"required"`
-	EndsAt          time.Time `json:"ends_at" binding:"required"`
-	Location        *string   `json:"location"`
-	Capacity        *int      `json:"capacity"`
-	Category        *string   `json:"category"`
-	Status          *string   `json:"status"`
-	Visibility      *bool     `json:"visibility"`
-	ImageURL        *string   `json:"image_url"`
-	Slug            *string   `json:"slug"`
-	RegisteredCount *int      `json:"registered_count"`
+	Title       string    `json:"title" binding:"required"`
+	Description *string   `json:"description"`
+	StartsAt    time.Time `json:"starts_at" binding:"required"`
+	EndsAt      time.Time `json:"ends_at" binding:"required"`
+	Location    *string   `json:"location"`
+	Capacity    *int      `json:"capacity"`
+	Category    *string   `json:"category"`
+	Status      *string   `json:"status"`
+	Visibility  *bool     `json:"visibility"`
+	ImageURL    *string   `json:"image_url"`
+	Slug        *string   `json:"slug"`
+	// RRule makes the event repeat; starts_at and ends_at are then the first occurrence
+	RRule       *string     `json:"rrule"`
+	Exdates     []time.Time `json:"exdates"`
 }
 
 type EventResponse struct {
-	ID              string  `json:"id"`
-	Title           string  `json:"title"`
-	Description     *string `json:"description"`
-	StartsAt        string  `json:"starts_at"`
-	EndsAt          string  `json:"ends_at"`
-	Location        *string `json:"location"`
-	Capacity        *int    `json:"capacity"`
-	Category        *string `json:"category"`
-	Status          string  `json:"status"`
-	Visibility      bool    `json:"visibility"`
-	ImageURL        *string `json:"image_url"`
-	Slug            *string `json:"slug"`
-	RegisteredCount int     `json:"registered_count"`
-	CreatedAt       string  `json:"created_at"`
-	UpdatedAt       string  `json:"updated_at"`
+	ID              string   `json:"id"`
+	Title           string   `json:"title"`
+	Description     *string  `json:"description"`
+	StartsAt        string   `json:"starts_at"`
+	EndsAt          string   `json:"ends_at"`
+	Location        *string  `json:"location"`
+	Capacity        *int     `json:"capacity"`
+	Category        *string  `json:"category"`
+	Status          string   `json:"status"`
+	Visibility      bool     `json:"visibility"`
+	ImageURL        *string  `json:"image_url"`
+	Slug            *string  `json:"slug"`
+	RegisteredCount int      `json:"registered_count"`
+	RRule           *string  `json:"rrule"`
+	Recurrence      string   `json:"recurrence,omitempty"`
+	Exdates         []string `json:"exdates"`
+	SeriesID        *string  `json:"series_id"`
+	OccurrenceStart *string  `json:"occurrence_start,omitempty"`
+	SpotsLeft       *int     `json:"spots_left,omitempty"`
+	Cancelled       bool     `json:"cancelled,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+// eventResponse renders an event row
+func eventResponse(s *eventSeries) EventResponse {
+	exdates := make([]string, len(s.Exdates))
+	for i, ex := range s.Exdates {
+		exdates[i] = ex.Format(time.RFC3339)
+	}
+	e := EventResponse{
+		ID:              s.ID,
+		Title:           s.Title,
+		Description:     s.Description,
+		StartsAt:        s.StartsAt.Format(time.RFC3339),
+		EndsAt:          s.EndsAt.Format(time.RFC3339),
+		Location:        s.Location,
+		Capacity:        s.Capacity,
+		Category:        s.Category,
+		Status:          s.Status,
+		Visibility:      s.Visibility,
+		ImageURL:        s.ImageURL,
+		Slug:            s.Slug,
+		RegisteredCount: s.Registered,
+		RRule:           s.RRule,
+		Exdates:         exdates,
+		SeriesID:        s.SeriesID,
+		CreatedAt:       s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       s.UpdatedAt.Format(time.RFC3339),
+	}
+	if s.RRule != nil {
+		if rule, err := schedule.ParseRule(*s.RRule); err == nil {
+			e.Recurrence = rule.Describe()
+		}
+	}
+	return e
+}
+
+// occurrenceResponse renders one expanded occurrence in the event shape
+func occurrenceResponse(o EventOccurrence) EventResponse {
+	e := eventResponse(o.series)
+	e.Title = o.Title
+	e.Description = o.Description
+	e.StartsAt = o.StartsAt.Format(time.RFC3339)
+	e.EndsAt = o.EndsAt.Format(time.RFC3339)
+	e.Location = o.Location
+	e.Capacity = o.Capacity
+	e.RegisteredCount = o.RegisteredCount
+	e.SpotsLeft = o.SpotsLeft
+	e.Cancelled = o.Cancelled
+	if o.OccurrenceStart != nil {
+		key := o.OccurrenceStart.Format(time.RFC3339)
+		e.OccurrenceStart = &key
+	}
+	return e
 }
 
+// ListEvents returns the tenant's events. Given a from/to range it returns
+// the occurrences in that range instead, with recurring events expanded.
 func (h *Handler) ListEvents(c *gin.Context) {
 	claims := middleware.GetClaimsFromContext(c)
 	if claims == nil {
@@ -249,9 +340,46 @@ func (h *Handler) ListEvents(c *gin.Context) {
 	}
 
 	ctx := context.Background()
+
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from := time.Now()
+		to := from.AddDate(0, 3, 0)
+		if v := c.Query("from"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339"})
+				return
+			}
+			from = t
+		}
+		if v := c.Query("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339"})
+				return
+			}
+			to = t
+		}
+		if !to.After(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+			return
+		}
+
+		occurrences, err := h.expandEvents(ctx, h.DB, claims.TenantID.String(), eventFilter{From: from, To: to, Limit: 1000})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		events := make([]EventResponse, 0, len(occurrences))
+		for _, o := range occurrences {
+			events = append(events, occurrenceResponse(o))
+		}
+		c.JSON(http.StatusOK, gin.H{"events": events})
+		return
+	}
+
 	rows, err := h.DB.Query(ctx,
-		`SELECT id, title, description, starts_at, ends_at, location, capacity, category, status, visibility, image_url, slug, registered_count, created_at, updated_at
-		 FROM events WHERE tenant_id = $1 ORDER BY starts_at DESC`,
+		`SELECT `+eventSeriesColumns+` FROM events e WHERE e.tenant_id = $1 ORDER BY e.starts_at DESC`,
 		claims.TenantID.String())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
@@ -261,36 +389,11 @@ func (h *Handler) ListEvents(c *gin.Context) {
 
 	var events []EventResponse
 	for rows.Next() {
-		var e EventResponse
-		var desc, location, category, imageURL, slug *string
-		var capacity *int
-		var id string
-		var status string
-		var visibility bool
-		var registeredCount int
-		var startsAt, endsAt, createdAt, updatedAt time.Time
-
-		if err := rows.Scan(&id, &e.Title, &desc, &startsAt, &endsAt, &location, &capacity, &category, &status, &visibility, &imageURL, &slug, &registeredCount, &createdAt, &updatedAt); err != nil {
+		s, err := scanEventSeries(rows)
+		if err != nil {
 			continue
 		}
-
-		events = append(events, EventResponse{
-			ID:              id,
-			Title:           e.Title,
-			Description:     desc,
-			StartsAt:        startsAt.Format(time.RFC3339),
-			EndsAt:          endsAt.Format(time.RFC3339),
-			Location:        location,
-			Capacity:        capacity,
-			Category:        category,
-			Status:          status,
-			Visibility:      visibility,
-			ImageURL:        imageURL,
-			Slug:            slug,
-			RegisteredCount: registeredCount,
-			CreatedAt:       createdAt.Format(time.RFC3339),
-			UpdatedAt:       updatedAt.Format(time.RFC3339),
-		})
+		events = append(events, eventResponse(s))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"events": events})
@@ -312,6 +415,16 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 	ctx := context.Background()
 	eventID := uuid.New()
 
+	rrule, err := normalizeEventRule(req.RRule, req.StartsAt, h.tenantLocation(ctx, h.DB, claims.TenantID.String()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exdates := req.Exdates
+	if exdates == nil {
+		exdates = []time.Time{}
+	}
+
 	// Default values
 	status := "active"
 	if req.Status != nil {
@@ -321,23 +434,81 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 	if req.Visibility != nil {
 		visibility = *req.Visibility
 	}
-	registeredCount := 0
-	if req.RegisteredCount != nil {
-		registeredCount = *req.RegisteredCount
-	}
 
-	_, err := h.DB.Exec(ctx,
-		`INSERT INTO events (id, tenant_id, title, description, starts_at, ends_at, location, capacity, category, status, visibility, image_url, slug, registered_count)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
-		eventID, claims.TenantID, req.Title, req.Description, req.StartsAt, req.EndsAt, req.Location, req.Capacity, req.Category, status, visibility, req.ImageURL, req.Slug, registeredCount)
+	slug, err := resolveEventSlug(ctx, h.DB, claims.TenantID.String(), req.Slug, req.Title, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create event"})
+		respondEventSlugError(c, err, "failed to create event")
+		return
+	}
+
+	req.RRule = rrule
+	req.Exdates = exdates
+	req.Slug = &slug
+	if err := insertEvent(ctx, h.DB, eventID, claims.TenantID, req, status, visibility); err != nil {
+		respondEventSlugError(c, err, "failed to create event")
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"id": eventID.String()})
 }
 
+// insertEvent stores a new event whose rrule is already normalized;
+// imports share it with CreateEvent
+func insertEvent(ctx context.Context, q dbtx, eventID, tenantID uuid.UUID, req EventRequest, status string, visibility bool) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO events (id, tenant_id, title, description, starts_at, ends_at, location, capacity, category, status, visibility, image_url, slug, rrule, exdates)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		eventID, tenantID, req.Title, req.Description, req.StartsAt, req.EndsAt, req.Location, req.Capacity, req.Category, status, visibility, req.ImageURL, req.Slug, req.RRule, req.Exdates)
+	return err
+}
+
+var (
+	errInvalidSlug = errors.New("slug must contain letters or numbers")
+	errSlugTaken   = errors.New("slug is already used by another event")
+)
+
+// resolveEventSlug returns the slug to save for an event. A requested slug
+// is normalized and must be free; without one the title's slug is made
+// unique. excludeID is the event being updated, or "".
+func resolveEventSlug(ctx context.Context, q dbtx, tenantID string, requested *string, title, excludeID string) (string, error) {
+	if requested == nil || strings.TrimSpace(*requested) == "" {
+		base := slugify(title)
+		if base == "" {
+			base = "event"
+		}
+		return uniqueSlug(ctx, q, "events", tenantID, base)
+	}
+
+	slug := slugify(*requested)
+	if slug == "" {
+		return "", errInvalidSlug
+	}
+	var taken bool
+	err := q.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM events WHERE tenant_id = $1 AND slug = $2 AND id::text <> $3)`,
+		tenantID, slug, excludeID).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", errSlugTaken
+	}
+	return slug, nil
+}
+
+// respondEventSlugError maps slug failures, including a slug taken by a
+// concurrent save, onto responses
+func respondEventSlugError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, errInvalidSlug):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errSlugTaken), isUniqueViolation(err):
+		c.JSON(http.StatusConflict, gin.H{"error": errSlugTaken.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
 func (h *Handler) UpdateEvent(c *gin.Context) {
 	claims := middleware.GetClaimsFromContext(c)
 	if claims == nil {
@@ -375,20 +546,72 @@ func (h *Handler) UpdateEvent(c *gin.Context) {
 	if req.Visibility != nil {
 		visibility = *req.Visibility
 	}
-	registeredCount := 0
-	if req.RegisteredCount != nil {
-		registeredCount = *req.RegisteredCount
+
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	rrule, err := normalizeEventRule(req.RRule, req.StartsAt, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exdates := req.Exdates
+	if exdates == nil {
+		exdates = []time.Time{}
 	}
 
-	_, err = h.DB.Exec(ctx,
+	// Without a slug in the request the event keeps its own
+	var slug *string
+	if req.Slug != nil {
+		s, err := resolveEventSlug(ctx, h.DB, tenantID, req.Slug, req.Title, eventID)
+		if err != nil {
+			respondEventSlugError(c, err, "update failed")
+			return
+		}
+		slug = &s
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// registered_count is maintained by RSVPs; capacity cannot drop below it
+	result, err := tx.Exec(ctx,
 		`UPDATE events SET title = $1, description = $2, starts_at = $3, ends_at = $4, location = $5, capacity = $6,
-		                   category = $7, status = $8, visibility = $9, image_url = $10, slug = $11, registered_count = $12, updated_at = now()
-		 WHERE id = $13`,
-		req.Title, req.Description, req.StartsAt, req.EndsAt, req.Location, req.Capacity, req.Category, status, visibility, req.ImageURL, req.Slug, registeredCount, eventID)
+		                   category = $7, status = $8, visibility = $9, image_url = $10, slug = COALESCE($11, slug),
+		                   rrule = $13, exdates = $14, updated_at = now()
+		 WHERE id = $12 AND ($6::int IS NULL OR $6::int >= registered_count)`,
+		req.Title, req.Description, req.StartsAt, req.EndsAt, req.Location, req.Capacity, req.Category, status, visibility, req.ImageURL, slug, eventID, rrule, exdates)
+	if err != nil {
+		respondEventSlugError(c, err, "update failed")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "capacity is below the number already registered"})
+		return
+	}
+
+	// Occurrences with RSVPs must survive a changed rule or capacity
+	series, err := lockEventSeries(ctx, tx, tenantID, eventID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
+	if err := reconcileSeries(ctx, tx, series, loc); err != nil {
+		var changeErr *eventChangeError
+		if errors.As(err, &changeErr) {
+			c.JSON(changeErr.status, gin.H{"error": changeErr.message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
@@ -432,6 +655,7 @@ type FacilityRequest struct {
 	Address *string `json:"address"`
 	Rules   *string `json:"rules"`
 	PhotoID *string `json:"photo_id"`
+	HourlyRateCents *int `json:"hourly_rate_cents"`
 }
 
 func (h *Handler) ListFacilities(c *gin.Context) {
@@ -443,7 +667,7 @@ func (h *Handler) ListFacilities(c *gin.Context) {
 
 	ctx := context.Background()
 	rows, err := h.DB.Query(ctx,
-		`SELECT id, name, type, address, rules, photo_id, created_at, updated_at
+		`SELECT id, name, type, address, rules, photo_id, hourly_rate_cents, created_at, updated_at
 		 FROM facilities WHERE tenant_id = $1 ORDER BY created_at DESC`,
 		claims.TenantID.String())
 	if err != nil {
@@ -455,7 +679,7 @@ func (h *Handler) ListFacilities(c *gin.Context) {
 	var facilities []interface{}
 	for rows.Next() {
 		var f models.Facility
-		if err := rows.Scan(&f.ID, &f.Name, &f.Type, &f.Address, &f.Rules, &f.PhotoID, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		if err := rows.Scan(&f.ID, &f.Name, &f.Type, &f.Address, &f.Rules, &f.PhotoID, &f.HourlyRateCents, &f.CreatedAt, &f.UpdatedAt); err != nil {
 			continue
 		}
 		facilities = append(facilities, gin.H{
@@ -464,6 +688,7 @@ func (h *Handler) ListFacilities(c *gin.Context) {
 			"type":    f.Type,
 			"address": f.Address,
 			"rules":   f.Rules,
+			"hourly_rate_cents": f.HourlyRateCents,
 		})
 	}
 
@@ -487,9 +712,9 @@ func (h *Handler) CreateFacility(c *gin.Context) {
 	facilityID := uuid.New()
 
 	_, err := h.DB.Exec(ctx,
-		`INSERT INTO facilities (id, tenant_id, name, type, address, rules)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		facilityID, claims.TenantID, req.Name, req.Type, req.Address, req.Rules)
+		`INSERT INTO facilities (id, tenant_id, name, type, address, rules, hourly_rate_cents)
+		 VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, 0))`,
+		facilityID, claims.TenantID, req.Name, req.Type, req.Address, req.Rules, req.HourlyRateCents)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create facility"})
 		return
@@ -527,9 +752,10 @@ func (h *Handler) UpdateFacility(c *gin.Context) {
 	}
 
 	_, err = h.DB.Exec(ctx,
-		`UPDATE facilities SET name = $1, type = $2, address = $3, rules = $4, updated_at = now()
-		 WHERE id = $5`,
-		req.Name, req.Type, req.Address, req.Rules, facilityID)
+		`UPDATE facilities SET name = $1, type = $2, address = $3, rules = $4,
+		 hourly_rate_cents = COALESCE($5, hourly_rate_cents), updated_at = now()
+		 WHERE id = $6`,
+		req.Name, req.Type, req.Address, req.Rules, req.HourlyRateCents, facilityID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
@@ -18,6 +18,7 @@ type DashboardSummary struct {
 	RegistrationsMTD  int          `json:"registrationsMTD"`
 	Utilization7dPct  float64      `json:"utilization7dPct"`
 	Payments          PaymentsInfo `json:"payments"`
+	Scholarships      *ScholarshipsInfo `json:"scholarships"`
 }
 
 type PaymentsInfo struct {
@@ -25,6 +26,17 @@ type PaymentsInfo struct {
 	GrossMTD float64 `json:"grossMTD"`
 }
 
+// ScholarshipsInfo is the scholarship fund's use this fiscal year; nil when
+// the tenant has no fund
+type ScholarshipsInfo struct {
+	FiscalYear          int     `json:"fiscalYear"`
+	Budget              float64 `json:"budget"`
+	Spent               float64 `json:"spent"`
+	Committed           float64 `json:"committed"`
+	Remaining           float64 `json:"remaining"`
+	PendingApplications int     `json:"pendingApplications"`
+}
+
 // Upcoming event response
 type DashboardUpcomingEvent struct {
 	ID         string    `json:"id"`
@@ -93,7 +105,7 @@ func (h *Handler) GetDashboardSummary(c *gin.Context) {
 
 	summary := DashboardSummary{
 		Payments: PaymentsInfo{
-			Enabled:  false,
+			Enabled:  h.Payments != nil,
 			GrossMTD: 0,
 		},
 	}
@@ -134,6 +146,15 @@ func (h *Handler) GetDashboardSummary(c *gin.Context) {
 		summary.RegistrationsMTD = registrationsMTD
 	}
 
+	// Gross payments MTD
+	var grossCents int64
+	err = h.DB.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_cents), 0) FROM payments WHERE tenant_id = $1 AND status = 'paid' AND paid_at >= $2`,
+		tenantID, monthStart).Scan(&grossCents)
+	if err == nil {
+		summary.Payments.GrossMTD = float64(grossCents) / 100.0
+	}
+
 	// Facility utilization (7 days)
 	var bookedMinutes, totalMinutes float64
 
@@ -146,7 +167,7 @@ func (h *Handler) GetDashboardSummary(c *gin.Context) {
 		WHERE f.tenant_id = $1
 		AND fs.starts_at < $3
 		AND fs.ends_at > $2
-		AND fs.status IN ('open', 'booked')`,
+		AND fs.status IN ('open', 'held', 'booked', 'reserved')`,
 		tenantID, weekAgo, now).Scan(&totalMinutes)
 
 	if err == nil && totalMinutes > 0 {
@@ -159,7 +180,7 @@ func (h *Handler) GetDashboardSummary(c *gin.Context) {
 			WHERE f.tenant_id = $1
 			AND fs.starts_at < $3
 			AND fs.ends_at > $2
-			AND fs.status = 'booked'`,
+			AND fs.status IN ('booked', 'reserved')`,
 			tenantID, weekAgo, now).Scan(&bookedMinutes)
 
 		if err == nil {
@@ -167,6 +188,20 @@ func (h *Handler) GetDashboardSummary(c *gin.Context) {
 		}
 	}
 
+	// Scholarship budget for the current fiscal year
+	if fund, err := loadScholarshipFund(ctx, h.DB, tenantID, false); err == nil && fund != nil {
+		if budget, err := h.scholarshipBudget(ctx, h.DB, tenantID, fund, now); err == nil {
+			summary.Scholarships = &ScholarshipsInfo{
+				FiscalYear:          budget.FiscalYear,
+				Budget:              float64(budget.BudgetCents) / 100.0,
+				Spent:               float64(budget.SpentCents) / 100.0,
+				Committed:           float64(budget.CommittedCents) / 100.0,
+				Remaining:           float64(budget.RemainingCents) / 100.0,
+				PendingApplications: budget.PendingApplications,
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, summary)
 }
 
@@ -183,35 +218,32 @@ func (h *Handler) GetDashboardUpcomingEvents(c *gin.Context) {
 	now := time.Now()
 	weekFromNow := now.AddDate(0, 0, 7)
 
-	rows, err := h.DB.Query(ctx,
-		`SELECT
-			e.id, e.title, e.starts_at, e.ends_at, e.location,
-			COALESCE(e.capacity, 0) as capacity,
-			0 as registered
-		FROM events e
-		WHERE e.tenant_id = $1
-		AND e.starts_at >= $2
-		AND e.starts_at <= $3
-		ORDER BY e.starts_at ASC
-		LIMIT 10`,
-		tenantID, now, weekFromNow)
-
+	occurrences, err := h.expandEvents(ctx, h.DB, tenantID, eventFilter{From: now, To: weekFromNow})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
 		return
 	}
-	defer rows.Close()
 
 	events := []DashboardUpcomingEvent{}
-	for rows.Next() {
-		var e DashboardUpcomingEvent
-		var location *string
-		err := rows.Scan(&e.ID, &e.Title, &e.StartsAt, &e.EndsAt, &location, &e.Capacity, &e.Registered)
-		if err != nil {
+	for _, o := range occurrences {
+		if o.Cancelled {
 			continue
 		}
-		if location != nil {
-			e.Location = *location
+		if len(events) == 10 {
+			break
+		}
+		e := DashboardUpcomingEvent{
+			ID:         o.EventID,
+			Title:      o.Title,
+			StartsAt:   o.StartsAt,
+			EndsAt:     o.EndsAt,
+			Registered: o.RegisteredCount,
+		}
+		if o.Capacity != nil {
+			e.Capacity = *o.Capacity
+		}
+		if o.Location != nil {
+			e.Location = *o.Location
 		}
 		events = append(events, e)
 	}
@@ -311,7 +343,7 @@ func (h *Handler) GetUtilizationSeries(c *gin.Context) {
 			WHERE f.tenant_id = $1
 			AND fs.starts_at < $3
 			AND fs.ends_at > $2
-			AND fs.status IN ('open', 'booked')`,
+			AND fs.status IN ('open', 'held', 'booked', 'reserved')`,
 			tenantID, weekStart, weekEnd).Scan(&totalMinutes)
 
 		pct := 0.0
@@ -325,7 +357,7 @@ func (h *Handler) GetUtilizationSeries(c *gin.Context) {
 				WHERE f.tenant_id = $1
 				AND fs.starts_at < $3
 				AND fs.ends_at > $2
-				AND fs.status = 'booked'`,
+				AND fs.status IN ('booked', 'reserved')`,
 				tenantID, weekStart, weekEnd).Scan(&bookedMinutes)
 
 			if err == nil {
@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Eligibility Rules ============
+
+type EligibilityRules struct {
+	MinAge                 *int       `json:"min_age"`
+	MaxAge                 *int       `json:"max_age"`
+	AgeCutoffDate          *time.Time `json:"-"`
+	ResidentsOnly          bool       `json:"residents_only"`
+	AllowedGenders         []string   `json:"allowed_genders"`
+	MembershipRequired     bool       `json:"membership_required"`
+	PrerequisiteProgramIDs []string   `json:"prerequisite_program_ids"`
+}
+
+type EligibilityRulesRequest struct {
+	MinAge                 *int     `json:"min_age"`
+	MaxAge                 *int     `json:"max_age"`
+	AgeCutoffDate          *string  `json:"age_cutoff_date"`
+	ResidentsOnly          bool     `json:"residents_only"`
+	AllowedGenders         []string `json:"allowed_genders"`
+	MembershipRequired     bool     `json:"membership_required"`
+	PrerequisiteProgramIDs []string `json:"prerequisite_program_ids"`
+}
+
+// EligibilityApplicant is everything the rules are evaluated against
+type EligibilityApplicant struct {
+	DateOfBirth       *time.Time
+	Age               *int
+	Gender            string
+	IsResident        bool
+	IsMember          bool
+	CompletedPrograms map[string]bool
+}
+
+// EligibilityViolation explains why a single rule rejected the applicant
+type EligibilityViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Evaluate checks every rule and returns one violation per failed rule.
+// Ages are computed as of the cutoff date, falling back to asOf.
+func (r *EligibilityRules) Evaluate(a EligibilityApplicant, asOf time.Time) []EligibilityViolation {
+	violations := []EligibilityViolation{}
+	if r == nil {
+		return violations
+	}
+
+	if r.MinAge != nil || r.MaxAge != nil {
+		cutoff := asOf
+		if r.AgeCutoffDate != nil {
+			cutoff = *r.AgeCutoffDate
+		}
+
+		var age *int
+		if a.DateOfBirth != nil {
+			computed := ageOn(*a.DateOfBirth, cutoff)
+			age = &computed
+		} else if a.Age != nil {
+			age = a.Age
+		}
+
+		switch {
+		case age == nil:
+			violations = append(violations, EligibilityViolation{
+				Rule:    "age",
+				Message: "participant date of birth is required for this program",
+			})
+		case r.MinAge != nil && *age < *r.MinAge:
+			violations = append(violations, EligibilityViolation{
+				Rule:    "age",
+				Message: fmt.Sprintf("participant must be at least %d as of %s", *r.MinAge, cutoff.Format("2006-01-02")),
+			})
+		case r.MaxAge != nil && *age > *r.MaxAge:
+			violations = append(violations, EligibilityViolation{
+				Rule:    "age",
+				Message: fmt.Sprintf("participant must be %d or younger as of %s", *r.MaxAge, cutoff.Format("2006-01-02")),
+			})
+		}
+	}
+
+	if r.ResidentsOnly && !a.IsResident {
+		violations = append(violations, EligibilityViolation{
+			Rule:    "residency",
+			Message: "this program is open to residents only",
+		})
+	}
+
+	if len(r.AllowedGenders) > 0 {
+		allowed := false
+		for _, g := range r.AllowedGenders {
+			if strings.EqualFold(g, a.Gender) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, EligibilityViolation{
+				Rule:    "gender",
+				Message: fmt.Sprintf("this division is limited to: %s", strings.Join(r.AllowedGenders, ", ")),
+			})
+		}
+	}
+
+	if r.MembershipRequired && !a.IsMember {
+		violations = append(violations, EligibilityViolation{
+			Rule:    "membership",
+			Message: "an active membership is required for this program",
+		})
+	}
+
+	for _, prereq := range r.PrerequisiteProgramIDs {
+		if !a.CompletedPrograms[prereq] {
+			violations = append(violations, EligibilityViolation{
+				Rule:    "prerequisite",
+				Message: fmt.Sprintf("prerequisite program %s has not been completed", prereq),
+			})
+		}
+	}
+
+	return violations
+}
+
+// ageOn returns the age in whole years of someone born on dob at date on
+func ageOn(dob, on time.Time) int {
+	age := on.Year() - dob.Year()
+	if on.Month() < dob.Month() || (on.Month() == dob.Month() && on.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
+
+// loadEligibilityRules returns the program's rules, or nil if it has none
+func (h *Handler) loadEligibilityRules(ctx context.Context, q dbtx, tenantID, programID string) (*EligibilityRules, error) {
+	var r EligibilityRules
+	err := q.QueryRow(ctx,
+		`SELECT min_age, max_age, age_cutoff_date, residents_only, allowed_genders,
+		        membership_required, prerequisite_program_ids::text[]
+		 FROM program_eligibility_rules WHERE program_id = $1 AND tenant_id = $2`,
+		programID, tenantID).Scan(&r.MinAge, &r.MaxAge, &r.AgeCutoffDate, &r.ResidentsOnly, &r.AllowedGenders,
+		&r.MembershipRequired, &r.PrerequisiteProgramIDs)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+var houseNumberPattern = regexp.MustCompile(`^[0-9]+[a-z]?\s+`)
+
+var streetSuffixes = map[string]string{
+	"street": "st", "avenue": "ave", "road": "rd", "drive": "dr", "lane": "ln",
+	"boulevard": "blvd", "court": "ct", "place": "pl", "terrace": "ter", "circle": "cir",
+	"parkway": "pkwy", "highway": "hwy", "way": "way",
+}
+
+// normalizeStreet reduces "123 Main Street" and "main st." to "main st" so a
+// resident's address can be compared against the tenant's street list
+func normalizeStreet(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, ".", "")
+	s = strings.ReplaceAll(s, ",", " ")
+	s = houseNumberPattern.ReplaceAllString(s, "")
+	words := strings.Fields(s)
+	if n := len(words); n > 0 {
+		if short, ok := streetSuffixes[words[n-1]]; ok {
+			words[n-1] = short
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeZip keeps the 5-digit prefix of a ZIP or ZIP+4
+func normalizeZip(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 5 {
+		s = s[:5]
+	}
+	return s
+}
+
+// isResident matches the user's address against the tenant's residency areas.
+// A tenant with no areas configured treats nobody as a resident.
+func (h *Handler) isResident(ctx context.Context, q dbtx, tenantID, userID string) (bool, error) {
+	var street, zip *string
+	err := q.QueryRow(ctx,
+		`SELECT street_address, zip_code FROM users WHERE id = $1`,
+		userID).Scan(&street, &zip)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT kind, value FROM tenant_residency_areas WHERE tenant_id = $1`,
+		tenantID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, value string
+		if err := rows.Scan(&kind, &value); err != nil {
+			return false, err
+		}
+		switch kind {
+		case "zip":
+			if zip != nil && normalizeZip(*zip) == normalizeZip(value) {
+				return true, nil
+			}
+		case "street":
+			if street != nil && normalizeStreet(*street) == normalizeStreet(value) {
+				return true, nil
+			}
+		}
+	}
+	return false, rows.Err()
+}
+
+// isMember reports whether the user holds a current membership with the tenant
+func (h *Handler) isMember(ctx context.Context, q dbtx, tenantID, userID string) (bool, error) {
+	var member bool
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(member_until >= CURRENT_DATE, false)
+		 FROM tenant_users WHERE tenant_id = $1 AND user_id = $2`,
+		tenantID, userID).Scan(&member)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return member, err
+}
+
+// completedPrograms returns the subset of programIDs the user has completed
+func (h *Handler) completedPrograms(ctx context.Context, q dbtx, tenantID, userID string, programIDs []string) (map[string]bool, error) {
+	completed := map[string]bool{}
+	if len(programIDs) == 0 {
+		return completed, nil
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT program_id::text FROM program_registrations
+		 WHERE tenant_id = $1 AND user_id = $2 AND status = 'completed' AND program_id = ANY($3::uuid[])`,
+		tenantID, userID, programIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		completed[id] = true
+	}
+	return completed, rows.Err()
+}
+
+// checkEligibility loads the program's rules and evaluates them for userID
+func (h *Handler) checkEligibility(ctx context.Context, q dbtx, tenantID, programID, userID string, a EligibilityApplicant) ([]EligibilityViolation, error) {
+	rules, err := h.loadEligibilityRules(ctx, q, tenantID, programID)
+	if err != nil || rules == nil {
+		return []EligibilityViolation{}, err
+	}
+
+	if rules.ResidentsOnly {
+		if a.IsResident, err = h.isResident(ctx, q, tenantID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if rules.MembershipRequired {
+		if a.IsMember, err = h.isMember(ctx, q, tenantID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if a.CompletedPrograms, err = h.completedPrograms(ctx, q, tenantID, userID, rules.PrerequisiteProgramIDs); err != nil {
+		return nil, err
+	}
+
+	// Without an explicit cutoff, age is measured on the program's first day
+	if rules.AgeCutoffDate == nil {
+		var startDate *time.Time
+		if err := q.QueryRow(ctx, `SELECT start_date FROM programs WHERE id = $1`, programID).Scan(&startDate); err == nil && startDate != nil {
+			rules.AgeCutoffDate = startDate
+		}
+	}
+
+	return rules.Evaluate(a, time.Now()), nil
+}
+
+// GetProgramEligibility returns the eligibility rules for a program
+func (h *Handler) GetProgramEligibility(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	programID := c.Param("id")
+
+	var exists bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM programs WHERE id = $1 AND tenant_id = $2)`,
+		programID, claims.TenantID).Scan(&exists)
+	if err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+
+	rules, err := h.loadEligibilityRules(ctx, h.DB, claims.TenantID.String(), programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if rules == nil {
+		rules = &EligibilityRules{}
+	}
+
+	var cutoff *string
+	if rules.AgeCutoffDate != nil {
+		formatted := rules.AgeCutoffDate.Format("2006-01-02")
+		cutoff = &formatted
+	}
+	if rules.AllowedGenders == nil {
+		rules.AllowedGenders = []string{}
+	}
+	if rules.PrerequisiteProgramIDs == nil {
+		rules.PrerequisiteProgramIDs = []string{}
+	}
+
+	c.JSON(http.StatusOK, EligibilityRulesRequest{
+		MinAge:                 rules.MinAge,
+		MaxAge:                 rules.MaxAge,
+		AgeCutoffDate:          cutoff,
+		ResidentsOnly:          rules.ResidentsOnly,
+		AllowedGenders:         rules.AllowedGenders,
+		MembershipRequired:     rules.MembershipRequired,
+		PrerequisiteProgramIDs: rules.PrerequisiteProgramIDs,
+	})
+}
+
+// UpdateProgramEligibility replaces the eligibility rules for a program
+func (h *Handler) UpdateProgramEligibility(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	var req EligibilityRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MinAge != nil && req.MaxAge != nil && *req.MinAge > *req.MaxAge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_age must not exceed max_age"})
+		return
+	}
+	if req.AgeCutoffDate != nil {
+		if _, err := time.Parse("2006-01-02", *req.AgeCutoffDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "age_cutoff_date must be YYYY-MM-DD"})
+			return
+		}
+	}
+	for _, id := range req.PrerequisiteProgramIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prerequisite program id: " + id})
+			return
+		}
+		if id == programID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a program cannot be its own prerequisite"})
+			return
+		}
+	}
+	if req.AllowedGenders == nil {
+		req.AllowedGenders = []string{}
+	}
+	if req.PrerequisiteProgramIDs == nil {
+		req.PrerequisiteProgramIDs = []string{}
+	}
+
+	ctx := context.Background()
+
+	// Verify ownership
+	var tenantID string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM programs WHERE id = $1`, programID).Scan(&tenantID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if tenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Prerequisites must be programs in the same tenant
+	var foreign int
+	err = h.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FROM unnest($1::uuid[]) AS p(id)
+		 WHERE NOT EXISTS (SELECT 1 FROM programs WHERE id = p.id AND tenant_id = $2)`,
+		req.PrerequisiteProgramIDs, tenantID).Scan(&foreign)
+	if err != nil || foreign > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prerequisite programs must belong to this department"})
+		return
+	}
+
+	_, err = h.DB.Exec(ctx,
+		`INSERT INTO program_eligibility_rules (
+			program_id, tenant_id, min_age, max_age, age_cutoff_date, residents_only,
+			allowed_genders, membership_required, prerequisite_program_ids
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::uuid[])
+		ON CONFLICT (program_id) DO UPDATE SET
+			min_age = EXCLUDED.min_age, max_age = EXCLUDED.max_age,
+			age_cutoff_date = EXCLUDED.age_cutoff_date, residents_only = EXCLUDED.residents_only,
+			allowed_genders = EXCLUDED.allowed_genders, membership_required = EXCLUDED.membership_required,
+			prerequisite_program_ids = EXCLUDED.prerequisite_program_ids, updated_at = now()`,
+		programID, tenantID, req.MinAge, req.MaxAge, req.AgeCutoffDate, req.ResidentsOnly,
+		req.AllowedGenders, req.MembershipRequired, req.PrerequisiteProgramIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save eligibility rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ============ Residency Areas ============
+
+type ResidencyAreasRequest struct {
+	Streets []string `json:"streets"`
+	Zips    []string `json:"zips"`
+}
+
+// GetResidencyAreas returns the streets and ZIP codes that count as resident
+func (h *Handler) GetResidencyAreas(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT kind, value FROM tenant_residency_areas WHERE tenant_id = $1 ORDER BY kind, value`,
+		claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	areas := ResidencyAreasRequest{Streets: []string{}, Zips: []string{}}
+	for rows.Next() {
+		var kind, value string
+		if err := rows.Scan(&kind, &value); err != nil {
+			continue
+		}
+		if kind == "zip" {
+			areas.Zips = append(areas.Zips, value)
+		} else {
+			areas.Streets = append(areas.Streets, value)
+		}
+	}
+
+	c.JSON(http.StatusOK, areas)
+}
+
+// UpdateResidencyAreas replaces the tenant's street and ZIP lists
+func (h *Handler) UpdateResidencyAreas(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ResidencyAreasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tenant_residency_areas WHERE tenant_id = $1`, claims.TenantID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update residency areas"})
+		return
+	}
+
+	insert := func(kind string, values []string, normalize func(string) string) error {
+		for _, v := range values {
+			v = normalize(v)
+			if v == "" {
+				continue
+			}
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO tenant_residency_areas (tenant_id, kind, value) VALUES ($1, $2, $3)
+				 ON CONFLICT DO NOTHING`,
+				claims.TenantID, kind, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert("street", req.Streets, normalizeStreet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update residency areas"})
+		return
+	}
+	if err := insert("zip", req.Zips, normalizeZip); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update residency areas"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ============ Memberships ============
+
+type MembershipRequest struct {
+	MemberUntil *string `json:"member_until"`
+}
+
+// UpdateResidentMembership sets or clears a resident's membership expiry
+func (h *Handler) UpdateResidentMembership(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MemberUntil != nil {
+		if _, err := time.Parse("2006-01-02", *req.MemberUntil); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "member_until must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`UPDATE tenant_users SET member_until = $1, updated_at = now()
+		 WHERE tenant_id = $2 AND user_id = $3`,
+		req.MemberUntil, claims.TenantID, c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resident not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,652 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/qr"
+	"github.com/rec-hub/backend/pkg/tickets"
+)
+
+// ============ Event Check-In ============
+
+// Check-in results. A scan of a party that is already in is a duplicate;
+// sync results for scans that could not be applied are rejected.
+const (
+	CheckInComplete  = "checked_in"
+	CheckInPartial   = "partial"
+	CheckInDuplicate = "duplicate"
+	CheckInRejected  = "rejected"
+)
+
+// ticketQRScale is the size of a ticket's QR code in pixels per module
+const ticketQRScale = 8
+
+var (
+	errTicketRequired    = errors.New("code or registration_id is required")
+	errTicketNotFound    = errors.New("ticket not found")
+	errTicketOtherEvent  = errors.New("ticket is for a different event")
+	errTicketOtherDate   = errors.New("ticket is for a different date")
+	errTicketCancelled   = errors.New("RSVP has been cancelled")
+	errCheckInOccurrence = errors.New("this occurrence has been cancelled")
+)
+
+// checkInCountError is a scan for more of the party than are left to
+// check in
+type checkInCountError struct {
+	Remaining int
+}
+
+func (e *checkInCountError) Error() string {
+	if e.Remaining == 1 {
+		return "only 1 person left to check in on this ticket"
+	}
+	return fmt.Sprintf("only %d people left to check in on this ticket", e.Remaining)
+}
+
+// CheckInRequest scans a ticket, or checks in an RSVP picked from the
+// attendee list. Count defaults to everyone in the party not yet in.
+type CheckInRequest struct {
+	Code            string     `json:"code"`
+	RegistrationID  string     `json:"registration_id"`
+	Count           int        `json:"count" binding:"omitempty,min=1,max=20"`
+	OccurrenceStart *time.Time `json:"occurrence_start"`
+}
+
+// CheckInScan is a scan made on a device, possibly while offline. ClientID
+// is the device's id for the scan, so uploading it again is harmless.
+type CheckInScan struct {
+	ClientID        string     `json:"client_id" binding:"required,max=100"`
+	Code            string     `json:"code"`
+	RegistrationID  string     `json:"registration_id"`
+	Count           int        `json:"count" binding:"omitempty,min=1,max=20"`
+	OccurrenceStart *time.Time `json:"occurrence_start"`
+	ScannedAt       *time.Time `json:"scanned_at"`
+}
+
+type CheckInSyncRequest struct {
+	Scans []CheckInScan `json:"scans" binding:"required,min=1,max=500,dive"`
+}
+
+type CheckInResult struct {
+	ClientID       string     `json:"client_id,omitempty"`
+	Result         string     `json:"result"`
+	Error          string     `json:"error,omitempty"`
+	Remaining      *int       `json:"remaining,omitempty"`
+	RegistrationID string     `json:"registration_id,omitempty"`
+	Name           string     `json:"name,omitempty"`
+	AttendeeCount  int        `json:"attendee_count,omitempty"`
+	Count          int        `json:"count"`
+	CheckedIn      int        `json:"checked_in"`
+	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"`
+	Replayed       bool       `json:"replayed,omitempty"`
+}
+
+// checkInCounts is the live tally for an event or occurrence
+type checkInCounts struct {
+	Expected         int        `json:"expected"`
+	CheckedIn        int        `json:"checked_in"`
+	Parties          int        `json:"parties"`
+	PartiesCheckedIn int        `json:"parties_checked_in"`
+	PartiesPartial   int        `json:"parties_partial"`
+	LastScanAt       *time.Time `json:"last_scan_at"`
+}
+
+// ticketCode signs the ticket for an RSVP
+func (h *Handler) ticketCode(tenantID string, registrationID uuid.UUID) string {
+	return tickets.Sign(h.Config.TicketSecret, tenantID, registrationID)
+}
+
+// ticketImageURL is where the ticket's QR code can be fetched, for emails
+func (h *Handler) ticketImageURL(ctx context.Context, tenantID, code string) string {
+	return fmt.Sprintf("https://%s/api/public/tickets/%s/qr.png", h.tenantPrimaryDomain(ctx, tenantID), code)
+}
+
+// scannedRegistration returns the RSVP a scan names, by ticket or by id
+func (h *Handler) scannedRegistration(tenantID, code, registrationID string) (string, error) {
+	if code != "" {
+		id, err := tickets.Verify(h.Config.TicketSecret, tenantID, code)
+		if err != nil {
+			return "", err
+		}
+		return id.String(), nil
+	}
+	if registrationID == "" {
+		return "", errTicketRequired
+	}
+	if _, err := uuid.Parse(registrationID); err != nil {
+		return "", errTicketNotFound
+	}
+	return registrationID, nil
+}
+
+// checkIn is one scan to apply
+type checkIn struct {
+	RegistrationID string
+	// Count is how many of the party came in; 0 means everyone left
+	Count     int
+	ClientID  *string
+	ScannedAt time.Time
+	ScannedBy uuid.UUID
+	// Clamp checks in whoever is left when Count is more than that, rather
+	// than failing, for scans that have already happened offline
+	Clamp bool
+}
+
+func sameOccurrence(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// applyCheckIn checks in some or all of an RSVP's party and records the
+// scan. The first check-in marks the RSVP attended; a scan once everyone
+// is in is recorded as a duplicate and changes nothing.
+func applyCheckIn(ctx context.Context, q dbtx, tenantID string, e *rsvpEvent, s checkIn) (*CheckInResult, error) {
+	r, err := lockEventRegistration(ctx, q, tenantID, s.RegistrationID)
+	if err == pgx.ErrNoRows {
+		return nil, errTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.EventID != e.ID:
+		return nil, errTicketOtherEvent
+	case !sameOccurrence(r.OccurrenceStart, e.Occurrence):
+		return nil, errTicketOtherDate
+	case r.Status == "cancelled":
+		return nil, errTicketCancelled
+	}
+
+	remaining := r.AttendeeCount - r.CheckedIn
+	count := s.Count
+	if count == 0 || (s.Clamp && count > remaining) {
+		count = remaining
+	}
+	if remaining > 0 && count > remaining {
+		return nil, &checkInCountError{Remaining: remaining}
+	}
+
+	result := &CheckInResult{RegistrationID: r.ID, Count: count}
+	switch {
+	case remaining == 0:
+		result.Result = CheckInDuplicate
+	case count == remaining:
+		result.Result = CheckInComplete
+	default:
+		result.Result = CheckInPartial
+	}
+
+	if count > 0 {
+		status := r.Status
+		if status != "attended" {
+			if err := eventRegistrationMachine.Check(status, "attended"); err != nil {
+				return nil, err
+			}
+			status = "attended"
+		}
+		_, err = q.Exec(ctx,
+			`UPDATE event_registrations
+			 SET checked_in_count = checked_in_count + $2, checked_in_at = COALESCE(checked_in_at, $3),
+			     status = $4, updated_at = now()
+			 WHERE id = $1`,
+			r.ID, count, s.ScannedAt, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = q.Exec(ctx,
+		`INSERT INTO event_check_ins (tenant_id, registration_id, event_id, occurrence_start, count, result, client_scan_id, scanned_by, scanned_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		tenantID, r.ID, r.EventID, r.OccurrenceStart, count, result.Result, s.ClientID, s.ScannedBy, s.ScannedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadCheckInState(ctx, q, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadCheckInState fills in who the RSVP is for and how many are in
+func loadCheckInState(ctx context.Context, q dbtx, result *CheckInResult) error {
+	return q.QueryRow(ctx,
+		`SELECT COALESCE(NULLIF(concat_ws(' ', u.first_name, u.last_name), ''), er.guest_name, ''),
+		        er.attendee_count, er.checked_in_count, er.checked_in_at
+		 FROM event_registrations er
+		 LEFT JOIN users u ON er.user_id = u.id
+		 WHERE er.id = $1`,
+		result.RegistrationID).Scan(&result.Name, &result.AttendeeCount, &result.CheckedIn, &result.CheckedInAt)
+}
+
+// replayedCheckIn returns the result of a synced scan that was already
+// applied, or nil
+func replayedCheckIn(ctx context.Context, q dbtx, tenantID, clientID string) (*CheckInResult, error) {
+	result := &CheckInResult{ClientID: clientID, Replayed: true}
+	err := q.QueryRow(ctx,
+		`SELECT registration_id::text, result, count FROM event_check_ins
+		 WHERE tenant_id = $1 AND client_scan_id = $2`,
+		tenantID, clientID).Scan(&result.RegistrationID, &result.Result, &result.Count)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := loadCheckInState(ctx, q, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func loadCheckInCounts(ctx context.Context, q dbtx, tenantID string, e *rsvpEvent) (*checkInCounts, error) {
+	var counts checkInCounts
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(attendee_count), 0), COALESCE(SUM(checked_in_count), 0), COUNT(*),
+		        COUNT(*) FILTER (WHERE checked_in_count = attendee_count),
+		        COUNT(*) FILTER (WHERE checked_in_count > 0 AND checked_in_count < attendee_count),
+		        (SELECT MAX(scanned_at) FROM event_check_ins
+		         WHERE event_id = $2 AND occurrence_start IS NOT DISTINCT FROM $3)
+		 FROM event_registrations
+		 WHERE tenant_id = $1 AND event_id = $2 AND occurrence_start IS NOT DISTINCT FROM $3 AND status <> 'cancelled'`,
+		tenantID, e.ID, e.Occurrence).Scan(&counts.Expected, &counts.CheckedIn, &counts.Parties,
+		&counts.PartiesCheckedIn, &counts.PartiesPartial, &counts.LastScanAt)
+	if err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// checkInRejection is the reason a scan cannot be applied, or "" when err
+// is not the scan's fault
+func checkInRejection(err error) (string, *int) {
+	var ce *checkInCountError
+	switch {
+	case errors.As(err, &ce):
+		return ce.Error(), &ce.Remaining
+	case errors.Is(err, tickets.ErrInvalid),
+		errors.Is(err, errTicketRequired),
+		errors.Is(err, errTicketNotFound),
+		errors.Is(err, errTicketOtherEvent),
+		errors.Is(err, errTicketOtherDate),
+		errors.Is(err, errTicketCancelled),
+		errors.Is(err, errCheckInOccurrence),
+		errors.Is(err, errOccurrenceRequired),
+		errors.Is(err, errOccurrenceNotFound):
+		return err.Error(), nil
+	}
+	return "", nil
+}
+
+// respondCheckInError maps check-in failures onto responses
+func respondCheckInError(c *gin.Context, err error) {
+	var ce *checkInCountError
+	switch {
+	case errors.As(err, &ce):
+		c.JSON(http.StatusConflict, gin.H{"error": ce.Error(), "remaining": ce.Remaining})
+	case errors.Is(err, tickets.ErrInvalid), errors.Is(err, errTicketRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errTicketNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errTicketOtherEvent), errors.Is(err, errTicketOtherDate),
+		errors.Is(err, errTicketCancelled), errors.Is(err, errCheckInOccurrence):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondRSVPError(c, err, "check-in failed")
+	}
+}
+
+// loadCheckInEvent loads the event or occurrence being checked into
+func (h *Handler) loadCheckInEvent(ctx context.Context, q dbtx, tenantID, eventID string, occurrence *time.Time) (*rsvpEvent, error) {
+	e, err := h.loadRSVPEvent(ctx, q, tenantID, eventID, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	if e.Cancelled {
+		return nil, errCheckInOccurrence
+	}
+	return e, nil
+}
+
+// CheckInEventTicket checks in a ticket scanned at the door
+func (h *Handler) CheckInEventTicket(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	registrationID, err := h.scannedRegistration(tenantID, req.Code, req.RegistrationID)
+	if err != nil {
+		respondCheckInError(c, err)
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	event, err := h.loadCheckInEvent(ctx, tx, tenantID, c.Param("id"), req.OccurrenceStart)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		respondCheckInError(c, err)
+		return
+	}
+
+	result, err := applyCheckIn(ctx, tx, tenantID, event, checkIn{
+		RegistrationID: registrationID,
+		Count:          req.Count,
+		ScannedAt:      time.Now(),
+		ScannedBy:      claims.UserID,
+	})
+	if err != nil {
+		respondCheckInError(c, err)
+		return
+	}
+
+	counts, err := loadCheckInCounts(ctx, tx, tenantID, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"check_in": result,
+		"totals":   counts,
+	})
+}
+
+// GetEventCheckIn returns the live check-in tally for an event
+func (h *Handler) GetEventCheckIn(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var occurrence *time.Time
+	if v := c.Query("occurrence_start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "occurrence_start must be RFC3339"})
+			return
+		}
+		occurrence = &t
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	event, err := h.loadRSVPEvent(ctx, h.DB, tenantID, c.Param("id"), occurrence)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		respondRSVPError(c, err, "database error")
+		return
+	}
+
+	counts, err := loadCheckInCounts(ctx, h.DB, tenantID, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":         event.ID,
+		"occurrence_start": event.Occurrence,
+		"title":            event.Title,
+		"starts_at":        event.StartsAt,
+		"capacity":         event.Capacity,
+		"cancelled":        event.Cancelled,
+		"totals":           counts,
+	})
+}
+
+// SyncEventCheckIns applies scans uploaded by a device that was offline.
+// Scans are applied in the order they were made, each with its own result,
+// and scans already uploaded return the result they had the first time.
+func (h *Handler) SyncEventCheckIns(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !canTakeAttendance(claims.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CheckInSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	eventID := c.Param("id")
+
+	var exists bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM events WHERE id = $1 AND tenant_id = $2)`,
+		eventID, tenantID).Scan(&exists)
+	if err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	now := time.Now()
+	order := make([]int, len(req.Scans))
+	for i := range order {
+		order[i] = i
+		if at := req.Scans[i].ScannedAt; at == nil || at.After(now) {
+			req.Scans[i].ScannedAt = &now
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return req.Scans[order[a]].ScannedAt.Before(*req.Scans[order[b]].ScannedAt)
+	})
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Scans usually share an occurrence, so load each one once
+	events := map[int64]*rsvpEvent{}
+	eventErrs := map[int64]error{}
+	loadEvent := func(occurrence *time.Time) (*rsvpEvent, error) {
+		var key int64
+		if occurrence != nil {
+			key = occurrence.Unix()
+		}
+		if e, ok := events[key]; ok {
+			return e, eventErrs[key]
+		}
+		e, err := h.loadCheckInEvent(ctx, tx, tenantID, eventID, occurrence)
+		events[key], eventErrs[key] = e, err
+		return e, err
+	}
+
+	results := make([]*CheckInResult, len(req.Scans))
+	tally := map[string]int{CheckInComplete: 0, CheckInPartial: 0, CheckInDuplicate: 0, CheckInRejected: 0}
+	for _, i := range order {
+		scan := req.Scans[i]
+
+		result, err := replayedCheckIn(ctx, tx, tenantID, scan.ClientID)
+		if err == nil && result == nil {
+			result, err = h.syncCheckIn(ctx, tx, tenantID, claims.UserID, scan, loadEvent)
+		}
+		if err != nil {
+			reason, remaining := checkInRejection(err)
+			if reason == "" {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "check-in sync failed"})
+				return
+			}
+			result = &CheckInResult{Result: CheckInRejected, Error: reason, Remaining: remaining}
+		}
+		result.ClientID = scan.ClientID
+		results[i] = result
+		tally[result.Result]++
+	}
+
+	// Totals are for the occurrence of the latest scan
+	var counts *checkInCounts
+	if e, err := loadEvent(req.Scans[order[len(order)-1]].OccurrenceStart); err == nil {
+		counts, err = loadCheckInCounts(ctx, tx, tenantID, e)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"summary": tally,
+		"totals":  counts,
+	})
+}
+
+// syncCheckIn applies one uploaded scan
+func (h *Handler) syncCheckIn(ctx context.Context, q dbtx, tenantID string, scannedBy uuid.UUID, scan CheckInScan, loadEvent func(*time.Time) (*rsvpEvent, error)) (*CheckInResult, error) {
+	registrationID, err := h.scannedRegistration(tenantID, scan.Code, scan.RegistrationID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := loadEvent(scan.OccurrenceStart)
+	if err == pgx.ErrNoRows {
+		return nil, errOccurrenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	clientID := scan.ClientID
+	return applyCheckIn(ctx, q, tenantID, event, checkIn{
+		RegistrationID: registrationID,
+		Count:          scan.Count,
+		ClientID:       &clientID,
+		ScannedAt:      *scan.ScannedAt,
+		ScannedBy:      scannedBy,
+		Clamp:          true,
+	})
+}
+
+// GetMyEventTicket returns the ticket for one of the caller's RSVPs
+func (h *Handler) GetMyEventTicket(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	var id uuid.UUID
+	var status string
+	var count, checkedIn int
+	err := h.DB.QueryRow(ctx,
+		`SELECT id, status, attendee_count, checked_in_count FROM event_registrations
+		 WHERE id = $1 AND tenant_id = $2 AND user_id = $3`,
+		c.Param("id"), tenantID, claims.UserID).Scan(&id, &status, &count, &checkedIn)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+		return
+	}
+	if status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": errTicketCancelled.Error()})
+		return
+	}
+
+	code := h.ticketCode(tenantID, id)
+	c.JSON(http.StatusOK, gin.H{
+		"registration_id": id.String(),
+		"code":            code,
+		"qr_url":          h.ticketImageURL(ctx, tenantID, code),
+		"status":          status,
+		"attendee_count":  count,
+		"checked_in":      checkedIn,
+	})
+}
+
+// GetPublicTicketQR renders a ticket as a QR code image. The code is the
+// credential, so nothing beyond its signature is checked.
+func (h *Handler) GetPublicTicketQR(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	code := c.Param("code")
+	if _, err := tickets.Verify(h.Config.TicketSecret, tenantID, code); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errTicketNotFound.Error()})
+		return
+	}
+
+	symbol, err := qr.Encode([]byte(code))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render ticket"})
+		return
+	}
+	png, err := symbol.PNG(ticketQRScale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render ticket"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, "image/png", png)
+}
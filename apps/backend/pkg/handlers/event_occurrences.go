@@ -0,0 +1,877 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Recurring Events ============
+
+var (
+	errOccurrenceRequired = errors.New("occurrence_start is required for recurring events")
+	errOccurrenceNotFound = errors.New("occurrence not found")
+)
+
+// eventChangeError is returned for event edits that conflict with RSVPs
+// or the series' rule
+type eventChangeError struct {
+	status  int
+	message string
+}
+
+func (e *eventChangeError) Error() string { return e.message }
+
+// EventOccurrenceRequest edits one occurrence of a recurring event.
+// Scope "this" changes only that occurrence, "following" splits the series
+// there and "all" changes the whole series. Omitted fields are unchanged.
+type EventOccurrenceRequest struct {
+	OccurrenceStart time.Time  `json:"occurrence_start" binding:"required"`
+	Scope           string     `json:"scope" binding:"required,oneof=this following all"`
+	Title           *string    `json:"title"`
+	Description     *string    `json:"description"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	Location        *string    `json:"location"`
+	Capacity        *int       `json:"capacity"`
+	Cancelled       *bool      `json:"cancelled"`
+	RRule           *string    `json:"rrule"`
+}
+
+// EventOccurrence is one dated instance of an event. One-off events have a
+// single occurrence with no occurrence_start.
+type EventOccurrence struct {
+	EventID         string     `json:"event_id"`
+	OccurrenceStart *time.Time `json:"occurrence_start"`
+	Title           string     `json:"title"`
+	Description     *string    `json:"description"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Location        *string    `json:"location"`
+	Capacity        *int       `json:"capacity"`
+	RegisteredCount int        `json:"registered_count"`
+	SpotsLeft       *int       `json:"spots_left"`
+	Category        *string    `json:"category"`
+	ImageURL        *string    `json:"image_url"`
+	Slug            *string    `json:"slug"`
+	Recurring       bool       `json:"recurring"`
+	Cancelled       bool       `json:"cancelled"`
+
+	series *eventSeries
+}
+
+// eventSeries is an event row. One-off events are series without a rule.
+type eventSeries struct {
+	ID          string
+	Title       string
+	Description *string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Location    *string
+	Capacity    *int
+	Category    *string
+	Status      string
+	Visibility  bool
+	ImageURL    *string
+	Slug        *string
+	Registered  int
+	RRule       *string
+	Exdates     []time.Time
+	SeriesID    *string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+const eventSeriesColumns = `e.id, e.title, e.description, e.starts_at, e.ends_at, e.location, e.capacity, e.category,
+	e.status, e.visibility, e.image_url, e.slug, e.registered_count, e.rrule, e.exdates, e.series_id::text,
+	e.created_at, e.updated_at`
+
+func scanEventSeries(row pgx.Row) (*eventSeries, error) {
+	var s eventSeries
+	err := row.Scan(&s.ID, &s.Title, &s.Description, &s.StartsAt, &s.EndsAt, &s.Location, &s.Capacity, &s.Category,
+		&s.Status, &s.Visibility, &s.ImageURL, &s.Slug, &s.Registered, &s.RRule, &s.Exdates, &s.SeriesID,
+		&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func lockEventSeries(ctx context.Context, q dbtx, tenantID, eventID string) (*eventSeries, error) {
+	return scanEventSeries(q.QueryRow(ctx,
+		`SELECT `+eventSeriesColumns+` FROM events e WHERE e.id = $1 AND e.tenant_id = $2 FOR UPDATE`,
+		eventID, tenantID))
+}
+
+// recurrence returns the series' rule anchored in loc, or nil for one-off
+// events
+func (s *eventSeries) recurrence(loc *time.Location) (*schedule.Recurrence, error) {
+	if s.RRule == nil || *s.RRule == "" {
+		return nil, nil
+	}
+	rule, err := schedule.ParseRule(*s.RRule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule.Recurrence{Rule: rule, Start: s.StartsAt, Location: loc, Exceptions: s.Exdates}, nil
+}
+
+// normalizeEventRule parses an rrule from a request and checks starts_at
+// is its first occurrence. It returns the canonical rule, or nil when the
+// event does not repeat.
+func normalizeEventRule(rrule *string, startsAt time.Time, loc *time.Location) (*string, error) {
+	if rrule == nil || strings.TrimSpace(*rrule) == "" {
+		return nil, nil
+	}
+	rule, err := schedule.ParseRule(*rrule)
+	if err != nil {
+		return nil, err
+	}
+	rec := schedule.Recurrence{Rule: rule, Start: startsAt, Location: loc}
+	if !rec.Includes(startsAt) {
+		return nil, errors.New("starts_at must fall on the rrule's pattern")
+	}
+	// Stored rules name UNTIL as an instant
+	canonical := rule.InLocation(loc).String()
+	return &canonical, nil
+}
+
+// occurrenceOverride is an event_occurrences row
+type occurrenceOverride struct {
+	OccurrenceStart time.Time
+	StartsAt        *time.Time
+	EndsAt          *time.Time
+	Title           *string
+	Description     *string
+	Location        *string
+	Capacity        *int
+	Cancelled       bool
+	Registered      int
+}
+
+// loadOccurrenceOverrides returns the overrides of the given events keyed
+// by event id and occurrence start
+func loadOccurrenceOverrides(ctx context.Context, q dbtx, eventIDs []string) (map[string]map[int64]*occurrenceOverride, error) {
+	out := make(map[string]map[int64]*occurrenceOverride)
+	if len(eventIDs) == 0 {
+		return out, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT event_id::text, occurrence_start, starts_at, ends_at, title, description, location, capacity, cancelled, registered_count
+		 FROM event_occurrences WHERE event_id = ANY($1::uuid[])`,
+		eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var eventID string
+		var o occurrenceOverride
+		if err := rows.Scan(&eventID, &o.OccurrenceStart, &o.StartsAt, &o.EndsAt, &o.Title, &o.Description,
+			&o.Location, &o.Capacity, &o.Cancelled, &o.Registered); err != nil {
+			return nil, err
+		}
+		if out[eventID] == nil {
+			out[eventID] = make(map[int64]*occurrenceOverride)
+		}
+		out[eventID][o.OccurrenceStart.Unix()] = &o
+	}
+	return out, rows.Err()
+}
+
+// occurrence builds the instance of the series starting at start, with
+// its override applied. One-off events pass their own start and no
+// override.
+func (s *eventSeries) occurrence(start time.Time, o *occurrenceOverride) EventOccurrence {
+	occ := EventOccurrence{
+		EventID:         s.ID,
+		Title:           s.Title,
+		Description:     s.Description,
+		StartsAt:        start,
+		EndsAt:          start.Add(s.EndsAt.Sub(s.StartsAt)),
+		Location:        s.Location,
+		Capacity:        s.Capacity,
+		RegisteredCount: s.Registered,
+		Category:        s.Category,
+		ImageURL:        s.ImageURL,
+		Slug:            s.Slug,
+		series:          s,
+	}
+	if s.RRule != nil {
+		key := start
+		occ.OccurrenceStart = &key
+		occ.Recurring = true
+		occ.RegisteredCount = 0
+	}
+	if o != nil {
+		if o.StartsAt != nil {
+			occ.StartsAt, occ.EndsAt = *o.StartsAt, *o.EndsAt
+		}
+		if o.Title != nil {
+			occ.Title = *o.Title
+		}
+		if o.Description != nil {
+			occ.Description = o.Description
+		}
+		if o.Location != nil {
+			occ.Location = o.Location
+		}
+		if o.Capacity != nil {
+			occ.Capacity = o.Capacity
+		}
+		occ.Cancelled = o.Cancelled
+		occ.RegisteredCount = o.Registered
+	}
+	if occ.Capacity != nil {
+		left := max(*occ.Capacity-occ.RegisteredCount, 0)
+		occ.SpotsLeft = &left
+	}
+	return occ
+}
+
+// eventFilter selects occurrences for expandEvents
+type eventFilter struct {
+	From time.Time
+	To   time.Time
+	// PublicOnly keeps active, visible events and drops cancelled occurrences
+	PublicOnly bool
+	// EventID keeps the occurrences of one event
+	EventID string
+	// Categories keeps events in any of these categories, ignoring case
+	Categories []string
+	// Search keeps occurrences whose title, description or location
+	// contains every word, ignoring case
+	Search string
+	// After skips occurrences up to and including a page's last one
+	After *eventCursor
+	// OneOffsUnbounded keeps one-off events starting after To; To then only
+	// bounds the expansion of recurring events
+	OneOffsUnbounded bool
+	Limit            int
+}
+
+// eventCursor is the position of an occurrence in start order
+type eventCursor struct {
+	StartsAt time.Time
+	EventID  string
+}
+
+// precedes reports whether the cursor comes before o
+func (c *eventCursor) precedes(o EventOccurrence) bool {
+	if !o.StartsAt.Equal(c.StartsAt) {
+		return o.StartsAt.After(c.StartsAt)
+	}
+	return o.EventID > c.EventID
+}
+
+// matches reports whether an occurrence contains every search word
+func (f *eventFilter) matches(o EventOccurrence) bool {
+	words := strings.Fields(strings.ToLower(f.Search))
+	if len(words) == 0 {
+		return true
+	}
+	text := strings.ToLower(o.Title + " " + derefString(o.Description) + " " + derefString(o.Location))
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// expandEvents returns the tenant's event occurrences starting within
+// [From, To), recurring events expanded, in start order
+func (h *Handler) expandEvents(ctx context.Context, q dbtx, tenantID string, f eventFilter) ([]EventOccurrence, error) {
+	categories := make([]string, 0, len(f.Categories))
+	for _, c := range f.Categories {
+		categories = append(categories, strings.ToLower(c))
+	}
+	rows, err := q.Query(ctx,
+		`SELECT `+eventSeriesColumns+` FROM events e
+		 WHERE e.tenant_id = $1 AND (e.starts_at < $3 OR ($7 AND e.rrule IS NULL))
+		   AND (e.rrule IS NOT NULL OR e.starts_at >= $2)
+		   AND (NOT $4 OR (e.status = 'active' AND e.visibility = true))
+		   AND (cardinality($5::text[]) = 0 OR lower(e.category) = ANY($5))
+		   AND ($6 = '' OR e.id::text = $6)`,
+		tenantID, f.From, f.To, f.PublicOnly, categories, f.EventID, f.OneOffsUnbounded)
+	if err != nil {
+		return nil, err
+	}
+	var series []*eventSeries
+	var recurringIDs []string
+	for rows.Next() {
+		s, err := scanEventSeries(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		series = append(series, s)
+		if s.RRule != nil {
+			recurringIDs = append(recurringIDs, s.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := loadOccurrenceOverrides(ctx, q, recurringIDs)
+	if err != nil {
+		return nil, err
+	}
+	loc := h.tenantLocation(ctx, q, tenantID)
+
+	inRange := func(t time.Time) bool { return !t.Before(f.From) && t.Before(f.To) }
+	selected := func(o EventOccurrence) bool {
+		return f.matches(o) && (f.After == nil || f.After.precedes(o))
+	}
+	keep := func(o EventOccurrence) bool { return inRange(o.StartsAt) && selected(o) }
+	var out []EventOccurrence
+	for _, s := range series {
+		rec, err := s.recurrence(loc)
+		if err != nil {
+			// A rule that no longer parses is listed as its first date
+			rec = nil
+		}
+		if rec == nil {
+			occ := s.occurrence(s.StartsAt, nil)
+			unbounded := f.OneOffsUnbounded && s.RRule == nil && !occ.StartsAt.Before(f.From)
+			if keep(occ) || (unbounded && selected(occ)) {
+				out = append(out, occ)
+			}
+			continue
+		}
+
+		seen := make(map[int64]bool)
+		add := func(start time.Time) {
+			seen[start.Unix()] = true
+			occ := s.occurrence(start, overrides[s.ID][start.Unix()])
+			if !keep(occ) || (f.PublicOnly && occ.Cancelled) {
+				return
+			}
+			out = append(out, occ)
+		}
+		for _, start := range rec.Between(f.From, f.To) {
+			add(start)
+		}
+		// Occurrences moved into the range from outside it
+		for key, o := range overrides[s.ID] {
+			if !seen[key] && o.StartsAt != nil && inRange(*o.StartsAt) && rec.Includes(o.OccurrenceStart) {
+				add(o.OccurrenceStart)
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].EventID < out[j].EventID
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+// wallClockShift returns a function moving times by the calendar days and
+// time of day between from and to in loc, so a series moved from Tuesday
+// 6 PM to Wednesday 7 PM stays at 7 PM across daylight saving changes
+func wallClockShift(from, to time.Time, loc *time.Location) func(time.Time) time.Time {
+	f, t := from.In(loc), to.In(loc)
+	days := int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(f.Year(), f.Month(), f.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	seconds := (t.Hour()*3600 + t.Minute()*60 + t.Second()) - (f.Hour()*3600 + f.Minute()*60 + f.Second())
+	return func(x time.Time) time.Time {
+		x = x.In(loc)
+		return time.Date(x.Year(), x.Month(), x.Day()+days, x.Hour(), x.Minute(), x.Second()+seconds, 0, loc)
+	}
+}
+
+// moveOccurrences re-keys the overrides and RSVPs of fromEventID at or
+// after since onto toEventID, shifting their occurrence starts. Rows are
+// moved in the order that keeps the unique keys free.
+func moveOccurrences(ctx context.Context, q dbtx, fromEventID, toEventID string, since time.Time, shift func(time.Time) time.Time) error {
+	order := "ASC"
+	probe := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	if shift(probe).After(probe) {
+		order = "DESC"
+	}
+
+	for _, table := range []string{"event_occurrences", "event_registrations"} {
+		rows, err := q.Query(ctx,
+			`SELECT id::text, occurrence_start FROM `+table+`
+			 WHERE event_id = $1 AND occurrence_start >= $2
+			 ORDER BY occurrence_start `+order,
+			fromEventID, since)
+		if err != nil {
+			return err
+		}
+		type moved struct {
+			id    string
+			start time.Time
+		}
+		var pending []moved
+		for rows.Next() {
+			var m moved
+			if err := rows.Scan(&m.id, &m.start); err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, m := range pending {
+			_, err := q.Exec(ctx,
+				`UPDATE `+table+` SET event_id = $2, occurrence_start = $3, updated_at = now() WHERE id = $1`,
+				m.id, toEventID, shift(m.start))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileSeries drops overrides for occurrences the series no longer
+// generates and checks every RSVP still has an occurrence with room for it
+func reconcileSeries(ctx context.Context, q dbtx, s *eventSeries, loc *time.Location) error {
+	rec, err := s.recurrence(loc)
+	if err != nil {
+		return err
+	}
+
+	var oneOffRSVPs int
+	err = q.QueryRow(ctx,
+		`SELECT count(*) FROM event_registrations
+		 WHERE event_id = $1 AND status <> 'cancelled' AND occurrence_start IS NULL`,
+		s.ID).Scan(&oneOffRSVPs)
+	if err != nil {
+		return err
+	}
+	if rec != nil && oneOffRSVPs > 0 {
+		return &eventChangeError{http.StatusConflict, "event already has RSVPs, so it cannot be made recurring"}
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT occurrence_start, registered_count, capacity FROM event_occurrences WHERE event_id = $1`,
+		s.ID)
+	if err != nil {
+		return err
+	}
+	var orphans []time.Time
+	var conflict error
+	for rows.Next() {
+		var start time.Time
+		var registered int
+		var capacity *int
+		if err := rows.Scan(&start, &registered, &capacity); err != nil {
+			rows.Close()
+			return err
+		}
+		switch {
+		case rec == nil || !rec.Includes(start):
+			if registered > 0 {
+				conflict = &eventChangeError{http.StatusConflict,
+					fmt.Sprintf("the occurrence on %s has RSVPs and would be removed; cancel them first", formatLocal(start, loc))}
+			}
+			orphans = append(orphans, start)
+		case capacity == nil && s.Capacity != nil && registered > *s.Capacity:
+			conflict = &eventChangeError{http.StatusConflict,
+				fmt.Sprintf("the occurrence on %s already has %d registered, more than the new capacity", formatLocal(start, loc), registered)}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if conflict != nil {
+		return conflict
+	}
+
+	if len(orphans) > 0 {
+		_, err = q.Exec(ctx,
+			`DELETE FROM event_occurrences WHERE event_id = $1 AND occurrence_start = ANY($2)`,
+			s.ID, orphans)
+	}
+	return err
+}
+
+// saveEventSeries writes a series' editable columns back
+func saveEventSeries(ctx context.Context, q dbtx, s *eventSeries) error {
+	_, err := q.Exec(ctx,
+		`UPDATE events SET title = $2, description = $3, starts_at = $4, ends_at = $5, location = $6, capacity = $7,
+		                   rrule = $8, exdates = COALESCE($9, '{}'), updated_at = now()
+		 WHERE id = $1`,
+		s.ID, s.Title, s.Description, s.StartsAt, s.EndsAt, s.Location, s.Capacity, s.RRule, s.Exdates)
+	return err
+}
+
+// applyTo copies the request's series-level fields onto s
+func (req *EventOccurrenceRequest) applyTo(s *eventSeries) {
+	if req.Title != nil {
+		s.Title = *req.Title
+	}
+	if req.Description != nil {
+		s.Description = req.Description
+	}
+	if req.Location != nil {
+		s.Location = req.Location
+	}
+	if req.Capacity != nil {
+		s.Capacity = req.Capacity
+	}
+}
+
+// UpdateEventOccurrence edits one occurrence, the occurrences from one
+// onwards, or every occurrence of a recurring event
+func (h *Handler) UpdateEventOccurrence(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req EventOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.StartsAt == nil) != (req.EndsAt == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "starts_at and ends_at must be given together"})
+		return
+	}
+	if req.StartsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+	if req.Capacity != nil && *req.Capacity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "capacity must not be negative"})
+		return
+	}
+	if req.Scope == "this" && req.RRule != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rrule can only be changed for following or all occurrences"})
+		return
+	}
+	if req.Scope == "all" && req.Cancelled != nil && *req.Cancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delete the event to cancel every occurrence"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := lockEventSeries(ctx, tx, tenantID, c.Param("id"))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	rec, err := s.recurrence(loc)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "event rrule is invalid: " + err.Error()})
+		return
+	}
+	if rec == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event does not repeat; update the event instead"})
+		return
+	}
+	if !rec.Includes(req.OccurrenceStart) {
+		c.JSON(http.StatusNotFound, gin.H{"error": errOccurrenceNotFound.Error()})
+		return
+	}
+
+	scope := req.Scope
+	if scope == "following" && req.OccurrenceStart.Equal(s.StartsAt) {
+		scope = "all"
+	}
+
+	eventID := s.ID
+	switch scope {
+	case "this":
+		err = h.editOccurrence(ctx, tx, tenantID, s, &req)
+	case "all":
+		err = editSeries(ctx, tx, s, rec, &req, loc)
+	case "following":
+		eventID, err = h.splitSeries(ctx, tx, tenantID, s, rec, &req, loc)
+	}
+	if err != nil {
+		var changeErr *eventChangeError
+		if errors.As(err, &changeErr) {
+			c.JSON(changeErr.status, gin.H{"error": changeErr.message})
+			return
+		}
+		respondRSVPError(c, err, "update failed")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "event_id": eventID, "scope": scope})
+}
+
+// editOccurrence records an override for a single occurrence. Cancelling
+// it cancels its RSVPs.
+func (h *Handler) editOccurrence(ctx context.Context, q dbtx, tenantID string, s *eventSeries, req *EventOccurrenceRequest) error {
+	var registered int
+	var capacity *int
+	var cancelled bool
+	err := q.QueryRow(ctx,
+		`INSERT INTO event_occurrences (tenant_id, event_id, occurrence_start, starts_at, ends_at, title, description, location, capacity, cancelled)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::bool, false))
+		 ON CONFLICT (event_id, occurrence_start) DO UPDATE SET
+		   starts_at = COALESCE(EXCLUDED.starts_at, event_occurrences.starts_at),
+		   ends_at = COALESCE(EXCLUDED.ends_at, event_occurrences.ends_at),
+		   title = COALESCE(EXCLUDED.title, event_occurrences.title),
+		   description = COALESCE(EXCLUDED.description, event_occurrences.description),
+		   location = COALESCE(EXCLUDED.location, event_occurrences.location),
+		   capacity = COALESCE(EXCLUDED.capacity, event_occurrences.capacity),
+		   cancelled = COALESCE($10::bool, event_occurrences.cancelled),
+		   updated_at = now()
+		 RETURNING registered_count, capacity, cancelled`,
+		tenantID, s.ID, req.OccurrenceStart, req.StartsAt, req.EndsAt, req.Title, req.Description,
+		req.Location, req.Capacity, req.Cancelled).Scan(&registered, &capacity, &cancelled)
+	if err != nil {
+		return err
+	}
+
+	if capacity == nil {
+		capacity = s.Capacity
+	}
+	if !cancelled && capacity != nil && registered > *capacity {
+		return &eventChangeError{http.StatusConflict, "capacity is below the number already registered"}
+	}
+	if req.Cancelled != nil && *req.Cancelled {
+		return h.cancelOccurrenceRSVPs(ctx, q, tenantID, s.ID, req.OccurrenceStart, req.OccurrenceStart)
+	}
+	return nil
+}
+
+// cancelOccurrenceRSVPs cancels the active RSVPs for occurrences of an
+// event starting in [from, to], notifying each registrant
+func (h *Handler) cancelOccurrenceRSVPs(ctx context.Context, q dbtx, tenantID, eventID string, from, to time.Time) error {
+	rows, err := q.Query(ctx,
+		`SELECT id::text, status FROM event_registrations
+		 WHERE event_id = $1 AND occurrence_start BETWEEN $2 AND $3 AND status <> 'cancelled'`,
+		eventID, from, to)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id, status string
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return err
+		}
+		if status == "attended" {
+			rows.Close()
+			return &eventChangeError{http.StatusConflict, "attendees have already checked in to an occurrence being cancelled"}
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		r, err := lockEventRegistration(ctx, q, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if err := h.cancelEventRegistration(ctx, q, tenantID, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editSeries changes every occurrence. A new time for the chosen
+// occurrence moves the whole series by the same days and time of day.
+func editSeries(ctx context.Context, q dbtx, s *eventSeries, rec *schedule.Recurrence, req *EventOccurrenceRequest, loc *time.Location) error {
+	rule := rec.Rule.InLocation(loc)
+	if req.RRule != nil {
+		r, err := schedule.ParseRule(*req.RRule)
+		if err != nil {
+			return &eventChangeError{http.StatusBadRequest, err.Error()}
+		}
+		rule = r
+	}
+
+	shift := func(t time.Time) time.Time { return t }
+	duration := s.EndsAt.Sub(s.StartsAt)
+	if req.StartsAt != nil {
+		shift = wallClockShift(req.OccurrenceStart, *req.StartsAt, loc)
+		duration = req.EndsAt.Sub(*req.StartsAt)
+		if req.RRule == nil && !rule.Until.IsZero() {
+			rule.Until = shift(rule.Until)
+		}
+		for i, ex := range s.Exdates {
+			s.Exdates[i] = shift(ex)
+		}
+	}
+	s.StartsAt = shift(s.StartsAt)
+	s.EndsAt = s.StartsAt.Add(duration)
+	req.applyTo(s)
+
+	ruleText := rule.String()
+	canonical, err := normalizeEventRule(&ruleText, s.StartsAt, loc)
+	if err != nil {
+		return &eventChangeError{http.StatusBadRequest, err.Error()}
+	}
+	s.RRule = canonical
+
+	if err := saveEventSeries(ctx, q, s); err != nil {
+		return err
+	}
+	if req.StartsAt != nil {
+		if err := moveOccurrences(ctx, q, s.ID, s.ID, time.Time{}, shift); err != nil {
+			return err
+		}
+	}
+	return reconcileSeries(ctx, q, s, loc)
+}
+
+// splitSeries ends the series before the chosen occurrence and continues
+// it, with the requested changes, as a new event in the same series.
+// Cancelling ends the series there instead.
+func (h *Handler) splitSeries(ctx context.Context, q dbtx, tenantID string, s *eventSeries, rec *schedule.Recurrence, req *EventOccurrenceRequest, loc *time.Location) (string, error) {
+	split := req.OccurrenceStart
+	head, tail := rec.Rule.InLocation(loc), rec.Rule.InLocation(loc)
+	if rec.Rule.Count > 0 {
+		head.Count = rec.CountBefore(split)
+		tail.Count = rec.Rule.Count - head.Count
+	} else {
+		head.Until = split.Add(-time.Second)
+	}
+
+	headExdates, tailExdates := []time.Time{}, []time.Time{}
+	for _, ex := range s.Exdates {
+		if ex.Before(split) {
+			headExdates = append(headExdates, ex)
+		} else {
+			tailExdates = append(tailExdates, ex)
+		}
+	}
+
+	if req.Cancelled != nil && *req.Cancelled {
+		if err := h.cancelOccurrenceRSVPs(ctx, q, tenantID, s.ID, split, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			return "", err
+		}
+		headRule := head.String()
+		s.RRule, s.Exdates = &headRule, headExdates
+		req.applyTo(s)
+		if err := saveEventSeries(ctx, q, s); err != nil {
+			return "", err
+		}
+		return s.ID, reconcileSeries(ctx, q, s, loc)
+	}
+
+	if req.RRule != nil {
+		r, err := schedule.ParseRule(*req.RRule)
+		if err != nil {
+			return "", &eventChangeError{http.StatusBadRequest, err.Error()}
+		}
+		tail = r
+	}
+
+	shift := func(t time.Time) time.Time { return t }
+	duration := s.EndsAt.Sub(s.StartsAt)
+	if req.StartsAt != nil {
+		shift = wallClockShift(split, *req.StartsAt, loc)
+		duration = req.EndsAt.Sub(*req.StartsAt)
+		if req.RRule == nil && !tail.Until.IsZero() {
+			tail.Until = shift(tail.Until)
+		}
+	}
+
+	next := *s
+	next.StartsAt = shift(split)
+	next.EndsAt = next.StartsAt.Add(duration)
+	next.Exdates = make([]time.Time, 0, len(tailExdates))
+	for _, ex := range tailExdates {
+		next.Exdates = append(next.Exdates, shift(ex))
+	}
+	req.applyTo(&next)
+	tailText := tail.String()
+	canonical, err := normalizeEventRule(&tailText, next.StartsAt, loc)
+	if err != nil {
+		return "", &eventChangeError{http.StatusBadRequest, err.Error()}
+	}
+	next.RRule = canonical
+
+	if s.Slug != nil {
+		slug, err := uniqueSlug(ctx, q, "events", tenantID, *s.Slug)
+		if err != nil {
+			return "", err
+		}
+		next.Slug = &slug
+	}
+	seriesID := s.ID
+	if s.SeriesID != nil {
+		seriesID = *s.SeriesID
+	}
+
+	err = q.QueryRow(ctx,
+		`INSERT INTO events (tenant_id, title, description, starts_at, ends_at, location, capacity, category,
+		                     status, visibility, image_url, slug, rrule, exdates, series_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING id::text`,
+		tenantID, next.Title, next.Description, next.StartsAt, next.EndsAt, next.Location, next.Capacity, next.Category,
+		next.Status, next.Visibility, next.ImageURL, next.Slug, next.RRule, next.Exdates, seriesID).Scan(&next.ID)
+	if err != nil {
+		return "", err
+	}
+
+	// The continuation needs the same waivers as the original
+	_, err = q.Exec(ctx,
+		`INSERT INTO waiver_attachments (tenant_id, waiver_id, subject_type, subject_key)
+		 SELECT tenant_id, waiver_id, subject_type, $2 FROM waiver_attachments
+		 WHERE subject_type = 'event' AND subject_key = $1
+		 ON CONFLICT DO NOTHING`,
+		s.ID, next.ID)
+	if err != nil {
+		return "", err
+	}
+
+	headRule := head.String()
+	s.RRule, s.Exdates = &headRule, headExdates
+	if err := saveEventSeries(ctx, q, s); err != nil {
+		return "", err
+	}
+	if err := moveOccurrences(ctx, q, s.ID, next.ID, split, shift); err != nil {
+		return "", err
+	}
+	if err := reconcileSeries(ctx, q, s, loc); err != nil {
+		return "", err
+	}
+	return next.ID, reconcileSeries(ctx, q, &next, loc)
+}
@@ -0,0 +1,827 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rec-hub/backend/pkg/mail"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/statemachine"
+)
+
+// ============ Event Registrations ============
+
+var eventRegistrationMachine = statemachine.New("rsvp", map[string][]string{
+	"registered": {"attended", "cancelled"},
+	"attended":   nil,
+	"cancelled":  nil,
+})
+
+var (
+	errEventFull         = errors.New("event is full")
+	errAlreadyRegistered = errors.New("already registered for this event")
+)
+
+type EventRSVPRequest struct {
+	EventID         string                   `json:"event_id" binding:"required"`
+	OccurrenceStart *time.Time               `json:"occurrence_start"`
+	AttendeeCount   int                      `json:"attendee_count" binding:"omitempty,min=1,max=20"`
+	Notes           string                   `json:"notes"`
+	Waivers         []WaiverSignatureRequest `json:"waivers"`
+}
+
+type PublicEventRSVPRequest struct {
+	Name            string                   `json:"name" binding:"required"`
+	Email           string                   `json:"email" binding:"required,email"`
+	OccurrenceStart *time.Time               `json:"occurrence_start"`
+	AttendeeCount   int                      `json:"attendee_count" binding:"omitempty,min=1,max=20"`
+	Notes           string                   `json:"notes"`
+	Waivers         []WaiverSignatureRequest `json:"waivers"`
+}
+
+type EventRSVPUpdateRequest struct {
+	AttendeeCount int `json:"attendee_count" binding:"required,min=1,max=20"`
+}
+
+type GuestRSVPCancelRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// rsvpEvent is an event, or one occurrence of a recurring event, as far
+// as RSVPs are concerned
+type rsvpEvent struct {
+	ID         string
+	Occurrence *time.Time
+	Title      string
+	StartsAt   time.Time
+	Location   *string
+	Capacity   *int
+	Registered int
+	Status     string
+	Visibility bool
+	Cancelled  bool
+}
+
+// SpotsLeft is nil for events without a capacity
+func (e *rsvpEvent) SpotsLeft() *int {
+	if e.Capacity == nil {
+		return nil
+	}
+	left := max(*e.Capacity-e.Registered, 0)
+	return &left
+}
+
+// closedReason explains why the event is not taking RSVPs, or is ""
+func (e *rsvpEvent) closedReason(now time.Time) string {
+	switch {
+	case e.Status != "active" || !e.Visibility:
+		return "event is not open for RSVPs"
+	case e.Cancelled:
+		return "this occurrence has been cancelled"
+	case !now.Before(e.StartsAt):
+		return "event has already started"
+	}
+	return ""
+}
+
+// loadRSVPEvent loads an event. Recurring events need the occurrence,
+// which is returned with its overrides applied.
+func (h *Handler) loadRSVPEvent(ctx context.Context, q dbtx, tenantID, eventID string, occurrence *time.Time) (*rsvpEvent, error) {
+	s, err := scanEventSeries(q.QueryRow(ctx,
+		`SELECT `+eventSeriesColumns+` FROM events e WHERE e.id = $1 AND e.tenant_id = $2`,
+		eventID, tenantID))
+	if err != nil {
+		return nil, err
+	}
+
+	occ := s.occurrence(s.StartsAt, nil)
+	if s.RRule != nil {
+		if occurrence == nil {
+			return nil, errOccurrenceRequired
+		}
+		rec, err := s.recurrence(h.tenantLocation(ctx, q, tenantID))
+		if err != nil {
+			return nil, err
+		}
+		if !rec.Includes(*occurrence) {
+			return nil, errOccurrenceNotFound
+		}
+		overrides, err := loadOccurrenceOverrides(ctx, q, []string{s.ID})
+		if err != nil {
+			return nil, err
+		}
+		occ = s.occurrence(*occurrence, overrides[s.ID][occurrence.Unix()])
+	}
+
+	return &rsvpEvent{
+		ID:         s.ID,
+		Occurrence: occ.OccurrenceStart,
+		Title:      occ.Title,
+		StartsAt:   occ.StartsAt,
+		Location:   occ.Location,
+		Capacity:   occ.Capacity,
+		Registered: occ.RegisteredCount,
+		Status:     s.Status,
+		Visibility: s.Visibility,
+		Cancelled:  occ.Cancelled,
+	}, nil
+}
+
+// reserveEventSpots changes the registered count of an event, or of one
+// occurrence of a recurring event, by delta in a single statement, so
+// concurrent RSVPs cannot take it over capacity
+func reserveEventSpots(ctx context.Context, q dbtx, tenantID, eventID string, occurrence *time.Time, delta int) error {
+	var result pgconn.CommandTag
+	var err error
+	if occurrence == nil {
+		result, err = q.Exec(ctx,
+			`UPDATE events SET registered_count = GREATEST(registered_count + $2, 0), updated_at = now()
+			 WHERE id = $1 AND ($2 <= 0 OR capacity IS NULL OR registered_count + $2 <= capacity)`,
+			eventID, delta)
+	} else {
+		_, err = q.Exec(ctx,
+			`INSERT INTO event_occurrences (tenant_id, event_id, occurrence_start) VALUES ($1, $2, $3)
+			 ON CONFLICT (event_id, occurrence_start) DO NOTHING`,
+			tenantID, eventID, *occurrence)
+		if err != nil {
+			return err
+		}
+		result, err = q.Exec(ctx,
+			`UPDATE event_occurrences o SET registered_count = GREATEST(o.registered_count + $3, 0), updated_at = now()
+			 FROM events e
+			 WHERE e.id = o.event_id AND o.event_id = $1 AND o.occurrence_start = $2
+			   AND ($3 <= 0 OR COALESCE(o.capacity, e.capacity) IS NULL OR o.registered_count + $3 <= COALESCE(o.capacity, e.capacity))`,
+			eventID, *occurrence, delta)
+	}
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errEventFull
+	}
+	return nil
+}
+
+func newCancelToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// eventRSVP is a new RSVP from a resident or a guest
+type eventRSVP struct {
+	TenantID      string
+	Event         *rsvpEvent
+	UserID        *string
+	GuestName     string
+	Email         string
+	AttendeeCount int
+	Notes         string
+	Waivers       []acceptedWaiver
+	Signer        waiverSigner
+}
+
+// createEventRegistration records an RSVP, takes its places and queues the
+// confirmation. Guests get a cancel token.
+func (h *Handler) createEventRegistration(ctx context.Context, q dbtx, r eventRSVP) (uuid.UUID, string, error) {
+	registrationID := uuid.New()
+	var token, guestName, guestEmail *string
+	if r.UserID == nil {
+		t, err := newCancelToken()
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		token, guestName, guestEmail = &t, &r.GuestName, &r.Email
+	}
+
+	_, err := q.Exec(ctx,
+		`INSERT INTO event_registrations (id, tenant_id, event_id, occurrence_start, user_id, guest_name, guest_email, cancel_token, attendee_count, notes)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		registrationID, r.TenantID, r.Event.ID, r.Event.Occurrence, r.UserID, guestName, guestEmail, token, r.AttendeeCount, nullIfEmpty(r.Notes))
+	if isUniqueViolation(err) {
+		return uuid.Nil, "", errAlreadyRegistered
+	}
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	if err := reserveEventSpots(ctx, q, r.TenantID, r.Event.ID, r.Event.Occurrence, r.AttendeeCount); err != nil {
+		return uuid.Nil, "", err
+	}
+
+	if len(r.Waivers) > 0 {
+		signer := r.Signer
+		signer.UserID = r.UserID
+		signer.Email = r.Email
+		signer.Participant = r.GuestName
+		signer.Purpose = "RSVP for " + r.Event.Title
+		if _, err := recordWaiverSignatures(ctx, q, signer, "event_registration", registrationID, r.Waivers); err != nil {
+			return uuid.Nil, "", err
+		}
+	}
+
+	cancelURL := ""
+	if token != nil {
+		cancelURL = fmt.Sprintf("https://%s/events/rsvp/%s/cancel?token=%s",
+			h.tenantPrimaryDomain(ctx, r.TenantID), registrationID, *token)
+	}
+	ticket := h.ticketCode(r.TenantID, registrationID)
+	if err := h.enqueueEventEmail(ctx, q, r.TenantID, r.Email, r.Event, "registered", r.AttendeeCount, cancelURL, ticket); err != nil {
+		return uuid.Nil, "", err
+	}
+
+	if token == nil {
+		return registrationID, "", nil
+	}
+	return registrationID, *token, nil
+}
+
+func (h *Handler) enqueueEventEmail(ctx context.Context, q dbtx, tenantID, to string, e *rsvpEvent, status string, partySize int, cancelURL, ticket string) error {
+	location := ""
+	if e.Location != nil {
+		location = *e.Location
+	}
+	ticketImage := ""
+	if ticket != "" {
+		ticketImage = h.ticketImageURL(ctx, tenantID, ticket)
+	}
+	subject, body := mail.EventRegistrationEmail(mail.EventNotification{
+		EventTitle:     e.Title,
+		When:           formatLocal(e.StartsAt, h.tenantLocation(ctx, q, tenantID)),
+		Location:       location,
+		Status:         status,
+		PartySize:      partySize,
+		CancelURL:      cancelURL,
+		TicketCode:     ticket,
+		TicketImageURL: ticketImage,
+	})
+	return enqueueEmail(ctx, q, tenantID, to, subject, body, "")
+}
+
+// eventRegistrationRow is a locked RSVP being changed
+type eventRegistrationRow struct {
+	ID              string
+	EventID         string
+	OccurrenceStart *time.Time
+	UserID          *string
+	Email           string
+	Status          string
+	AttendeeCount   int
+	CheckedIn       int
+	CancelToken     *string
+}
+
+func lockEventRegistration(ctx context.Context, q dbtx, tenantID, registrationID string) (*eventRegistrationRow, error) {
+	var r eventRegistrationRow
+	err := q.QueryRow(ctx,
+		`SELECT er.id, er.event_id, er.occurrence_start, er.user_id, COALESCE(u.email, er.guest_email, ''), er.status, er.attendee_count, er.checked_in_count, er.cancel_token
+		 FROM event_registrations er
+		 LEFT JOIN users u ON er.user_id = u.id
+		 WHERE er.id = $1 AND er.tenant_id = $2
+		 FOR UPDATE OF er`,
+		registrationID, tenantID).Scan(&r.ID, &r.EventID, &r.OccurrenceStart, &r.UserID, &r.Email, &r.Status, &r.AttendeeCount, &r.CheckedIn, &r.CancelToken)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// cancelEventRegistration cancels an RSVP and gives its places back
+func (h *Handler) cancelEventRegistration(ctx context.Context, q dbtx, tenantID string, r *eventRegistrationRow) error {
+	if err := eventRegistrationMachine.Check(r.Status, "cancelled"); err != nil {
+		return err
+	}
+
+	_, err := q.Exec(ctx,
+		`UPDATE event_registrations SET status = 'cancelled', cancelled_at = now(), updated_at = now() WHERE id = $1`,
+		r.ID)
+	if err != nil {
+		return err
+	}
+	if err := reserveEventSpots(ctx, q, tenantID, r.EventID, r.OccurrenceStart, -r.AttendeeCount); err != nil {
+		return err
+	}
+	if err := cancelReminders(ctx, q, tenantID, "event_registration:"+r.ID); err != nil {
+		return err
+	}
+
+	e, err := h.loadRSVPEvent(ctx, q, tenantID, r.EventID, r.OccurrenceStart)
+	if err != nil {
+		return err
+	}
+	return h.enqueueEventEmail(ctx, q, tenantID, r.Email, e, "cancelled", r.AttendeeCount, "", "")
+}
+
+// respondRSVPError maps RSVP failures onto responses
+func respondRSVPError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, errEventFull):
+		c.JSON(http.StatusConflict, gin.H{"error": "not enough places left for this party"})
+	case errors.Is(err, errAlreadyRegistered):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errOccurrenceRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errOccurrenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		respondTransitionError(c, err, fallback)
+	}
+}
+
+// prepareRSVP checks the event is open and the required waivers are signed.
+// It writes the error response and returns false when the RSVP cannot go ahead.
+func (h *Handler) prepareRSVP(c *gin.Context, ctx context.Context, q dbtx, tenantID, eventID string, occurrence *time.Time, signatures []WaiverSignatureRequest) (*rsvpEvent, []acceptedWaiver, bool) {
+	e, err := h.loadRSVPEvent(ctx, q, tenantID, eventID, occurrence)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return nil, nil, false
+	}
+	if err != nil {
+		respondRSVPError(c, err, "database error")
+		return nil, nil, false
+	}
+	if reason := e.closedReason(time.Now()); reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return nil, nil, false
+	}
+
+	required, err := requiredWaivers(ctx, q, tenantID, WaiverForEvent, e.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load waivers"})
+		return nil, nil, false
+	}
+	accepted, issues := matchWaiverSignatures(required, signatures)
+	if len(issues) > 0 {
+		respondWaiverIssues(c, issues)
+		return nil, nil, false
+	}
+	return e, accepted, true
+}
+
+// CreatePublicEventRSVP lets a guest RSVP without an account
+func (h *Handler) CreatePublicEventRSVP(c *gin.Context) {
+	var req PublicEventRSVPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.AttendeeCount == 0 {
+		req.AttendeeCount = 1
+	}
+
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	event, waivers, ok := h.prepareRSVP(c, ctx, h.DB, tenantID, c.Param("id"), req.OccurrenceStart, req.Waivers)
+	if !ok {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	registrationID, token, err := h.createEventRegistration(ctx, tx, eventRSVP{
+		TenantID:      tenantID,
+		Event:         event,
+		GuestName:     strings.TrimSpace(req.Name),
+		Email:         strings.TrimSpace(req.Email),
+		AttendeeCount: req.AttendeeCount,
+		Notes:         req.Notes,
+		Waivers:       waivers,
+		Signer:        signerFromRequest(c, tenantID),
+	})
+	if err != nil {
+		respondRSVPError(c, err, "failed to create RSVP")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":               registrationID.String(),
+		"occurrence_start": event.Occurrence,
+		"status":           "registered",
+		"attendee_count":   req.AttendeeCount,
+		"cancel_token":     token,
+		"ticket_code":      h.ticketCode(tenantID, registrationID),
+	})
+}
+
+// CancelPublicEventRSVP cancels a guest RSVP using the token it was issued
+func (h *Handler) CancelPublicEventRSVP(c *gin.Context) {
+	var req GuestRSVPCancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	r, err := lockEventRegistration(ctx, tx, tenantID, c.Param("id"))
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err == pgx.ErrNoRows || r.CancelToken == nil || *r.CancelToken != req.Token {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+		return
+	}
+
+	if err := h.cancelEventRegistration(ctx, tx, tenantID, r); err != nil {
+		respondRSVPError(c, err, "failed to cancel RSVP")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "cancelled"})
+}
+
+// CreateMyEventRSVP RSVPs the signed-in resident's household to an event
+func (h *Handler) CreateMyEventRSVP(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req EventRSVPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.AttendeeCount == 0 {
+		req.AttendeeCount = 1
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	userID := claims.UserID.String()
+
+	event, waivers, ok := h.prepareRSVP(c, ctx, h.DB, tenantID, req.EventID, req.OccurrenceStart, req.Waivers)
+	if !ok {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	registrationID, _, err := h.createEventRegistration(ctx, tx, eventRSVP{
+		TenantID:      tenantID,
+		Event:         event,
+		UserID:        &userID,
+		Email:         claims.Email,
+		AttendeeCount: req.AttendeeCount,
+		Notes:         req.Notes,
+		Waivers:       waivers,
+		Signer:        signerFromRequest(c, tenantID),
+	})
+	if err != nil {
+		respondRSVPError(c, err, "failed to create RSVP")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":               registrationID.String(),
+		"occurrence_start": event.Occurrence,
+		"status":           "registered",
+		"attendee_count":   req.AttendeeCount,
+		"ticket_code":      h.ticketCode(tenantID, registrationID),
+	})
+}
+
+// ListMyEventRSVPs returns the caller's RSVPs, upcoming events first
+func (h *Handler) ListMyEventRSVPs(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT id, event_id, occurrence_start, title, starts_at, ends_at, location,
+		        status, attendee_count, notes, registered_at, cancelled_at
+		 FROM (
+		   SELECT er.id, er.event_id, er.occurrence_start, COALESCE(o.title, e.title) AS title,
+		          COALESCE(o.starts_at, er.occurrence_start, e.starts_at) AS starts_at,
+		          COALESCE(o.ends_at, COALESCE(er.occurrence_start, e.starts_at) + (e.ends_at - e.starts_at)) AS ends_at,
+		          COALESCE(o.location, e.location) AS location,
+		          er.status, er.attendee_count, er.notes, er.registered_at, er.cancelled_at
+		   FROM event_registrations er
+		   JOIN events e ON er.event_id = e.id
+		   LEFT JOIN event_occurrences o ON o.event_id = er.event_id AND o.occurrence_start = er.occurrence_start
+		   WHERE er.tenant_id = $1 AND er.user_id = $2
+		 ) r
+		 ORDER BY (starts_at < now()), CASE WHEN starts_at >= now() THEN starts_at END ASC, starts_at DESC`,
+		claims.TenantID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	rsvps := []gin.H{}
+	now := time.Now()
+	for rows.Next() {
+		var id, eventID, title, status string
+		var location, notes *string
+		var startsAt, endsAt, registeredAt time.Time
+		var occurrenceStart, cancelledAt *time.Time
+		var count int
+		if err := rows.Scan(&id, &eventID, &occurrenceStart, &title, &startsAt, &endsAt, &location,
+			&status, &count, &notes, &registeredAt, &cancelledAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		rsvps = append(rsvps, gin.H{
+			"id":               id,
+			"event_id":         eventID,
+			"occurrence_start": occurrenceStart,
+			"event_title":      title,
+			"starts_at":        startsAt,
+			"ends_at":          endsAt,
+			"location":         location,
+			"status":           status,
+			"attendee_count":   count,
+			"notes":            notes,
+			"registered_at":    registeredAt,
+			"cancelled_at":     cancelledAt,
+			"can_change":       status == "registered" && now.Before(startsAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, rsvps)
+}
+
+// UpdateMyEventRSVP changes the party size of one of the caller's RSVPs
+func (h *Handler) UpdateMyEventRSVP(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req EventRSVPUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	r, ok := lockMyEventRegistration(c, ctx, tx, tenantID, claims.UserID.String())
+	if !ok {
+		return
+	}
+	if r.Status != "registered" {
+		c.JSON(http.StatusConflict, gin.H{"error": "RSVP is " + r.Status})
+		return
+	}
+	event, err := h.loadRSVPEvent(ctx, tx, tenantID, r.EventID, r.OccurrenceStart)
+	if err != nil {
+		respondRSVPError(c, err, "database error")
+		return
+	}
+	if reason := event.closedReason(time.Now()); reason != "" && req.AttendeeCount > r.AttendeeCount {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+	if !time.Now().Before(event.StartsAt) {
+		c.JSON(http.StatusConflict, gin.H{"error": "event has already started"})
+		return
+	}
+
+	if delta := req.AttendeeCount - r.AttendeeCount; delta != 0 {
+		if err := reserveEventSpots(ctx, tx, tenantID, r.EventID, r.OccurrenceStart, delta); err != nil {
+			respondRSVPError(c, err, "update failed")
+			return
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE event_registrations SET attendee_count = $1, updated_at = now() WHERE id = $2`,
+			req.AttendeeCount, r.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+			return
+		}
+		ticket := h.ticketCode(tenantID, uuid.MustParse(r.ID))
+		if err := h.enqueueEventEmail(ctx, tx, tenantID, r.Email, event, "registered", req.AttendeeCount, "", ticket); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue notification"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "attendee_count": req.AttendeeCount})
+}
+
+// CancelMyEventRSVP cancels one of the caller's RSVPs
+func (h *Handler) CancelMyEventRSVP(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	r, ok := lockMyEventRegistration(c, ctx, tx, tenantID, claims.UserID.String())
+	if !ok {
+		return
+	}
+	if err := h.cancelEventRegistration(ctx, tx, tenantID, r); err != nil {
+		respondRSVPError(c, err, "failed to cancel RSVP")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "cancelled"})
+}
+
+// lockMyEventRegistration loads and locks the caller's RSVP named by the
+// :id parameter, writing the error response when it is not theirs
+func lockMyEventRegistration(c *gin.Context, ctx context.Context, q dbtx, tenantID, userID string) (*eventRegistrationRow, bool) {
+	r, err := lockEventRegistration(ctx, q, tenantID, c.Param("id"))
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	if err == pgx.ErrNoRows || r.UserID == nil || *r.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+		return nil, false
+	}
+	return r, true
+}
+
+// ListEventAttendees returns an event's RSVPs for staff, with totals
+func (h *Handler) ListEventAttendees(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var occurrence *time.Time
+	if v := c.Query("occurrence_start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "occurrence_start must be RFC3339"})
+			return
+		}
+		occurrence = &t
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	event, err := h.loadRSVPEvent(ctx, h.DB, tenantID, c.Param("id"), occurrence)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		respondRSVPError(c, err, "database error")
+		return
+	}
+
+	includeCancelled := c.Query("include_cancelled") == "true"
+	rows, err := h.DB.Query(ctx,
+		`SELECT er.id, er.user_id,
+		        COALESCE(NULLIF(concat_ws(' ', u.first_name, u.last_name), ''), er.guest_name, ''),
+		        COALESCE(u.email, er.guest_email, ''), u.phone,
+		        er.status, er.attendee_count, er.checked_in_count, er.checked_in_at,
+		        er.notes, er.registered_at, er.cancelled_at
+		 FROM event_registrations er
+		 LEFT JOIN users u ON er.user_id = u.id
+		 WHERE er.event_id = $1 AND er.tenant_id = $2 AND ($3 OR er.status <> 'cancelled')
+		   AND er.occurrence_start IS NOT DISTINCT FROM $4
+		 ORDER BY er.registered_at ASC`,
+		event.ID, tenantID, includeCancelled, event.Occurrence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	attendees := []gin.H{}
+	attended := 0
+	for rows.Next() {
+		var id, name, email, status string
+		var userID, phone, notes *string
+		var count, checkedIn int
+		var registeredAt time.Time
+		var checkedInAt, cancelledAt *time.Time
+		if err := rows.Scan(&id, &userID, &name, &email, &phone, &status, &count, &checkedIn, &checkedInAt,
+			&notes, &registeredAt, &cancelledAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		attended += checkedIn
+		attendees = append(attendees, gin.H{
+			"id":             id,
+			"user_id":        userID,
+			"guest":          userID == nil,
+			"name":           name,
+			"email":          email,
+			"phone":          phone,
+			"status":         status,
+			"attendee_count": count,
+			"checked_in":     checkedIn,
+			"checked_in_at":  checkedInAt,
+			"notes":          notes,
+			"registered_at":  registeredAt,
+			"cancelled_at":   cancelledAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":         event.ID,
+		"occurrence_start": event.Occurrence,
+		"title":            event.Title,
+		"starts_at":        event.StartsAt,
+		"capacity":         event.Capacity,
+		"registered_count": event.Registered,
+		"attended_count":   attended,
+		"spots_left":       event.SpotsLeft(),
+		"attendees":        attendees,
+	})
+}
@@ -0,0 +1,726 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Facility Availability Templates ============
+
+const (
+	defaultHorizonDays = 60
+	maxHorizonDays     = 180
+)
+
+type AvailabilityTemplateRequest struct {
+	Name           *string  `json:"name"`
+	Weekdays       []int    `json:"weekdays" binding:"required"`
+	OpenTime       string   `json:"open_time" binding:"required"`
+	CloseTime      string   `json:"close_time" binding:"required"`
+	SlotMinutes    int      `json:"slot_minutes" binding:"required"`
+	BufferMinutes  int      `json:"buffer_minutes"`
+	ValidFrom      *string  `json:"valid_from"`
+	ValidUntil     *string  `json:"valid_until"`
+	ExceptionDates []string `json:"exception_dates"`
+	HorizonDays    int      `json:"horizon_days"`
+	Active         *bool    `json:"active"`
+}
+
+// availabilityTemplate is a facility's stored weekly operating hours.
+// Pattern.StartTime and EndTime are the opening and closing times; its
+// dates are unused, the template is bounded by ValidFrom and ValidUntil.
+type availabilityTemplate struct {
+	ID            string
+	FacilityID    string
+	FacilityName  string
+	Name          *string
+	Pattern       schedule.Weekly
+	SlotMinutes   int
+	BufferMinutes int
+	ValidFrom     *time.Time
+	ValidUntil    *time.Time
+	HorizonDays   int
+	Active        bool
+	GeneratedAt   *time.Time
+}
+
+// slotGeneration reports what generating a template's slots changed
+type slotGeneration struct {
+	Created int `json:"created"`
+	Removed int `json:"removed"`
+	// Kept counts booked or reserved slots the template no longer covers,
+	// which are left alone
+	Kept             int            `json:"kept"`
+	Conflicts        []SlotConflict `json:"conflicts"`
+	GeneratedThrough time.Time      `json:"generated_through"`
+}
+
+// templateFromRequest validates a template request
+func templateFromRequest(req AvailabilityTemplateRequest) (*availabilityTemplate, error) {
+	t := &availabilityTemplate{
+		Name:          req.Name,
+		SlotMinutes:   req.SlotMinutes,
+		BufferMinutes: req.BufferMinutes,
+		HorizonDays:   req.HorizonDays,
+		Active:        req.Active == nil || *req.Active,
+	}
+	var err error
+
+	for _, d := range req.Weekdays {
+		t.Pattern.Weekdays = append(t.Pattern.Weekdays, time.Weekday(d))
+	}
+	if t.Pattern.StartTime, err = schedule.ParseClock(req.OpenTime); err != nil {
+		return nil, err
+	}
+	if t.Pattern.EndTime, err = schedule.ParseClock(req.CloseTime); err != nil {
+		return nil, err
+	}
+	for _, d := range req.ExceptionDates {
+		day, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return nil, errors.New("exception_dates must be YYYY-MM-DD")
+		}
+		t.Pattern.Exceptions = append(t.Pattern.Exceptions, day)
+	}
+	if t.ValidFrom, err = parseOptionalDate("valid_from", req.ValidFrom); err != nil {
+		return nil, err
+	}
+	if t.ValidUntil, err = parseOptionalDate("valid_until", req.ValidUntil); err != nil {
+		return nil, err
+	}
+	if t.HorizonDays == 0 {
+		t.HorizonDays = defaultHorizonDays
+	}
+	return t, t.validate()
+}
+
+func (t *availabilityTemplate) validate() error {
+	w := t.Pattern
+	w.IntervalWeeks = 1
+	if err := w.Validate(); err != nil {
+		if w.EndTime <= w.StartTime {
+			return errors.New("close_time must be after open_time")
+		}
+		return err
+	}
+	if t.SlotMinutes < 5 || t.SlotMinutes > int(w.EndTime-w.StartTime) {
+		return errors.New("slot_minutes must be at least 5 and fit between open_time and close_time")
+	}
+	if t.BufferMinutes < 0 || t.BufferMinutes > 24*60 {
+		return errors.New("buffer_minutes must be between 0 and 1440")
+	}
+	if t.ValidFrom != nil && t.ValidUntil != nil && t.ValidUntil.Before(*t.ValidFrom) {
+		return errors.New("valid_until must not be before valid_from")
+	}
+	if t.HorizonDays < 1 || t.HorizonDays > maxHorizonDays {
+		return fmt.Errorf("horizon_days must be between 1 and %d", maxHorizonDays)
+	}
+	return nil
+}
+
+// slots expands the template into the slots starting in (from, to]
+func (t *availabilityTemplate) slots(loc *time.Location, from, to time.Time) []schedule.Occurrence {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	w := t.Pattern
+	w.IntervalWeeks = 1
+	w.StartsOn = day(from.In(loc))
+	w.EndsOn = day(to.In(loc))
+	if t.ValidFrom != nil && t.ValidFrom.After(w.StartsOn) {
+		w.StartsOn = *t.ValidFrom
+	}
+	if t.ValidUntil != nil && t.ValidUntil.Before(w.EndsOn) {
+		w.EndsOn = *t.ValidUntil
+	}
+	if w.EndsOn.Before(w.StartsOn) {
+		return nil
+	}
+
+	var out []schedule.Occurrence
+	for _, o := range w.Slots(loc, t.SlotMinutes, t.BufferMinutes) {
+		if o.StartsAt.After(from) && !o.StartsAt.After(to) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// templateColumns is the select list scanned by scanTemplate
+const templateColumns = `t.id::text, t.facility_id::text, f.name, t.name, t.weekdays,
+	to_char(t.open_time, 'HH24:MI'), to_char(t.close_time, 'HH24:MI'), t.slot_minutes, t.buffer_minutes,
+	t.valid_from, t.valid_until, t.exception_dates, t.horizon_days, t.active, t.generated_at`
+
+func scanTemplate(row pgx.Row) (*availabilityTemplate, error) {
+	var t availabilityTemplate
+	var weekdays []int32
+	var openTime, closeTime string
+	err := row.Scan(&t.ID, &t.FacilityID, &t.FacilityName, &t.Name, &weekdays,
+		&openTime, &closeTime, &t.SlotMinutes, &t.BufferMinutes,
+		&t.ValidFrom, &t.ValidUntil, &t.Pattern.Exceptions, &t.HorizonDays, &t.Active, &t.GeneratedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range weekdays {
+		t.Pattern.Weekdays = append(t.Pattern.Weekdays, time.Weekday(d))
+	}
+	t.Pattern.StartTime, _ = schedule.ParseClock(openTime)
+	t.Pattern.EndTime, _ = schedule.ParseClock(closeTime)
+	return &t, nil
+}
+
+func loadTemplate(ctx context.Context, q dbtx, tenantID, facilityID, templateID string) (*availabilityTemplate, error) {
+	return scanTemplate(q.QueryRow(ctx,
+		`SELECT `+templateColumns+`
+		 FROM facility_availability_templates t
+		 JOIN facilities f ON t.facility_id = f.id
+		 WHERE t.id = $1 AND t.tenant_id = $2 AND t.facility_id = $3`,
+		templateID, tenantID, facilityID))
+}
+
+func templateResponse(t *availabilityTemplate) gin.H {
+	weekdays := make([]int, len(t.Pattern.Weekdays))
+	for i, d := range t.Pattern.Weekdays {
+		weekdays[i] = int(d)
+	}
+	exceptions := make([]string, len(t.Pattern.Exceptions))
+	for i, d := range t.Pattern.Exceptions {
+		exceptions[i] = d.Format("2006-01-02")
+	}
+	date := func(d *time.Time) *string {
+		if d == nil {
+			return nil
+		}
+		s := isoDate(*d)
+		return &s
+	}
+	w := t.Pattern
+	w.IntervalWeeks = 1
+	summary := fmt.Sprintf("%s in %d minute slots", w.Summary(), t.SlotMinutes)
+	if t.BufferMinutes > 0 {
+		summary += fmt.Sprintf(" with %d minutes between", t.BufferMinutes)
+	}
+	return gin.H{
+		"id":              t.ID,
+		"facility_id":     t.FacilityID,
+		"facility_name":   t.FacilityName,
+		"name":            t.Name,
+		"weekdays":        weekdays,
+		"open_time":       t.Pattern.StartTime.String(),
+		"close_time":      t.Pattern.EndTime.String(),
+		"slot_minutes":    t.SlotMinutes,
+		"buffer_minutes":  t.BufferMinutes,
+		"valid_from":      date(t.ValidFrom),
+		"valid_until":     date(t.ValidUntil),
+		"exception_dates": exceptions,
+		"horizon_days":    t.HorizonDays,
+		"active":          t.Active,
+		"generated_at":    t.GeneratedAt,
+		"summary":         summary,
+	}
+}
+
+// syncTemplateSlots makes a template's future slots match it up to its
+// horizon. New slots are created open; an unbooked open slot with exactly
+// the same times is adopted instead. Unbooked open slots the template no
+// longer covers are removed. Booked and reserved slots are never touched,
+// and slots that would overlap another slot are skipped and reported.
+func (h *Handler) syncTemplateSlots(ctx context.Context, q dbtx, tenantID string, t *availabilityTemplate, now time.Time) (*slotGeneration, error) {
+	horizon := now.AddDate(0, 0, t.HorizonDays)
+	result := &slotGeneration{Conflicts: []SlotConflict{}, GeneratedThrough: horizon}
+	loc := h.tenantLocation(ctx, q, tenantID)
+
+	// Serialise slot changes per facility
+	if _, err := q.Exec(ctx, `SELECT 1 FROM facilities WHERE id = $1 FOR UPDATE`, t.FacilityID); err != nil {
+		return nil, err
+	}
+
+	var desired []schedule.Occurrence
+	if t.Active {
+		desired = t.slots(loc, now, horizon)
+	}
+	wanted := make(map[int64]schedule.Occurrence, len(desired))
+	for _, o := range desired {
+		wanted[o.StartsAt.Unix()] = o
+	}
+
+	type facilitySlot struct {
+		id         string
+		startsAt   time.Time
+		endsAt     time.Time
+		status     string
+		templateID *string
+		booked     bool
+	}
+	rows, err := q.Query(ctx,
+		`SELECT fs.id::text, fs.starts_at, fs.ends_at, fs.status, fs.template_id::text,
+		        EXISTS(SELECT 1 FROM bookings b
+		               WHERE b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		                 AND b.status NOT IN ('declined', 'cancelled'))
+		 FROM facility_slots fs
+		 WHERE fs.facility_id = $1 AND fs.ends_at > $2
+		 ORDER BY fs.starts_at
+		 FOR UPDATE OF fs`,
+		t.FacilityID, now)
+	if err != nil {
+		return nil, err
+	}
+	var existing []facilitySlot
+	for rows.Next() {
+		var s facilitySlot
+		if err := rows.Scan(&s.id, &s.startsAt, &s.endsAt, &s.status, &s.templateID, &s.booked); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing = append(existing, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The template's own future slots that still match are kept. The rest
+	// are removed unless they are taken, in which case they stay in the way.
+	var others []facilitySlot
+	for _, s := range existing {
+		if s.templateID == nil || *s.templateID != t.ID || !s.startsAt.After(now) {
+			others = append(others, s)
+			continue
+		}
+		if o, ok := wanted[s.startsAt.Unix()]; ok && o.EndsAt.Equal(s.endsAt) {
+			delete(wanted, s.startsAt.Unix())
+			continue
+		}
+		if s.status != "open" || s.booked {
+			result.Kept++
+			others = append(others, s)
+			continue
+		}
+		if _, err := q.Exec(ctx, `DELETE FROM facility_slots WHERE id = $1`, s.id); err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	for _, o := range desired {
+		if _, ok := wanted[o.StartsAt.Unix()]; !ok {
+			continue
+		}
+
+		var adopt *facilitySlot
+		var conflict *SlotConflict
+		for i := range others {
+			s := &others[i]
+			if !s.startsAt.Before(o.EndsAt) || !s.endsAt.After(o.StartsAt) {
+				continue
+			}
+			exact := s.startsAt.Equal(o.StartsAt) && s.endsAt.Equal(o.EndsAt)
+			if exact && s.templateID == nil && s.status == "open" && !s.booked && adopt == nil {
+				adopt = s
+				continue
+			}
+			if conflict == nil {
+				conflict = &SlotConflict{StartsAt: o.StartsAt, EndsAt: o.EndsAt, SlotID: s.id, Status: s.status, Reason: slotConflictReason(s.status, s.booked)}
+			}
+		}
+		if conflict != nil {
+			result.Conflicts = append(result.Conflicts, *conflict)
+			continue
+		}
+		if adopt != nil {
+			_, err := q.Exec(ctx,
+				`UPDATE facility_slots SET template_id = $1, updated_at = now() WHERE id = $2`,
+				t.ID, adopt.id)
+			if err != nil {
+				return nil, err
+			}
+			adopt.templateID = &t.ID
+			continue
+		}
+
+		_, err := q.Exec(ctx,
+			`INSERT INTO facility_slots (id, facility_id, starts_at, ends_at, status, template_id)
+			 VALUES ($1, $2, $3, $4, 'open', $5)`,
+			uuid.New(), t.FacilityID, o.StartsAt, o.EndsAt, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Created++
+	}
+
+	_, err = q.Exec(ctx,
+		`UPDATE facility_availability_templates SET generated_at = now() WHERE id = $1`,
+		t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// facilityTenant verifies the facility exists and belongs to the tenant,
+// writing the error response if not
+func (h *Handler) facilityTenant(ctx context.Context, c *gin.Context, facilityID, tenantID string) bool {
+	var owner string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM facilities WHERE id = $1`, facilityID).Scan(&owner)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return false
+	}
+	if owner != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) ListAvailabilityTemplates(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	facilityID := c.Param("id")
+	if !h.facilityTenant(ctx, c, facilityID, tenantID) {
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+templateColumns+`
+		 FROM facility_availability_templates t
+		 JOIN facilities f ON t.facility_id = f.id
+		 WHERE t.tenant_id = $1 AND t.facility_id = $2
+		 ORDER BY t.valid_from NULLS FIRST, t.open_time`,
+		tenantID, facilityID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		list = append(list, templateResponse(t))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": list})
+}
+
+// saveTemplate inserts or updates a template and generates its slots in one
+// transaction. With dryRun the changes are rolled back but still reported.
+func (h *Handler) saveTemplate(c *gin.Context, tenantID, facilityID, templateID string, req *AvailabilityTemplateRequest, dryRun bool) {
+	ctx := context.Background()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	status := http.StatusOK
+	if req != nil {
+		t, err := templateFromRequest(*req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		weekdays := make([]int32, len(t.Pattern.Weekdays))
+		for i, d := range t.Pattern.Weekdays {
+			weekdays[i] = int32(d)
+		}
+		exceptions := t.Pattern.Exceptions
+		if exceptions == nil {
+			exceptions = []time.Time{}
+		}
+
+		if templateID == "" {
+			templateID = uuid.New().String()
+			status = http.StatusCreated
+			_, err = tx.Exec(ctx,
+				`INSERT INTO facility_availability_templates (id, tenant_id, facility_id, name, weekdays, open_time, close_time,
+				                                              slot_minutes, buffer_minutes, valid_from, valid_until, exception_dates,
+				                                              horizon_days, active)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+				templateID, tenantID, facilityID, t.Name, weekdays, t.Pattern.StartTime.String(), t.Pattern.EndTime.String(),
+				t.SlotMinutes, t.BufferMinutes, t.ValidFrom, t.ValidUntil, exceptions, t.HorizonDays, t.Active)
+		} else {
+			var result pgconn.CommandTag
+			result, err = tx.Exec(ctx,
+				`UPDATE facility_availability_templates SET name = $1, weekdays = $2, open_time = $3, close_time = $4,
+				        slot_minutes = $5, buffer_minutes = $6, valid_from = $7, valid_until = $8, exception_dates = $9,
+				        horizon_days = $10, active = $11, updated_at = now()
+				 WHERE id = $12 AND tenant_id = $13 AND facility_id = $14`,
+				t.Name, weekdays, t.Pattern.StartTime.String(), t.Pattern.EndTime.String(),
+				t.SlotMinutes, t.BufferMinutes, t.ValidFrom, t.ValidUntil, exceptions, t.HorizonDays, t.Active,
+				templateID, tenantID, facilityID)
+			if err == nil && result.RowsAffected() == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+				return
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save template"})
+			return
+		}
+	}
+
+	t, err := loadTemplate(ctx, tx, tenantID, facilityID, templateID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	result, err := h.syncTemplateSlots(ctx, tx, tenantID, t, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate slots"})
+		return
+	}
+
+	if !dryRun {
+		if err := tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"template": templateResponse(t),
+		"slots":    result,
+		"dry_run":  dryRun,
+	})
+}
+
+func (h *Handler) CreateAvailabilityTemplate(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req AvailabilityTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	facilityID := c.Param("id")
+	if !h.facilityTenant(context.Background(), c, facilityID, tenantID) {
+		return
+	}
+
+	h.saveTemplate(c, tenantID, facilityID, "", &req, c.Query("dry_run") == "true")
+}
+
+func (h *Handler) UpdateAvailabilityTemplate(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req AvailabilityTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	facilityID := c.Param("id")
+	if !h.facilityTenant(context.Background(), c, facilityID, tenantID) {
+		return
+	}
+
+	h.saveTemplate(c, tenantID, facilityID, c.Param("template_id"), &req, c.Query("dry_run") == "true")
+}
+
+// GenerateTemplateSlots re-runs slot generation for a template, e.g. after
+// a conflicting slot was removed. Pass ?dry_run=true to preview.
+func (h *Handler) GenerateTemplateSlots(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	facilityID := c.Param("id")
+	if !h.facilityTenant(context.Background(), c, facilityID, tenantID) {
+		return
+	}
+
+	h.saveTemplate(c, tenantID, facilityID, c.Param("template_id"), nil, c.Query("dry_run") == "true")
+}
+
+func (h *Handler) DeleteAvailabilityTemplate(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	facilityID := c.Param("id")
+	templateID := c.Param("template_id")
+	if !h.facilityTenant(ctx, c, facilityID, tenantID) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := loadTemplate(ctx, tx, tenantID, facilityID, templateID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	// Future slots nobody has taken go with the template; past, booked and
+	// reserved slots stay
+	_, err = tx.Exec(ctx,
+		`DELETE FROM facility_slots fs
+		 WHERE fs.template_id = $1 AND fs.starts_at > now() AND fs.status = 'open'
+		   AND NOT EXISTS(SELECT 1 FROM bookings b
+		                  WHERE b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		                    AND b.status NOT IN ('declined', 'cancelled'))`,
+		templateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM facility_availability_templates WHERE id = $1`, templateID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// GenerateFacilitySlots extends every active template's slots to its
+// horizon
+func (h *Handler) GenerateFacilitySlots(ctx context.Context) error {
+	rows, err := h.DB.Query(ctx,
+		`SELECT id::text, tenant_id::text, facility_id::text FROM facility_availability_templates
+		 WHERE active
+		 ORDER BY generated_at NULLS FIRST`)
+	if err != nil {
+		return err
+	}
+	type templateRef struct {
+		id, tenantID, facilityID string
+	}
+	var templates []templateRef
+	for rows.Next() {
+		var t templateRef
+		if err := rows.Scan(&t.id, &t.tenantID, &t.facilityID); err != nil {
+			rows.Close()
+			return err
+		}
+		templates = append(templates, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, ref := range templates {
+		if err := h.generateTemplate(ctx, ref.tenantID, ref.facilityID, ref.id); err != nil {
+			return fmt.Errorf("template %s: %w", ref.id, err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) generateTemplate(ctx context.Context, tenantID, facilityID, templateID string) error {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := loadTemplate(ctx, tx, tenantID, facilityID, templateID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := h.syncTemplateSlots(ctx, tx, tenantID, t, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RunSlotGeneration keeps template slots generated until ctx is cancelled
+func (h *Handler) RunSlotGeneration(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.GenerateFacilitySlots(ctx); err != nil {
+			log.Printf("Facility slot generation: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
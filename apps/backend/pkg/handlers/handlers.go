@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
 	"github.com/rec-hub/backend/pkg/config"
+	"github.com/rec-hub/backend/pkg/payments"
+	"github.com/redis/go-redis/v9"
 )
 
 // Handler holds dependencies for all HTTP handlers
@@ -11,5 +17,27 @@ type Handler struct {
 	DB       *pgxpool.Pool
 	Redis    *redis.Client
 	Config   *config.Config
-	TenantID string // Set by middleware
+	Payments payments.Provider // nil when online payments are disabled
+	TenantID string            // Set by middleware
+}
+
+// dbtx is satisfied by both *pgxpool.Pool and pgx.Tx so helpers can run
+// inside or outside a transaction
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isExclusionViolation reports whether err is a Postgres exclusion constraint
+// violation, such as overlapping facility slots
+func isExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01"
 }
@@ -0,0 +1,893 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rec-hub/backend/pkg/ical"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Bulk Import ============
+
+const maxImportRows = 2000
+
+// ImportRequest imports events or programs from an iCalendar or CSV file.
+// Mapping names the CSV column or iCalendar property that fills each
+// field; unmapped fields fall back to the usual column names and
+// properties.
+type ImportRequest struct {
+	Format  string            `json:"format" binding:"omitempty,oneof=ics csv"`
+	Content string            `json:"content" binding:"required"`
+	Mapping map[string]string `json:"mapping"`
+	// OnDuplicate decides what happens to rows whose slug is already
+	// taken: skip them or import them under a numbered slug
+	OnDuplicate string `json:"on_duplicate" binding:"omitempty,oneof=skip rename"`
+	// SkipInvalid imports the valid rows when others have errors
+	SkipInvalid bool `json:"skip_invalid"`
+	DryRun      bool `json:"dry_run"`
+}
+
+// ImportRow is the outcome for one CSV row or iCalendar VEVENT. Row is the
+// CSV line number or the line of the VEVENT's BEGIN.
+type ImportRow struct {
+	Row     int             `json:"row"`
+	Status  string          `json:"status"`
+	Title   string          `json:"title"`
+	Slug    string          `json:"slug,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	Errors  []string        `json:"errors,omitempty"`
+	Event   *EventRequest   `json:"event,omitempty"`
+	Program *ProgramRequest `json:"program,omitempty"`
+}
+
+// Import row statuses
+const (
+	importCreated   = "created"
+	importDuplicate = "duplicate"
+	importSkipped   = "skipped"
+	importInvalid   = "invalid"
+)
+
+// importFields are the fields a file can fill, with the CSV headers
+// recognised for each when the request does not map it
+var importFields = map[string]map[string][]string{
+	"events": {
+		"title":       {"title", "name", "subject", "summary", "event"},
+		"description": {"description", "details", "notes"},
+		"starts_at":   {"starts_at", "start", "starts", "start_datetime"},
+		"ends_at":     {"ends_at", "end", "ends", "end_datetime"},
+		"start_date":  {"start_date", "date"},
+		"start_time":  {"start_time", "time"},
+		"end_date":    {"end_date"},
+		"end_time":    {"end_time"},
+		"all_day":     {"all_day", "all_day_event"},
+		"location":    {"location", "venue", "place", "where"},
+		"capacity":    {"capacity", "max_attendees", "spots"},
+		"category":    {"category", "type"},
+		"status":      {"status"},
+		"visibility":  {"visibility", "visible", "public"},
+		"image_url":   {"image_url", "image"},
+		"slug":        {"slug"},
+		"rrule":       {"rrule", "recurrence"},
+		"exdates":     {"exdates"},
+	},
+	"programs": {
+		"title":       {"title", "name", "program"},
+		"description": {"description", "details"},
+		"season":      {"season", "term"},
+		"category":    {"category", "type"},
+		"start_date":  {"start_date", "starts", "start"},
+		"end_date":    {"end_date", "ends", "end"},
+		"price_cents": {"price_cents"},
+		"price":       {"price", "fee", "cost"},
+		"capacity":    {"capacity", "max_participants", "spots"},
+		"status":      {"status"},
+		"image_url":   {"image_url", "image"},
+		"slug":        {"slug"},
+	},
+}
+
+// icsDefaults are the properties read for each field when the request
+// does not map it
+var icsDefaults = map[string]map[string]string{
+	"events": {
+		"title": "SUMMARY", "description": "DESCRIPTION", "starts_at": "DTSTART", "ends_at": "DTEND",
+		"location": "LOCATION", "category": "CATEGORIES", "rrule": "RRULE", "exdates": "EXDATE",
+	},
+	"programs": {
+		"title": "SUMMARY", "description": "DESCRIPTION", "start_date": "DTSTART", "end_date": "DTEND",
+		"category": "CATEGORIES",
+	},
+}
+
+var nonHeaderChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalizeHeader(s string) string {
+	return strings.Trim(nonHeaderChars.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+// importRecord is one row of the file with its values looked up by field
+type importRecord struct {
+	row int
+	// text returns the raw value of a field, "" when unmapped or empty
+	text func(field string) string
+	// event is the VEVENT the row came from, nil for CSV, with the
+	// property read for each field and the zone of its floating times
+	event *ical.Component
+	props map[string]string
+	zone  *time.Location
+}
+
+// importCandidate is a parsed row waiting to be saved
+type importCandidate struct {
+	ImportRow
+	baseSlug string
+}
+
+// ImportEvents creates events from an iCalendar or CSV file
+func (h *Handler) ImportEvents(c *gin.Context) {
+	h.runImport(c, "events")
+}
+
+// ImportPrograms creates programs from an iCalendar or CSV file
+func (h *Handler) ImportPrograms(c *gin.Context) {
+	h.runImport(c, "programs")
+}
+
+// runImport validates every row, then saves the valid ones in one
+// transaction. Rows whose slug is taken by an existing row or an earlier
+// row of the file are duplicates. Nothing is saved on a dry run, or when
+// any row is invalid unless skip_invalid is set.
+func (h *Handler) runImport(c *gin.Context, kind string) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.OnDuplicate == "" {
+		req.OnDuplicate = "skip"
+	}
+	for field := range req.Mapping {
+		if _, ok := importFields[kind][field]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown field %q in mapping; expected one of %s",
+				field, strings.Join(importMappingFields(kind), ", "))})
+			return
+		}
+	}
+
+	content := strings.TrimPrefix(req.Content, "\ufeff")
+	if req.Format == "" {
+		req.Format = "csv"
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(content)), "BEGIN:VCALENDAR") {
+			req.Format = "ics"
+		}
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+
+	var records []importRecord
+	var err error
+	if req.Format == "ics" {
+		records, err = icsImportRecords(content, kind, req.Mapping, loc)
+	} else {
+		records, err = csvImportRecords(content, kind, req.Mapping)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the file has no rows"})
+		return
+	}
+	if len(records) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("import at most %d rows at a time", maxImportRows)})
+		return
+	}
+
+	candidates := make([]importCandidate, len(records))
+	for i, rec := range records {
+		if kind == "events" {
+			candidates[i] = buildImportEvent(rec, loc)
+		} else {
+			candidates[i] = h.buildImportProgram(ctx, tenantID, rec, loc)
+		}
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	invalid := 0
+	for _, cand := range candidates {
+		if cand.Status == importInvalid {
+			invalid++
+		}
+	}
+	// Invalid rows stop the import unless they are skipped; the valid
+	// rows are still saved and rolled back so the response previews them
+	preview := req.DryRun || (invalid > 0 && !req.SkipInvalid)
+
+	counts := map[string]int{importCreated: 0, importDuplicate: 0, importSkipped: 0, importInvalid: 0}
+	rows := make([]ImportRow, 0, len(candidates))
+	for i := range candidates {
+		cand := &candidates[i]
+		if cand.Status == "" {
+			if err := saveImportCandidate(ctx, tx, claims.TenantID, kind, cand, req.OnDuplicate); err != nil {
+				if isUniqueViolation(err) {
+					c.JSON(http.StatusConflict, gin.H{"error": "a slug was taken while importing; try again"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import row " + strconv.Itoa(cand.Row)})
+				return
+			}
+		}
+		if preview {
+			cand.ID = ""
+		}
+		counts[cand.Status]++
+		rows = append(rows, cand.ImportRow)
+	}
+
+	summary := gin.H{
+		"kind":       kind,
+		"format":     req.Format,
+		"dry_run":    req.DryRun,
+		"total":      len(rows),
+		"created":    counts[importCreated],
+		"duplicates": counts[importDuplicate],
+		"skipped":    counts[importSkipped],
+		"invalid":    counts[importInvalid],
+	}
+
+	if preview && !req.DryRun {
+		summary["error"] = fmt.Sprintf("%d rows are invalid; nothing was imported", invalid)
+		summary["rows"] = rows
+		c.JSON(http.StatusUnprocessableEntity, summary)
+		return
+	}
+
+	if !req.DryRun {
+		err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "import", kind, nil, nil, summary)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+	}
+
+	summary["rows"] = rows
+	c.JSON(http.StatusOK, summary)
+}
+
+// saveImportCandidate dedupes a valid row's slug and inserts it
+func saveImportCandidate(ctx context.Context, q dbtx, tenantID uuid.UUID, kind string, cand *importCandidate, onDuplicate string) error {
+	table := kind
+	slug := cand.baseSlug
+	if slug == "" {
+		slug = strings.TrimSuffix(kind, "s")
+	}
+	var taken bool
+	err := q.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE tenant_id = $1 AND slug = $2)`,
+		tenantID, slug).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		if onDuplicate == "skip" {
+			cand.Status = importDuplicate
+			cand.Slug = slug
+			cand.Reason = "slug " + slug + " already exists"
+			return nil
+		}
+		if slug, err = uniqueSlug(ctx, q, table, tenantID.String(), slug); err != nil {
+			return err
+		}
+	}
+
+	id := uuid.New()
+	if kind == "events" {
+		cand.Event.Slug = &slug
+		status, visibility := "active", true
+		if cand.Event.Status != nil {
+			status = *cand.Event.Status
+		}
+		if cand.Event.Visibility != nil {
+			visibility = *cand.Event.Visibility
+		}
+		err = insertEvent(ctx, q, id, tenantID, *cand.Event, status, visibility)
+	} else {
+		cand.Program.Slug = &slug
+		status := "active"
+		if cand.Program.Status != nil {
+			status = *cand.Program.Status
+		}
+		err = insertProgram(ctx, q, id, tenantID, *cand.Program, status)
+	}
+	if err != nil {
+		return err
+	}
+	cand.Status = importCreated
+	cand.Slug = slug
+	cand.ID = id.String()
+	return nil
+}
+
+// csvImportRecords reads a CSV file with a header row
+func csvImportRecords(content, kind string, mapping map[string]string) ([]importRecord, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if _, ok := columns[normalizeHeader(name)]; !ok {
+			columns[normalizeHeader(name)] = i
+		}
+	}
+
+	// Resolve each field to a column
+	fieldColumn := make(map[string]int)
+	for field, aliases := range importFields[kind] {
+		if source, ok := mapping[field]; ok {
+			if source == "" {
+				continue
+			}
+			i, ok := columns[normalizeHeader(source)]
+			if !ok {
+				return nil, fmt.Errorf("column %q mapped to %s was not found", source, field)
+			}
+			fieldColumn[field] = i
+			continue
+		}
+		for _, alias := range aliases {
+			if i, ok := columns[alias]; ok {
+				fieldColumn[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := fieldColumn["title"]; !ok {
+		return nil, fmt.Errorf("no column maps to title")
+	}
+
+	var records []importRecord
+	for {
+		cells, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		blank := true
+		for _, cell := range cells {
+			if strings.TrimSpace(cell) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+		if len(records) > maxImportRows {
+			break
+		}
+		row := cells
+		line, _ := r.FieldPos(0)
+		records = append(records, importRecord{
+			row: line,
+			text: func(field string) string {
+				i, ok := fieldColumn[field]
+				if !ok || i >= len(row) {
+					return ""
+				}
+				return strings.TrimSpace(row[i])
+			},
+		})
+	}
+	return records, nil
+}
+
+// icsImportRecords reads the VEVENTs of an iCalendar file. Floating times
+// are read in the calendar's X-WR-TIMEZONE when it has one, otherwise in
+// loc.
+func icsImportRecords(content, kind string, mapping map[string]string, loc *time.Location) ([]importRecord, error) {
+	cal, err := ical.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid iCalendar file: %v", err)
+	}
+	zone := loc
+	if p := cal.Get("X-WR-TIMEZONE"); p != nil {
+		if l, err := time.LoadLocation(p.Value); err == nil {
+			zone = l
+		}
+	}
+
+	props := make(map[string]string)
+	for field, prop := range icsDefaults[kind] {
+		props[field] = prop
+	}
+	for field, prop := range mapping {
+		props[field] = strings.ToUpper(strings.TrimSpace(prop))
+	}
+
+	var records []importRecord
+	for _, vevent := range cal.Children("VEVENT") {
+		ev := vevent
+		records = append(records, importRecord{
+			row:   ev.Line,
+			event: ev,
+			props: props,
+			zone:  zone,
+			text: func(field string) string {
+				p := ev.Get(props[field])
+				if props[field] == "" || p == nil {
+					return ""
+				}
+				if p.Name == "CATEGORIES" {
+					// The first of a list of categories
+					first, _, _ := strings.Cut(p.Value, ",")
+					return strings.TrimSpace(ical.Unescape(first))
+				}
+				return strings.TrimSpace(p.Text())
+			},
+		})
+	}
+	return records, nil
+}
+
+// icsSkipReason explains why a VEVENT is not imported, or returns ""
+func icsSkipReason(ev *ical.Component) string {
+	if ev.Get("RECURRENCE-ID") != nil {
+		return "edited occurrence of a recurring event; the series is imported with its rule"
+	}
+	if p := ev.Get("STATUS"); p != nil && strings.EqualFold(p.Value, "CANCELLED") {
+		return "cancelled in the source calendar"
+	}
+	return ""
+}
+
+// icsTimes reads an event's start and end properties. All-day events run
+// from midnight to midnight in loc; a missing end is taken from DURATION
+// or, for all-day events, one day.
+func icsTimes(rec importRecord, loc *time.Location) (start, end time.Time, allDay bool, err error) {
+	startProp := rec.event.Get(rec.props["starts_at"])
+	if startProp == nil {
+		return start, end, false, fmt.Errorf("%s is required", rec.props["starts_at"])
+	}
+	if start, allDay, err = startProp.Time(rec.zone); err != nil {
+		return start, end, false, err
+	}
+	if allDay {
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	}
+
+	if endProp := rec.event.Get(rec.props["ends_at"]); endProp != nil {
+		var endAllDay bool
+		if end, endAllDay, err = endProp.Time(rec.zone); err != nil {
+			return start, end, false, err
+		}
+		if endAllDay {
+			end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+		}
+		return start, end, allDay, nil
+	}
+	if p := rec.event.Get("DURATION"); p != nil {
+		d, err := p.Duration()
+		if err != nil {
+			return start, end, false, err
+		}
+		return start, start.Add(d), allDay, nil
+	}
+	if allDay {
+		return start, start.AddDate(0, 0, 1), true, nil
+	}
+	return start, end, false, fmt.Errorf("%s or DURATION is required", rec.props["ends_at"])
+}
+
+var importDateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "1/2/06", "Jan 2, 2006", "January 2, 2006", "2 Jan 2006"}
+
+var importClockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04 pm", "3:04pm", "3 PM", "3PM", "3 pm", "3pm"}
+
+// parseImportDate reads a calendar date in one of the common spreadsheet
+// formats
+func parseImportDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseImportDateTime reads RFC3339, a wall-clock time in loc, or a date
+// and a clock time in separate values
+func parseImportDateTime(value, clock string, loc *time.Location) (time.Time, error) {
+	if clock == "" {
+		if t, err := parseTenantTime(value, loc); err == nil {
+			return t, nil
+		}
+		// "date time" in one cell
+		if i := strings.IndexByte(value, ' '); i > 0 {
+			if d, err := parseImportDate(value[:i], loc); err == nil {
+				return parseImportDateTime(d.Format("2006-01-02"), strings.TrimSpace(value[i+1:]), loc)
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date and time %q", value)
+	}
+	d, err := parseImportDate(value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range importClockLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(clock)); err == nil {
+			return time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", clock)
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1", "public", "visible":
+		return true, nil
+	case "false", "no", "n", "0", "private", "hidden":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid yes/no value %q", value)
+}
+
+// parseImportCents reads an amount such as "$25", "25.50" or "1,200.00"
+func parseImportCents(value string) (int, error) {
+	v := strings.NewReplacer("$", "", ",", "", " ", "").Replace(value)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid price %q", value)
+	}
+	return int(f*100 + 0.5), nil
+}
+
+// importStatus reads an active/inactive status
+func importStatus(value string) (*string, error) {
+	status := strings.ToLower(value)
+	if status != "active" && status != "inactive" {
+		return nil, fmt.Errorf("status must be active or inactive")
+	}
+	return &status, nil
+}
+
+// buildImportEvent turns a row into an EventRequest and checks it the way
+// CreateEvent would
+func buildImportEvent(rec importRecord, loc *time.Location) importCandidate {
+	cand := importCandidate{ImportRow: ImportRow{Row: rec.row, Title: rec.text("title")}}
+	if rec.event != nil {
+		if reason := icsSkipReason(rec.event); reason != "" {
+			cand.Status = importSkipped
+			cand.Reason = reason
+			return cand
+		}
+	}
+	var errs []string
+	req := EventRequest{
+		Title:       cand.Title,
+		Description: nullIfEmpty(rec.text("description")),
+		Location:    nullIfEmpty(rec.text("location")),
+		Category:    nullIfEmpty(rec.text("category")),
+		ImageURL:    nullIfEmpty(rec.text("image_url")),
+		RRule:       nullIfEmpty(rec.text("rrule")),
+		Exdates:     []time.Time{},
+	}
+	if req.Title == "" {
+		errs = append(errs, "title is required")
+	}
+	if v := rec.text("capacity"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, "capacity must be a whole number")
+		} else {
+			req.Capacity = &n
+		}
+	}
+	if v := rec.text("status"); v != "" {
+		status, err := importStatus(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.Status = status
+	}
+	if v := rec.text("visibility"); v != "" {
+		visible, err := parseImportBool(v)
+		if err != nil {
+			errs = append(errs, "visibility: "+err.Error())
+		} else {
+			req.Visibility = &visible
+		}
+	}
+
+	var timeErr error
+	if rec.event != nil {
+		req.StartsAt, req.EndsAt, _, timeErr = icsTimes(rec, loc)
+		if timeErr != nil {
+			errs = append(errs, timeErr.Error())
+		}
+		for _, p := range rec.event.All(rec.props["exdates"]) {
+			times, _, err := p.Times(rec.zone)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			req.Exdates = append(req.Exdates, times...)
+		}
+	} else {
+		req.StartsAt, req.EndsAt, errs = csvEventTimes(rec, loc, errs)
+		for _, v := range strings.FieldsFunc(rec.text("exdates"), func(r rune) bool { return r == ';' || r == '|' }) {
+			t, err := parseImportDateTime(strings.TrimSpace(v), "", loc)
+			if err != nil {
+				errs = append(errs, "exdates: "+err.Error())
+				continue
+			}
+			req.Exdates = append(req.Exdates, t)
+		}
+	}
+	if len(errs) == 0 && !req.EndsAt.After(req.StartsAt) {
+		errs = append(errs, "ends_at must be after starts_at")
+	}
+	if len(errs) == 0 && req.RRule != nil {
+		rrule, err := normalizeEventRule(req.RRule, req.StartsAt, loc)
+		if err != nil {
+			errs = append(errs, "rrule: "+err.Error())
+		}
+		req.RRule = rrule
+	}
+	if req.RRule == nil {
+		req.Exdates = []time.Time{}
+	}
+
+	cand.Event = &req
+	if len(errs) > 0 {
+		cand.Status = importInvalid
+		cand.Errors = errs
+		return cand
+	}
+	if v := rec.text("slug"); v != "" {
+		cand.baseSlug = slugify(v)
+	} else {
+		cand.baseSlug = slugify(req.Title + " " + req.StartsAt.In(loc).Format("2006-01-02"))
+	}
+	cand.Slug = cand.baseSlug
+	return cand
+}
+
+// csvEventTimes reads starts_at/ends_at, or separate date and time
+// columns. An all-day row, or one with dates but no times, runs from
+// midnight on its start date to midnight after its end date.
+func csvEventTimes(rec importRecord, loc *time.Location, errs []string) (time.Time, time.Time, []string) {
+	var start, end time.Time
+	var err error
+	allDay := false
+	if v := rec.text("all_day"); v != "" {
+		if allDay, err = parseImportBool(v); err != nil {
+			errs = append(errs, "all_day: "+err.Error())
+		}
+	}
+
+	if v := rec.text("starts_at"); v != "" {
+		if start, err = parseImportDateTime(v, "", loc); err != nil {
+			errs = append(errs, "starts_at: "+err.Error())
+		}
+	} else if v := rec.text("start_date"); v != "" {
+		clock := rec.text("start_time")
+		if clock == "" {
+			allDay = true
+		}
+		if allDay {
+			start, err = parseImportDate(v, loc)
+		} else {
+			start, err = parseImportDateTime(v, clock, loc)
+		}
+		if err != nil {
+			errs = append(errs, "start: "+err.Error())
+		}
+	} else {
+		errs = append(errs, "starts_at is required")
+	}
+
+	if v := rec.text("ends_at"); v != "" {
+		if end, err = parseImportDateTime(v, "", loc); err != nil {
+			errs = append(errs, "ends_at: "+err.Error())
+		}
+	} else if v, clock := rec.text("end_date"), rec.text("end_time"); v != "" || clock != "" {
+		if v == "" && !start.IsZero() {
+			v = start.In(loc).Format("2006-01-02")
+		}
+		if allDay {
+			if end, err = parseImportDate(v, loc); err == nil {
+				end = end.AddDate(0, 0, 1)
+			}
+		} else {
+			end, err = parseImportDateTime(v, clock, loc)
+		}
+		if err != nil {
+			errs = append(errs, "end: "+err.Error())
+		}
+	} else if allDay && !start.IsZero() {
+		end = start.AddDate(0, 0, 1)
+	} else {
+		errs = append(errs, "ends_at is required")
+	}
+	return start, end, errs
+}
+
+// buildImportProgram turns a row into a ProgramRequest and checks it the
+// way CreateProgram would. From iCalendar, a bounded recurring event ends
+// on its last occurrence.
+func (h *Handler) buildImportProgram(ctx context.Context, tenantID string, rec importRecord, loc *time.Location) importCandidate {
+	cand := importCandidate{ImportRow: ImportRow{Row: rec.row, Title: rec.text("title")}}
+	if rec.event != nil {
+		if reason := icsSkipReason(rec.event); reason != "" {
+			cand.Status = importSkipped
+			cand.Reason = reason
+			return cand
+		}
+	}
+	var errs []string
+	req := ProgramRequest{
+		Title:       cand.Title,
+		Description: nullIfEmpty(rec.text("description")),
+		Season:      nullIfEmpty(rec.text("season")),
+		Category:    nullIfEmpty(rec.text("category")),
+		ImageURL:    nullIfEmpty(rec.text("image_url")),
+	}
+	if req.Title == "" {
+		errs = append(errs, "title is required")
+	}
+	if v := rec.text("price_cents"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, "price_cents must be a whole number")
+		}
+		req.PriceCents = n
+	} else if v := rec.text("price"); v != "" {
+		n, err := parseImportCents(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.PriceCents = n
+	}
+	if v := rec.text("capacity"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, "capacity must be a whole number")
+		} else {
+			req.Capacity = &n
+		}
+	}
+	if v := rec.text("status"); v != "" {
+		status, err := importStatus(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.Status = status
+	}
+
+	var startDate, endDate *time.Time
+	if rec.event != nil {
+		start, end, allDay, err := icsTimes(rec, loc)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			if allDay {
+				end = end.AddDate(0, 0, -1)
+			}
+			if p := rec.event.Get("RRULE"); p != nil {
+				if rule, err := schedule.ParseRule(p.Value); err == nil {
+					rec := schedule.Recurrence{Rule: rule, Start: start, Location: loc}
+					if last, ok := rec.Last(); ok {
+						end = last.Add(end.Sub(start))
+					}
+				}
+			}
+			startDate, endDate = &start, &end
+		}
+	} else {
+		for _, field := range []string{"start_date", "end_date"} {
+			v := rec.text(field)
+			if v == "" {
+				continue
+			}
+			d, err := parseImportDate(v, loc)
+			if err != nil {
+				errs = append(errs, field+": "+err.Error())
+				continue
+			}
+			if field == "start_date" {
+				startDate = &d
+			} else {
+				endDate = &d
+			}
+		}
+	}
+	if startDate != nil {
+		s := isoDate(startDate.In(loc))
+		req.StartDate = &s
+	}
+	if endDate != nil {
+		s := isoDate(endDate.In(loc))
+		req.EndDate = &s
+	}
+	if req.StartDate != nil && req.EndDate != nil && *req.EndDate < *req.StartDate {
+		errs = append(errs, "end_date must not be before start_date")
+	}
+
+	if err := h.resolveProgramSeason(ctx, tenantID, &req); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	cand.Program = &req
+	if len(errs) > 0 {
+		cand.Status = importInvalid
+		cand.Errors = errs
+		return cand
+	}
+	if v := rec.text("slug"); v != "" {
+		cand.baseSlug = slugify(v)
+	} else if req.Season != nil {
+		cand.baseSlug = slugify(req.Title + " " + *req.Season)
+	} else {
+		cand.baseSlug = slugify(req.Title)
+	}
+	cand.Slug = cand.baseSlug
+	return cand
+}
+
+// importMappingFields lists the fields a kind accepts, for error messages
+func importMappingFields(kind string) []string {
+	var fields []string
+	for field := range importFields[kind] {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,1281 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/auth"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Instructors ============
+
+type InstructorRequest struct {
+	Name   string  `json:"name" binding:"required"`
+	Email  *string `json:"email"`
+	Phone  *string `json:"phone"`
+	Kind   string  `json:"kind"`
+	Bio    *string `json:"bio"`
+	Active *bool   `json:"active"`
+	// UserID links a staff login so the instructor sees their own rosters
+	UserID *string `json:"user_id"`
+}
+
+type CertificationRequest struct {
+	Name             string  `json:"name" binding:"required"`
+	Issuer           *string `json:"issuer"`
+	CredentialNumber *string `json:"credential_number"`
+	IssuedOn         *string `json:"issued_on"`
+	ExpiresOn        *string `json:"expires_on"`
+}
+
+type Certification struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Issuer           *string `json:"issuer"`
+	CredentialNumber *string `json:"credential_number"`
+	IssuedOn         *string `json:"issued_on"`
+	ExpiresOn        *string `json:"expires_on"`
+	Expired          bool    `json:"expired"`
+}
+
+// CertificationIssue is a required certification an instructor lacks on a date
+type CertificationIssue struct {
+	Name      string  `json:"name"`
+	Reason    string  `json:"reason"` // missing or expired
+	ExpiresOn *string `json:"expires_on,omitempty"`
+}
+
+type AssignInstructorRequest struct {
+	InstructorID string `json:"instructor_id" binding:"required"`
+	Role         string `json:"role"`
+	// AllowOverlap assigns even when the instructor already teaches at the same time
+	AllowOverlap bool `json:"allow_overlap"`
+}
+
+type RequiredCertificationsRequest struct {
+	Certifications []string `json:"certifications"`
+}
+
+type sessionRef struct {
+	SessionID    string    `json:"session_id"`
+	ProgramID    string    `json:"program_id"`
+	ProgramTitle string    `json:"program_title"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+// InstructorOverlap is a session that clashes with another the instructor teaches
+type InstructorOverlap struct {
+	InstructorID  string     `json:"instructor_id"`
+	Session       sessionRef `json:"session"`
+	ConflictsWith sessionRef `json:"conflicts_with"`
+}
+
+func isoDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// parseOptionalDate reads a YYYY-MM-DD date, treating "" as unset
+func parseOptionalDate(field string, s *string) (*time.Time, error) {
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD", field)
+	}
+	return &t, nil
+}
+
+// normalizeCertName makes certification names match regardless of case and spacing
+func normalizeCertName(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func (h *Handler) loadCertifications(ctx context.Context, q dbtx, instructorID, today string) ([]Certification, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id, name, issuer, credential_number, issued_on::text, expires_on::text
+		 FROM instructor_certifications
+		 WHERE instructor_id = $1
+		 ORDER BY lower(name), expires_on DESC NULLS FIRST`,
+		instructorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	certs := []Certification{}
+	for rows.Next() {
+		var cert Certification
+		if err := rows.Scan(&cert.ID, &cert.Name, &cert.Issuer, &cert.CredentialNumber, &cert.IssuedOn, &cert.ExpiresOn); err != nil {
+			return nil, err
+		}
+		cert.Expired = cert.ExpiresOn != nil && *cert.ExpiresOn < today
+		certs = append(certs, cert)
+	}
+	return certs, rows.Err()
+}
+
+// checkCertifications reports which required certifications the instructor
+// does not hold, or holds only with an expiry before the given date
+func checkCertifications(certs []Certification, required []string, on string) []CertificationIssue {
+	type best struct {
+		never     bool
+		expiresOn string
+	}
+	held := map[string]*best{}
+	for _, cert := range certs {
+		key := normalizeCertName(cert.Name)
+		b := held[key]
+		if b == nil {
+			b = &best{}
+			held[key] = b
+		}
+		if cert.ExpiresOn == nil {
+			b.never = true
+		} else if *cert.ExpiresOn > b.expiresOn {
+			b.expiresOn = *cert.ExpiresOn
+		}
+	}
+
+	issues := []CertificationIssue{}
+	for _, name := range required {
+		b := held[normalizeCertName(name)]
+		switch {
+		case b == nil:
+			issues = append(issues, CertificationIssue{Name: name, Reason: "missing"})
+		case !b.never && b.expiresOn < on:
+			expires := b.expiresOn
+			issues = append(issues, CertificationIssue{Name: name, Reason: "expired", ExpiresOn: &expires})
+		}
+	}
+	return issues
+}
+
+// taughtSessionsSQL selects the upcoming scheduled sessions an instructor
+// teaches, through a program assignment or a single-session assignment.
+// $1 is the tenant and $2 the instructor.
+const taughtSessionsSQL = `
+	SELECT s.id, s.program_id, s.starts_at, s.ends_at
+	FROM program_sessions s
+	WHERE s.tenant_id = $1 AND s.status = 'scheduled' AND s.ends_at > now()
+	  AND (EXISTS (SELECT 1 FROM program_instructors pi WHERE pi.program_id = s.program_id AND pi.instructor_id = $2)
+	       OR EXISTS (SELECT 1 FROM session_instructors si WHERE si.session_id = s.id AND si.instructor_id = $2))`
+
+// findOverlaps returns clashes between the candidate sessions and the
+// sessions the instructor already teaches. With nil candidates it reports
+// clashes among the instructor's current sessions, each pair once.
+func findOverlaps(ctx context.Context, q dbtx, tenantID, instructorID string, candidates []string) ([]InstructorOverlap, error) {
+	var query string
+	args := []interface{}{tenantID, instructorID}
+	if candidates == nil {
+		query = `WITH taught AS (` + taughtSessionsSQL + `)
+			SELECT c.id, c.program_id, cp.title, c.starts_at, c.ends_at, t.id, t.program_id, tp.title, t.starts_at, t.ends_at
+			FROM taught c
+			JOIN taught t ON t.id > c.id AND t.starts_at < c.ends_at AND t.ends_at > c.starts_at`
+	} else {
+		query = `WITH taught AS (` + taughtSessionsSQL + `),
+			candidates AS (
+				SELECT s.id, s.program_id, s.starts_at, s.ends_at FROM program_sessions s
+				WHERE s.id = ANY($3::uuid[]) AND s.status = 'scheduled' AND s.ends_at > now()
+			)
+			SELECT c.id, c.program_id, cp.title, c.starts_at, c.ends_at, t.id, t.program_id, tp.title, t.starts_at, t.ends_at
+			FROM candidates c
+			JOIN taught t ON t.id <> c.id AND t.starts_at < c.ends_at AND t.ends_at > c.starts_at`
+		args = append(args, candidates)
+	}
+	query += `
+			JOIN programs cp ON c.program_id = cp.id
+			JOIN programs tp ON t.program_id = tp.id
+			ORDER BY c.starts_at, t.starts_at`
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overlaps := []InstructorOverlap{}
+	for rows.Next() {
+		o := InstructorOverlap{InstructorID: instructorID}
+		if err := rows.Scan(&o.Session.SessionID, &o.Session.ProgramID, &o.Session.ProgramTitle, &o.Session.StartsAt, &o.Session.EndsAt,
+			&o.ConflictsWith.SessionID, &o.ConflictsWith.ProgramID, &o.ConflictsWith.ProgramTitle,
+			&o.ConflictsWith.StartsAt, &o.ConflictsWith.EndsAt); err != nil {
+			return nil, err
+		}
+		overlaps = append(overlaps, o)
+	}
+	return overlaps, rows.Err()
+}
+
+// staffProgramIDs lists the programs a user teaches through their instructor
+// profile, including programs where they only cover single sessions
+func staffProgramIDs(ctx context.Context, q dbtx, tenantID, userID string) ([]string, error) {
+	rows, err := q.Query(ctx,
+		`SELECT pi.program_id::text
+		 FROM program_instructors pi
+		 JOIN instructors i ON pi.instructor_id = i.id
+		 WHERE i.tenant_id = $1 AND i.user_id = $2
+		 UNION
+		 SELECT s.program_id::text
+		 FROM session_instructors si
+		 JOIN instructors i ON si.instructor_id = i.id
+		 JOIN program_sessions s ON si.session_id = s.id
+		 WHERE i.tenant_id = $1 AND i.user_id = $2`,
+		tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// canViewRoster reports whether the caller may see a program's rosters and
+// attendance. OWNER and ADMIN see every program; STAFF only the programs they
+// are assigned to, or with sessionID, that session's program or the session
+// itself.
+func (h *Handler) canViewRoster(ctx context.Context, claims *auth.Claims, programID string, sessionID *string) bool {
+	switch claims.Role {
+	case "OWNER", "ADMIN":
+		return true
+	case "STAFF":
+	default:
+		return false
+	}
+
+	var assigned bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS (
+		   SELECT 1 FROM instructors i
+		   WHERE i.tenant_id = $1 AND i.user_id = $2 AND i.active
+		     AND (EXISTS (SELECT 1 FROM program_instructors pi WHERE pi.instructor_id = i.id AND pi.program_id = $3)
+		          OR EXISTS (SELECT 1 FROM session_instructors si
+		                     JOIN program_sessions s ON si.session_id = s.id
+		                     WHERE si.instructor_id = i.id AND s.program_id = $3
+		                       AND ($4::uuid IS NULL OR si.session_id = $4)))
+		 )`,
+		claims.TenantID, claims.UserID, programID, sessionID).Scan(&assigned)
+	return err == nil && assigned
+}
+
+type instructorRow struct {
+	ID     string  `json:"id"`
+	UserID *string `json:"user_id"`
+	Name   string  `json:"name"`
+	Email  *string `json:"email"`
+	Phone  *string `json:"phone"`
+	Kind   string  `json:"kind"`
+	Bio    *string `json:"bio"`
+	Active bool    `json:"active"`
+}
+
+const instructorColumns = `id, user_id::text, name, email, phone, kind, bio, active`
+
+func scanInstructor(row pgx.Row) (*instructorRow, error) {
+	var i instructorRow
+	if err := row.Scan(&i.ID, &i.UserID, &i.Name, &i.Email, &i.Phone, &i.Kind, &i.Bio, &i.Active); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (h *Handler) loadInstructor(ctx context.Context, q dbtx, tenantID, instructorID string) (*instructorRow, error) {
+	return scanInstructor(q.QueryRow(ctx,
+		`SELECT `+instructorColumns+` FROM instructors WHERE id = $1 AND tenant_id = $2`,
+		instructorID, tenantID))
+}
+
+// today returns the current date in the tenant's timezone as YYYY-MM-DD
+func (h *Handler) today(ctx context.Context, tenantID string) string {
+	return isoDate(time.Now().In(h.tenantLocation(ctx, h.DB, tenantID)))
+}
+
+// validateInstructorRequest normalizes the request and checks a linked user
+// is a staff member of the tenant
+func (h *Handler) validateInstructorRequest(ctx context.Context, tenantID string, req *InstructorRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if req.Kind == "" {
+		req.Kind = "staff"
+	}
+	if req.Kind != "staff" && req.Kind != "contractor" {
+		return errors.New("kind must be staff or contractor")
+	}
+	if req.UserID != nil && *req.UserID == "" {
+		req.UserID = nil
+	}
+	if req.UserID != nil {
+		var role string
+		err := h.DB.QueryRow(ctx,
+			`SELECT role FROM tenant_users WHERE tenant_id = $1 AND user_id::text = $2`,
+			tenantID, *req.UserID).Scan(&role)
+		if err != nil || role == "RESIDENT" || role == "VIEWER" {
+			return errors.New("user_id must be a staff member of this organization")
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ListInstructors(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	today := h.today(ctx, tenantID)
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+instructorColumns+` FROM instructors
+		 WHERE tenant_id = $1 AND ($2 = '' OR active = ($2 = 'true'))
+		 ORDER BY lower(name)`,
+		tenantID, c.Query("active"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	var instructors []*instructorRow
+	for rows.Next() {
+		i, err := scanInstructor(rows)
+		if err != nil {
+			continue
+		}
+		instructors = append(instructors, i)
+	}
+	rows.Close()
+
+	list := []gin.H{}
+	for _, i := range instructors {
+		certs, err := h.loadCertifications(ctx, h.DB, i.ID, today)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		list = append(list, gin.H{"instructor": i, "certifications": certs})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instructors": list})
+}
+
+// GetInstructor returns a profile with certifications, program assignments
+// and upcoming sessions
+func (h *Handler) GetInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	instructor, err := h.loadInstructor(ctx, h.DB, tenantID, c.Param("id"))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instructor not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	h.respondInstructorDetail(c, ctx, tenantID, instructor)
+}
+
+func (h *Handler) respondInstructorDetail(c *gin.Context, ctx context.Context, tenantID string, instructor *instructorRow) {
+	certs, err := h.loadCertifications(ctx, h.DB, instructor.ID, h.today(ctx, tenantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT p.id, p.title, pi.role
+		 FROM program_instructors pi
+		 JOIN programs p ON pi.program_id = p.id
+		 WHERE pi.instructor_id = $1
+		 ORDER BY p.title`,
+		instructor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	programs := []gin.H{}
+	for rows.Next() {
+		var id, title, role string
+		if err := rows.Scan(&id, &title, &role); err == nil {
+			programs = append(programs, gin.H{"program_id": id, "title": title, "role": role})
+		}
+	}
+	rows.Close()
+
+	rows, err = h.DB.Query(ctx,
+		`WITH taught AS (`+taughtSessionsSQL+`)
+		 SELECT t.id, t.program_id, p.title, t.starts_at, t.ends_at
+		 FROM taught t
+		 JOIN programs p ON t.program_id = p.id
+		 ORDER BY t.starts_at
+		 LIMIT 100`,
+		tenantID, instructor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	sessions := []sessionRef{}
+	for rows.Next() {
+		var s sessionRef
+		if err := rows.Scan(&s.SessionID, &s.ProgramID, &s.ProgramTitle, &s.StartsAt, &s.EndsAt); err == nil {
+			sessions = append(sessions, s)
+		}
+	}
+	rows.Close()
+
+	overlaps, err := findOverlaps(ctx, h.DB, tenantID, instructor.ID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instructor":        instructor,
+		"certifications":    certs,
+		"programs":          programs,
+		"upcoming_sessions": sessions,
+		"overlaps":          overlaps,
+	})
+}
+
+func (h *Handler) CreateInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req InstructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	if err := h.validateInstructorRequest(ctx, tenantID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	instructor, err := scanInstructor(h.DB.QueryRow(ctx,
+		`INSERT INTO instructors (tenant_id, user_id, name, email, phone, kind, bio, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING `+instructorColumns,
+		tenantID, req.UserID, req.Name, req.Email, req.Phone, req.Kind, req.Bio, active))
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "this user already has an instructor profile"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create instructor"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"instructor": instructor, "certifications": []Certification{}})
+}
+
+func (h *Handler) UpdateInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req InstructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	if err := h.validateInstructorRequest(ctx, tenantID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	instructor, err := scanInstructor(h.DB.QueryRow(ctx,
+		`UPDATE instructors SET user_id = $1, name = $2, email = $3, phone = $4, kind = $5, bio = $6,
+		        active = COALESCE($7, active), updated_at = now()
+		 WHERE id = $8 AND tenant_id = $9
+		 RETURNING `+instructorColumns,
+		req.UserID, req.Name, req.Email, req.Phone, req.Kind, req.Bio, req.Active, c.Param("id"), tenantID))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instructor not found"})
+		return
+	}
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "this user already has an instructor profile"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update instructor"})
+		return
+	}
+
+	h.respondInstructorDetail(c, ctx, tenantID, instructor)
+}
+
+func (h *Handler) DeleteInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`DELETE FROM instructors WHERE id = $1 AND tenant_id = $2`,
+		c.Param("id"), claims.TenantID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instructor not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ============ Certifications ============
+
+func certificationFromRequest(req *CertificationRequest) (issued, expires *time.Time, err error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, nil, errors.New("name is required")
+	}
+	if issued, err = parseOptionalDate("issued_on", req.IssuedOn); err != nil {
+		return nil, nil, err
+	}
+	if expires, err = parseOptionalDate("expires_on", req.ExpiresOn); err != nil {
+		return nil, nil, err
+	}
+	if issued != nil && expires != nil && expires.Before(*issued) {
+		return nil, nil, errors.New("expires_on must not be before issued_on")
+	}
+	return issued, expires, nil
+}
+
+func (h *Handler) AddInstructorCertification(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CertificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	issued, expires, err := certificationFromRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	instructor, err := h.loadInstructor(ctx, h.DB, tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instructor not found"})
+		return
+	}
+
+	_, err = h.DB.Exec(ctx,
+		`INSERT INTO instructor_certifications (id, tenant_id, instructor_id, name, issuer, credential_number, issued_on, expires_on)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		uuid.New(), tenantID, instructor.ID, req.Name, req.Issuer, req.CredentialNumber, issued, expires)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add certification"})
+		return
+	}
+
+	certs, err := h.loadCertifications(ctx, h.DB, instructor.ID, h.today(ctx, tenantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"certifications": certs})
+}
+
+func (h *Handler) UpdateInstructorCertification(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CertificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	issued, expires, err := certificationFromRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	instructorID := c.Param("id")
+
+	result, err := h.DB.Exec(ctx,
+		`UPDATE instructor_certifications
+		 SET name = $1, issuer = $2, credential_number = $3, issued_on = $4, expires_on = $5
+		 WHERE id = $6 AND instructor_id = $7 AND tenant_id = $8`,
+		req.Name, req.Issuer, req.CredentialNumber, issued, expires, c.Param("cert_id"), instructorID, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update certification"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "certification not found"})
+		return
+	}
+
+	certs, err := h.loadCertifications(ctx, h.DB, instructorID, h.today(ctx, tenantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"certifications": certs})
+}
+
+func (h *Handler) DeleteInstructorCertification(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`DELETE FROM instructor_certifications WHERE id = $1 AND instructor_id = $2 AND tenant_id = $3`,
+		c.Param("cert_id"), c.Param("id"), claims.TenantID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "certification not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ============ Instructor Assignments ============
+
+// assignmentCheck validates an instructor can take on sessions: the profile
+// must be active, required certifications valid on certOn and, unless
+// allowOverlap, no clash with sessions they already teach. It writes the
+// error response and returns false when the assignment is blocked.
+func (h *Handler) assignmentCheck(c *gin.Context, ctx context.Context, q dbtx, tenantID string, instructor *instructorRow,
+	required []string, certOn string, sessionIDs []string, allowOverlap bool) ([]Certification, []InstructorOverlap, bool) {
+	if !instructor.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "instructor is inactive"})
+		return nil, nil, false
+	}
+
+	certs, err := h.loadCertifications(ctx, q, instructor.ID, certOn)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, nil, false
+	}
+	if issues := checkCertifications(certs, required, certOn); len(issues) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "instructor does not hold a valid required certification",
+			"certifications": issues,
+		})
+		return nil, nil, false
+	}
+
+	overlaps := []InstructorOverlap{}
+	if len(sessionIDs) > 0 {
+		overlaps, err = findOverlaps(ctx, q, tenantID, instructor.ID, sessionIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return nil, nil, false
+		}
+	}
+	if len(overlaps) > 0 && !allowOverlap {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "instructor is already teaching at an overlapping time",
+			"overlaps": overlaps,
+		})
+		return nil, nil, false
+	}
+
+	return certs, overlaps, true
+}
+
+func (h *Handler) programRequiredCertifications(ctx context.Context, q dbtx, programID string) ([]string, error) {
+	var required []string
+	err := q.QueryRow(ctx, `SELECT required_certifications FROM programs WHERE id = $1`, programID).Scan(&required)
+	return required, err
+}
+
+// ListProgramInstructors returns the program's required certifications, its
+// instructors and single-session assignments
+func (h *Handler) ListProgramInstructors(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	required, err := h.programRequiredCertifications(ctx, h.DB, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT i.id, i.name, i.active, pi.role
+		 FROM program_instructors pi
+		 JOIN instructors i ON pi.instructor_id = i.id
+		 WHERE pi.program_id = $1
+		 ORDER BY pi.role DESC, lower(i.name)`,
+		programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	type assigned struct {
+		id, name, role string
+		active         bool
+	}
+	var list []assigned
+	for rows.Next() {
+		var a assigned
+		if err := rows.Scan(&a.id, &a.name, &a.active, &a.role); err == nil {
+			list = append(list, a)
+		}
+	}
+	rows.Close()
+
+	today := h.today(ctx, tenantID)
+	instructors := []gin.H{}
+	for _, a := range list {
+		certs, err := h.loadCertifications(ctx, h.DB, a.id, today)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		instructors = append(instructors, gin.H{
+			"instructor_id":        a.id,
+			"name":                 a.name,
+			"active":               a.active,
+			"role":                 a.role,
+			"certification_issues": checkCertifications(certs, required, today),
+		})
+	}
+
+	rows, err = h.DB.Query(ctx,
+		`SELECT s.id, s.starts_at, s.ends_at, i.id, i.name
+		 FROM session_instructors si
+		 JOIN program_sessions s ON si.session_id = s.id
+		 JOIN instructors i ON si.instructor_id = i.id
+		 WHERE s.program_id = $1 AND s.ends_at > now()
+		 ORDER BY s.starts_at, lower(i.name)`,
+		programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []gin.H{}
+	for rows.Next() {
+		var sessionID, instructorID, name string
+		var startsAt, endsAt time.Time
+		if err := rows.Scan(&sessionID, &startsAt, &endsAt, &instructorID, &name); err != nil {
+			continue
+		}
+		sessions = append(sessions, gin.H{
+			"session_id":    sessionID,
+			"starts_at":     startsAt,
+			"ends_at":       endsAt,
+			"instructor_id": instructorID,
+			"name":          name,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"required_certifications": required,
+		"instructors":             instructors,
+		"session_assignments":     sessions,
+	})
+}
+
+func (h *Handler) UpdateProgramRequiredCertifications(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req RequiredCertificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	required := []string{}
+	seen := map[string]bool{}
+	for _, name := range req.Certifications {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || seen[normalizeCertName(name)] {
+			continue
+		}
+		seen[normalizeCertName(name)] = true
+		required = append(required, name)
+	}
+	sort.Strings(required)
+
+	_, err := h.DB.Exec(ctx,
+		`UPDATE programs SET required_certifications = $1, updated_at = now() WHERE id = $2`,
+		required, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update program"})
+		return
+	}
+
+	h.ListProgramInstructors(c)
+}
+
+// AssignProgramInstructor assigns an instructor to every session of a program
+func (h *Handler) AssignProgramInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req AssignInstructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = "lead"
+	}
+	if req.Role != "lead" && req.Role != "assistant" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be lead or assistant"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	instructor, err := h.loadInstructor(ctx, tx, tenantID, req.InstructorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instructor not found"})
+		return
+	}
+	// Serialize assignments for the same instructor so overlap checks see each other
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM instructors WHERE id = $1 FOR UPDATE`, instructor.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	required, err := h.programRequiredCertifications(ctx, tx, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT id::text, starts_at FROM program_sessions
+		 WHERE program_id = $1 AND status = 'scheduled' AND ends_at > now()
+		 ORDER BY starts_at`,
+		programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	sessionIDs := []string{}
+	var lastSession time.Time
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id, &lastSession); err == nil {
+			sessionIDs = append(sessionIDs, id)
+		}
+	}
+	rows.Close()
+
+	today := h.today(ctx, tenantID)
+	certs, overlaps, ok := h.assignmentCheck(c, ctx, tx, tenantID, instructor, required, today, sessionIDs, req.AllowOverlap)
+	if !ok {
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO program_instructors (tenant_id, program_id, instructor_id, role)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (program_id, instructor_id) DO UPDATE SET role = EXCLUDED.role`,
+		tenantID, programID, instructor.ID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign instructor"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	// Certifications that lapse before the last session are allowed but flagged
+	warnings := []CertificationIssue{}
+	if len(sessionIDs) > 0 {
+		loc := h.tenantLocation(ctx, h.DB, tenantID)
+		warnings = checkCertifications(certs, required, isoDate(lastSession.In(loc)))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"program_id":             programID,
+		"instructor_id":          instructor.ID,
+		"role":                   req.Role,
+		"sessions":               len(sessionIDs),
+		"overlaps":               overlaps,
+		"certifications_lapsing": warnings,
+	})
+}
+
+func (h *Handler) RemoveProgramInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`DELETE FROM program_instructors WHERE program_id = $1 AND instructor_id = $2 AND tenant_id = $3`,
+		c.Param("id"), c.Param("instructor_id"), claims.TenantID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "assignment not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// AssignSessionInstructor adds an instructor to one session, e.g. a substitute
+func (h *Handler) AssignSessionInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req AssignInstructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	session, err := h.loadSession(ctx, tx, tenantID, c.Param("id"))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if session.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "session is cancelled"})
+		return
+	}
+
+	instructor, err := h.loadInstructor(ctx, tx, tenantID, req.InstructorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instructor not found"})
+		return
+	}
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM instructors WHERE id = $1 FOR UPDATE`, instructor.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	required, err := h.programRequiredCertifications(ctx, tx, session.ProgramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	loc := h.tenantLocation(ctx, tx, tenantID)
+	certOn := isoDate(session.StartsAt.In(loc))
+	if today := isoDate(time.Now().In(loc)); today > certOn {
+		certOn = today
+	}
+	_, overlaps, ok := h.assignmentCheck(c, ctx, tx, tenantID, instructor, required, certOn, []string{session.ID}, req.AllowOverlap)
+	if !ok {
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO session_instructors (tenant_id, session_id, instructor_id)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (session_id, instructor_id) DO NOTHING`,
+		tenantID, session.ID, instructor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign instructor"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":    session.ID,
+		"instructor_id": instructor.ID,
+		"overlaps":      overlaps,
+	})
+}
+
+func (h *Handler) RemoveSessionInstructor(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`DELETE FROM session_instructors WHERE session_id = $1 AND instructor_id = $2 AND tenant_id = $3`,
+		c.Param("id"), c.Param("instructor_id"), claims.TenantID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "assignment not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// GetInstructorConflicts reports every overlapping pair of upcoming sessions
+// per instructor, e.g. after a schedule change generated new sessions
+func (h *Handler) GetInstructorConflicts(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT id::text FROM instructors
+		 WHERE tenant_id = $1 AND active AND ($2 = '' OR id::text = $2)
+		 ORDER BY lower(name)`,
+		tenantID, c.Query("instructor_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	overlaps := []InstructorOverlap{}
+	for _, id := range ids {
+		found, err := findOverlaps(ctx, h.DB, tenantID, id, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		overlaps = append(overlaps, found...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"overlaps": overlaps})
+}
+
+// GetMyTeaching is the instructor's own view: their profile, programs and
+// upcoming sessions. Rosters for these programs are available through the
+// roster and attendance endpoints.
+func (h *Handler) GetMyTeaching(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	instructor, err := scanInstructor(h.DB.QueryRow(ctx,
+		`SELECT `+instructorColumns+` FROM instructors WHERE tenant_id = $1 AND user_id = $2`,
+		tenantID, claims.UserID))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no instructor profile is linked to this account"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	h.respondInstructorDetail(c, ctx, tenantID, instructor)
+}
@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/ledger"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Household Ledger ============
+
+type LedgerPostRequest struct {
+	Kind        string `json:"kind" binding:"required"`
+	AmountCents int    `json:"amount_cents" binding:"required"`
+	Description string `json:"description" binding:"required"`
+}
+
+type RefundRequest struct {
+	AmountCents *int   `json:"amount_cents"`
+	Method      string `json:"method" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+// ledgerRef links a ledger transaction to what caused it
+type ledgerRef struct {
+	SubjectType *string
+	SubjectID   *string
+	PaymentID   *string
+	RefundID    *string
+	CreatedBy   *string
+}
+
+// postLedger writes a balanced transaction to a household account
+func postLedger(ctx context.Context, q dbtx, tenantID, userID string, txn ledger.Transaction, ref ledgerRef) error {
+	if err := txn.Validate(); err != nil {
+		return err
+	}
+
+	txnID := uuid.New()
+	_, err := q.Exec(ctx,
+		`INSERT INTO ledger_transactions (id, tenant_id, user_id, kind, description, subject_type, subject_id, payment_id, refund_id, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		txnID, tenantID, userID, txn.Kind, txn.Description,
+		ref.SubjectType, ref.SubjectID, ref.PaymentID, ref.RefundID, ref.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range txn.Entries {
+		if e.AmountCents == 0 {
+			continue
+		}
+		_, err = q.Exec(ctx,
+			`INSERT INTO ledger_entries (transaction_id, account, amount_cents) VALUES ($1, $2, $3)`,
+			txnID, e.Account, e.AmountCents)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountBalance sums a household's ledger by account
+func accountBalance(ctx context.Context, q dbtx, tenantID, userID string) (ledger.Balance, error) {
+	rows, err := q.Query(ctx,
+		`SELECT le.account, SUM(le.amount_cents)
+		 FROM ledger_entries le
+		 JOIN ledger_transactions lt ON le.transaction_id = lt.id
+		 WHERE lt.tenant_id = $1 AND lt.user_id = $2
+		 GROUP BY le.account`,
+		tenantID, userID)
+	if err != nil {
+		return ledger.Balance{}, err
+	}
+	defer rows.Close()
+
+	totals := map[string]int{}
+	for rows.Next() {
+		var account string
+		var sum int
+		if err := rows.Scan(&account, &sum); err != nil {
+			return ledger.Balance{}, err
+		}
+		totals[account] = sum
+	}
+	return ledger.BalanceFromTotals(totals), rows.Err()
+}
+
+// availableCredit is the household's credit less what pending checkouts
+// have already reserved
+func availableCredit(ctx context.Context, q dbtx, tenantID, userID string) (int, error) {
+	balance, err := accountBalance(ctx, q, tenantID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var reserved int
+	err = q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(credit_applied_cents), 0) FROM payments
+		 WHERE tenant_id = $1 AND user_id = $2 AND status = 'pending'`,
+		tenantID, userID).Scan(&reserved)
+	if err != nil {
+		return 0, err
+	}
+
+	if available := balance.CreditCents - reserved; available > 0 {
+		return available, nil
+	}
+	return 0, nil
+}
+
+// ensureCharge bills the household for a subject unless it already has been
+func ensureCharge(ctx context.Context, q dbtx, s *checkoutSubject) error {
+	if s.UserID == nil {
+		return nil
+	}
+
+	var exists bool
+	err := q.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM ledger_transactions WHERE subject_type = $1 AND subject_id = $2 AND kind = 'charge')`,
+		s.Type, s.ID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	return postLedger(ctx, q, s.TenantID, *s.UserID, ledger.Charge(s.Description, s.AmountCents),
+		ledgerRef{SubjectType: &s.Type, SubjectID: &s.ID})
+}
+
+// postPaymentLedger records a completed payment: the credit it spent and the
+// money received
+func postPaymentLedger(ctx context.Context, q dbtx, tenantID string, userID *string, paymentID, subjectType, subjectID string, amountCents, creditCents int) error {
+	if userID == nil {
+		return nil
+	}
+
+	ref := ledgerRef{SubjectType: &subjectType, SubjectID: &subjectID, PaymentID: &paymentID}
+	if creditCents > 0 {
+		if err := postLedger(ctx, q, tenantID, *userID, ledger.CreditApplied("Account credit applied", creditCents), ref); err != nil {
+			return err
+		}
+	}
+	if amountCents > 0 {
+		if err := postLedger(ctx, q, tenantID, *userID, ledger.Payment("Online payment", amountCents), ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLedger returns a household's balance and recent transactions
+func (h *Handler) loadLedger(ctx context.Context, tenantID, userID string) (gin.H, error) {
+	balance, err := accountBalance(ctx, h.DB, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT lt.id, lt.kind, lt.description, lt.subject_type, lt.subject_id, lt.payment_id, lt.refund_id, lt.created_at,
+		        COALESCE(json_agg(json_build_object('account', le.account, 'amount_cents', le.amount_cents)) FILTER (WHERE le.id IS NOT NULL), '[]')
+		 FROM ledger_transactions lt
+		 LEFT JOIN ledger_entries le ON le.transaction_id = lt.id
+		 WHERE lt.tenant_id = $1 AND lt.user_id = $2
+		 GROUP BY lt.id
+		 ORDER BY lt.created_at DESC
+		 LIMIT 200`,
+		tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []gin.H{}
+	for rows.Next() {
+		var (
+			id                             uuid.UUID
+			kind, description              string
+			subjectType                    *string
+			subjectID, paymentID, refundID *uuid.UUID
+			createdAt                      time.Time
+			entriesJSON                    []byte
+		)
+		if err := rows.Scan(&id, &kind, &description, &subjectType, &subjectID, &paymentID, &refundID, &createdAt, &entriesJSON); err != nil {
+			return nil, err
+		}
+		var entries []ledger.Entry
+		_ = json.Unmarshal(entriesJSON, &entries)
+
+		transactions = append(transactions, gin.H{
+			"id":           id.String(),
+			"kind":         kind,
+			"description":  description,
+			"subject_type": subjectType,
+			"subject_id":   subjectID,
+			"payment_id":   paymentID,
+			"refund_id":    refundID,
+			"entries":      entries,
+			"created_at":   createdAt,
+		})
+	}
+
+	return gin.H{"balance": balance, "transactions": transactions}, rows.Err()
+}
+
+// GetMyLedger returns the signed-in resident's balance and history
+func (h *Handler) GetMyLedger(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.loadLedger(context.Background(), claims.TenantID.String(), claims.UserID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetResidentLedger returns a household's ledger for admins
+func (h *Handler) GetResidentLedger(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	userID := c.Param("user_id")
+	if !h.isTenantUser(ctx, claims.TenantID.String(), userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resident not found"})
+		return
+	}
+
+	result, err := h.loadLedger(ctx, claims.TenantID.String(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// PostResidentLedger grants account credit or adjusts what a household owes
+func (h *Handler) PostResidentLedger(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req LedgerPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var txn ledger.Transaction
+	switch req.Kind {
+	case ledger.KindCredit:
+		if req.AmountCents <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "credit amount must be positive"})
+			return
+		}
+		txn = ledger.GrantCredit(req.Description, req.AmountCents)
+	case ledger.KindAdjustment:
+		txn = ledger.Adjustment(req.Description, req.AmountCents)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be credit or adjustment"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	userID := c.Param("user_id")
+	if !h.isTenantUser(ctx, tenantID, userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resident not found"})
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	actorID := claims.UserID.String()
+	if err := postLedger(ctx, tx, tenantID, userID, txn, ledgerRef{CreatedBy: &actorID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to post transaction"})
+		return
+	}
+
+	residentID, _ := uuid.Parse(userID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "ledger_"+req.Kind, "user", &residentID,
+		nil, gin.H{"amount_cents": req.AmountCents, "description": req.Description})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	balance, _ := accountBalance(ctx, h.DB, tenantID, userID)
+	c.JSON(http.StatusCreated, gin.H{"balance": balance})
+}
+
+// isTenantUser reports whether the user belongs to the tenant
+func (h *Handler) isTenantUser(ctx context.Context, tenantID, userID string) bool {
+	if _, err := uuid.Parse(userID); err != nil {
+		return false
+	}
+	var exists bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM tenant_users WHERE tenant_id = $1 AND user_id = $2)`,
+		tenantID, userID).Scan(&exists)
+	return err == nil && exists
+}
+
+// refundablePayment is a payment row locked for refunding
+type refundablePayment struct {
+	ID, TenantID, Status, Provider, SubjectType, SubjectID string
+	UserID, PaymentRef                                     *string
+	AmountCents, CreditCents, RefundedCents                int
+	// RefundedToOriginal is what has already gone back to the payment method
+	RefundedToOriginal int
+}
+
+// loadRefundablePayment locks a payment and totals its earlier refunds
+func loadRefundablePayment(ctx context.Context, q dbtx, paymentID string) (*refundablePayment, error) {
+	p := &refundablePayment{ID: paymentID}
+	err := q.QueryRow(ctx,
+		`SELECT tenant_id::text, status, provider, subject_type, subject_id::text, user_id::text, provider_payment_ref,
+		        amount_cents, credit_applied_cents, refunded_cents
+		 FROM payments WHERE id = $1 FOR UPDATE`,
+		paymentID).Scan(&p.TenantID, &p.Status, &p.Provider, &p.SubjectType, &p.SubjectID, &p.UserID, &p.PaymentRef,
+		&p.AmountCents, &p.CreditCents, &p.RefundedCents)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_cents), 0) FROM refunds
+		 WHERE payment_id = $1 AND method = 'original_payment' AND status <> 'failed'`,
+		paymentID).Scan(&p.RefundedToOriginal)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Refundable is the most that can still be refunded by the given method
+func (p *refundablePayment) Refundable(method string) int {
+	refundable := p.AmountCents + p.CreditCents - p.RefundedCents
+	if method == "original_payment" {
+		// The part paid with account credit can only go back as credit
+		if remaining := p.AmountCents - p.RefundedToOriginal; remaining < refundable {
+			refundable = remaining
+		}
+	}
+	if refundable < 0 {
+		return 0
+	}
+	return refundable
+}
+
+// canRefundToOriginal reports whether the configured provider can send
+// money back for this payment
+func (h *Handler) canRefundToOriginal(p *refundablePayment) bool {
+	return h.Payments != nil && h.Payments.Name() == p.Provider && p.PaymentRef != nil
+}
+
+// recordRefund records a refund of amount against a locked payment and
+// reserves it in refunded_cents. Account credit refunds are complete and
+// posted to the ledger straight away. Refunds to the original payment are
+// recorded as pending: the caller commits first and then calls
+// settleRefund, so money never leaves before the refund is on record.
+// Callers validate the amount against Refundable first.
+func (h *Handler) recordRefund(ctx context.Context, q dbtx, p *refundablePayment, amount int, method, reason, actorID string) (*refundRecord, error) {
+	r := &refundRecord{
+		ID:            uuid.NewString(),
+		TenantID:      p.TenantID,
+		PaymentID:     p.ID,
+		Method:        method,
+		Status:        "succeeded",
+		Reason:        nullIfEmpty(reason),
+		CreatedBy:     nullIfEmpty(actorID),
+		AmountCents:   amount,
+		UserID:        p.UserID,
+		PaymentRef:    p.PaymentRef,
+		SubjectType:   p.SubjectType,
+		SubjectID:     p.SubjectID,
+		PaymentStatus: p.Status,
+	}
+	switch method {
+	case "original_payment":
+		if !h.canRefundToOriginal(p) {
+			return nil, &checkoutError{http.StatusBadRequest, "payment cannot be refunded to its original method; refund as account credit"}
+		}
+		r.Status = "pending"
+	case "account_credit":
+		if p.UserID == nil {
+			return nil, &checkoutError{http.StatusBadRequest, "payment is not linked to a resident account"}
+		}
+	}
+
+	_, err := q.Exec(ctx,
+		`INSERT INTO refunds (id, tenant_id, payment_id, amount_cents, method, reason, status, settled_at, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $7 = 'succeeded' THEN now() END, $8)`,
+		r.ID, r.TenantID, r.PaymentID, amount, method, r.Reason, r.Status, r.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = q.Exec(ctx,
+		`UPDATE payments SET refunded_cents = refunded_cents + $1, updated_at = now() WHERE id = $2`,
+		amount, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.RefundedCents += amount
+	if method == "original_payment" {
+		p.RefundedToOriginal += amount
+	}
+
+	if r.Status == "succeeded" {
+		if err := postRefundLedger(ctx, q, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// postRefundLedger posts a completed refund to the household's account.
+// Payments that never reached the ledger are refunded outside it.
+func postRefundLedger(ctx context.Context, q dbtx, r *refundRecord) error {
+	if r.UserID == nil || r.PaymentStatus != "paid" {
+		return nil
+	}
+	description := "Refund to original payment"
+	txn := ledger.RefundToPayment(description, r.AmountCents)
+	if r.Method == "account_credit" {
+		description = "Refund as account credit"
+		txn = ledger.RefundToCredit(description, r.AmountCents)
+	}
+	if r.Reason != nil {
+		txn.Description = description + ": " + *r.Reason
+	}
+	return postLedger(ctx, q, r.TenantID, *r.UserID, txn, ledgerRef{
+		SubjectType: &r.SubjectType,
+		SubjectID:   &r.SubjectID,
+		PaymentID:   &r.PaymentID,
+		RefundID:    &r.ID,
+		CreatedBy:   r.CreatedBy,
+	})
+}
+
+// RefundPayment returns part or all of a payment, either to the original
+// payment method or as account credit
+func (h *Handler) RefundPayment(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Method != "original_payment" && req.Method != "account_credit" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method must be original_payment or account_credit"})
+		return
+	}
+
+	ctx := context.Background()
+	paymentID := c.Param("id")
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	payment, err := loadRefundablePayment(ctx, tx, paymentID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if payment.TenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	// Late and mismatched payments never reached the ledger, so they can
+	// only go back where they came from
+	exception := payment.Status == "refund_due" || payment.Status == "review"
+	if payment.Status != "paid" && !(exception && req.Method == "original_payment") {
+		c.JSON(http.StatusConflict, gin.H{"error": "only paid payments can be refunded"})
+		return
+	}
+
+	refundable := payment.Refundable(req.Method)
+	amount := refundable
+	if req.AmountCents != nil {
+		amount = *req.AmountCents
+	}
+	if amount <= 0 || amount > refundable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("refund amount must be between 1 and %d cents", refundable)})
+		return
+	}
+
+	refund, err := h.recordRefund(ctx, tx, payment, amount, req.Method, req.Reason, claims.UserID.String())
+	var ce *checkoutError
+	if errors.As(err, &ce) {
+		c.JSON(ce.status, gin.H{"error": ce.message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record refund"})
+		return
+	}
+
+	refundID := uuid.MustParse(refund.ID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "refund", "refund", &refundID,
+		nil, gin.H{"payment_id": paymentID, "amount_cents": amount, "method": req.Method, "reason": req.Reason})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	// The refund is on record; only now is the provider asked to send it
+	var settleErr error
+	if refund.Status == "pending" {
+		refund, settleErr = h.settleRefund(ctx, refund)
+	}
+	response := refund.response()
+	if settleErr != nil {
+		// Still on record as pending or failed; a pending refund is retried
+		response["error"] = "payment provider did not complete the refund: " + settleErr.Error()
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
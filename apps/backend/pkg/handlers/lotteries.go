@@ -0,0 +1,630 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/lottery"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Lotteries ============
+
+// Lottery phases
+const (
+	LotteryUpcoming = "upcoming"
+	LotteryOpen     = "open"
+	LotteryClosed   = "closed"
+	LotteryDrawn    = "drawn"
+)
+
+type LotteryRequest struct {
+	OpensAt        time.Time `json:"opens_at" binding:"required"`
+	ClosesAt       time.Time `json:"closes_at" binding:"required"`
+	OfferHours     *int      `json:"offer_hours"`
+	ResidentWeight *float64  `json:"resident_weight"`
+	SiblingWeight  *float64  `json:"sibling_weight"`
+}
+
+type programLottery struct {
+	ProgramID      string          `json:"program_id"`
+	OpensAt        time.Time       `json:"opens_at"`
+	ClosesAt       time.Time       `json:"closes_at"`
+	OfferHours     int             `json:"offer_hours"`
+	ResidentWeight float64         `json:"resident_weight"`
+	SiblingWeight  float64         `json:"sibling_weight"`
+	Seed           *string         `json:"seed"`
+	EntriesDigest  *string         `json:"entries_digest"`
+	Algorithm      *string         `json:"algorithm"`
+	Results        json.RawMessage `json:"results"`
+	DrawnAt        *time.Time      `json:"drawn_at"`
+	DrawnBy        *string         `json:"drawn_by"`
+}
+
+// Phase reports where the lottery is at the given time
+func (l *programLottery) Phase(now time.Time) string {
+	switch {
+	case l.DrawnAt != nil:
+		return LotteryDrawn
+	case now.Before(l.OpensAt):
+		return LotteryUpcoming
+	case now.Before(l.ClosesAt):
+		return LotteryOpen
+	default:
+		return LotteryClosed
+	}
+}
+
+// drawOutcome is a lottery result and what it led to
+type drawOutcome struct {
+	lottery.Result
+	Outcome string `json:"outcome"`
+}
+
+// loadLottery returns the program's lottery, or nil when the program uses
+// first-come-first-served registration. lock takes the row for update.
+func loadLottery(ctx context.Context, q dbtx, programID string, lock bool) (*programLottery, error) {
+	query := `SELECT program_id::text, opens_at, closes_at, offer_hours, resident_weight, sibling_weight,
+	                 seed, entries_digest, algorithm, results, drawn_at, drawn_by::text
+	          FROM program_lotteries WHERE program_id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var l programLottery
+	err := q.QueryRow(ctx, query, programID).Scan(&l.ProgramID, &l.OpensAt, &l.ClosesAt, &l.OfferHours,
+		&l.ResidentWeight, &l.SiblingWeight, &l.Seed, &l.EntriesDigest, &l.Algorithm, &l.Results, &l.DrawnAt, &l.DrawnBy)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func lotteryResponse(l *programLottery, entries int, now time.Time) gin.H {
+	return gin.H{
+		"program_id":      l.ProgramID,
+		"phase":           l.Phase(now),
+		"opens_at":        l.OpensAt,
+		"closes_at":       l.ClosesAt,
+		"offer_hours":     l.OfferHours,
+		"resident_weight": l.ResidentWeight,
+		"sibling_weight":  l.SiblingWeight,
+		"entries":         entries,
+		"seed":            l.Seed,
+		"entries_digest":  l.EntriesDigest,
+		"algorithm":       l.Algorithm,
+		"results":         l.Results,
+		"drawn_at":        l.DrawnAt,
+		"drawn_by":        l.DrawnBy,
+	}
+}
+
+// GetProgramLottery returns a program's lottery settings and, once drawn,
+// the seed and full results needed to reproduce the draw
+func (h *Handler) GetProgramLottery(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	ctx := context.Background()
+	if !h.programTenant(ctx, c, programID, claims.TenantID.String()) {
+		return
+	}
+
+	l, err := loadLottery(ctx, h.DB, programID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if l == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program does not use a lottery"})
+		return
+	}
+
+	var entries int
+	err = h.DB.QueryRow(ctx,
+		`SELECT count(*) FROM program_registrations
+		 WHERE program_id = $1 AND (status = 'entered' OR lottery_rank IS NOT NULL)`,
+		programID).Scan(&entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lotteryResponse(l, entries, time.Now()))
+}
+
+// UpdateProgramLottery turns on lottery mode or changes its settings until
+// the draw has run
+func (h *Handler) UpdateProgramLottery(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req LotteryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.ClosesAt.After(req.OpensAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "closes_at must be after opens_at"})
+		return
+	}
+	offerHours := 48
+	if req.OfferHours != nil {
+		offerHours = *req.OfferHours
+	}
+	if offerHours <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offer_hours must be positive"})
+		return
+	}
+	residentWeight, siblingWeight := 1.0, 1.0
+	if req.ResidentWeight != nil {
+		residentWeight = *req.ResidentWeight
+	}
+	if req.SiblingWeight != nil {
+		siblingWeight = *req.SiblingWeight
+	}
+	if residentWeight <= 0 || siblingWeight <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weights must be positive"})
+		return
+	}
+
+	programID := c.Param("id")
+	ctx := context.Background()
+	if !h.programTenant(ctx, c, programID, claims.TenantID.String()) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	existing, err := loadLottery(ctx, tx, programID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if existing != nil && existing.DrawnAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "the lottery has already been drawn"})
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO program_lotteries (program_id, tenant_id, opens_at, closes_at, offer_hours, resident_weight, sibling_weight)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (program_id) DO UPDATE SET
+			opens_at = EXCLUDED.opens_at, closes_at = EXCLUDED.closes_at, offer_hours = EXCLUDED.offer_hours,
+			resident_weight = EXCLUDED.resident_weight, sibling_weight = EXCLUDED.sibling_weight, updated_at = now()`,
+		programID, claims.TenantID, req.OpensAt, req.ClosesAt, offerHours, residentWeight, siblingWeight)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save lottery"})
+		return
+	}
+
+	id := uuid.MustParse(programID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "lottery_updated", "program", &id, existing, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// DeleteProgramLottery returns a program to first-come-first-served. It is
+// refused once entries have been taken.
+func (h *Handler) DeleteProgramLottery(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	ctx := context.Background()
+	if !h.programTenant(ctx, c, programID, claims.TenantID.String()) {
+		return
+	}
+
+	var entries int
+	err := h.DB.QueryRow(ctx,
+		`SELECT count(*) FROM program_registrations
+		 WHERE program_id = $1 AND (status = 'entered' OR lottery_rank IS NOT NULL)`,
+		programID).Scan(&entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if entries > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "the lottery already has entries"})
+		return
+	}
+
+	if _, err := h.DB.Exec(ctx, `DELETE FROM program_lotteries WHERE program_id = $1`, programID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// DrawProgramLottery ranks the entries once the entry window has closed.
+// Entries within the program's remaining capacity are offered a place; the
+// rest are waitlisted in rank order. The seed is derived from the server's
+// lottery secret and the closed entry set, so no one can know it while
+// entries can still be added or cancelled. The seed, entry digest, weights
+// and every entry's draw value are stored so the draw can be reproduced.
+func (h *Handler) DrawProgramLottery(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := loadLottery(ctx, tx, programID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if l == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program does not use a lottery"})
+		return
+	}
+	now := time.Now()
+	switch l.Phase(now) {
+	case LotteryDrawn:
+		c.JSON(http.StatusConflict, gin.H{"error": "the lottery has already been drawn"})
+		return
+	case LotteryUpcoming, LotteryOpen:
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("entries are open until %s", l.ClosesAt.Format(time.RFC3339))})
+		return
+	}
+
+	// Entries and the households they come from
+	rows, err := tx.Query(ctx,
+		`SELECT id::text, user_id::text FROM program_registrations
+		 WHERE program_id = $1 AND status = 'entered'
+		 FOR UPDATE`,
+		programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	type entry struct{ id, userID string }
+	var entered []entry
+	perHousehold := map[string]int{}
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.userID); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		entered = append(entered, e)
+		perHousehold[e.userID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	// Households already holding a place count as siblings too
+	seated := map[string]bool{}
+	rows, err = tx.Query(ctx,
+		`SELECT DISTINCT user_id::text FROM program_registrations
+		 WHERE program_id = $1 AND status = ANY($2)`,
+		programID, seatStatuses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err == nil {
+			seated[userID] = true
+		}
+	}
+	rows.Close()
+
+	// Weigh each entry
+	residents := map[string]bool{}
+	entries := make([]lottery.Entry, 0, len(entered))
+	for _, e := range entered {
+		resident, ok := residents[e.userID]
+		if !ok {
+			resident, err = h.isResident(ctx, tx, tenantID, e.userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check residency"})
+				return
+			}
+			residents[e.userID] = resident
+		}
+		weight := 1.0
+		if resident {
+			weight *= l.ResidentWeight
+		}
+		if perHousehold[e.userID] > 1 || seated[e.userID] {
+			weight *= l.SiblingWeight
+		}
+		entries = append(entries, lottery.Entry{ID: e.id, Weight: weight})
+	}
+
+	ids := make([]string, len(entered))
+	for i, e := range entered {
+		ids[i] = e.id
+	}
+	digest := lottery.EntriesDigest(ids)
+	seed := lottery.DeriveSeed(h.Config.LotterySecret, programID, digest)
+	results := lottery.Draw(seed, entries)
+
+	var capacity *int
+	var enrolled int
+	err = tx.QueryRow(ctx,
+		`SELECT capacity, enrolled_count FROM programs WHERE id = $1 FOR UPDATE`,
+		programID).Scan(&capacity, &enrolled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	spots := len(results)
+	if capacity != nil {
+		spots = *capacity - enrolled
+	}
+
+	outcomes := make([]drawOutcome, 0, len(results))
+	for i, r := range results {
+		_, err := tx.Exec(ctx,
+			`UPDATE program_registrations SET lottery_rank = $1, lottery_weight = $2 WHERE id = $3`,
+			r.Rank, r.Weight, r.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record draw"})
+			return
+		}
+
+		outcome := "waitlisted"
+		if i < spots {
+			outcome = "offered"
+		}
+		if _, err := h.transitionRegistration(ctx, tx, claims.TenantID, claims.UserID, r.ID, outcome, "lottery draw"); err != nil {
+			respondTransitionError(c, err, "failed to record draw")
+			return
+		}
+		outcomes = append(outcomes, drawOutcome{Result: r, Outcome: outcome})
+	}
+
+	resultsJSON, _ := json.Marshal(outcomes)
+	_, err = tx.Exec(ctx,
+		`UPDATE program_lotteries
+		 SET seed = $1, entries_digest = $2, algorithm = $3, results = $4, drawn_at = now(), drawn_by = $5, updated_at = now()
+		 WHERE program_id = $6`,
+		seed, digest, lottery.Algorithm, resultsJSON, claims.UserID, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record draw"})
+		return
+	}
+
+	id := uuid.MustParse(programID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "lottery_drawn", "program", &id, nil,
+		gin.H{"seed": seed, "entries_digest": digest, "algorithm": lottery.Algorithm, "spots": spots, "results": outcomes})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"seed":           seed,
+		"entries_digest": digest,
+		"algorithm":      lottery.Algorithm,
+		"entries":        len(outcomes),
+		"offered":        min(max(spots, 0), len(outcomes)),
+		"results":        outcomes,
+	})
+}
+
+// AcceptMyRegistrationOffer confirms a lottery offer before it expires. The
+// registration moves to pending and the household is billed.
+func (h *Handler) AcceptMyRegistrationOffer(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	reg, ok := loadMyRegistration(c, ctx, tx, tenantID, claims.UserID.String(), c.Param("id"))
+	if !ok {
+		return
+	}
+	if reg.Status != "offered" {
+		c.JSON(http.StatusConflict, gin.H{"error": "registration has no open offer"})
+		return
+	}
+
+	var participantName string
+	var expiresAt *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT participant_name, offer_expires_at FROM program_registrations WHERE id = $1`,
+		reg.ID).Scan(&participantName, &expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if expiresAt != nil && !time.Now().Before(*expiresAt) {
+		c.JSON(http.StatusConflict, gin.H{"error": "the offer has expired"})
+		return
+	}
+
+	t, err := h.transitionRegistration(ctx, tx, claims.TenantID, claims.UserID, reg.ID, "pending", "offer accepted")
+	if err != nil {
+		respondTransitionError(c, err, "failed to accept offer")
+		return
+	}
+
+	if reg.PriceCents > 0 {
+		subject := &checkoutSubject{
+			TenantID:    tenantID,
+			Type:        "program_registration",
+			ID:          reg.ID,
+			UserID:      &reg.UserID,
+			AmountCents: reg.PriceCents,
+			Description: fmt.Sprintf("%s: %s", reg.ProgramTitle, participantName),
+		}
+		if err := ensureCharge(ctx, tx, subject); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record charge"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": reg.ID, "status": t.To, "price_cents": reg.PriceCents})
+}
+
+// ExpireLotteryOffers cancels offers past their deadline. Each cancellation
+// frees the place, which rolls down to the next entrant on the waitlist.
+func (h *Handler) ExpireLotteryOffers(ctx context.Context) (int, error) {
+	rows, err := h.DB.Query(ctx,
+		`SELECT id::text, tenant_id FROM program_registrations
+		 WHERE status = 'offered' AND offer_expires_at <= now()
+		 ORDER BY offer_expires_at
+		 LIMIT 100`)
+	if err != nil {
+		return 0, err
+	}
+	type offer struct {
+		id       string
+		tenantID uuid.UUID
+	}
+	var due []offer
+	for rows.Next() {
+		var o offer
+		if err := rows.Scan(&o.id, &o.tenantID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, o)
+	}
+	rows.Close()
+
+	expired := 0
+	for _, o := range due {
+		ok, err := h.expireOffer(ctx, o.tenantID, o.id)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expireOffer cancels one offer if it is still outstanding and overdue
+func (h *Handler) expireOffer(ctx context.Context, tenantID uuid.UUID, registrationID string) (bool, error) {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var due bool
+	err = tx.QueryRow(ctx,
+		`SELECT status = 'offered' AND offer_expires_at <= now() FROM program_registrations
+		 WHERE id = $1 FOR UPDATE`,
+		registrationID).Scan(&due)
+	if err != nil || !due {
+		return false, err
+	}
+
+	if _, err := h.transitionRegistration(ctx, tx, tenantID, uuid.Nil, registrationID, "cancelled", "offer expired"); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// RunOfferExpiry expires lottery offers until ctx is cancelled
+func (h *Handler) RunOfferExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := h.ExpireLotteryOffers(ctx); err != nil {
+			log.Printf("Lottery offer expiry: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
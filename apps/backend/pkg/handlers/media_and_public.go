@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -180,12 +181,22 @@ func (h *Handler) GetPublicPrograms(c *gin.Context) {
 		return
 	}
 
+	// Optional season filter by slug or id, and grouping by season
+	seasonFilter := c.Query("season")
+	groupBySeason := c.Query("group_by") == "season"
+	limit := 12
+	if seasonFilter != "" || groupBySeason {
+		limit = 500
+	}
+
 	// Get programs
 	rows, err := h.DB.Query(ctx,
-		`SELECT id, title, description, price_cents FROM programs
-		 WHERE tenant_id = $1 AND status = 'active'
-		 ORDER BY created_at DESC LIMIT 12`,
-		tenantID)
+		`SELECT p.id, p.title, p.description, p.price_cents, p.season_id::text FROM programs p
+		 LEFT JOIN seasons s ON p.season_id = s.id
+		 WHERE p.tenant_id = $1 AND p.status = 'active'
+		   AND ($2 = '' OR s.slug = $2 OR s.id::text = $2)
+		 ORDER BY p.created_at DESC LIMIT $3`,
+		tenantID, seasonFilter, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
@@ -195,9 +206,9 @@ func (h *Handler) GetPublicPrograms(c *gin.Context) {
 	var programs []gin.H
 	for rows.Next() {
 		var id, title string
-		var description *string
+		var description, seasonID *string
 		var priceCents int
-		if err := rows.Scan(&id, &title, &description, &priceCents); err != nil {
+		if err := rows.Scan(&id, &title, &description, &priceCents, &seasonID); err != nil {
 			continue
 		}
 		programs = append(programs, gin.H{
@@ -205,10 +216,45 @@ func (h *Handler) GetPublicPrograms(c *gin.Context) {
 			"title":        title,
 			"description":  description,
 			"price_cents":  priceCents,
+			"season_id":    seasonID,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"programs": programs})
+	if !groupBySeason {
+		c.JSON(http.StatusOK, gin.H{"programs": programs})
+		return
+	}
+
+	// Group under each season with its registration window
+	seasons, err := h.loadSeasons(ctx, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	now := time.Now()
+
+	bySeason := map[string][]gin.H{}
+	var unassigned []gin.H
+	for _, p := range programs {
+		if sid, _ := p["season_id"].(*string); sid != nil {
+			bySeason[*sid] = append(bySeason[*sid], p)
+		} else {
+			unassigned = append(unassigned, p)
+		}
+	}
+
+	groups := []gin.H{}
+	for _, s := range seasons {
+		if len(bySeason[s.ID]) == 0 {
+			continue
+		}
+		group := seasonResponse(s, loc, now)
+		group["programs"] = bySeason[s.ID]
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": groups, "unassigned": unassigned})
 }
 
 // ============ Public Events ============
@@ -228,36 +274,30 @@ func (h *Handler) GetUpcomingEvents(c *gin.Context) {
 		return
 	}
 
-	// Get upcoming events
-	rows, err := h.DB.Query(ctx,
-		`SELECT id, title, description, starts_at, ends_at, location, capacity FROM events
-		 WHERE tenant_id = $1 AND starts_at > now()
-		 ORDER BY starts_at ASC LIMIT 10`,
-		tenantID)
+	// Recurring events are expanded into their occurrences over the next
+	// days; one-off events are listed however far ahead they are
+	days := 90
+	if v := c.Query("days"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 366 {
+			days = n
+		}
+	}
+	now := time.Now()
+	occurrences, err := h.expandEvents(ctx, h.DB, tenantID, eventFilter{
+		From:             now,
+		To:               now.AddDate(0, 0, days),
+		PublicOnly:       true,
+		OneOffsUnbounded: true,
+		Limit:            10,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
-	defer rows.Close()
 
 	var events []gin.H
-	for rows.Next() {
-		var id, title string
-		var description, location *string
-		var startsAt, endsAt time.Time
-		var capacity *int
-		if err := rows.Scan(&id, &title, &description, &startsAt, &endsAt, &location, &capacity); err != nil {
-			continue
-		}
-		events = append(events, gin.H{
-			"id":          id,
-			"title":       title,
-			"description": description,
-			"starts_at":   startsAt,
-			"ends_at":     endsAt,
-			"location":    location,
-			"capacity":    capacity,
-		})
+	for _, o := range occurrences {
+		events = append(events, publicEventResponse(o))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"events": events})
@@ -0,0 +1,714 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/ledger"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ My Registrations ============
+
+// CancellationPolicy is read from tenant settings (config.cancellation_policy).
+// Cancelling at least CutoffHours before the program starts refunds
+// RefundPercent of the price; later cancellations refund LateRefundPercent.
+// FeeCents is kept in either case.
+type CancellationPolicy struct {
+	CutoffHours       int    `json:"cutoff_hours"`
+	RefundPercent     int    `json:"refund_percent"`
+	LateRefundPercent int    `json:"late_refund_percent"`
+	FeeCents          int    `json:"fee_cents"`
+	RefundMethod      string `json:"refund_method"`
+	AllowAfterStart   bool   `json:"allow_after_start"`
+}
+
+type MyRegistrationUpdateRequest struct {
+	ParticipantName        *string                `json:"participant_name"`
+	ParticipantAge         *int                   `json:"participant_age"`
+	ParticipantDateOfBirth *string                `json:"participant_date_of_birth"`
+	ParticipantGender      *string                `json:"participant_gender"`
+	EmergencyContactName   *string                `json:"emergency_contact_name"`
+	EmergencyContactPhone  *string                `json:"emergency_contact_phone"`
+	Notes                  *string                `json:"notes"`
+	Answers                map[string]interface{} `json:"answers"`
+}
+
+type CancelRegistrationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// cancellationQuote is what cancelling a registration now would do
+type cancellationQuote struct {
+	Allowed       bool   `json:"allowed"`
+	Reason        string `json:"reason,omitempty"`
+	Late          bool   `json:"late"`
+	RefundPercent int    `json:"refund_percent"`
+	PriceCents    int    `json:"price_cents"`
+	PaidCents     int    `json:"paid_cents"`
+	FeeCents      int    `json:"fee_cents"`
+	KeptCents     int    `json:"kept_cents"`
+	RefundCents   int    `json:"refund_cents"`
+	WriteOffCents int    `json:"write_off_cents"`
+	RefundMethod  string `json:"refund_method"`
+}
+
+// activeRegistrationStatuses can still be edited or cancelled
+var activeRegistrationStatuses = []string{"entered", "offered", "pending", "approved", "waitlisted"}
+
+func clampPercent(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
+
+// cancellationPolicy loads the tenant's policy; without one, cancellations
+// are refunded in full as account credit
+func (h *Handler) cancellationPolicy(ctx context.Context, q dbtx, tenantID string) CancellationPolicy {
+	policy := CancellationPolicy{RefundPercent: 100, RefundMethod: "account_credit"}
+
+	var raw []byte
+	err := q.QueryRow(ctx,
+		`SELECT config->'cancellation_policy' FROM tenant_settings WHERE tenant_id = $1`,
+		tenantID).Scan(&raw)
+	if err == nil && len(raw) > 0 {
+		_ = json.Unmarshal(raw, &policy)
+	}
+
+	policy.RefundPercent = clampPercent(policy.RefundPercent)
+	policy.LateRefundPercent = clampPercent(policy.LateRefundPercent)
+	if policy.CutoffHours < 0 {
+		policy.CutoffHours = 0
+	}
+	if policy.FeeCents < 0 {
+		policy.FeeCents = 0
+	}
+	if policy.RefundMethod != "original_payment" {
+		policy.RefundMethod = "account_credit"
+	}
+	return policy
+}
+
+// programStart is when the program's first session starts, falling back to
+// the start of its start_date in the tenant's timezone. Nil when unknown.
+func programStart(ctx context.Context, q dbtx, programID string, loc *time.Location) (*time.Time, error) {
+	var firstSession *time.Time
+	var startDate *string
+	err := q.QueryRow(ctx,
+		`SELECT (SELECT min(starts_at) FROM program_sessions WHERE program_id = p.id AND status = 'scheduled'),
+		        p.start_date::text
+		 FROM programs p WHERE p.id = $1`,
+		programID).Scan(&firstSession, &startDate)
+	if err != nil {
+		return nil, err
+	}
+	if firstSession != nil {
+		return firstSession, nil
+	}
+	if startDate != nil {
+		if t, err := time.ParseInLocation("2006-01-02", *startDate, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// quoteCancellation applies the policy. The household keeps owing the
+// non-refundable part: anything paid beyond it is refunded and any unpaid
+// balance beyond it is written off.
+func quoteCancellation(policy CancellationPolicy, status string, start *time.Time, now time.Time, priceCents, paidCents, outstandingCents int) cancellationQuote {
+	q := cancellationQuote{PriceCents: priceCents, PaidCents: paidCents, RefundMethod: policy.RefundMethod}
+
+	if !containsString(activeRegistrationStatuses, status) {
+		q.Reason = "registration is already " + status
+		return q
+	}
+	if start != nil && !now.Before(*start) && !policy.AllowAfterStart {
+		q.Reason = "the program has already started"
+		return q
+	}
+	q.Allowed = true
+
+	q.RefundPercent = policy.RefundPercent
+	if start != nil && now.After(start.Add(-time.Duration(policy.CutoffHours)*time.Hour)) {
+		q.Late = true
+		q.RefundPercent = policy.LateRefundPercent
+	}
+	// Waitlisted registrations, lottery entries and unaccepted offers never
+	// held a confirmed spot, so nothing is kept
+	unconfirmed := status == "waitlisted" || status == "entered" || status == "offered"
+	if unconfirmed {
+		q.RefundPercent = 100
+	}
+
+	q.KeptCents = priceCents - priceCents*q.RefundPercent/100
+	if !unconfirmed && priceCents > 0 {
+		q.FeeCents = policy.FeeCents
+		if q.KeptCents+q.FeeCents > priceCents {
+			q.FeeCents = priceCents - q.KeptCents
+		}
+		q.KeptCents += q.FeeCents
+	}
+
+	if paidCents > q.KeptCents {
+		q.RefundCents = paidCents - q.KeptCents
+	}
+	stillOwed := q.KeptCents - paidCents
+	if stillOwed < 0 {
+		stillOwed = 0
+	}
+	if outstandingCents > stillOwed {
+		q.WriteOffCents = outstandingCents - stillOwed
+	}
+	return q
+}
+
+type myRegistration struct {
+	ID, ProgramID, ProgramTitle, Status, UserID string
+	PriceCents                                  int
+}
+
+// loadMyRegistration loads and locks the caller's registration, writing the
+// error response when it is not theirs
+func loadMyRegistration(c *gin.Context, ctx context.Context, q dbtx, tenantID, userID, registrationID string) (*myRegistration, bool) {
+	var r myRegistration
+	err := q.QueryRow(ctx,
+		`SELECT pr.id, pr.program_id, p.title, pr.status, pr.user_id, pr.price_cents
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 WHERE pr.id = $1 AND pr.tenant_id = $2
+		 FOR UPDATE OF pr`,
+		registrationID, tenantID).Scan(&r.ID, &r.ProgramID, &r.ProgramTitle, &r.Status, &r.UserID, &r.PriceCents)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	if r.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
+		return nil, false
+	}
+	return &r, true
+}
+
+// registrationMoney totals what has been paid for a registration and what is
+// still owed on it
+func registrationMoney(ctx context.Context, q dbtx, registrationID string) (paid, outstanding int, err error) {
+	err = q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_cents + credit_applied_cents - refunded_cents), 0)
+		 FROM payments
+		 WHERE subject_type = 'program_registration' AND subject_id = $1 AND status = 'paid'`,
+		registrationID).Scan(&paid)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(le.amount_cents), 0)
+		 FROM ledger_entries le
+		 JOIN ledger_transactions lt ON le.transaction_id = lt.id
+		 WHERE lt.subject_type = 'program_registration' AND lt.subject_id = $1 AND le.account = $2`,
+		registrationID, ledger.Receivable).Scan(&outstanding)
+	return paid, outstanding, err
+}
+
+func (h *Handler) ListMyRegistrations(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT pr.id, pr.program_id, p.title, pr.status,
+		        pr.participant_name, pr.participant_age, pr.participant_date_of_birth::text, pr.participant_gender,
+		        pr.emergency_contact_name, pr.emergency_contact_phone, pr.notes, pr.answers,
+		        pr.price_cents, pr.registered_at, pr.cancelled_at, pr.offer_expires_at,
+		        (SELECT min(starts_at) FROM program_sessions WHERE program_id = p.id AND status = 'scheduled'),
+		        p.start_date::text,
+		        COALESCE((SELECT SUM(amount_cents + credit_applied_cents) FROM payments
+		                  WHERE subject_type = 'program_registration' AND subject_id = pr.id AND status = 'paid'), 0),
+		        COALESCE((SELECT SUM(refunded_cents) FROM payments
+		                  WHERE subject_type = 'program_registration' AND subject_id = pr.id), 0)
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 WHERE pr.tenant_id = $1 AND pr.user_id = $2
+		 ORDER BY pr.registered_at DESC`,
+		tenantID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	registrations := []gin.H{}
+	for rows.Next() {
+		var (
+			id, programID, title, status, participantName string
+			age                                           *int
+			dob, gender, emergencyName, emergencyPhone    *string
+			notes, startDate                              *string
+			answers                                       json.RawMessage
+			priceCents, paidCents, refundedCents          int
+			registeredAt                                  time.Time
+			cancelledAt, offerExpiresAt, firstSession     *time.Time
+		)
+		if err := rows.Scan(&id, &programID, &title, &status,
+			&participantName, &age, &dob, &gender,
+			&emergencyName, &emergencyPhone, &notes, &answers,
+			&priceCents, &registeredAt, &cancelledAt, &offerExpiresAt,
+			&firstSession, &startDate, &paidCents, &refundedCents); err != nil {
+			continue
+		}
+
+		start := firstSession
+		if start == nil && startDate != nil {
+			if t, err := time.ParseInLocation("2006-01-02", *startDate, loc); err == nil {
+				start = &t
+			}
+		}
+		changeable := containsString(activeRegistrationStatuses, status) && (start == nil || now.Before(*start))
+		if status != "offered" {
+			offerExpiresAt = nil
+		}
+
+		registrations = append(registrations, gin.H{
+			"id":                        id,
+			"program_id":                programID,
+			"program_title":             title,
+			"program_starts_at":         start,
+			"status":                    status,
+			"participant_name":          participantName,
+			"participant_age":           age,
+			"participant_date_of_birth": dob,
+			"participant_gender":        gender,
+			"emergency_contact_name":    emergencyName,
+			"emergency_contact_phone":   emergencyPhone,
+			"notes":                     notes,
+			"answers":                   answers,
+			"price_cents":               priceCents,
+			"paid_cents":                paidCents,
+			"refunded_cents":            refundedCents,
+			"registered_at":             registeredAt,
+			"cancelled_at":              cancelledAt,
+			"offer_expires_at":          offerExpiresAt,
+			"can_edit":                  changeable,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"registrations":       registrations,
+		"cancellation_policy": h.cancellationPolicy(ctx, h.DB, tenantID),
+	})
+}
+
+// UpdateMyRegistration changes participant and emergency details until the
+// program starts. Changes to age, date of birth or gender are re-checked
+// against the program's eligibility rules.
+func (h *Handler) UpdateMyRegistration(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req MyRegistrationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ParticipantName != nil && strings.TrimSpace(*req.ParticipantName) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participant_name cannot be empty"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	userID := claims.UserID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	reg, ok := loadMyRegistration(c, ctx, tx, tenantID, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !containsString(activeRegistrationStatuses, reg.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "registration is already " + reg.Status})
+		return
+	}
+	start, err := programStart(ctx, tx, reg.ProgramID, h.tenantLocation(ctx, tx, tenantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if start != nil && !time.Now().Before(*start) {
+		c.JSON(http.StatusConflict, gin.H{"error": "registrations cannot be changed after the program starts"})
+		return
+	}
+
+	var (
+		name                          string
+		age                           *int
+		dob                           *time.Time
+		gender                        *string
+		emergencyName, emergencyPhone *string
+		notes                         *string
+		answersJSON                   []byte
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT participant_name, participant_age, participant_date_of_birth, participant_gender,
+		        emergency_contact_name, emergency_contact_phone, notes, answers
+		 FROM program_registrations WHERE id = $1`,
+		reg.ID).Scan(&name, &age, &dob, &gender, &emergencyName, &emergencyPhone, &notes, &answersJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	before := gin.H{"participant_name": name, "participant_age": age, "participant_date_of_birth": formatOptionalDate(dob),
+		"participant_gender": gender, "emergency_contact_name": emergencyName, "emergency_contact_phone": emergencyPhone}
+
+	recheck := false
+	if req.ParticipantName != nil {
+		name = strings.TrimSpace(*req.ParticipantName)
+		var duplicate bool
+		err := tx.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM program_registrations
+			               WHERE program_id = $1 AND user_id = $2 AND lower(participant_name) = lower($3)
+			                 AND id <> $4 AND status <> 'cancelled')`,
+			reg.ProgramID, userID, name, reg.ID).Scan(&duplicate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if duplicate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this participant is already registered for this program"})
+			return
+		}
+	}
+	if req.ParticipantAge != nil {
+		age = req.ParticipantAge
+		recheck = true
+	}
+	if req.ParticipantDateOfBirth != nil {
+		parsed, err := parseOptionalDate("participant_date_of_birth", req.ParticipantDateOfBirth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dob = parsed
+		recheck = true
+	}
+	if req.ParticipantGender != nil {
+		gender = nullIfEmpty(*req.ParticipantGender)
+		recheck = true
+	}
+	if req.EmergencyContactName != nil {
+		emergencyName = req.EmergencyContactName
+	}
+	if req.EmergencyContactPhone != nil {
+		emergencyPhone = req.EmergencyContactPhone
+	}
+	if req.Notes != nil {
+		notes = req.Notes
+	}
+
+	if recheck {
+		violations, err := h.checkEligibility(ctx, tx, tenantID, reg.ProgramID, userID, EligibilityApplicant{
+			DateOfBirth: dob,
+			Age:         age,
+			Gender:      derefString(gender),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate eligibility"})
+			return
+		}
+		if len(violations) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "participant is not eligible for this program",
+				"reasons": violations,
+			})
+			return
+		}
+	}
+
+	if req.Answers != nil {
+		fields, err := h.loadProgramForm(ctx, tx, tenantID, reg.ProgramID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load registration form"})
+			return
+		}
+		answers, fieldErrs := validateFormAnswers(fields, req.Answers)
+		if len(fieldErrs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form answers", "fields": fieldErrs})
+			return
+		}
+		answersJSON, _ = json.Marshal(answers)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE program_registrations
+		 SET participant_name = $1, participant_age = $2, participant_date_of_birth = $3, participant_gender = $4,
+		     emergency_contact_name = $5, emergency_contact_phone = $6, notes = $7, answers = $8, updated_at = now()
+		 WHERE id = $9`,
+		name, age, dob, gender, emergencyName, emergencyPhone, notes, answersJSON, reg.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update registration"})
+		return
+	}
+
+	regID := uuid.MustParse(reg.ID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "registration_updated", "program_registration", &regID,
+		before, gin.H{"participant_name": name, "participant_age": age, "participant_date_of_birth": formatOptionalDate(dob),
+			"participant_gender": gender, "emergency_contact_name": emergencyName, "emergency_contact_phone": emergencyPhone})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "registration updated"})
+}
+
+// GetMyRegistrationCancellation previews what cancelling would refund
+func (h *Handler) GetMyRegistrationCancellation(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	reg, ok := loadMyRegistration(c, ctx, tx, tenantID, claims.UserID.String(), c.Param("id"))
+	if !ok {
+		return
+	}
+	quote, err := h.quoteMyCancellation(ctx, tx, tenantID, reg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+func (h *Handler) quoteMyCancellation(ctx context.Context, q dbtx, tenantID string, reg *myRegistration) (cancellationQuote, error) {
+	start, err := programStart(ctx, q, reg.ProgramID, h.tenantLocation(ctx, q, tenantID))
+	if err != nil {
+		return cancellationQuote{}, err
+	}
+	paid, outstanding, err := registrationMoney(ctx, q, reg.ID)
+	if err != nil {
+		return cancellationQuote{}, err
+	}
+	policy := h.cancellationPolicy(ctx, q, tenantID)
+	return quoteCancellation(policy, reg.Status, start, time.Now(), reg.PriceCents, paid, outstanding), nil
+}
+
+// CancelMyRegistration cancels under the tenant's cancellation policy: it
+// refunds what the policy allows, writes off the unpaid balance, cancels any
+// checkout in progress and offers the freed place to the waitlist. Refunds
+// to the original payment are sent once the cancellation is committed.
+func (h *Handler) CancelMyRegistration(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CancelRegistrationRequest
+	_ = c.ShouldBindJSON(&req)
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	actorID := claims.UserID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	reg, ok := loadMyRegistration(c, ctx, tx, tenantID, actorID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	// Checkouts in progress are abandoned before totals are taken
+	_, err = tx.Exec(ctx,
+		`UPDATE payments SET status = 'cancelled', updated_at = now()
+		 WHERE subject_type = 'program_registration' AND subject_id = $1 AND status = 'pending'`,
+		reg.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	quote, err := h.quoteMyCancellation(ctx, tx, tenantID, reg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !quote.Allowed {
+		c.JSON(http.StatusConflict, gin.H{"error": quote.Reason})
+		return
+	}
+
+	// The transition frees the seat for the longest-waiting entry
+	t, err := h.transitionRegistration(ctx, tx, claims.TenantID, claims.UserID, reg.ID, "cancelled", req.Reason)
+	if err != nil {
+		respondTransitionError(c, err, "failed to cancel registration")
+		return
+	}
+	promotedID := t.PromotedID
+
+	reason := "Cancelled " + reg.ProgramTitle
+	if quote.WriteOffCents > 0 {
+		subjectType := "program_registration"
+		err = postLedger(ctx, tx, tenantID, reg.UserID, ledger.Adjustment(reason, -quote.WriteOffCents), ledgerRef{
+			SubjectType: &subjectType,
+			SubjectID:   &reg.ID,
+			CreatedBy:   &actorID,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to post cancellation"})
+			return
+		}
+	}
+
+	// Refunds are recorded with the cancellation and sent to the provider
+	// only after both are committed
+	refunds, err := h.refundRegistration(ctx, tx, reg.ID, quote.RefundCents, quote.RefundMethod, reason, actorID)
+	var ce *checkoutError
+	if errors.As(err, &ce) {
+		c.JSON(ce.status, gin.H{"error": ce.message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund registration"})
+		return
+	}
+
+	regID := uuid.MustParse(reg.ID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "registration_cancelled", "program_registration", &regID,
+		gin.H{"status": reg.Status},
+		gin.H{"status": "cancelled", "reason": req.Reason, "quote": quote, "refunds": refundResponses(refunds), "promoted_registration_id": promotedID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	h.settleRefunds(ctx, refunds)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                       reg.ID,
+		"status":                   "cancelled",
+		"cancellation":             quote,
+		"refunds":                  refundResponses(refunds),
+		"promoted_registration_id": promotedID,
+	})
+}
+
+// refundRegistration records a refund spread over the registration's paid
+// payments, newest first. original_payment refunds fall back to account
+// credit for whatever the provider cannot return. Refunds to the original
+// payment are pending until the caller commits and settles them.
+func (h *Handler) refundRegistration(ctx context.Context, q dbtx, registrationID string, amount int, method, reason, actorID string) ([]*refundRecord, error) {
+	refunds := []*refundRecord{}
+	if amount <= 0 {
+		return refunds, nil
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT id::text FROM payments
+		 WHERE subject_type = 'program_registration' AND subject_id = $1 AND status = 'paid'
+		 ORDER BY paid_at DESC NULLS LAST`,
+		registrationID)
+	if err != nil {
+		return nil, err
+	}
+	var paymentIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paymentIDs = append(paymentIDs, id)
+	}
+	rows.Close()
+
+	remaining := amount
+	for _, paymentID := range paymentIDs {
+		if remaining == 0 {
+			break
+		}
+		payment, err := loadRefundablePayment(ctx, q, paymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		methods := []string{"account_credit"}
+		if method == "original_payment" && h.canRefundToOriginal(payment) {
+			methods = []string{"original_payment", "account_credit"}
+		}
+		for _, m := range methods {
+			part := payment.Refundable(m)
+			if part > remaining {
+				part = remaining
+			}
+			if part == 0 {
+				continue
+			}
+			refund, err := h.recordRefund(ctx, q, payment, part, m, reason, actorID)
+			if err != nil {
+				return nil, err
+			}
+			refunds = append(refunds, refund)
+			remaining -= part
+		}
+	}
+	return refunds, nil
+}
@@ -0,0 +1,695 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/payments"
+)
+
+// ============ Payments ============
+
+type CheckoutRequest struct {
+	SubjectType string `json:"subject_type" binding:"required"`
+	SubjectID   string `json:"subject_id" binding:"required"`
+	SuccessURL  string `json:"success_url"`
+	CancelURL   string `json:"cancel_url"`
+}
+
+type PublicBookingCheckoutRequest struct {
+	RequesterEmail string `json:"requester_email" binding:"required,email"`
+	SuccessURL     string `json:"success_url"`
+	CancelURL      string `json:"cancel_url"`
+}
+
+// checkoutSubject is what a payment is for and who pays it
+type checkoutSubject struct {
+	TenantID    string
+	Type        string
+	ID          string
+	UserID      *string
+	Email       string
+	AmountCents int
+	Description string
+	// ExpiresAt closes the provider checkout when a slot hold lapses
+	ExpiresAt *time.Time
+}
+
+// checkoutError is returned for subjects that cannot be paid for
+type checkoutError struct {
+	status  int
+	message string
+}
+
+func (e *checkoutError) Error() string { return e.message }
+
+// tenantPrimaryDomain returns the tenant's primary domain for building links
+func (h *Handler) tenantPrimaryDomain(ctx context.Context, tenantID string) string {
+	var domain string
+	err := h.DB.QueryRow(ctx,
+		`SELECT domain FROM tenant_domains WHERE tenant_id = $1 ORDER BY is_primary DESC, created_at ASC LIMIT 1`,
+		tenantID).Scan(&domain)
+	if err != nil {
+		return h.Config.PublicBaseDomain
+	}
+	return domain
+}
+
+// tenantUserIDByEmail finds the tenant account with the given email
+func (h *Handler) tenantUserIDByEmail(ctx context.Context, tenantID, email string) *string {
+	var userID string
+	err := h.DB.QueryRow(ctx,
+		`SELECT u.id::text FROM users u
+		 JOIN tenant_users tu ON tu.user_id = u.id
+		 WHERE tu.tenant_id = $1 AND lower(u.email) = lower($2)`,
+		tenantID, email).Scan(&userID)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// loadRegistrationSubject prices a program registration for checkout
+func (h *Handler) loadRegistrationSubject(ctx context.Context, tenantID, registrationID string) (*checkoutSubject, error) {
+	var s checkoutSubject
+	var userID, status, title, participant string
+	err := h.DB.QueryRow(ctx,
+		`SELECT pr.user_id::text, u.email, pr.price_cents, pr.status, p.title, pr.participant_name
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 JOIN users u ON pr.user_id = u.id
+		 WHERE pr.id = $1 AND pr.tenant_id = $2`,
+		registrationID, tenantID).Scan(&userID, &s.Email, &s.AmountCents, &status, &title, &participant)
+	if err == pgx.ErrNoRows {
+		return nil, &checkoutError{http.StatusNotFound, "registration not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "cancelled" {
+		return nil, &checkoutError{http.StatusConflict, "registration has been cancelled"}
+	}
+	if status == "entered" || status == "offered" {
+		return nil, &checkoutError{http.StatusConflict, "registration has not been confirmed"}
+	}
+
+	s.TenantID = tenantID
+	s.Type = "program_registration"
+	s.ID = registrationID
+	s.UserID = &userID
+	s.Description = fmt.Sprintf("%s: %s", title, participant)
+	return &s, nil
+}
+
+// loadBookingSubject prices a facility slot booking at the facility's hourly rate
+func (h *Handler) loadBookingSubject(ctx context.Context, tenantID, bookingID string) (*checkoutSubject, error) {
+	var s checkoutSubject
+	var status, resourceType string
+	var facilityName *string
+	var rateCents *int
+	var startsAt, endsAt *time.Time
+	err := h.DB.QueryRow(ctx,
+		`SELECT b.requester_email, b.status, b.resource_type, f.name, f.hourly_rate_cents, fs.starts_at, fs.ends_at
+		 FROM bookings b
+		 LEFT JOIN facility_slots fs ON b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		 LEFT JOIN facilities f ON fs.facility_id = f.id AND f.tenant_id = b.tenant_id
+		 WHERE b.id = $1 AND b.tenant_id = $2`,
+		bookingID, tenantID).Scan(&s.Email, &status, &resourceType, &facilityName, &rateCents, &startsAt, &endsAt)
+	if err == pgx.ErrNoRows {
+		return nil, &checkoutError{http.StatusNotFound, "booking not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "declined" || status == "cancelled" {
+		return nil, &checkoutError{http.StatusConflict, "booking is " + status}
+	}
+	if resourceType != "facility_slot" || facilityName == nil || rateCents == nil || startsAt == nil || endsAt == nil {
+		return nil, &checkoutError{http.StatusBadRequest, "booking is not for a facility slot"}
+	}
+
+	s.TenantID = tenantID
+	s.Type = "booking"
+	s.ID = bookingID
+	s.AmountCents = slotPriceCents(*rateCents, *startsAt, *endsAt)
+	s.Description = fmt.Sprintf("%s, %s", *facilityName, startsAt.Format("Jan 2 3:04 PM"))
+	return &s, nil
+}
+
+// abandonCheckouts cancels a subject's unfinished payments and returns
+// their provider checkout sessions, to be expired once committed
+func abandonCheckouts(ctx context.Context, q dbtx, subjectType, subjectID string) ([]string, error) {
+	rows, err := q.Query(ctx,
+		`UPDATE payments SET status = 'cancelled', updated_at = now()
+		 WHERE subject_type = $1 AND subject_id = $2 AND status = 'pending'
+		 RETURNING provider_session_id`,
+		subjectType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []string
+	for rows.Next() {
+		var sessionID *string
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, err
+		}
+		if sessionID != nil {
+			sessions = append(sessions, *sessionID)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// startCheckout records a payment for the subject and opens a checkout
+// session with the configured provider. Household account credit is applied
+// first; when it covers the whole amount no provider checkout is needed.
+func (h *Handler) startCheckout(ctx context.Context, s *checkoutSubject, successURL, cancelURL string) (gin.H, error) {
+	if s.AmountCents <= 0 {
+		return nil, &checkoutError{http.StatusBadRequest, "nothing to pay"}
+	}
+
+	var paid bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM payments WHERE subject_type = $1 AND subject_id = $2 AND status = 'paid')`,
+		s.Type, s.ID).Scan(&paid)
+	if err != nil {
+		return nil, err
+	}
+	if paid {
+		return nil, &checkoutError{http.StatusConflict, "already paid"}
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Abandon earlier unfinished checkouts for the same subject, releasing
+	// any credit they reserved
+	abandoned, err := abandonCheckouts(ctx, tx, s.Type, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	creditCents := 0
+	if s.UserID != nil {
+		// Serialise checkouts per household so credit is not spent twice
+		if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, *s.UserID); err != nil {
+			return nil, err
+		}
+		if err := ensureCharge(ctx, tx, s); err != nil {
+			return nil, err
+		}
+		available, err := availableCredit(ctx, tx, s.TenantID, *s.UserID)
+		if err != nil {
+			return nil, err
+		}
+		creditCents = min(available, s.AmountCents)
+	}
+	dueCents := s.AmountCents - creditCents
+	paymentID := uuid.New()
+
+	if dueCents == 0 {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO payments (id, tenant_id, subject_type, subject_id, user_id, payer_email, amount_cents, credit_applied_cents,
+			                       currency, provider, status, paid_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, 'account_credit', 'paid', now())`,
+			paymentID, s.TenantID, s.Type, s.ID, s.UserID, s.Email, creditCents, h.Config.PaymentsCurrency)
+		if err != nil {
+			return nil, err
+		}
+		err = postPaymentLedger(ctx, tx, s.TenantID, s.UserID, paymentID.String(), s.Type, s.ID, 0, creditCents)
+		if err != nil {
+			return nil, err
+		}
+		holds, err := h.confirmSlotHold(ctx, tx, s.Type, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !holds {
+			return nil, &checkoutError{http.StatusConflict, "slot hold has expired"}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		h.expireCheckoutSessions(ctx, abandoned)
+		return gin.H{
+			"payment_id":           paymentID.String(),
+			"status":               "paid",
+			"checkout_url":         nil,
+			"amount_cents":         0,
+			"credit_applied_cents": creditCents,
+			"currency":             h.Config.PaymentsCurrency,
+		}, nil
+	}
+
+	if h.Payments == nil {
+		return nil, &checkoutError{http.StatusServiceUnavailable, "online payments are not enabled"}
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO payments (id, tenant_id, subject_type, subject_id, user_id, payer_email, amount_cents, credit_applied_cents, currency, provider)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		paymentID, s.TenantID, s.Type, s.ID, s.UserID, s.Email, dueCents, creditCents, h.Config.PaymentsCurrency, h.Payments.Name())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	h.expireCheckoutSessions(ctx, abandoned)
+
+	var expiresAt time.Time
+	if s.ExpiresAt != nil {
+		expiresAt = *s.ExpiresAt
+	}
+	domain := h.tenantPrimaryDomain(ctx, s.TenantID)
+	if successURL == "" {
+		successURL = fmt.Sprintf("https://%s/checkout/success?payment_id=%s", domain, paymentID)
+	}
+	if cancelURL == "" {
+		cancelURL = fmt.Sprintf("https://%s/checkout/cancelled?payment_id=%s", domain, paymentID)
+	}
+
+	session, err := h.Payments.CreateCheckoutSession(ctx, payments.CheckoutRequest{
+		PaymentID:     paymentID.String(),
+		AmountCents:   dueCents,
+		Currency:      h.Config.PaymentsCurrency,
+		Description:   s.Description,
+		CustomerEmail: s.Email,
+		SuccessURL:    successURL,
+		CancelURL:     cancelURL,
+		ExpiresAt:     expiresAt,
+	})
+	if err != nil {
+		_, _ = h.DB.Exec(ctx,
+			`UPDATE payments SET status = 'failed', failure_reason = $1, updated_at = now() WHERE id = $2`,
+			err.Error(), paymentID)
+		return nil, &checkoutError{http.StatusBadGateway, "payment provider unavailable"}
+	}
+
+	_, err = h.DB.Exec(ctx,
+		`UPDATE payments SET provider_session_id = $1, updated_at = now() WHERE id = $2`,
+		session.ID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"payment_id":           paymentID.String(),
+		"status":               "pending",
+		"checkout_url":         session.URL,
+		"amount_cents":         dueCents,
+		"credit_applied_cents": creditCents,
+		"currency":             h.Config.PaymentsCurrency,
+	}, nil
+}
+
+// respondCheckoutError maps checkout failures to HTTP responses
+func respondCheckoutError(c *gin.Context, err error) {
+	var ce *checkoutError
+	if errors.As(err, &ce) {
+		c.JSON(ce.status, gin.H{"error": ce.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start checkout"})
+}
+
+// CreateCheckout starts a checkout for a registration or booking
+func (h *Handler) CreateCheckout(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	isAdmin := claims.Role == "OWNER" || claims.Role == "ADMIN"
+
+	var subject *checkoutSubject
+	var err error
+	switch req.SubjectType {
+	case "program_registration":
+		subject, err = h.loadRegistrationSubject(ctx, tenantID, req.SubjectID)
+		if err == nil && !isAdmin && *subject.UserID != claims.UserID.String() {
+			err = &checkoutError{http.StatusForbidden, "forbidden"}
+		}
+		// Awards approved after registering are applied before paying
+		if err == nil {
+			if err = h.applyScholarshipAtCheckout(ctx, tenantID, req.SubjectID); err == nil {
+				subject, err = h.loadRegistrationSubject(ctx, tenantID, req.SubjectID)
+			}
+		}
+	case "booking":
+		subject, err = h.loadBookingSubject(ctx, tenantID, req.SubjectID)
+		if err == nil && !isAdmin && !strings.EqualFold(subject.Email, claims.Email) {
+			err = &checkoutError{http.StatusForbidden, "forbidden"}
+		}
+		// Signed-in bookings go on the requester's household ledger. Guest
+		// checkouts stay off it so an email address alone cannot spend credit.
+		if err == nil {
+			subject.UserID = h.tenantUserIDByEmail(ctx, tenantID, subject.Email)
+			subject.ExpiresAt, err = h.holdForCheckout(ctx, tenantID, req.SubjectID)
+		}
+	default:
+		err = &checkoutError{http.StatusBadRequest, "subject_type must be program_registration or booking"}
+	}
+	if err != nil {
+		respondCheckoutError(c, err)
+		return
+	}
+
+	result, err := h.startCheckout(ctx, subject, req.SuccessURL, req.CancelURL)
+	if err != nil {
+		respondCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// CreatePublicBookingCheckout lets a booking requester pay without an account
+func (h *Handler) CreatePublicBookingCheckout(c *gin.Context) {
+	var req PublicBookingCheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	subject, err := h.loadBookingSubject(ctx, tenantID, c.Param("id"))
+	if err == nil && !strings.EqualFold(subject.Email, req.RequesterEmail) {
+		// Do not reveal that the booking exists
+		err = &checkoutError{http.StatusNotFound, "booking not found"}
+	}
+	if err == nil {
+		subject.ExpiresAt, err = h.holdForCheckout(ctx, tenantID, c.Param("id"))
+	}
+	if err != nil {
+		respondCheckoutError(c, err)
+		return
+	}
+
+	result, err := h.startCheckout(ctx, subject, req.SuccessURL, req.CancelURL)
+	if err != nil {
+		respondCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// HandlePaymentWebhook receives signed notifications from the payment provider
+func (h *Handler) HandlePaymentWebhook(c *gin.Context) {
+	if h.Payments == nil || c.Param("provider") != h.Payments.Name() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unable to read body"})
+		return
+	}
+
+	evt, err := h.Payments.ParseWebhook(payload, c.GetHeader(h.Payments.SignatureHeader()))
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.applyPaymentEvent(context.Background(), evt); err != nil {
+		log.Printf("Failed to apply payment event %s: %v\n", evt.ID, err)
+		// A 5xx makes the provider retry delivery
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// applyPaymentEvent marks the matching payment paid or failed. Each provider
+// event is applied at most once.
+func (h *Handler) applyPaymentEvent(ctx context.Context, evt *payments.WebhookEvent) error {
+	if evt.Type == "" {
+		return nil
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`INSERT INTO payment_webhook_events (provider, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		h.Payments.Name(), evt.ID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	var paymentID, tenantID, status, subjectType, subjectID string
+	var userID *string
+	var amountCents, creditCents int
+	err = tx.QueryRow(ctx,
+		`SELECT id::text, tenant_id::text, status, subject_type, subject_id::text, user_id::text, amount_cents, credit_applied_cents
+		 FROM payments
+		 WHERE provider = $1 AND (provider_session_id = $2 OR id::text = $3)
+		 FOR UPDATE`,
+		h.Payments.Name(), evt.SessionID, evt.PaymentID).Scan(&paymentID, &tenantID, &status, &subjectType, &subjectID,
+		&userID, &amountCents, &creditCents)
+	if err == pgx.ErrNoRows {
+		log.Printf("Payment event %s does not match any payment\n", evt.ID)
+		return tx.Commit(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	var refunds []*refundRecord
+	switch evt.Type {
+	case payments.EventPaymentSucceeded:
+		switch {
+		case status == "cancelled" || status == "failed":
+			// The subject gave up on this checkout, usually because it was
+			// cancelled, so the money is sent back
+			refunds, err = h.refundLatePayment(ctx, tx, paymentID, "after the checkout was "+status, evt)
+		case status != "pending":
+			// Already settled by an earlier event
+		case evt.AmountCents != 0 && evt.AmountCents != amountCents:
+			log.Printf("Payment %s amount mismatch: expected %d, provider reported %d\n", paymentID, amountCents, evt.AmountCents)
+			_, err = tx.Exec(ctx,
+				`UPDATE payments SET status = 'review', provider_payment_ref = $1, failure_reason = $2, updated_at = now()
+				 WHERE id = $3`,
+				nullIfEmpty(evt.PaymentRef),
+				fmt.Sprintf("amount mismatch: expected %d, provider reported %d", amountCents, evt.AmountCents), paymentID)
+		default:
+			// A booking's slot may have gone to someone else once its hold
+			// lapsed; the money then goes back rather than being kept
+			var holds bool
+			holds, err = h.confirmSlotHold(ctx, tx, subjectType, subjectID)
+			switch {
+			case err != nil:
+			case !holds:
+				refunds, err = h.refundLatePayment(ctx, tx, paymentID, "after the booking lost its slot", evt)
+			default:
+				err = h.settlePayment(ctx, tx, paymentID, tenantID, userID, subjectType, subjectID, amountCents, creditCents, evt.PaymentRef)
+			}
+		}
+	case payments.EventPaymentFailed:
+		if status != "pending" {
+			break
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE payments SET status = 'failed', failure_reason = $1, updated_at = now() WHERE id = $2`,
+			nullIfEmpty(evt.Reason), paymentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	h.settleRefunds(ctx, refunds)
+	return nil
+}
+
+// settlePayment marks a pending payment paid and posts it
+func (h *Handler) settlePayment(ctx context.Context, q dbtx, paymentID, tenantID string, userID *string, subjectType, subjectID string, amountCents, creditCents int, paymentRef string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE payments SET status = 'paid', paid_at = now(), provider_payment_ref = $1, failure_reason = NULL, updated_at = now()
+		 WHERE id = $2`,
+		nullIfEmpty(paymentRef), paymentID)
+	if err != nil {
+		return err
+	}
+	return postPaymentLedger(ctx, q, tenantID, userID, paymentID, subjectType, subjectID, amountCents, creditCents)
+}
+
+// refundLatePayment records money collected when it should not have been,
+// e.g. for a checkout that was already cancelled, as refund_due and
+// refunds all of it to the original payment. Credit the checkout reserved
+// is not spent. Without a provider reference the payment stays refund_due
+// for staff.
+func (h *Handler) refundLatePayment(ctx context.Context, q dbtx, paymentID, why string, evt *payments.WebhookEvent) ([]*refundRecord, error) {
+	reason := "paid " + why
+	_, err := q.Exec(ctx,
+		`UPDATE payments SET status = 'refund_due', paid_at = now(), provider_payment_ref = $1, credit_applied_cents = 0,
+		        amount_cents = CASE WHEN $2 > 0 THEN $2 ELSE amount_cents END, failure_reason = $3, updated_at = now()
+		 WHERE id = $4`,
+		nullIfEmpty(evt.PaymentRef), evt.AmountCents, reason, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Payment %s was %s; refunding\n", paymentID, reason)
+
+	payment, err := loadRefundablePayment(ctx, q, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	amount := payment.Refundable("original_payment")
+	if !h.canRefundToOriginal(payment) || amount == 0 {
+		return nil, nil
+	}
+	refund, err := h.recordRefund(ctx, q, payment, amount, "original_payment", "Payment received "+why, "")
+	if err != nil {
+		return nil, err
+	}
+	return []*refundRecord{refund}, nil
+}
+
+// CompleteFakeCheckout stands in for the hosted checkout page of the fake
+// provider. It sends a signed webhook through the normal verification path.
+// Pass ?outcome=failed to simulate a declined card.
+func (h *Handler) CompleteFakeCheckout(c *gin.Context) {
+	fake, ok := h.Payments.(*payments.FakeProvider)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	ctx := context.Background()
+	sessionID := c.Param("session_id")
+
+	var paymentID string
+	var amountCents int
+	err := h.DB.QueryRow(ctx,
+		`SELECT id::text, amount_cents FROM payments WHERE provider = 'fake' AND provider_session_id = $1`,
+		sessionID).Scan(&paymentID, &amountCents)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "checkout session not found"})
+		return
+	}
+
+	payload, signature := fake.BuildEvent(sessionID, paymentID, amountCents, c.Query("outcome") != "failed")
+	evt, err := fake.ParseWebhook(payload, signature)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.applyPaymentEvent(ctx, evt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process event"})
+		return
+	}
+
+	var status string
+	_ = h.DB.QueryRow(ctx, `SELECT status FROM payments WHERE id = $1`, paymentID).Scan(&status)
+	c.JSON(http.StatusOK, gin.H{"payment_id": paymentID, "status": status})
+}
+
+// ListPayments returns the tenant's payments, newest first
+func (h *Handler) ListPayments(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT id, subject_type, subject_id, payer_email, amount_cents, credit_applied_cents, refunded_cents,
+		        currency, status, provider, failure_reason, paid_at, created_at
+		 FROM payments
+		 WHERE tenant_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY created_at DESC LIMIT 200`,
+		claims.TenantID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		var (
+			id, subjectID                           uuid.UUID
+			subjectType, currency, status, provider string
+			payerEmail, failureReason               *string
+			amountCents, creditCents, refundedCents int
+			paidAt                                  *time.Time
+			createdAt                               time.Time
+		)
+		if err := rows.Scan(&id, &subjectType, &subjectID, &payerEmail, &amountCents, &creditCents, &refundedCents,
+			&currency, &status, &provider,
+			&failureReason, &paidAt, &createdAt); err != nil {
+			continue
+		}
+		list = append(list, gin.H{
+			"id":                   id.String(),
+			"subject_type":         subjectType,
+			"subject_id":           subjectID.String(),
+			"payer_email":          payerEmail,
+			"amount_cents":         amountCents,
+			"credit_applied_cents": creditCents,
+			"refunded_cents":       refundedCents,
+			"currency":             currency,
+			"status":               status,
+			"provider":             provider,
+			"failure_reason":       failureReason,
+			"paid_at":              paidAt,
+			"created_at":           createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payments": list})
+}
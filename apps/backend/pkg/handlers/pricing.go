@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/pricing"
+)
+
+// ============ Pricing ============
+
+// pricingError is a problem with the caller's input (such as an unknown
+// coupon) rather than a server failure
+type pricingError string
+
+func (e pricingError) Error() string { return string(e) }
+
+type couponRef struct {
+	ID   uuid.UUID
+	Code string
+}
+
+// quoteRegistration prices a new registration of userID into programID using
+// the program's price rules, an optional coupon code and the household's
+// scholarship award, if any
+func (h *Handler) quoteRegistration(ctx context.Context, q dbtx, tenantID, programID, userID, couponCode string, at time.Time) (pricing.Quote, *couponRef, *scholarshipAward, error) {
+	var basePrice int
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(price_cents, 0) FROM programs WHERE id = $1 AND tenant_id = $2`,
+		programID, tenantID).Scan(&basePrice)
+	if err == pgx.ErrNoRows {
+		return pricing.Quote{}, nil, nil, pricingError("program not found")
+	}
+	if err != nil {
+		return pricing.Quote{}, nil, nil, err
+	}
+
+	rules, err := h.loadPriceRules(ctx, q, tenantID, programID)
+	if err != nil {
+		return pricing.Quote{}, nil, nil, err
+	}
+
+	in := pricing.Input{BasePriceCents: basePrice, At: at, SiblingIndex: 1}
+
+	for _, r := range rules {
+		if r.Kind == pricing.KindNonResidentSurcharge {
+			if in.IsResident, err = h.isResident(ctx, q, tenantID, userID); err != nil {
+				return pricing.Quote{}, nil, nil, err
+			}
+			break
+		}
+	}
+
+	// Earlier registrations from the same household make this a sibling
+	var existing int
+	err = q.QueryRow(ctx,
+		`SELECT COUNT(*) FROM program_registrations
+		 WHERE program_id = $1 AND user_id = $2 AND status <> 'cancelled'`,
+		programID, userID).Scan(&existing)
+	if err != nil {
+		return pricing.Quote{}, nil, nil, err
+	}
+	in.SiblingIndex = existing + 1
+
+	var ref *couponRef
+	if code := strings.TrimSpace(couponCode); code != "" {
+		coupon, id, err := h.findCoupon(ctx, q, tenantID, code, at)
+		if err != nil {
+			return pricing.Quote{}, nil, nil, err
+		}
+		in.Coupon = coupon
+		ref = &couponRef{ID: id, Code: coupon.Code}
+	}
+
+	award, err := h.scholarshipFor(ctx, q, tenantID, userID, at)
+	if err != nil {
+		return pricing.Quote{}, nil, nil, err
+	}
+	if award != nil {
+		in.Scholarship = &award.Scholarship
+	}
+
+	return pricing.Compute(rules, in), ref, award, nil
+}
+
+func (h *Handler) loadPriceRules(ctx context.Context, q dbtx, tenantID, programID string) ([]pricing.Rule, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id::text, kind, COALESCE(label, ''), amount_cents, percent::float8, ends_at, min_sibling_index
+		 FROM program_price_rules WHERE program_id = $1 AND tenant_id = $2
+		 ORDER BY created_at ASC`,
+		programID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []pricing.Rule{}
+	for rows.Next() {
+		var r pricing.Rule
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Label, &r.AmountCents, &r.Percent, &r.EndsAt, &r.MinSiblingIndex); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// findCoupon looks up an active coupon that is valid at the given time
+func (h *Handler) findCoupon(ctx context.Context, q dbtx, tenantID, code string, at time.Time) (*pricing.Coupon, uuid.UUID, error) {
+	var (
+		id               uuid.UUID
+		coupon           pricing.Coupon
+		startsAt, endsAt *time.Time
+		maxRedemptions   *int
+		redemptionCount  int
+		active           bool
+	)
+	err := q.QueryRow(ctx,
+		`SELECT id, code, percent_off::float8, amount_off_cents, starts_at, ends_at, max_redemptions, redemption_count, active
+		 FROM coupons WHERE tenant_id = $1 AND code = $2`,
+		tenantID, strings.ToUpper(code)).Scan(&id, &coupon.Code, &coupon.PercentOff, &coupon.AmountOffCents,
+		&startsAt, &endsAt, &maxRedemptions, &redemptionCount, &active)
+	if err == pgx.ErrNoRows {
+		return nil, uuid.Nil, pricingError("coupon code not found")
+	}
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	switch {
+	case !active:
+		return nil, uuid.Nil, pricingError("coupon code is no longer active")
+	case startsAt != nil && at.Before(*startsAt):
+		return nil, uuid.Nil, pricingError("coupon code is not valid yet")
+	case endsAt != nil && !at.Before(*endsAt):
+		return nil, uuid.Nil, pricingError("coupon code has expired")
+	case maxRedemptions != nil && redemptionCount >= *maxRedemptions:
+		return nil, uuid.Nil, pricingError("coupon code has reached its redemption limit")
+	}
+
+	return &coupon, id, nil
+}
+
+// redeemCoupon counts a redemption, failing if the limit was reached by a
+// concurrent registration
+func redeemCoupon(ctx context.Context, q dbtx, couponID uuid.UUID) error {
+	result, err := q.Exec(ctx,
+		`UPDATE coupons SET redemption_count = redemption_count + 1, updated_at = now()
+		 WHERE id = $1 AND (max_redemptions IS NULL OR redemption_count < max_redemptions)`,
+		couponID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pricingError("coupon code has reached its redemption limit")
+	}
+	return nil
+}
+
+type QuoteRequest struct {
+	ProgramID  string  `json:"program_id" binding:"required"`
+	CouponCode string  `json:"coupon_code"`
+	UserID     *string `json:"user_id"`
+}
+
+// QuoteProgramRegistration returns the itemised price a registration would be charged
+func (h *Handler) QuoteProgramRegistration(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := claims.UserID.String()
+	if req.UserID != nil && *req.UserID != userID {
+		if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot quote on behalf of another user"})
+			return
+		}
+		userID = *req.UserID
+	}
+
+	ctx := context.Background()
+	quote, _, _, err := h.quoteRegistration(ctx, h.DB, claims.TenantID.String(), req.ProgramID, userID, req.CouponCode, time.Now())
+	var pe pricingError
+	if errors.As(err, &pe) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pe.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// ============ Program Price Rules ============
+
+type PriceRuleRequest struct {
+	Kind            string     `json:"kind" binding:"required"`
+	Label           string     `json:"label"`
+	AmountCents     int        `json:"amount_cents"`
+	Percent         float64    `json:"percent"`
+	EndsAt          *time.Time `json:"ends_at"`
+	MinSiblingIndex int        `json:"min_sibling_index"`
+}
+
+type PriceRulesRequest struct {
+	Rules []PriceRuleRequest `json:"rules"`
+}
+
+// GetProgramPriceRules lists the price rules attached to a program
+func (h *Handler) GetProgramPriceRules(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	rules, err := h.loadPriceRules(ctx, h.DB, claims.TenantID.String(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// UpdateProgramPriceRules replaces the price rules attached to a program
+func (h *Handler) UpdateProgramPriceRules(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	var req PriceRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, r := range req.Rules {
+		if !pricing.ValidKind(r.Kind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown rule kind: " + r.Kind})
+			return
+		}
+		if r.AmountCents < 0 || r.Percent < 0 || r.Percent > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amount_cents must be positive and percent between 0 and 100"})
+			return
+		}
+		if r.Kind == pricing.KindEarlyBird && r.EndsAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "early_bird rules need ends_at"})
+			return
+		}
+	}
+
+	ctx := context.Background()
+
+	// Verify ownership
+	var tenantID string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM programs WHERE id = $1`, programID).Scan(&tenantID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if tenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM program_price_rules WHERE program_id = $1`, programID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update price rules"})
+		return
+	}
+
+	for _, r := range req.Rules {
+		minSibling := r.MinSiblingIndex
+		if minSibling < 2 {
+			minSibling = 2
+		}
+		_, err := tx.Exec(ctx,
+			`INSERT INTO program_price_rules (tenant_id, program_id, kind, label, amount_cents, percent, ends_at, min_sibling_index)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			tenantID, programID, r.Kind, nullIfEmpty(r.Label), r.AmountCents, r.Percent, r.EndsAt, minSibling)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update price rules"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ============ Coupons ============
+
+type CouponRequest struct {
+	Code           string     `json:"code" binding:"required"`
+	Description    *string    `json:"description"`
+	PercentOff     float64    `json:"percent_off"`
+	AmountOffCents int        `json:"amount_off_cents"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	Active         *bool      `json:"active"`
+}
+
+func (r CouponRequest) validate() string {
+	if r.PercentOff < 0 || r.PercentOff > 100 {
+		return "percent_off must be between 0 and 100"
+	}
+	if r.AmountOffCents < 0 {
+		return "amount_off_cents must not be negative"
+	}
+	if r.PercentOff == 0 && r.AmountOffCents == 0 {
+		return "coupon needs percent_off or amount_off_cents"
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return "ends_at must be after starts_at"
+	}
+	return ""
+}
+
+// ListCoupons returns the tenant's coupon codes
+func (h *Handler) ListCoupons(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT id, code, description, percent_off::float8, amount_off_cents, starts_at, ends_at,
+		        max_redemptions, redemption_count, active, created_at
+		 FROM coupons WHERE tenant_id = $1 ORDER BY created_at DESC`,
+		claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	coupons := []gin.H{}
+	for rows.Next() {
+		var (
+			id                         uuid.UUID
+			code                       string
+			description                *string
+			percentOff                 float64
+			amountOff, redemptionCount int
+			startsAt, endsAt           *time.Time
+			maxRedemptions             *int
+			active                     bool
+			createdAt                  time.Time
+		)
+		if err := rows.Scan(&id, &code, &description, &percentOff, &amountOff, &startsAt, &endsAt,
+			&maxRedemptions, &redemptionCount, &active, &createdAt); err != nil {
+			continue
+		}
+		coupons = append(coupons, gin.H{
+			"id":               id.String(),
+			"code":             code,
+			"description":      description,
+			"percent_off":      percentOff,
+			"amount_off_cents": amountOff,
+			"starts_at":        startsAt,
+			"ends_at":          endsAt,
+			"max_redemptions":  maxRedemptions,
+			"redemption_count": redemptionCount,
+			"active":           active,
+			"created_at":       createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+func (h *Handler) CreateCoupon(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	ctx := context.Background()
+	couponID := uuid.New()
+	_, err := h.DB.Exec(ctx,
+		`INSERT INTO coupons (id, tenant_id, code, description, percent_off, amount_off_cents, starts_at, ends_at, max_redemptions, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		couponID, claims.TenantID, strings.ToUpper(strings.TrimSpace(req.Code)), req.Description,
+		req.PercentOff, req.AmountOffCents, req.StartsAt, req.EndsAt, req.MaxRedemptions, active)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a coupon with this code already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": couponID.String()})
+}
+
+func (h *Handler) UpdateCoupon(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`UPDATE coupons SET code = $1, description = $2, percent_off = $3, amount_off_cents = $4,
+		                    starts_at = $5, ends_at = $6, max_redemptions = $7, active = $8, updated_at = now()
+		 WHERE id = $9 AND tenant_id = $10`,
+		strings.ToUpper(strings.TrimSpace(req.Code)), req.Description, req.PercentOff, req.AmountOffCents,
+		req.StartsAt, req.EndsAt, req.MaxRedemptions, active, c.Param("id"), claims.TenantID)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a coupon with this code already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// DeleteCoupon deactivates a coupon so past registrations keep their reference
+func (h *Handler) DeleteCoupon(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.DB.Exec(ctx,
+		`UPDATE coupons SET active = false, updated_at = now() WHERE id = $1 AND tenant_id = $2`,
+		c.Param("id"), claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Program Registration Forms ============
+
+// Supported form field types
+const (
+	FieldText        = "text"
+	FieldTextarea    = "textarea"
+	FieldNumber      = "number"
+	FieldSelect      = "select"
+	FieldMultiSelect = "multiselect"
+	FieldCheckbox    = "checkbox"
+	FieldDate        = "date"
+)
+
+// FormField is a single custom question on a program's registration form
+type FormField struct {
+	Key       string         `json:"key"`
+	Label     string         `json:"label"`
+	Type      string         `json:"type"`
+	Required  bool           `json:"required"`
+	Options   []string       `json:"options,omitempty"`
+	HelpText  string         `json:"help_text,omitempty"`
+	VisibleIf *FormCondition `json:"visible_if,omitempty"`
+}
+
+// FormCondition shows a field only when another field's answer equals a value.
+// For multiselect fields the condition holds if the value is among the selections.
+type FormCondition struct {
+	Field  string      `json:"field"`
+	Equals interface{} `json:"equals"`
+}
+
+// FormFieldError describes why a definition or answer was rejected
+type FormFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ProgramFormRequest struct {
+	Fields []FormField `json:"fields"`
+}
+
+var formKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+const maxTextAnswerLength = 2000
+
+// validateFormDefinition checks that field keys are unique, types are known,
+// choice fields have options and conditions reference earlier fields
+func validateFormDefinition(fields []FormField) []FormFieldError {
+	errs := []FormFieldError{}
+	seen := map[string]FormField{}
+
+	for i, f := range fields {
+		name := f.Key
+		if name == "" {
+			name = fmt.Sprintf("fields[%d]", i)
+		}
+
+		if !formKeyPattern.MatchString(f.Key) {
+			errs = append(errs, FormFieldError{name, "key must be lowercase letters, digits and underscores"})
+		}
+		if _, dup := seen[f.Key]; dup {
+			errs = append(errs, FormFieldError{name, "duplicate field key"})
+		}
+		if strings.TrimSpace(f.Label) == "" {
+			errs = append(errs, FormFieldError{name, "label is required"})
+		}
+
+		switch f.Type {
+		case FieldText, FieldTextarea, FieldNumber, FieldCheckbox, FieldDate:
+			if len(f.Options) > 0 {
+				errs = append(errs, FormFieldError{name, "options are only allowed on select fields"})
+			}
+		case FieldSelect, FieldMultiSelect:
+			if len(f.Options) == 0 {
+				errs = append(errs, FormFieldError{name, "select fields need at least one option"})
+			}
+		default:
+			errs = append(errs, FormFieldError{name, "unknown field type " + strconv.Quote(f.Type)})
+		}
+
+		if f.VisibleIf != nil {
+			if _, ok := seen[f.VisibleIf.Field]; !ok {
+				errs = append(errs, FormFieldError{name, "visible_if must reference an earlier field"})
+			}
+		}
+
+		seen[f.Key] = f
+	}
+
+	return errs
+}
+
+// conditionHolds evaluates a visibility condition against cleaned answers
+func conditionHolds(cond *FormCondition, answers map[string]interface{}) bool {
+	if cond == nil {
+		return true
+	}
+	answer, ok := answers[cond.Field]
+	if !ok {
+		return false
+	}
+	if selections, ok := answer.([]string); ok {
+		for _, s := range selections {
+			if fmt.Sprint(s) == fmt.Sprint(cond.Equals) {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(answer) == fmt.Sprint(cond.Equals)
+}
+
+// validateFormAnswers type-checks answers against the form definition. It
+// returns the cleaned answers with unknown and hidden fields removed.
+func validateFormAnswers(fields []FormField, answers map[string]interface{}) (map[string]interface{}, []FormFieldError) {
+	cleaned := map[string]interface{}{}
+	errs := []FormFieldError{}
+
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f.Key] = true
+	}
+	for key := range answers {
+		if !known[key] {
+			errs = append(errs, FormFieldError{key, "unknown field"})
+		}
+	}
+
+	// Fields are evaluated in order so conditions see earlier cleaned answers
+	for _, f := range fields {
+		if !conditionHolds(f.VisibleIf, cleaned) {
+			continue
+		}
+
+		raw, present := answers[f.Key]
+		if !present || raw == nil || raw == "" {
+			if f.Required {
+				errs = append(errs, FormFieldError{f.Key, f.Label + " is required"})
+			}
+			continue
+		}
+
+		value, msg := coerceFormAnswer(f, raw)
+		if msg != "" {
+			errs = append(errs, FormFieldError{f.Key, msg})
+			continue
+		}
+		if f.Required && f.Type == FieldMultiSelect && len(value.([]string)) == 0 {
+			errs = append(errs, FormFieldError{f.Key, f.Label + " is required"})
+			continue
+		}
+		cleaned[f.Key] = value
+	}
+
+	return cleaned, errs
+}
+
+func coerceFormAnswer(f FormField, raw interface{}) (interface{}, string) {
+	switch f.Type {
+	case FieldText, FieldTextarea:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, "must be text"
+		}
+		if len(s) > maxTextAnswerLength {
+			return nil, fmt.Sprintf("must be at most %d characters", maxTextAnswerLength)
+		}
+		return strings.TrimSpace(s), ""
+	case FieldNumber:
+		n, ok := raw.(float64)
+		if !ok {
+			return nil, "must be a number"
+		}
+		return n, ""
+	case FieldCheckbox:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, "must be true or false"
+		}
+		return b, ""
+	case FieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, "must be a date"
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, "must be a date in YYYY-MM-DD format"
+		}
+		return s, ""
+	case FieldSelect:
+		s, ok := raw.(string)
+		if !ok || !containsString(f.Options, s) {
+			return nil, "must be one of: " + strings.Join(f.Options, ", ")
+		}
+		return s, ""
+	case FieldMultiSelect:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil, "must be a list of options"
+		}
+		selections := []string{}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || !containsString(f.Options, s) {
+				return nil, "each selection must be one of: " + strings.Join(f.Options, ", ")
+			}
+			selections = append(selections, s)
+		}
+		return selections, ""
+	}
+	return nil, "unsupported field type"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// loadProgramForm returns the program's form fields; a program without a
+// form has no custom questions
+func (h *Handler) loadProgramForm(ctx context.Context, q dbtx, tenantID, programID string) ([]FormField, error) {
+	var raw []byte
+	err := q.QueryRow(ctx,
+		`SELECT fields FROM program_forms WHERE program_id = $1 AND tenant_id = $2`,
+		programID, tenantID).Scan(&raw)
+	if err == pgx.ErrNoRows {
+		return []FormField{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []FormField{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// GetProgramForm returns the registration form definition for a program
+func (h *Handler) GetProgramForm(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	programID := c.Param("id")
+
+	var exists bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM programs WHERE id = $1 AND tenant_id = $2)`,
+		programID, claims.TenantID).Scan(&exists)
+	if err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+
+	fields, err := h.loadProgramForm(ctx, h.DB, claims.TenantID.String(), programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fields": fields})
+}
+
+// UpdateProgramForm replaces the registration form definition for a program
+func (h *Handler) UpdateProgramForm(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Param("id")
+	var req ProgramFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Fields == nil {
+		req.Fields = []FormField{}
+	}
+
+	if errs := validateFormDefinition(req.Fields); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form definition", "fields": errs})
+		return
+	}
+
+	ctx := context.Background()
+
+	// Verify ownership
+	var tenantID string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM programs WHERE id = $1`, programID).Scan(&tenantID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if tenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	fieldsJSON, _ := json.Marshal(req.Fields)
+	_, err = h.DB.Exec(ctx,
+		`INSERT INTO program_forms (program_id, tenant_id, fields) VALUES ($1, $2, $3)
+		 ON CONFLICT (program_id) DO UPDATE SET fields = EXCLUDED.fields, updated_at = now()`,
+		programID, tenantID, fieldsJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save form"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// GetPublicProgramForm returns the questions residents answer when registering
+func (h *Handler) GetPublicProgramForm(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	programID := c.Param("id")
+	var exists bool
+	err = h.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM programs WHERE id = $1 AND tenant_id = $2 AND status = 'active')`,
+		programID, tenantID).Scan(&exists)
+	if err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+
+	fields, err := h.loadProgramForm(ctx, h.DB, tenantID, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fields": fields})
+}
+
+// ExportProgramRegistrations streams a program's registrations, including
+// custom form answers, as CSV
+func (h *Handler) ExportProgramRegistrations(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && claims.Role != "STAFF" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	programID := c.Query("program_id")
+	if programID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "program_id is required"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	var programTitle string
+	err := h.DB.QueryRow(ctx,
+		`SELECT title FROM programs WHERE id = $1 AND tenant_id = $2`,
+		programID, tenantID).Scan(&programTitle)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+	if !h.canViewRoster(ctx, claims, programID, nil) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not assigned to this program"})
+		return
+	}
+
+	fields, err := h.loadProgramForm(ctx, h.DB, tenantID, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT pr.id, u.email, pr.participant_name, pr.participant_age, pr.participant_date_of_birth,
+		        pr.emergency_contact_name, pr.emergency_contact_phone, pr.notes, pr.status,
+		        pr.registered_at, pr.answers
+		 FROM program_registrations pr
+		 JOIN users u ON pr.user_id = u.id
+		 WHERE pr.tenant_id = $1 AND pr.program_id = $2
+		 ORDER BY pr.registered_at ASC`,
+		tenantID, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	header := []string{
+		"registration_id", "user_email", "participant_name", "participant_age", "participant_date_of_birth",
+		"emergency_contact_name", "emergency_contact_phone", "notes", "status", "registered_at",
+	}
+	for _, f := range fields {
+		header = append(header, f.Label)
+	}
+
+	filename := fmt.Sprintf("registrations-%s.csv", slugify(programTitle))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(header)
+
+	for rows.Next() {
+		var (
+			id, email, participantName, status string
+			age                                *int
+			dob                                *time.Time
+			emergencyName, emergencyPhone      *string
+			notes                              *string
+			registeredAt                       time.Time
+			answersJSON                        []byte
+		)
+		if err := rows.Scan(&id, &email, &participantName, &age, &dob, &emergencyName, &emergencyPhone,
+			&notes, &status, &registeredAt, &answersJSON); err != nil {
+			continue
+		}
+
+		answers := map[string]interface{}{}
+		_ = json.Unmarshal(answersJSON, &answers)
+
+		record := []string{
+			id, email, participantName, formatOptionalInt(age), formatOptionalDate(dob),
+			derefString(emergencyName), derefString(emergencyPhone), derefString(notes), status,
+			registeredAt.Format(time.RFC3339),
+		}
+		for _, f := range fields {
+			record = append(record, formatAnswer(answers[f.Key]))
+		}
+		_ = w.Write(record)
+	}
+
+	w.Flush()
+}
+
+// formatAnswer renders a stored answer as a single CSV cell
+func formatAnswer(v interface{}) string {
+	switch a := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, 0, len(a))
+		for _, item := range a {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, "; ")
+	case bool:
+		if a {
+			return "yes"
+		}
+		return "no"
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	default:
+		return fmt.Sprint(a)
+	}
+}
+
+func formatOptionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a title into a lowercase, hyphen-separated slug
+func slugify(s string) string {
+	s = nonSlugChars.ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(s, "-")
+}
@@ -2,20 +2,42 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rec-hub/backend/pkg/mail"
 	"github.com/rec-hub/backend/pkg/middleware"
 )
 
 type ProgramRegistrationRequest struct {
-	ProgramID              string `json:"program_id" binding:"required"`
-	ParticipantName        string `json:"participant_name" binding:"required"`
-	ParticipantAge         *int   `json:"participant_age"`
-	EmergencyContactName   string `json:"emergency_contact_name" binding:"required"`
-	EmergencyContactPhone  string `json:"emergency_contact_phone" binding:"required"`
-	Notes                  string `json:"notes"`
+	ProgramID              string  `json:"program_id" binding:"required"`
+	ParticipantName        string  `json:"participant_name" binding:"required"`
+	ParticipantAge         *int    `json:"participant_age"`
+	ParticipantDateOfBirth *string `json:"participant_date_of_birth"`
+	ParticipantGender      string  `json:"participant_gender"`
+	EmergencyContactName   string  `json:"emergency_contact_name" binding:"required"`
+	EmergencyContactPhone  string  `json:"emergency_contact_phone" binding:"required"`
+	Notes                  string  `json:"notes"`
+
+	// Answers to the program's custom form questions, keyed by field key
+	Answers map[string]interface{} `json:"answers"`
+
+	CouponCode string `json:"coupon_code"`
+
+	// Signatures for the program's required waivers
+	Waivers []WaiverSignatureRequest `json:"waivers"`
+
+	// Admins may register on behalf of a resident and override failed
+	// eligibility rules with a recorded reason
+	UserID              *string `json:"user_id"`
+	OverrideEligibility bool    `json:"override_eligibility"`
+	OverrideReason      string  `json:"override_reason"`
 }
 
 // CreateProgramRegistration allows residents to register for programs
@@ -38,53 +60,317 @@ func (h *Handler) CreateProgramRegistration(c *gin.Context) {
 		return
 	}
 
+	isAdmin := claims.Role == "OWNER" || claims.Role == "ADMIN"
+	if req.OverrideEligibility && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can override eligibility"})
+		return
+	}
+
+	var dob *time.Time
+	if req.ParticipantDateOfBirth != nil {
+		parsed, err := time.Parse("2006-01-02", *req.ParticipantDateOfBirth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "participant_date_of_birth must be YYYY-MM-DD"})
+			return
+		}
+		dob = &parsed
+	}
+
 	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	// Admins may register a resident of their tenant
+	userID := claims.UserID.String()
+	if req.UserID != nil && *req.UserID != userID {
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot register on behalf of another user"})
+			return
+		}
+		var role string
+		err := h.DB.QueryRow(ctx,
+			`SELECT role FROM tenant_users WHERE tenant_id = $1 AND user_id = $2`,
+			tenantID, *req.UserID).Scan(&role)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "resident not found"})
+			return
+		}
+		userID = *req.UserID
+	}
 
 	// Verify program exists and belongs to this tenant
 	var programTitle string
 	err := h.DB.QueryRow(ctx,
 		`SELECT title FROM programs WHERE id = $1 AND tenant_id = $2`,
-		req.ProgramID, claims.TenantID.String()).Scan(&programTitle)
+		req.ProgramID, tenantID).Scan(&programTitle)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
 		return
 	}
 
-	// Check if user is already registered
+	// Lottery programs take entries during their window instead of places.
+	// Staff may add entries until the draw.
+	lot, err := loadLottery(ctx, h.DB, req.ProgramID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load lottery"})
+		return
+	}
+	lotteryPhase := ""
+	if lot != nil {
+		lotteryPhase = lot.Phase(time.Now())
+	}
+	if !isAdmin {
+		switch lotteryPhase {
+		case LotteryUpcoming:
+			c.JSON(http.StatusForbidden, gin.H{"error": "lottery entries open at " + lot.OpensAt.Format(time.RFC3339)})
+			return
+		case LotteryClosed:
+			c.JSON(http.StatusForbidden, gin.H{"error": "lottery entries have closed; the draw has not run yet"})
+			return
+		}
+	}
+	entering := lotteryPhase != "" && lotteryPhase != LotteryDrawn
+
+	// Enforce the season's registration windows; staff registering on a
+	// resident's behalf are exempt
+	if !isAdmin {
+		message, err := h.checkRegistrationWindow(ctx, h.DB, tenantID, req.ProgramID, userID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check registration window"})
+			return
+		}
+		if message != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": message})
+			return
+		}
+	}
+
+	// Check if this participant is already registered
 	var existingID string
 	err = h.DB.QueryRow(ctx,
-		`SELECT id FROM program_registrations WHERE program_id = $1 AND user_id = $2`,
-		req.ProgramID, claims.UserID.String()).Scan(&existingID)
+		`SELECT id FROM program_registrations
+		 WHERE program_id = $1 AND user_id = $2 AND lower(participant_name) = lower($3) AND status <> 'cancelled'`,
+		req.ProgramID, userID, req.ParticipantName).Scan(&existingID)
 	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "you are already registered for this program"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this participant is already registered for this program"})
 		return
 	}
 
+	// Evaluate eligibility rules
+	violations, err := h.checkEligibility(ctx, h.DB, tenantID, req.ProgramID, userID, EligibilityApplicant{
+		DateOfBirth: dob,
+		Age:         req.ParticipantAge,
+		Gender:      req.ParticipantGender,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate eligibility"})
+		return
+	}
+	overridden := len(violations) > 0 && req.OverrideEligibility
+	if len(violations) > 0 && !overridden {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "participant is not eligible for this program",
+			"reasons": violations,
+		})
+		return
+	}
+	if overridden && strings.TrimSpace(req.OverrideReason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "override_reason is required when overriding eligibility"})
+		return
+	}
+
+	// Validate custom form answers
+	fields, err := h.loadProgramForm(ctx, h.DB, tenantID, req.ProgramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load registration form"})
+		return
+	}
+	answers, fieldErrs := validateFormAnswers(fields, req.Answers)
+	if len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form answers", "fields": fieldErrs})
+		return
+	}
+	answersJSON, _ := json.Marshal(answers)
+
+	// Required waivers must be signed up front. Staff registering on a
+	// resident's behalf may leave them for the resident to sign later.
+	required, err := requiredWaivers(ctx, h.DB, tenantID, WaiverForProgram, req.ProgramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load waivers"})
+		return
+	}
+	onBehalf := userID != claims.UserID.String()
+	var signedWaivers []acceptedWaiver
+	if !onBehalf || len(req.Waivers) > 0 {
+		var issues []WaiverIssue
+		signedWaivers, issues = matchWaiverSignatures(required, req.Waivers)
+		if len(issues) > 0 {
+			respondWaiverIssues(c, issues)
+			return
+		}
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Price the registration inside the transaction so sibling counts and
+	// coupon redemptions are consistent with the insert
+	quote, coupon, award, err := h.quoteRegistration(ctx, tx, tenantID, req.ProgramID, userID, req.CouponCode, time.Now())
+	var pe pricingError
+	if errors.As(err, &pe) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pe.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute price"})
+		return
+	}
+	var couponCode *string
+	if coupon != nil {
+		if err := redeemCoupon(ctx, tx, coupon.ID); err != nil {
+			if errors.As(err, &pe) {
+				c.JSON(http.StatusConflict, gin.H{"error": pe.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to redeem coupon"})
+			return
+		}
+		couponCode = &coupon.Code
+	}
+	quoteJSON, _ := json.Marshal(quote)
+
+	// A full program waitlists new registrations. The program row stays
+	// locked so concurrent registrations cannot overfill it.
+	var capacity *int
+	var enrolled int
+	err = tx.QueryRow(ctx,
+		`SELECT capacity, enrolled_count FROM programs WHERE id = $1 FOR UPDATE`,
+		req.ProgramID).Scan(&capacity, &enrolled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	status := "pending"
+	if entering {
+		status = "entered"
+	} else if capacity != nil && enrolled >= *capacity {
+		status = "waitlisted"
+	}
+
 	// Create registration
 	registrationID := uuid.New()
-	_, err = h.DB.Exec(ctx,
+	_, err = tx.Exec(ctx,
 		`INSERT INTO program_registrations (
 			id, tenant_id, program_id, user_id,
-			participant_name, participant_age,
-			emergency_contact_name, emergency_contact_phone, notes
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-		registrationID, claims.TenantID, req.ProgramID, claims.UserID,
-		req.ParticipantName, req.ParticipantAge,
-		req.EmergencyContactName, req.EmergencyContactPhone, req.Notes)
+			participant_name, participant_age, participant_date_of_birth, participant_gender,
+			emergency_contact_name, emergency_contact_phone, notes, answers,
+			price_cents, price_quote, coupon_code, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		registrationID, claims.TenantID, req.ProgramID, userID,
+		req.ParticipantName, req.ParticipantAge, dob, nullIfEmpty(req.ParticipantGender),
+		req.EmergencyContactName, req.EmergencyContactPhone, req.Notes, answersJSON,
+		quote.TotalCents, quoteJSON, couponCode, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create registration"})
 		return
 	}
+	if err := recordScholarship(ctx, tx, tenantID, award, registrationID, quote); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply scholarship"})
+		return
+	}
+	if status == "pending" {
+		_, err = tx.Exec(ctx,
+			`UPDATE programs SET enrolled_count = enrolled_count + 1 WHERE id = $1`,
+			req.ProgramID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create registration"})
+			return
+		}
+	}
+
+	var email string
+	emailErr := tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email)
+
+	if len(signedWaivers) > 0 {
+		signer := signerFromRequest(c, tenantID)
+		signer.UserID = &userID
+		signer.Email = email
+		signer.Participant = req.ParticipantName
+		signer.Purpose = "Registration for " + programTitle
+		if _, err := recordWaiverSignatures(ctx, tx, signer, "program_registration", registrationID, signedWaivers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record waiver signatures"})
+			return
+		}
+	}
+
+	if emailErr == nil {
+		subject, body := mail.RegistrationStatusEmail(programTitle, req.ParticipantName, status)
+		if err := enqueueEmail(ctx, tx, tenantID, email, subject, body, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue notification"})
+			return
+		}
+	}
+
+	// Bill the household; lottery entries are billed when an offer is accepted
+	if quote.TotalCents > 0 && status != "entered" {
+		subject := &checkoutSubject{
+			TenantID:    tenantID,
+			Type:        "program_registration",
+			ID:          registrationID.String(),
+			UserID:      &userID,
+			AmountCents: quote.TotalCents,
+			Description: fmt.Sprintf("%s: %s", programTitle, req.ParticipantName),
+		}
+		if err := ensureCharge(ctx, tx, subject); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record charge"})
+			return
+		}
+	}
+
+	if overridden {
+		err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID,
+			"eligibility_override", "program_registration", &registrationID,
+			gin.H{"violations": violations},
+			gin.H{"program_id": req.ProgramID, "user_id": userID, "reason": req.OverrideReason})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record override"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
 
-	// TODO: Send email notification to admin
+	message := "registration submitted successfully"
+	switch status {
+	case "waitlisted":
+		message = "program is full; participant added to the waitlist"
+	case "entered":
+		message = "lottery entry submitted"
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":      registrationID.String(),
-		"message": "registration submitted successfully",
+		"status":  status,
+		"message": message,
+		"price":   quote,
 	})
 }
 
-// ListProgramRegistrations returns all program registrations for the tenant
+// nullIfEmpty maps "" to NULL for optional text columns
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ListProgramRegistrations returns the program registrations the caller may see
 func (h *Handler) ListProgramRegistrations(c *gin.Context) {
 	claims := middleware.GetClaimsFromContext(c)
 	if claims == nil {
@@ -94,19 +380,39 @@ func (h *Handler) ListProgramRegistrations(c *gin.Context) {
 
 	ctx := context.Background()
 
+	// Residents only see their own registrations; staff only those for the
+	// programs they teach
+	ownerID := ""
+	if claims.Role == "RESIDENT" {
+		ownerID = claims.UserID.String()
+	}
+	var programIDs []string
+	if claims.Role == "STAFF" {
+		var err error
+		programIDs, err = staffProgramIDs(ctx, h.DB, claims.TenantID.String(), claims.UserID.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	}
+
 	rows, err := h.DB.Query(ctx,
 		`SELECT
 			pr.id, pr.program_id, p.title as program_title,
 			pr.user_id, u.email as user_email,
 			pr.participant_name, pr.participant_age,
 			pr.emergency_contact_name, pr.emergency_contact_phone,
-			pr.notes, pr.status, pr.registered_at
+			pr.notes, pr.status, pr.registered_at, pr.answers,
+			pr.price_cents, pr.coupon_code
 		FROM program_registrations pr
 		JOIN programs p ON pr.program_id = p.id
 		JOIN users u ON pr.user_id = u.id
 		WHERE pr.tenant_id = $1
+		AND ($2 = '' OR pr.program_id::text = $2)
+		AND ($3::text[] IS NULL OR pr.program_id::text = ANY($3))
+		AND ($4 = '' OR pr.user_id::text = $4)
 		ORDER BY pr.registered_at DESC`,
-		claims.TenantID.String())
+		claims.TenantID.String(), c.Query("program_id"), programIDs, ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
@@ -117,33 +423,42 @@ func (h *Handler) ListProgramRegistrations(c *gin.Context) {
 	for rows.Next() {
 		var (
 			id, programID, programTitle, userID, userEmail string
-			participantName, emergencyContactName, emergencyContactPhone, notes, status string
-			registeredAt string
-			participantAge *int
+			participantName, status                        string
+			emergencyContactName, emergencyContactPhone    *string
+			notes                                          *string
+			registeredAt                                   time.Time
+			participantAge                                 *int
+			answers                                        json.RawMessage
+			priceCents                                     int
+			couponCode                                     *string
 		)
 
 		if err := rows.Scan(
 			&id, &programID, &programTitle, &userID, &userEmail,
 			&participantName, &participantAge,
 			&emergencyContactName, &emergencyContactPhone,
-			&notes, &status, &registeredAt,
+			&notes, &status, &registeredAt, &answers,
+			&priceCents, &couponCode,
 		); err != nil {
 			continue
 		}
 
 		registrations = append(registrations, map[string]interface{}{
-			"id":                        id,
-			"program_id":                programID,
-			"program_title":             programTitle,
-			"user_id":                   userID,
-			"user_email":                userEmail,
-			"participant_name":          participantName,
-			"participant_age":           participantAge,
-			"emergency_contact_name":    emergencyContactName,
-			"emergency_contact_phone":   emergencyContactPhone,
-			"notes":                     notes,
-			"status":                    status,
-			"registered_at":             registeredAt,
+			"id":                      id,
+			"program_id":              programID,
+			"program_title":           programTitle,
+			"user_id":                 userID,
+			"user_email":              userEmail,
+			"participant_name":        participantName,
+			"participant_age":         participantAge,
+			"emergency_contact_name":  emergencyContactName,
+			"emergency_contact_phone": emergencyContactPhone,
+			"notes":                   notes,
+			"status":                  status,
+			"registered_at":           registeredAt,
+			"answers":                 answers,
+			"price_cents":             priceCents,
+			"coupon_code":             couponCode,
 		})
 	}
 
@@ -154,7 +469,8 @@ func (h *Handler) ListProgramRegistrations(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"registrations": registrations})
 }
 
-// UpdateProgramRegistrationStatus allows admins to approve/reject registrations
+// UpdateProgramRegistrationStatus moves a registration through the
+// registration state machine
 func (h *Handler) UpdateProgramRegistrationStatus(c *gin.Context) {
 	claims := middleware.GetClaimsFromContext(c)
 	if claims == nil {
@@ -171,6 +487,7 @@ func (h *Handler) UpdateProgramRegistrationStatus(c *gin.Context) {
 	registrationID := c.Param("id")
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -179,30 +496,23 @@ func (h *Handler) UpdateProgramRegistrationStatus(c *gin.Context) {
 
 	ctx := context.Background()
 
-	// Verify registration belongs to tenant
-	var tenantID string
-	err := h.DB.QueryRow(ctx,
-		`SELECT tenant_id FROM program_registrations WHERE id = $1`,
-		registrationID).Scan(&tenantID)
+	tx, err := h.DB.Begin(ctx)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
-		return
-	}
-	if tenantID != claims.TenantID.String() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	// Update status
-	_, err = h.DB.Exec(ctx,
-		`UPDATE program_registrations SET status = $1, updated_at = now() WHERE id = $2`,
-		req.Status, registrationID)
+	t, err := h.transitionRegistration(ctx, tx, claims.TenantID, claims.UserID, registrationID, req.Status, req.Reason)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+		respondTransitionError(c, err, "failed to update status")
 		return
 	}
 
-	// TODO: Send email notification to user
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"success": true, "transition": t})
 }
@@ -0,0 +1,826 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Program Schedules ============
+
+type ScheduleRequest struct {
+	FacilityID     *string  `json:"facility_id"`
+	Weekdays       []int    `json:"weekdays" binding:"required"`
+	StartTime      string   `json:"start_time" binding:"required"`
+	EndTime        string   `json:"end_time" binding:"required"`
+	StartsOn       string   `json:"starts_on" binding:"required"`
+	EndsOn         string   `json:"ends_on" binding:"required"`
+	IntervalWeeks  int      `json:"interval_weeks"`
+	ExceptionDates []string `json:"exception_dates"`
+	LocationNote   *string  `json:"location_note"`
+}
+
+// programSchedule is a stored weekly meeting pattern
+type programSchedule struct {
+	ID           string
+	ProgramID    string
+	FacilityID   *string
+	FacilityName *string
+	LocationNote *string
+	Pattern      schedule.Weekly
+}
+
+// SlotConflict explains why a time range at a facility cannot be reserved
+type SlotConflict struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	SlotID   string    `json:"slot_id"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason"`
+}
+
+// sessionSync reports what syncing a schedule's sessions changed
+type sessionSync struct {
+	Created   int            `json:"created"`
+	Removed   int            `json:"removed"`
+	Conflicts []SlotConflict `json:"conflicts"`
+}
+
+// patternFromRequest validates a schedule request
+func patternFromRequest(req ScheduleRequest) (schedule.Weekly, error) {
+	var w schedule.Weekly
+	var err error
+
+	for _, d := range req.Weekdays {
+		w.Weekdays = append(w.Weekdays, time.Weekday(d))
+	}
+	if w.StartTime, err = schedule.ParseClock(req.StartTime); err != nil {
+		return w, err
+	}
+	if w.EndTime, err = schedule.ParseClock(req.EndTime); err != nil {
+		return w, err
+	}
+	if w.StartsOn, err = time.Parse("2006-01-02", req.StartsOn); err != nil {
+		return w, fmt.Errorf("starts_on must be YYYY-MM-DD")
+	}
+	if w.EndsOn, err = time.Parse("2006-01-02", req.EndsOn); err != nil {
+		return w, fmt.Errorf("ends_on must be YYYY-MM-DD")
+	}
+	for _, d := range req.ExceptionDates {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return w, fmt.Errorf("exception_dates must be YYYY-MM-DD")
+		}
+		w.Exceptions = append(w.Exceptions, t)
+	}
+	w.IntervalWeeks = req.IntervalWeeks
+	if w.IntervalWeeks == 0 {
+		w.IntervalWeeks = 1
+	}
+	return w, w.Validate()
+}
+
+// scheduleColumns is the select list scanned by scanSchedule
+const scheduleColumns = `ps.id::text, ps.program_id::text, ps.facility_id::text, f.name, ps.location_note, ps.weekdays,
+	to_char(ps.start_time, 'HH24:MI'), to_char(ps.end_time, 'HH24:MI'), ps.starts_on, ps.ends_on,
+	ps.interval_weeks, ps.exception_dates`
+
+func scanSchedule(row pgx.Row) (*programSchedule, error) {
+	var s programSchedule
+	var weekdays []int32
+	var startTime, endTime string
+	err := row.Scan(&s.ID, &s.ProgramID, &s.FacilityID, &s.FacilityName, &s.LocationNote, &weekdays,
+		&startTime, &endTime, &s.Pattern.StartsOn, &s.Pattern.EndsOn,
+		&s.Pattern.IntervalWeeks, &s.Pattern.Exceptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range weekdays {
+		s.Pattern.Weekdays = append(s.Pattern.Weekdays, time.Weekday(d))
+	}
+	s.Pattern.StartTime, _ = schedule.ParseClock(startTime)
+	s.Pattern.EndTime, _ = schedule.ParseClock(endTime)
+	return &s, nil
+}
+
+func (h *Handler) loadProgramSchedules(ctx context.Context, q dbtx, tenantID, programID string) ([]*programSchedule, error) {
+	rows, err := q.Query(ctx,
+		`SELECT `+scheduleColumns+`
+		 FROM program_schedules ps
+		 LEFT JOIN facilities f ON ps.facility_id = f.id
+		 WHERE ps.tenant_id = $1 AND ps.program_id = $2
+		 ORDER BY ps.starts_on, ps.start_time`,
+		tenantID, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*programSchedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (h *Handler) loadSchedule(ctx context.Context, q dbtx, tenantID, programID, scheduleID string) (*programSchedule, error) {
+	return scanSchedule(q.QueryRow(ctx,
+		`SELECT `+scheduleColumns+`
+		 FROM program_schedules ps
+		 LEFT JOIN facilities f ON ps.facility_id = f.id
+		 WHERE ps.id = $1 AND ps.tenant_id = $2 AND ps.program_id = $3`,
+		scheduleID, tenantID, programID))
+}
+
+func scheduleResponse(s *programSchedule) gin.H {
+	weekdays := make([]int, len(s.Pattern.Weekdays))
+	for i, d := range s.Pattern.Weekdays {
+		weekdays[i] = int(d)
+	}
+	exceptions := make([]string, len(s.Pattern.Exceptions))
+	for i, d := range s.Pattern.Exceptions {
+		exceptions[i] = d.Format("2006-01-02")
+	}
+	summary := s.Pattern.Summary()
+	if s.FacilityName != nil {
+		summary += " at " + *s.FacilityName
+	}
+	return gin.H{
+		"id":              s.ID,
+		"facility_id":     s.FacilityID,
+		"facility_name":   s.FacilityName,
+		"location_note":   s.LocationNote,
+		"weekdays":        weekdays,
+		"start_time":      s.Pattern.StartTime.String(),
+		"end_time":        s.Pattern.EndTime.String(),
+		"starts_on":       s.Pattern.StartsOn.Format("2006-01-02"),
+		"ends_on":         s.Pattern.EndsOn.Format("2006-01-02"),
+		"interval_weeks":  s.Pattern.IntervalWeeks,
+		"exception_dates": exceptions,
+		"summary":         summary,
+	}
+}
+
+// slotConflictReason explains why an overlapping slot is in the way
+func slotConflictReason(status string, booked bool) string {
+	switch {
+	case status == "reserved":
+		return "reserved for a program session"
+	case status == "held":
+		return "held for a booking in checkout"
+	case status == "booked" || booked:
+		return "booked"
+	}
+	return "overlaps an open rental slot"
+}
+
+// inspectFacilityRange looks for slots overlapping a range at a facility. An
+// unbooked open slot with exactly the same times can be taken over; anything
+// else overlapping is a conflict. Overlapping slots are locked.
+func inspectFacilityRange(ctx context.Context, q dbtx, facilityID string, start, end time.Time) (*string, *SlotConflict, error) {
+	rows, err := q.Query(ctx,
+		`SELECT fs.id::text, fs.starts_at, fs.ends_at, fs.status,
+		        EXISTS(SELECT 1 FROM bookings b
+		               WHERE b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		                 AND b.status NOT IN ('declined', 'cancelled'))
+		 FROM facility_slots fs
+		 WHERE fs.facility_id = $1 AND fs.starts_at < $3 AND fs.ends_at > $2
+		 ORDER BY fs.starts_at
+		 FOR UPDATE OF fs`,
+		facilityID, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var reuse *string
+	var conflict *SlotConflict
+	for rows.Next() {
+		var id, status string
+		var slotStart, slotEnd time.Time
+		var booked bool
+		if err := rows.Scan(&id, &slotStart, &slotEnd, &status, &booked); err != nil {
+			return nil, nil, err
+		}
+		exact := slotStart.Equal(start) && slotEnd.Equal(end)
+		if exact && status == "open" && !booked && reuse == nil {
+			reuse = &id
+			continue
+		}
+		if conflict != nil {
+			continue
+		}
+		conflict = &SlotConflict{StartsAt: start, EndsAt: end, SlotID: id, Status: status, Reason: slotConflictReason(status, booked)}
+	}
+	if conflict != nil {
+		return nil, conflict, rows.Err()
+	}
+	return reuse, nil, rows.Err()
+}
+
+// reserveFacilityRange reserves a facility for a program session, taking
+// over a matching open slot or creating a new one
+func reserveFacilityRange(ctx context.Context, q dbtx, facilityID string, start, end time.Time) (string, bool, *SlotConflict, error) {
+	reuse, conflict, err := inspectFacilityRange(ctx, q, facilityID, start, end)
+	if err != nil || conflict != nil {
+		return "", false, conflict, err
+	}
+
+	if reuse != nil {
+		_, err = q.Exec(ctx,
+			`UPDATE facility_slots SET status = 'reserved', updated_at = now() WHERE id = $1`,
+			*reuse)
+		return *reuse, false, nil, err
+	}
+
+	slotID := uuid.New().String()
+	_, err = q.Exec(ctx,
+		`INSERT INTO facility_slots (id, facility_id, starts_at, ends_at, status)
+		 VALUES ($1, $2, $3, $4, 'reserved')`,
+		slotID, facilityID, start, end)
+	return slotID, true, nil, err
+}
+
+// releaseSessionSlot frees the facility slot a session held
+func releaseSessionSlot(ctx context.Context, q dbtx, slotID *string, ownsSlot bool) error {
+	if slotID == nil {
+		return nil
+	}
+	if ownsSlot {
+		_, err := q.Exec(ctx, `DELETE FROM facility_slots WHERE id = $1 AND status = 'reserved'`, *slotID)
+		return err
+	}
+	_, err := q.Exec(ctx,
+		`UPDATE facility_slots SET status = 'open', updated_at = now() WHERE id = $1 AND status = 'reserved'`,
+		*slotID)
+	return err
+}
+
+// syncScheduleSessions makes a schedule's future sessions match its pattern.
+// Sessions no longer in the pattern are removed and their slots released;
+// new occurrences reserve facility slots. Occurrences that would double-book
+// the facility are skipped and reported. Past sessions are never touched.
+func (h *Handler) syncScheduleSessions(ctx context.Context, q dbtx, tenantID string, s *programSchedule, now time.Time) (*sessionSync, error) {
+	result := &sessionSync{Conflicts: []SlotConflict{}}
+	loc := h.tenantLocation(ctx, q, tenantID)
+
+	if s.FacilityID != nil {
+		// Serialise reservations per facility
+		if _, err := q.Exec(ctx, `SELECT 1 FROM facilities WHERE id = $1 FOR UPDATE`, *s.FacilityID); err != nil {
+			return nil, err
+		}
+	}
+
+	desired := map[int64]schedule.Occurrence{}
+	for _, o := range s.Pattern.Occurrences(loc) {
+		if o.StartsAt.After(now) {
+			desired[o.StartsAt.Unix()] = o
+		}
+	}
+
+	type existingSession struct {
+		id         string
+		startsAt   time.Time
+		endsAt     time.Time
+		facilityID *string
+		slotID     *string
+		ownsSlot   bool
+	}
+	rows, err := q.Query(ctx,
+		`SELECT id::text, starts_at, ends_at, facility_id::text, facility_slot_id::text, owns_slot
+		 FROM program_sessions
+		 WHERE schedule_id = $1 AND starts_at > $2`,
+		s.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	var existing []existingSession
+	for rows.Next() {
+		var e existingSession
+		if err := rows.Scan(&e.id, &e.startsAt, &e.endsAt, &e.facilityID, &e.slotID, &e.ownsSlot); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing = append(existing, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sameFacility := func(a, b *string) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	for _, e := range existing {
+		o, ok := desired[e.startsAt.Unix()]
+		if ok && o.EndsAt.Equal(e.endsAt) && sameFacility(e.facilityID, s.FacilityID) {
+			delete(desired, e.startsAt.Unix())
+			continue
+		}
+		if err := releaseSessionSlot(ctx, q, e.slotID, e.ownsSlot); err != nil {
+			return nil, err
+		}
+		if _, err := q.Exec(ctx, `DELETE FROM program_sessions WHERE id = $1`, e.id); err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	occurrences := make([]schedule.Occurrence, 0, len(desired))
+	for _, o := range s.Pattern.Occurrences(loc) {
+		if _, ok := desired[o.StartsAt.Unix()]; ok {
+			occurrences = append(occurrences, o)
+		}
+	}
+
+	for _, o := range occurrences {
+		var slotID *string
+		ownsSlot := true
+		if s.FacilityID != nil {
+			id, owns, conflict, err := reserveFacilityRange(ctx, q, *s.FacilityID, o.StartsAt, o.EndsAt)
+			if err != nil {
+				return nil, err
+			}
+			if conflict != nil {
+				result.Conflicts = append(result.Conflicts, *conflict)
+				continue
+			}
+			slotID, ownsSlot = &id, owns
+		}
+
+		_, err := q.Exec(ctx,
+			`INSERT INTO program_sessions (tenant_id, program_id, schedule_id, facility_id, facility_slot_id, owns_slot, starts_at, ends_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			tenantID, s.ProgramID, s.ID, s.FacilityID, slotID, ownsSlot, o.StartsAt, o.EndsAt)
+		if err != nil {
+			return nil, err
+		}
+		result.Created++
+	}
+
+	return result, nil
+}
+
+// programTenant verifies the program exists and belongs to the tenant,
+// writing the error response if not
+func (h *Handler) programTenant(ctx context.Context, c *gin.Context, programID, tenantID string) bool {
+	var owner string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM programs WHERE id = $1`, programID).Scan(&owner)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return false
+	}
+	if owner != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) ListProgramSchedules(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	schedules, err := h.loadProgramSchedules(ctx, h.DB, tenantID, programID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	list := []gin.H{}
+	for _, s := range schedules {
+		list = append(list, scheduleResponse(s))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": list})
+}
+
+// saveSchedule inserts or updates a schedule and syncs its sessions in one
+// transaction. With dryRun the changes are rolled back but still reported.
+func (h *Handler) saveSchedule(c *gin.Context, tenantID, programID, scheduleID string, req *ScheduleRequest, dryRun bool) {
+	ctx := context.Background()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	status := http.StatusOK
+	if req != nil {
+		pattern, err := patternFromRequest(*req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.FacilityID != nil && *req.FacilityID == "" {
+			req.FacilityID = nil
+		}
+		if req.FacilityID != nil {
+			var facilityTenant string
+			err := tx.QueryRow(ctx, `SELECT tenant_id FROM facilities WHERE id = $1`, *req.FacilityID).Scan(&facilityTenant)
+			if err != nil || facilityTenant != tenantID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "facility not found"})
+				return
+			}
+		}
+
+		weekdays := make([]int32, len(pattern.Weekdays))
+		for i, d := range pattern.Weekdays {
+			weekdays[i] = int32(d)
+		}
+		exceptions := pattern.Exceptions
+		if exceptions == nil {
+			exceptions = []time.Time{}
+		}
+
+		if scheduleID == "" {
+			scheduleID = uuid.New().String()
+			status = http.StatusCreated
+			_, err = tx.Exec(ctx,
+				`INSERT INTO program_schedules (id, tenant_id, program_id, facility_id, weekdays, start_time, end_time,
+				                                starts_on, ends_on, interval_weeks, exception_dates, location_note)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+				scheduleID, tenantID, programID, req.FacilityID, weekdays, pattern.StartTime.String(), pattern.EndTime.String(),
+				pattern.StartsOn, pattern.EndsOn, pattern.IntervalWeeks, exceptions, req.LocationNote)
+		} else {
+			var result pgconn.CommandTag
+			result, err = tx.Exec(ctx,
+				`UPDATE program_schedules SET facility_id = $1, weekdays = $2, start_time = $3, end_time = $4,
+				        starts_on = $5, ends_on = $6, interval_weeks = $7, exception_dates = $8, location_note = $9,
+				        updated_at = now()
+				 WHERE id = $10 AND tenant_id = $11 AND program_id = $12`,
+				req.FacilityID, weekdays, pattern.StartTime.String(), pattern.EndTime.String(),
+				pattern.StartsOn, pattern.EndsOn, pattern.IntervalWeeks, exceptions, req.LocationNote,
+				scheduleID, tenantID, programID)
+			if err == nil && result.RowsAffected() == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+				return
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save schedule"})
+			return
+		}
+	}
+
+	s, err := h.loadSchedule(ctx, tx, tenantID, programID, scheduleID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	result, err := h.syncScheduleSessions(ctx, tx, tenantID, s, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate sessions"})
+		return
+	}
+
+	if !dryRun {
+		if err := tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"schedule": scheduleResponse(s),
+		"sessions": result,
+		"dry_run":  dryRun,
+	})
+}
+
+func (h *Handler) CreateProgramSchedule(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(context.Background(), c, programID, tenantID) {
+		return
+	}
+
+	h.saveSchedule(c, tenantID, programID, "", &req, c.Query("dry_run") == "true")
+}
+
+func (h *Handler) UpdateProgramSchedule(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(context.Background(), c, programID, tenantID) {
+		return
+	}
+
+	h.saveSchedule(c, tenantID, programID, c.Param("schedule_id"), &req, c.Query("dry_run") == "true")
+}
+
+// GenerateProgramSessions re-runs session generation for a schedule, e.g.
+// after a conflicting rental was cancelled. Pass ?dry_run=true to preview.
+func (h *Handler) GenerateProgramSessions(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(context.Background(), c, programID, tenantID) {
+		return
+	}
+
+	h.saveSchedule(c, tenantID, programID, c.Param("schedule_id"), nil, c.Query("dry_run") == "true")
+}
+
+func (h *Handler) DeleteProgramSchedule(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	scheduleID := c.Param("schedule_id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := h.loadSchedule(ctx, tx, tenantID, programID, scheduleID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+
+	// Release future sessions; past sessions stay for attendance history
+	rows, err := tx.Query(ctx,
+		`DELETE FROM program_sessions WHERE schedule_id = $1 AND starts_at > now()
+		 RETURNING facility_slot_id::text, owns_slot`,
+		scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	type released struct {
+		slotID *string
+		owns   bool
+	}
+	var slots []released
+	for rows.Next() {
+		var r released
+		if err := rows.Scan(&r.slotID, &r.owns); err == nil {
+			slots = append(slots, r)
+		}
+	}
+	rows.Close()
+	for _, r := range slots {
+		if err := releaseSessionSlot(ctx, tx, r.slotID, r.owns); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM program_schedules WHERE id = $1`, scheduleID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// loadProgramSessions returns a program's sessions in a time range
+func (h *Handler) loadProgramSessions(ctx context.Context, tenantID, programID string, from, to time.Time, limit int) ([]gin.H, error) {
+	rows, err := h.DB.Query(ctx,
+		`SELECT s.id::text, s.schedule_id::text, s.facility_id::text, f.name, s.facility_slot_id::text, s.starts_at, s.ends_at, s.status
+		 FROM program_sessions s
+		 LEFT JOIN facilities f ON s.facility_id = f.id
+		 WHERE s.tenant_id = $1 AND s.program_id = $2 AND s.starts_at >= $3 AND s.starts_at < $4
+		 ORDER BY s.starts_at
+		 LIMIT $5`,
+		tenantID, programID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []gin.H{}
+	for rows.Next() {
+		var id, status string
+		var scheduleID, facilityID, facilityName, slotID *string
+		var startsAt, endsAt time.Time
+		if err := rows.Scan(&id, &scheduleID, &facilityID, &facilityName, &slotID, &startsAt, &endsAt, &status); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, gin.H{
+			"id":               id,
+			"schedule_id":      scheduleID,
+			"facility_id":      facilityID,
+			"facility_name":    facilityName,
+			"facility_slot_id": slotID,
+			"starts_at":        startsAt,
+			"ends_at":          endsAt,
+			"status":           status,
+		})
+	}
+	return sessions, rows.Err()
+}
+
+func (h *Handler) ListProgramSessions(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	programID := c.Param("id")
+	if !h.programTenant(ctx, c, programID, tenantID) {
+		return
+	}
+
+	from := time.Now().AddDate(-1, 0, 0)
+	to := time.Now().AddDate(2, 0, 0)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339"})
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339"})
+			return
+		}
+		to = t
+	}
+
+	sessions, err := h.loadProgramSessions(ctx, tenantID, programID, from, to, 1000)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// GetPublicProgram returns a program's public detail with its meeting
+// schedule and upcoming sessions
+func (h *Handler) GetPublicProgram(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	var id, title string
+	var description, season, seasonID, category, startDate, endDate, imageURL, slug *string
+	var priceCents int
+	err = h.DB.QueryRow(ctx,
+		`SELECT id::text, title, description, season, season_id::text, category, start_date::text, end_date::text,
+		        price_cents, image_url, slug
+		 FROM programs
+		 WHERE tenant_id = $1 AND status = 'active' AND (id::text = $2 OR slug = $2)`,
+		tenantID, c.Param("id")).Scan(&id, &title, &description, &season, &seasonID, &category, &startDate, &endDate,
+		&priceCents, &imageURL, &slug)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+		return
+	}
+
+	schedules, err := h.loadProgramSchedules(ctx, h.DB, tenantID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	scheduleList := []gin.H{}
+	for _, s := range schedules {
+		scheduleList = append(scheduleList, scheduleResponse(s))
+	}
+
+	sessions, err := h.loadProgramSessions(ctx, tenantID, id, time.Now(), time.Now().AddDate(1, 0, 0), 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	// Slot ids are internal
+	for _, s := range sessions {
+		delete(s, "facility_slot_id")
+	}
+
+	var lotteryInfo gin.H
+	lot, err := loadLottery(ctx, h.DB, id, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if lot != nil {
+		lotteryInfo = gin.H{"phase": lot.Phase(time.Now()), "opens_at": lot.OpensAt, "closes_at": lot.ClosesAt, "offer_hours": lot.OfferHours}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          id,
+		"title":       title,
+		"description": description,
+		"season":      season,
+		"season_id":   seasonID,
+		"category":    category,
+		"start_date":  startDate,
+		"end_date":    endDate,
+		"price_cents": priceCents,
+		"image_url":   imageURL,
+		"slug":        slug,
+		"schedules":   scheduleList,
+		"sessions":    sessions,
+		"lottery":     lotteryInfo,
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,11 +14,13 @@ import (
 )
 
 type PublicRegisterRequest struct {
-	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Phone     string `json:"phone"`
+	Email         string `json:"email" binding:"required,email"`
+	Password      string `json:"password" binding:"required,min=8"`
+	FirstName     string `json:"first_name" binding:"required"`
+	LastName      string `json:"last_name" binding:"required"`
+	Phone         string `json:"phone"`
+	StreetAddress string `json:"street_address"`
+	ZipCode       string `json:"zip_code"`
 }
 
 type PublicLoginRequest struct {
@@ -98,9 +101,9 @@ func (h *Handler) PublicRegister(c *gin.Context) {
 	// Create new user
 	userID := uuid.New()
 	_, err = h.DB.Exec(ctx,
-		`INSERT INTO users (id, email, password_hash, first_name, last_name, phone)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		userID, req.Email, passwordHash, req.FirstName, req.LastName, req.Phone)
+		`INSERT INTO users (id, email, password_hash, first_name, last_name, phone, street_address, zip_code)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		userID, req.Email, passwordHash, req.FirstName, req.LastName, req.Phone, req.StreetAddress, req.ZipCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
@@ -209,6 +212,55 @@ func (h *Handler) PublicLogin(c *gin.Context) {
 	})
 }
 
+type ProfileUpdateRequest struct {
+	FirstName     *string `json:"first_name"`
+	LastName      *string `json:"last_name"`
+	Phone         *string `json:"phone"`
+	StreetAddress *string `json:"street_address"`
+	ZipCode       *string `json:"zip_code"`
+	DateOfBirth   *string `json:"date_of_birth"`
+}
+
+// UpdateMyProfile lets a signed-in user update their contact and address details
+func (h *Handler) UpdateMyProfile(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DateOfBirth != nil {
+		if _, err := time.Parse("2006-01-02", *req.DateOfBirth); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date_of_birth must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	_, err := h.DB.Exec(ctx,
+		`UPDATE users SET
+			first_name = COALESCE($1, first_name),
+			last_name = COALESCE($2, last_name),
+			phone = COALESCE($3, phone),
+			street_address = COALESCE($4, street_address),
+			zip_code = COALESCE($5, zip_code),
+			date_of_birth = COALESCE($6::date, date_of_birth),
+			updated_at = now()
+		 WHERE id = $7`,
+		req.FirstName, req.LastName, req.Phone, req.StreetAddress, req.ZipCode, req.DateOfBirth, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
 // Helper function to generate JWT token
 func (h *Handler) generateToken(userID, tenantID, email, role string) (string, error) {
 	userUUID, _ := uuid.Parse(userID)
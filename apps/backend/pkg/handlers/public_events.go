@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Public Events ============
+
+const (
+	publicEventsPageSize    = 20
+	publicEventsMaxPageSize = 100
+	// publicEventsDays is the default date range; publicEventsMaxDays is
+	// the longest allowed
+	publicEventsDays    = 90
+	publicEventsMaxDays = 366
+	// publicEventOccurrences is how many upcoming dates an event's page lists
+	publicEventOccurrences = 50
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeEventCursor returns the cursor for the page after o
+func encodeEventCursor(o EventOccurrence) string {
+	raw := fmt.Sprintf("%d|%s", o.StartsAt.UnixNano(), o.EventID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeEventCursor(s string) (*eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	nanos, eventID, ok := strings.Cut(string(raw), "|")
+	if !ok || eventID == "" {
+		return nil, errInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &eventCursor{StartsAt: time.Unix(0, n), EventID: eventID}, nil
+}
+
+// parseEventRangeBound reads a from or to parameter: a date in the
+// tenant's zone or a time. With endOfDay a date means the end of that day,
+// so to=2025-06-30 includes events on the 30th.
+func parseEventRangeBound(value string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if d, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		if endOfDay {
+			return d.AddDate(0, 0, 1), nil
+		}
+		return d, nil
+	}
+	return parseTenantTime(value, loc)
+}
+
+// queryCategories reads category filters given as repeated or
+// comma-separated parameters
+func queryCategories(c *gin.Context) []string {
+	var categories []string
+	for _, v := range c.QueryArray("category") {
+		for _, category := range strings.Split(v, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+	}
+	return categories
+}
+
+// publicEventResponse renders an occurrence for the public site
+func publicEventResponse(o EventOccurrence) gin.H {
+	return gin.H{
+		"id":               o.EventID,
+		"slug":             o.Slug,
+		"occurrence_start": o.OccurrenceStart,
+		"recurring":        o.Recurring,
+		"title":            o.Title,
+		"description":      o.Description,
+		"starts_at":        o.StartsAt,
+		"ends_at":          o.EndsAt,
+		"location":         o.Location,
+		"category":         o.Category,
+		"image_url":        o.ImageURL,
+		"capacity":         o.Capacity,
+		"registered_count": o.RegisteredCount,
+		"spots_left":       o.SpotsLeft,
+	}
+}
+
+// GetPublicEvents lists the published events in a date range, recurring
+// events expanded, a page at a time in start order
+func (h *Handler) GetPublicEvents(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+
+	from := time.Now()
+	if v := c.Query("from"); v != "" {
+		if from, err = parseEventRangeBound(v, loc, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD or RFC3339"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, publicEventsDays)
+	if v := c.Query("to"); v != "" {
+		if to, err = parseEventRangeBound(v, loc, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD or RFC3339"})
+			return
+		}
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	if to.Sub(from) > publicEventsMaxDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("date range cannot be longer than %d days", publicEventsMaxDays)})
+		return
+	}
+
+	limit := publicEventsPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > publicEventsMaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", publicEventsMaxPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	filter := eventFilter{
+		From:       from,
+		To:         to,
+		PublicOnly: true,
+		Categories: queryCategories(c),
+		Search:     strings.TrimSpace(c.Query("q")),
+		Limit:      limit + 1,
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeEventCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.After = cursor
+		if cursor.StartsAt.After(filter.From) {
+			filter.From = cursor.StartsAt
+		}
+	}
+
+	occurrences, err := h.expandEvents(ctx, h.DB, tenantID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var nextCursor *string
+	if len(occurrences) > limit {
+		occurrences = occurrences[:limit]
+		next := encodeEventCursor(occurrences[limit-1])
+		nextCursor = &next
+	}
+
+	events := make([]gin.H, 0, len(occurrences))
+	for _, o := range occurrences {
+		events = append(events, publicEventResponse(o))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"from":        from,
+		"to":          to,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetPublicEvent returns a published event by slug or id, with its
+// upcoming dates
+func (h *Handler) GetPublicEvent(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	s, err := scanEventSeries(h.DB.QueryRow(ctx,
+		`SELECT `+eventSeriesColumns+` FROM events e
+		 WHERE e.tenant_id = $1 AND e.status = 'active' AND e.visibility = true
+		   AND (e.slug = $2 OR e.id::text = $2)
+		 ORDER BY e.slug = $2 DESC
+		 LIMIT 1`,
+		tenantID, c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	now := time.Now()
+	upcoming, err := h.expandEvents(ctx, h.DB, tenantID, eventFilter{
+		From:       now,
+		To:         now.AddDate(0, 0, publicEventsMaxDays),
+		PublicOnly: true,
+		EventID:    s.ID,
+		Limit:      publicEventOccurrences,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	occurrences := make([]gin.H, 0, len(upcoming))
+	for _, o := range upcoming {
+		occurrences = append(occurrences, publicEventResponse(o))
+	}
+
+	event := publicEventResponse(s.occurrence(s.StartsAt, nil))
+	delete(event, "occurrence_start")
+	if s.RRule != nil {
+		// Places are per occurrence
+		delete(event, "registered_count")
+		delete(event, "spots_left")
+		if rule, err := schedule.ParseRule(*s.RRule); err == nil {
+			event["recurrence"] = rule.Describe()
+		}
+	}
+	event["past"] = s.RRule == nil && !now.Before(s.StartsAt)
+	event["occurrences"] = occurrences
+
+	c.JSON(http.StatusOK, event)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rec-hub/backend/pkg/payments"
+)
+
+// ============ Refund Settlement ============
+
+// maxRefundAttempts is how many times a pending refund is sent to the
+// provider before it is marked failed and its amount released
+const maxRefundAttempts = 12
+
+// refundRecord is a refund with what is needed to settle and post it
+type refundRecord struct {
+	ID, TenantID, PaymentID, Method, Status string
+	Reason, CreatedBy, ProviderRef          *string
+	AmountCents, Attempts                   int
+	// From the payment being refunded
+	UserID, PaymentRef                    *string
+	SubjectType, SubjectID, PaymentStatus string
+}
+
+func (r *refundRecord) response() gin.H {
+	return gin.H{
+		"id":                  r.ID,
+		"payment_id":          r.PaymentID,
+		"amount_cents":        r.AmountCents,
+		"method":              r.Method,
+		"status":              r.Status,
+		"provider_refund_ref": r.ProviderRef,
+	}
+}
+
+func refundResponses(refunds []*refundRecord) []gin.H {
+	out := make([]gin.H, len(refunds))
+	for i, r := range refunds {
+		out[i] = r.response()
+	}
+	return out
+}
+
+// loadRefund loads a refund and its payment, locking the refund when lock
+// is set
+func loadRefund(ctx context.Context, q dbtx, refundID string, lock bool) (*refundRecord, error) {
+	query := `SELECT r.id::text, r.tenant_id::text, r.payment_id::text, r.method, r.status, r.reason, r.created_by::text,
+		        r.provider_refund_ref, r.amount_cents, r.attempts,
+		        p.user_id::text, p.provider_payment_ref, p.subject_type, p.subject_id::text, p.status
+		 FROM refunds r
+		 JOIN payments p ON r.payment_id = p.id
+		 WHERE r.id = $1`
+	if lock {
+		query += ` FOR UPDATE OF r`
+	}
+	r := &refundRecord{}
+	err := q.QueryRow(ctx, query, refundID).Scan(&r.ID, &r.TenantID, &r.PaymentID, &r.Method, &r.Status, &r.Reason,
+		&r.CreatedBy, &r.ProviderRef, &r.AmountCents, &r.Attempts, &r.UserID, &r.PaymentRef, &r.SubjectType, &r.SubjectID, &r.PaymentStatus)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// settleRefund sends a committed pending refund to the provider and marks
+// it succeeded, posting it to the ledger. The refund's id is the
+// idempotency key, so sending it again after a crash or timeout never
+// refunds twice. A provider error leaves the refund pending for
+// SettlePendingRefunds to retry, until maxRefundAttempts marks it failed
+// and releases its amount. The refund is returned as it now stands.
+func (h *Handler) settleRefund(ctx context.Context, r *refundRecord) (*refundRecord, error) {
+	if r.Status != "pending" {
+		return r, nil
+	}
+
+	var callErr error
+	var providerRef string
+	switch {
+	case h.Payments == nil || r.PaymentRef == nil:
+		callErr = errors.New("online payments are not enabled")
+	default:
+		result, err := h.Payments.Refund(ctx, payments.RefundRequest{
+			RefundID:    r.ID,
+			PaymentRef:  *r.PaymentRef,
+			AmountCents: r.AmountCents,
+		})
+		if err != nil {
+			callErr = err
+		} else {
+			providerRef = result.ID
+		}
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return r, err
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := loadRefund(ctx, tx, r.ID, true)
+	if err != nil {
+		return r, err
+	}
+	if current.Status != "pending" {
+		return current, nil
+	}
+	current.Attempts++
+
+	if callErr != nil {
+		if current.Attempts >= maxRefundAttempts {
+			current.Status = "failed"
+			_, err = tx.Exec(ctx,
+				`UPDATE payments SET refunded_cents = refunded_cents - $1, updated_at = now() WHERE id = $2`,
+				current.AmountCents, current.PaymentID)
+			if err != nil {
+				return r, err
+			}
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE refunds SET status = $1, attempts = $2, last_error = $3,
+			        settled_at = CASE WHEN $1 = 'failed' THEN now() END
+			 WHERE id = $4`,
+			current.Status, current.Attempts, callErr.Error(), current.ID)
+		if err != nil {
+			return r, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return r, err
+		}
+		return current, callErr
+	}
+
+	current.Status = "succeeded"
+	current.ProviderRef = &providerRef
+	_, err = tx.Exec(ctx,
+		`UPDATE refunds SET status = 'succeeded', provider_refund_ref = $1, attempts = $2, last_error = NULL, settled_at = now()
+		 WHERE id = $3`,
+		providerRef, current.Attempts, current.ID)
+	if err != nil {
+		return r, err
+	}
+	if err := postRefundLedger(ctx, tx, current); err != nil {
+		return r, err
+	}
+	// Money returned for a payment that should not have been taken
+	// closes it out
+	_, err = tx.Exec(ctx,
+		`UPDATE payments SET status = 'refunded', updated_at = now()
+		 WHERE id = $1 AND status IN ('refund_due', 'review') AND refunded_cents >= amount_cents + credit_applied_cents`,
+		current.PaymentID)
+	if err != nil {
+		return r, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return r, err
+	}
+	return current, nil
+}
+
+// settleRefunds settles refunds just committed by the caller. Failures are
+// logged; the refunds stay pending and are retried in the background.
+func (h *Handler) settleRefunds(ctx context.Context, refunds []*refundRecord) {
+	for i, r := range refunds {
+		settled, err := h.settleRefund(ctx, r)
+		if err != nil {
+			log.Printf("Refund %s not settled: %v\n", r.ID, err)
+		}
+		refunds[i] = settled
+	}
+}
+
+// SettlePendingRefunds retries refunds the provider has not yet completed.
+// Refunds younger than a minute are left to the request that made them.
+func (h *Handler) SettlePendingRefunds(ctx context.Context) (int, error) {
+	rows, err := h.DB.Query(ctx,
+		`SELECT id::text FROM refunds
+		 WHERE status = 'pending' AND created_at < now() - interval '1 minute'
+		 ORDER BY created_at
+		 LIMIT 100`)
+	if err != nil {
+		return 0, err
+	}
+	var due []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+
+	settled := 0
+	for _, id := range due {
+		r, err := loadRefund(ctx, h.DB, id, false)
+		if err != nil {
+			return settled, err
+		}
+		r, err = h.settleRefund(ctx, r)
+		if err != nil {
+			log.Printf("Refund %s not settled (attempt %d): %v\n", id, r.Attempts, err)
+			continue
+		}
+		if r.Status == "succeeded" {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// RunRefundSettlement retries pending refunds until ctx is cancelled
+func (h *Handler) RunRefundSettlement(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := h.SettlePendingRefunds(ctx); err != nil {
+			log.Printf("Refund settlement: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
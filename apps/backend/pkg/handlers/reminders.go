@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rec-hub/backend/pkg/mail"
+)
+
+// ============ Reminders ============
+
+const (
+	// maxReminders is how many reminders are sent for one start
+	maxReminders = 5
+	// maxReminderHours is the earliest a reminder can be sent, two weeks out
+	maxReminderHours = 14 * 24
+)
+
+// reminderStatuses are the program registration statuses that get reminders
+var reminderStatuses = []string{"pending", "approved"}
+
+// ReminderSettings is read from tenant settings (config.reminders).
+// HoursBefore lists when reminders go out ahead of a start; Events,
+// Programs and Bookings turn each kind of reminder on or off.
+type ReminderSettings struct {
+	HoursBefore []float64 `json:"hours_before"`
+	Events      bool      `json:"events"`
+	Programs    bool      `json:"programs"`
+	Bookings    bool      `json:"bookings"`
+}
+
+// offsets returns the valid, distinct offsets, furthest ahead first
+func (s ReminderSettings) offsets() []time.Duration {
+	var offsets []time.Duration
+	for _, h := range s.HoursBefore {
+		if h <= 0 || h > maxReminderHours {
+			continue
+		}
+		d := time.Duration(h * float64(time.Hour)).Round(time.Minute)
+		if d <= 0 {
+			continue
+		}
+		seen := false
+		for _, o := range offsets {
+			seen = seen || o == d
+		}
+		if !seen {
+			offsets = append(offsets, d)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	if len(offsets) > maxReminders {
+		offsets = offsets[:maxReminders]
+	}
+	return offsets
+}
+
+// reminderSettings loads the tenant's settings; without any, a reminder is
+// sent a day before every kind of start
+func (h *Handler) reminderSettings(ctx context.Context, q dbtx, tenantID string) ReminderSettings {
+	settings := ReminderSettings{HoursBefore: []float64{24}, Events: true, Programs: true, Bookings: true}
+
+	var raw []byte
+	err := q.QueryRow(ctx,
+		`SELECT config->'reminders' FROM tenant_settings WHERE tenant_id = $1`,
+		tenantID).Scan(&raw)
+	if err == nil && len(raw) > 0 {
+		_ = json.Unmarshal(raw, &settings)
+	}
+	return settings
+}
+
+// reminderSubject is something starting soon that someone should be
+// reminded of. Key names it in email_outbox.reminder_for.
+type reminderSubject struct {
+	Key      string
+	To       string
+	StartsAt time.Time
+	// CreatedAt is when the registration or booking was made; reminders
+	// that would have been due before then are not sent
+	CreatedAt time.Time
+	Notice    mail.ReminderNotification
+}
+
+// eventReminderSubjects loads the RSVPs for event dates starting in
+// (from, to]
+func (h *Handler) eventReminderSubjects(ctx context.Context, q dbtx, tenantID string, from, to time.Time) ([]reminderSubject, error) {
+	rows, err := q.Query(ctx,
+		`SELECT er.id, COALESCE(u.email, er.guest_email, ''),
+		        COALESCE(o.title, e.title), COALESCE(o.location, e.location, ''),
+		        COALESCE(o.starts_at, er.occurrence_start, e.starts_at),
+		        COALESCE(er.registered_at, 'epoch')
+		 FROM event_registrations er
+		 JOIN events e ON er.event_id = e.id
+		 LEFT JOIN users u ON er.user_id = u.id
+		 LEFT JOIN event_occurrences o ON o.event_id = er.event_id AND o.occurrence_start = er.occurrence_start
+		 WHERE er.tenant_id = $1 AND er.status = 'registered' AND e.status = 'active'
+		   AND NOT COALESCE(o.cancelled, false)
+		   AND COALESCE(o.starts_at, er.occurrence_start, e.starts_at) > $2
+		   AND COALESCE(o.starts_at, er.occurrence_start, e.starts_at) <= $3`,
+		tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []reminderSubject
+	for rows.Next() {
+		var id uuid.UUID
+		s := reminderSubject{Notice: mail.ReminderNotification{Kind: "event"}}
+		if err := rows.Scan(&id, &s.To, &s.Notice.Title, &s.Notice.Location, &s.StartsAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Key = "event_registration:" + id.String()
+		s.Notice.TicketCode = h.ticketCode(tenantID, id)
+		subjects = append(subjects, s)
+	}
+	return subjects, rows.Err()
+}
+
+// programReminderSubjects loads the registrations for programs whose first
+// scheduled session starts in (from, to]
+func programReminderSubjects(ctx context.Context, q dbtx, tenantID string, from, to time.Time) ([]reminderSubject, error) {
+	rows, err := q.Query(ctx,
+		`SELECT pr.id::text, u.email, p.title, pr.participant_name, COALESCE(f.name, ''),
+		        s.starts_at, COALESCE(pr.registered_at, 'epoch')
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 JOIN users u ON pr.user_id = u.id
+		 JOIN LATERAL (
+		   SELECT starts_at, facility_id FROM program_sessions
+		   WHERE program_id = p.id AND status = 'scheduled'
+		   ORDER BY starts_at
+		   LIMIT 1
+		 ) s ON true
+		 LEFT JOIN facilities f ON s.facility_id = f.id
+		 WHERE pr.tenant_id = $1 AND pr.status = ANY($2) AND p.status = 'active'
+		   AND s.starts_at > $3 AND s.starts_at <= $4`,
+		tenantID, reminderStatuses, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []reminderSubject
+	for rows.Next() {
+		var id string
+		s := reminderSubject{Notice: mail.ReminderNotification{Kind: "program"}}
+		if err := rows.Scan(&id, &s.To, &s.Notice.Title, &s.Notice.Participant, &s.Notice.Location, &s.StartsAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Key = "program_registration:" + id
+		subjects = append(subjects, s)
+	}
+	return subjects, rows.Err()
+}
+
+// bookingReminderSubjects loads the approved facility slot bookings
+// starting in (from, to]
+func bookingReminderSubjects(ctx context.Context, q dbtx, tenantID string, from, to time.Time) ([]reminderSubject, error) {
+	rows, err := q.Query(ctx,
+		`SELECT b.id::text, b.requester_email, f.name, COALESCE(f.address, ''),
+		        fs.starts_at, COALESCE(b.updated_at, b.created_at, 'epoch')
+		 FROM bookings b
+		 JOIN facility_slots fs ON b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		 JOIN facilities f ON fs.facility_id = f.id
+		 WHERE b.tenant_id = $1 AND b.status = 'approved'
+		   AND fs.starts_at > $2 AND fs.starts_at <= $3`,
+		tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []reminderSubject
+	for rows.Next() {
+		var id string
+		s := reminderSubject{Notice: mail.ReminderNotification{Kind: "booking"}}
+		if err := rows.Scan(&id, &s.To, &s.Notice.Title, &s.Notice.Location, &s.StartsAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Key = "booking:" + id
+		subjects = append(subjects, s)
+	}
+	return subjects, rows.Err()
+}
+
+// ScheduleReminders brings every tenant's queued reminders in line with
+// what is coming up
+func (h *Handler) ScheduleReminders(ctx context.Context) error {
+	rows, err := h.DB.Query(ctx, `SELECT id::text FROM tenants`)
+	if err != nil {
+		return err
+	}
+	var tenantIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		tenantIDs = append(tenantIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenantIDs {
+		if err := h.scheduleTenantReminders(ctx, tenantID); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenantID, err)
+		}
+	}
+	return nil
+}
+
+// scheduleTenantReminders queues a reminder for each configured offset
+// before every upcoming start. A reminder is keyed by what it is for, the
+// start and the offset, so a changed time queues new reminders and those
+// for the old time, or for anything cancelled, are cancelled.
+func (h *Handler) scheduleTenantReminders(ctx context.Context, tenantID string) error {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	settings := h.reminderSettings(ctx, tx, tenantID)
+	offsets := settings.offsets()
+	now := time.Now()
+
+	var subjects []reminderSubject
+	if len(offsets) > 0 {
+		until := now.Add(offsets[0])
+		if settings.Events {
+			s, err := h.eventReminderSubjects(ctx, tx, tenantID, now, until)
+			if err != nil {
+				return err
+			}
+			subjects = append(subjects, s...)
+		}
+		if settings.Programs {
+			s, err := programReminderSubjects(ctx, tx, tenantID, now, until)
+			if err != nil {
+				return err
+			}
+			subjects = append(subjects, s...)
+		}
+		if settings.Bookings {
+			s, err := bookingReminderSubjects(ctx, tx, tenantID, now, until)
+			if err != nil {
+				return err
+			}
+			subjects = append(subjects, s...)
+		}
+	}
+
+	loc := h.tenantLocation(ctx, tx, tenantID)
+	wanted := []string{}
+	for _, s := range subjects {
+		if s.To == "" {
+			continue
+		}
+		s.Notice.When = formatLocal(s.StartsAt, loc)
+		if s.Notice.TicketCode != "" {
+			s.Notice.TicketImageURL = h.ticketImageURL(ctx, tenantID, s.Notice.TicketCode)
+		}
+		subject, body := mail.ReminderEmail(s.Notice)
+
+		// Of the reminders already due only the latest is sent, so a
+		// record made or moved close to its start gets one reminder
+		var due *time.Duration
+		for i := range offsets {
+			sendAt := s.StartsAt.Add(-offsets[i])
+			if sendAt.Before(s.CreatedAt) {
+				continue
+			}
+			if !sendAt.After(now) {
+				due = &offsets[i]
+				continue
+			}
+			key, err := queueReminder(ctx, tx, tenantID, s, offsets[i], subject, body)
+			if err != nil {
+				return err
+			}
+			wanted = append(wanted, key)
+		}
+		if due != nil {
+			key, err := queueReminder(ctx, tx, tenantID, s, *due, subject, body)
+			if err != nil {
+				return err
+			}
+			wanted = append(wanted, key)
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE email_outbox SET status = 'cancelled'
+		 WHERE tenant_id = $1 AND status = 'pending' AND reminder_for IS NOT NULL
+		   AND NOT (dedupe_key = ANY($2))`,
+		tenantID, wanted)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// queueReminder adds the reminder sent offset before s starts, or refreshes
+// it if it has not been sent. It returns the reminder's dedupe key.
+func queueReminder(ctx context.Context, q dbtx, tenantID string, s reminderSubject, offset time.Duration, subject, body string) (string, error) {
+	key := fmt.Sprintf("reminder:%s:%d:%d", s.Key, s.StartsAt.Unix(), int(offset.Minutes()))
+	_, err := q.Exec(ctx,
+		`INSERT INTO email_outbox (tenant_id, to_email, subject, body_html, dedupe_key, send_after, reminder_for)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (dedupe_key) DO UPDATE
+		 SET to_email = EXCLUDED.to_email, subject = EXCLUDED.subject, body_html = EXCLUDED.body_html,
+		     send_after = CASE WHEN email_outbox.status = 'cancelled' THEN EXCLUDED.send_after ELSE email_outbox.send_after END,
+		     status = 'pending'
+		 WHERE email_outbox.status = 'cancelled'
+		    OR (email_outbox.status = 'pending'
+		        AND (email_outbox.to_email, email_outbox.subject, email_outbox.body_html)
+		            IS DISTINCT FROM (EXCLUDED.to_email, EXCLUDED.subject, EXCLUDED.body_html))`,
+		tenantID, s.To, subject, body, key, s.StartsAt.Add(-offset), s.Key)
+	return key, err
+}
+
+// cancelReminders cancels the unsent reminders for a registration or
+// booking, named as in email_outbox.reminder_for
+func cancelReminders(ctx context.Context, q dbtx, tenantID, reminderFor string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE email_outbox SET status = 'cancelled'
+		 WHERE tenant_id = $1 AND reminder_for = $2 AND status = 'pending'`,
+		tenantID, reminderFor)
+	return err
+}
+
+// RunReminders schedules reminders until ctx is cancelled
+func (h *Handler) RunReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.ScheduleReminders(ctx); err != nil {
+			log.Printf("Reminders: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,977 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/ledger"
+	"github.com/rec-hub/backend/pkg/mail"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/pricing"
+	"github.com/rec-hub/backend/pkg/statemachine"
+)
+
+// ============ Scholarships ============
+
+// Award kinds
+const (
+	AwardPercent = "percent"
+	AwardFixed   = "fixed"
+)
+
+var scholarshipMachine = statemachine.New("application", map[string][]string{
+	"submitted": {"approved", "denied", "withdrawn"},
+	"approved":  {"revoked"},
+	"denied":    nil,
+	"withdrawn": nil,
+	"revoked":   nil,
+})
+
+type ScholarshipFundRequest struct {
+	Name                  *string `json:"name"`
+	Description           *string `json:"description"`
+	AnnualBudgetCents     *int    `json:"annual_budget_cents" binding:"omitempty,min=0"`
+	FiscalYearStartMonth  *int    `json:"fiscal_year_start_month" binding:"omitempty,min=1,max=12"`
+	AcceptingApplications *bool   `json:"accepting_applications"`
+}
+
+type ScholarshipApplicationRequest struct {
+	HouseholdSize     *int     `json:"household_size" binding:"omitempty,min=1"`
+	AnnualIncomeCents *int     `json:"annual_income_cents" binding:"omitempty,min=0"`
+	Statement         string   `json:"statement"`
+	DocumentIDs       []string `json:"document_ids"`
+}
+
+// ScholarshipDecisionRequest approves, denies or revokes an application.
+// Approvals carry the award: a percentage off (award_amount_cents then caps
+// the total discount) or a fixed amount.
+type ScholarshipDecisionRequest struct {
+	Status           string   `json:"status" binding:"required"`
+	AwardKind        string   `json:"award_kind"`
+	AwardPercent     *float64 `json:"award_percent"`
+	AwardAmountCents *int     `json:"award_amount_cents"`
+	AwardExpiresOn   *string  `json:"award_expires_on"`
+	Note             string   `json:"note"`
+}
+
+type scholarshipFund struct {
+	Name                  string  `json:"name"`
+	Description           *string `json:"description"`
+	AnnualBudgetCents     int     `json:"annual_budget_cents"`
+	FiscalYearStartMonth  int     `json:"fiscal_year_start_month"`
+	AcceptingApplications bool    `json:"accepting_applications"`
+}
+
+// ScholarshipBudget is how much of the current fiscal year's budget has been
+// used. Committed is what fixed awards can still draw; Remaining does not
+// subtract it, since awards often go partly unused.
+type ScholarshipBudget struct {
+	FiscalYear          int    `json:"fiscal_year"`
+	StartsOn            string `json:"starts_on"`
+	EndsOn              string `json:"ends_on"`
+	BudgetCents         int    `json:"budget_cents"`
+	SpentCents          int    `json:"spent_cents"`
+	CommittedCents      int    `json:"committed_cents"`
+	RemainingCents      int    `json:"remaining_cents"`
+	PendingApplications int    `json:"pending_applications"`
+}
+
+// scholarshipAward is the part of a household's award available to a
+// registration priced now
+type scholarshipAward struct {
+	ApplicationID string
+	FiscalYear    int
+	Scholarship   pricing.Scholarship
+}
+
+// loadScholarshipFund returns the tenant's fund, or nil when it has none.
+// Locking it serialises use of the budget.
+func loadScholarshipFund(ctx context.Context, q dbtx, tenantID string, lock bool) (*scholarshipFund, error) {
+	query := `SELECT name, description, annual_budget_cents, fiscal_year_start_month, accepting_applications
+	          FROM scholarship_funds WHERE tenant_id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var f scholarshipFund
+	err := q.QueryRow(ctx, query, tenantID).Scan(&f.Name, &f.Description, &f.AnnualBudgetCents,
+		&f.FiscalYearStartMonth, &f.AcceptingApplications)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// fiscalYear returns the year the fiscal year containing t starts in, with
+// its first and last days
+func (f *scholarshipFund) fiscalYear(t time.Time) (int, time.Time, time.Time) {
+	year := t.Year()
+	if int(t.Month()) < f.FiscalYearStartMonth {
+		year--
+	}
+	start := time.Date(year, time.Month(f.FiscalYearStartMonth), 1, 0, 0, 0, 0, time.UTC)
+	return year, start, start.AddDate(1, 0, -1)
+}
+
+// scholarshipBudget totals the fund's use in the fiscal year containing now
+func (h *Handler) scholarshipBudget(ctx context.Context, q dbtx, tenantID string, f *scholarshipFund, now time.Time) (ScholarshipBudget, error) {
+	year, start, end := f.fiscalYear(now.In(h.tenantLocation(ctx, q, tenantID)))
+	b := ScholarshipBudget{
+		FiscalYear:  year,
+		StartsOn:    isoDate(start),
+		EndsOn:      isoDate(end),
+		BudgetCents: f.AnnualBudgetCents,
+	}
+
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_cents), 0) FROM scholarship_redemptions
+		 WHERE tenant_id = $1 AND fiscal_year = $2 AND released_at IS NULL`,
+		tenantID, year).Scan(&b.SpentCents)
+	if err != nil {
+		return b, err
+	}
+
+	err = q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(GREATEST(a.award_amount_cents - COALESCE(r.used, 0), 0)), 0)
+		 FROM scholarship_applications a
+		 LEFT JOIN (
+		   SELECT application_id, SUM(amount_cents) AS used FROM scholarship_redemptions
+		   WHERE released_at IS NULL GROUP BY application_id
+		 ) r ON r.application_id = a.id
+		 WHERE a.tenant_id = $1 AND a.status = 'approved' AND a.award_kind = 'fixed'
+		   AND (a.award_expires_on IS NULL OR a.award_expires_on >= $2)`,
+		tenantID, isoDate(now)).Scan(&b.CommittedCents)
+	if err != nil {
+		return b, err
+	}
+
+	err = q.QueryRow(ctx,
+		`SELECT COUNT(*) FROM scholarship_applications WHERE tenant_id = $1 AND status = 'submitted'`,
+		tenantID).Scan(&b.PendingApplications)
+	if err != nil {
+		return b, err
+	}
+
+	b.RemainingCents = max(b.BudgetCents-b.SpentCents, 0)
+	return b, nil
+}
+
+// scholarshipFor finds the household's current award and how much of it a
+// registration priced at the given time may use, limited by what is left of
+// both the award and the fund's budget. The fund is locked so concurrent
+// registrations cannot overspend it.
+func (h *Handler) scholarshipFor(ctx context.Context, q dbtx, tenantID, userID string, at time.Time) (*scholarshipAward, error) {
+	fund, err := loadScholarshipFund(ctx, q, tenantID, true)
+	if err != nil || fund == nil {
+		return nil, err
+	}
+
+	var (
+		applicationID string
+		kind          string
+		percent       *float64
+		amountCents   *int
+		used          int
+	)
+	err = q.QueryRow(ctx,
+		`SELECT a.id, a.award_kind, a.award_percent::float8, a.award_amount_cents,
+		        (SELECT COALESCE(SUM(amount_cents), 0) FROM scholarship_redemptions
+		         WHERE application_id = a.id AND released_at IS NULL)
+		 FROM scholarship_applications a
+		 WHERE a.tenant_id = $1 AND a.user_id = $2 AND a.status = 'approved'
+		   AND (a.award_expires_on IS NULL OR a.award_expires_on >= $3)
+		 ORDER BY a.decided_at DESC
+		 LIMIT 1`,
+		tenantID, userID, isoDate(at)).Scan(&applicationID, &kind, &percent, &amountCents, &used)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	budget, err := h.scholarshipBudget(ctx, q, tenantID, fund, at)
+	if err != nil {
+		return nil, err
+	}
+
+	award := &scholarshipAward{
+		ApplicationID: applicationID,
+		FiscalYear:    budget.FiscalYear,
+		Scholarship:   pricing.Scholarship{Label: fund.Name, MaxCents: budget.RemainingCents},
+	}
+	if amountCents != nil {
+		award.Scholarship.MaxCents = min(award.Scholarship.MaxCents, *amountCents-used)
+	}
+	if kind == AwardPercent && percent != nil {
+		award.Scholarship.PercentOff = *percent
+	}
+	if award.Scholarship.MaxCents <= 0 {
+		return nil, nil
+	}
+	return award, nil
+}
+
+// recordScholarship records the scholarship line of a registration's quote
+// against the award and fund
+func recordScholarship(ctx context.Context, q dbtx, tenantID string, award *scholarshipAward, registrationID uuid.UUID, quote pricing.Quote) error {
+	amount := 0
+	for _, l := range quote.Lines {
+		if l.Kind == pricing.LineScholarship {
+			amount -= l.AmountCents
+		}
+	}
+	if award == nil || amount <= 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO scholarship_redemptions (tenant_id, application_id, registration_id, amount_cents, fiscal_year)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		tenantID, award.ApplicationID, registrationID, amount, award.FiscalYear)
+	return err
+}
+
+// applyScholarshipAtCheckout discounts an unpaid registration that was
+// priced before the household's award was approved. The registration's
+// price and quote are updated and the household's charge is written down.
+func (h *Handler) applyScholarshipAtCheckout(ctx context.Context, tenantID, registrationID string) error {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, status, programTitle, participant string
+	var priceCents int
+	var quoteJSON []byte
+	err = tx.QueryRow(ctx,
+		`SELECT pr.user_id, pr.status, pr.price_cents, pr.price_quote, p.title, pr.participant_name
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 WHERE pr.id = $1 AND pr.tenant_id = $2
+		 FOR UPDATE OF pr`,
+		registrationID, tenantID).Scan(&userID, &status, &priceCents, &quoteJSON, &programTitle, &participant)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if priceCents <= 0 || !containsString([]string{"pending", "approved", "waitlisted"}, status) {
+		return nil
+	}
+
+	var settled bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM scholarship_redemptions WHERE registration_id = $1)
+		     OR EXISTS (SELECT 1 FROM payments WHERE subject_type = 'program_registration' AND subject_id = $1 AND status = 'paid')`,
+		registrationID).Scan(&settled)
+	if err != nil || settled {
+		return err
+	}
+
+	award, err := h.scholarshipFor(ctx, tx, tenantID, userID, time.Now())
+	if err != nil || award == nil {
+		return err
+	}
+
+	var quote pricing.Quote
+	if err := json.Unmarshal(quoteJSON, &quote); err != nil || quote.Lines == nil {
+		quote = pricing.Quote{Lines: []pricing.Line{{Kind: pricing.LineBase, Label: "Base price", AmountCents: priceCents}}}
+	}
+	quote.TotalCents = priceCents
+	amount := quote.ApplyScholarship(award.Scholarship)
+	if amount == 0 {
+		return nil
+	}
+	updatedJSON, _ := json.Marshal(quote)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE program_registrations SET price_cents = $1, price_quote = $2, updated_at = now() WHERE id = $3`,
+		quote.TotalCents, updatedJSON, registrationID)
+	if err != nil {
+		return err
+	}
+	id, _ := uuid.Parse(registrationID)
+	if err := recordScholarship(ctx, tx, tenantID, award, id, quote); err != nil {
+		return err
+	}
+
+	// The household was billed the full price at registration
+	var charged bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM ledger_transactions WHERE subject_type = 'program_registration' AND subject_id = $1 AND kind = 'charge')`,
+		registrationID).Scan(&charged)
+	if err != nil {
+		return err
+	}
+	if charged {
+		subjectType := "program_registration"
+		err = postLedger(ctx, tx, tenantID, userID,
+			ledger.Adjustment(fmt.Sprintf("%s: %s: %s", award.Scholarship.Label, programTitle, participant), -amount),
+			ledgerRef{SubjectType: &subjectType, SubjectID: &registrationID})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// describeAward summarises an award for notices, e.g. "50% off program fees"
+func describeAward(kind string, percent *float64, amountCents *int) string {
+	switch {
+	case kind == AwardPercent && percent != nil && amountCents != nil:
+		return fmt.Sprintf("%g%% off program fees, up to $%.2f", *percent, float64(*amountCents)/100)
+	case kind == AwardPercent && percent != nil:
+		return fmt.Sprintf("%g%% off program fees", *percent)
+	case kind == AwardFixed && amountCents != nil:
+		return fmt.Sprintf("$%.2f toward program fees", float64(*amountCents)/100)
+	}
+	return ""
+}
+
+// GetScholarshipFund returns the fund settings and this fiscal year's budget
+func (h *Handler) GetScholarshipFund(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	fund, err := loadScholarshipFund(ctx, h.DB, tenantID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if fund == nil {
+		c.JSON(http.StatusOK, gin.H{"fund": nil, "budget": nil})
+		return
+	}
+
+	budget, err := h.scholarshipBudget(ctx, h.DB, tenantID, fund, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fund": fund, "budget": budget})
+}
+
+// UpdateScholarshipFund creates or updates the tenant's fund
+func (h *Handler) UpdateScholarshipFund(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ScholarshipFundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	ctx := context.Background()
+	_, err := h.DB.Exec(ctx,
+		`INSERT INTO scholarship_funds (tenant_id, name, description, annual_budget_cents, fiscal_year_start_month, accepting_applications)
+		 VALUES ($1, COALESCE($2, 'Financial Assistance'), $3, COALESCE($4, 0), COALESCE($5, 1), COALESCE($6, true))
+		 ON CONFLICT (tenant_id) DO UPDATE SET
+		   name = COALESCE($2, scholarship_funds.name),
+		   description = COALESCE($3, scholarship_funds.description),
+		   annual_budget_cents = COALESCE($4, scholarship_funds.annual_budget_cents),
+		   fiscal_year_start_month = COALESCE($5, scholarship_funds.fiscal_year_start_month),
+		   accepting_applications = COALESCE($6, scholarship_funds.accepting_applications),
+		   updated_at = now()`,
+		claims.TenantID, req.Name, req.Description, req.AnnualBudgetCents, req.FiscalYearStartMonth, req.AcceptingApplications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func scanScholarshipApplication(row pgx.Row) (gin.H, error) {
+	var (
+		id, userID, email, status    string
+		firstName, lastName          *string
+		householdSize, incomeCents   *int
+		statement                    *string
+		documentIDs                  []string
+		awardKind                    *string
+		awardPercent                 *float64
+		awardAmountCents, usedCents  *int
+		awardExpiresOn, decisionNote *string
+		decidedAt                    *time.Time
+		submittedAt                  time.Time
+	)
+	err := row.Scan(&id, &userID, &email, &firstName, &lastName, &status, &householdSize, &incomeCents,
+		&statement, &documentIDs, &awardKind, &awardPercent, &awardAmountCents, &awardExpiresOn,
+		&decisionNote, &decidedAt, &submittedAt, &usedCents)
+	if err != nil {
+		return nil, err
+	}
+	if documentIDs == nil {
+		documentIDs = []string{}
+	}
+	name := strings.TrimSpace(strings.Join([]string{derefString(firstName), derefString(lastName)}, " "))
+	return gin.H{
+		"id":                  id,
+		"user_id":             userID,
+		"email":               email,
+		"name":                name,
+		"status":              status,
+		"household_size":      householdSize,
+		"annual_income_cents": incomeCents,
+		"statement":           statement,
+		"document_ids":        documentIDs,
+		"award_kind":          awardKind,
+		"award_percent":       awardPercent,
+		"award_amount_cents":  awardAmountCents,
+		"award_expires_on":    awardExpiresOn,
+		"award_used_cents":    usedCents,
+		"decision_note":       decisionNote,
+		"decided_at":          decidedAt,
+		"submitted_at":        submittedAt,
+	}, nil
+}
+
+const scholarshipApplicationColumns = `a.id, a.user_id, u.email, u.first_name, u.last_name, a.status,
+	a.household_size, a.annual_income_cents, a.statement, a.document_ids::text[],
+	a.award_kind, a.award_percent::float8, a.award_amount_cents, a.award_expires_on::text,
+	a.decision_note, a.decided_at, a.submitted_at,
+	(SELECT COALESCE(SUM(amount_cents), 0)::int FROM scholarship_redemptions r
+	 WHERE r.application_id = a.id AND r.released_at IS NULL)`
+
+// ListScholarshipApplications lists applications, oldest submitted first.
+// ?status filters by status.
+func (h *Handler) ListScholarshipApplications(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	var status *string
+	if s := c.Query("status"); s != "" {
+		if !scholarshipMachine.Known(s) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status " + s})
+			return
+		}
+		status = &s
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+scholarshipApplicationColumns+`
+		 FROM scholarship_applications a
+		 JOIN users u ON a.user_id = u.id
+		 WHERE a.tenant_id = $1 AND ($2::text IS NULL OR a.status = $2)
+		 ORDER BY a.submitted_at ASC`,
+		claims.TenantID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	applications := []gin.H{}
+	for rows.Next() {
+		app, err := scanScholarshipApplication(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		applications = append(applications, app)
+	}
+
+	c.JSON(http.StatusOK, applications)
+}
+
+// GetScholarshipApplication returns an application with its supporting
+// documents and the registrations its award has discounted
+func (h *Handler) GetScholarshipApplication(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	applicationID := c.Param("id")
+
+	app, err := scanScholarshipApplication(h.DB.QueryRow(ctx,
+		`SELECT `+scholarshipApplicationColumns+`
+		 FROM scholarship_applications a
+		 JOIN users u ON a.user_id = u.id
+		 WHERE a.id = $1 AND a.tenant_id = $2`,
+		applicationID, claims.TenantID))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT m.id, m.path, m.mime, m.size_bytes
+		 FROM media_assets m
+		 WHERE m.tenant_id = $1 AND m.id = ANY($2::uuid[])
+		 ORDER BY m.created_at`,
+		claims.TenantID, app["document_ids"])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	documents := []gin.H{}
+	for rows.Next() {
+		var id, path, mime string
+		var size *int
+		if err := rows.Scan(&id, &path, &mime, &size); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		documents = append(documents, gin.H{"id": id, "path": path, "mime": mime, "size_bytes": size})
+	}
+	rows.Close()
+	app["documents"] = documents
+
+	rows, err = h.DB.Query(ctx,
+		`SELECT r.registration_id, p.title, pr.participant_name, r.amount_cents, r.fiscal_year, r.released_at, r.created_at
+		 FROM scholarship_redemptions r
+		 JOIN program_registrations pr ON r.registration_id = pr.id
+		 JOIN programs p ON pr.program_id = p.id
+		 WHERE r.application_id = $1
+		 ORDER BY r.created_at DESC`,
+		applicationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+	redemptions := []gin.H{}
+	for rows.Next() {
+		var registrationID, title, participant string
+		var amount, year int
+		var releasedAt *time.Time
+		var createdAt time.Time
+		if err := rows.Scan(&registrationID, &title, &participant, &amount, &year, &releasedAt, &createdAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		redemptions = append(redemptions, gin.H{
+			"registration_id":  registrationID,
+			"program_title":    title,
+			"participant_name": participant,
+			"amount_cents":     amount,
+			"fiscal_year":      year,
+			"released_at":      releasedAt,
+			"created_at":       createdAt,
+		})
+	}
+	app["redemptions"] = redemptions
+
+	c.JSON(http.StatusOK, app)
+}
+
+// DecideScholarshipApplication approves or denies a submitted application,
+// or revokes an approved award. Revoking stops the award applying to new
+// registrations; discounts already given stand.
+func (h *Handler) DecideScholarshipApplication(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ScholarshipDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == "withdrawn" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only the applicant can withdraw an application"})
+		return
+	}
+
+	if req.Status == "approved" {
+		switch req.AwardKind {
+		case AwardPercent:
+			if req.AwardPercent == nil || *req.AwardPercent <= 0 || *req.AwardPercent > 100 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "award_percent must be greater than 0 and at most 100"})
+				return
+			}
+		case AwardFixed:
+			req.AwardPercent = nil
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "award_kind must be percent or fixed"})
+			return
+		}
+		if req.AwardKind == AwardFixed && req.AwardAmountCents == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "award_amount_cents is required for fixed awards"})
+			return
+		}
+		if req.AwardAmountCents != nil && *req.AwardAmountCents <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "award_amount_cents must be positive"})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	applicationID := c.Param("id")
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	fund, err := loadScholarshipFund(ctx, tx, tenantID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if fund == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "scholarship fund is not set up"})
+		return
+	}
+
+	var from, email string
+	err = tx.QueryRow(ctx,
+		`SELECT a.status, u.email FROM scholarship_applications a
+		 JOIN users u ON a.user_id = u.id
+		 WHERE a.id = $1 AND a.tenant_id = $2
+		 FOR UPDATE OF a`,
+		applicationID, tenantID).Scan(&from, &email)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err := scholarshipMachine.Check(from, req.Status); err != nil {
+		respondTransitionError(c, err, "update failed")
+		return
+	}
+
+	award := ""
+	if req.Status == "approved" {
+		budget, err := h.scholarshipBudget(ctx, tx, tenantID, fund, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute budget"})
+			return
+		}
+
+		// Fixed awards reserve their amount, so they cannot exceed what is
+		// left after other fixed awards
+		if req.AwardKind == AwardFixed {
+			available := budget.RemainingCents - budget.CommittedCents
+			if *req.AwardAmountCents > available {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":           "award exceeds the remaining budget",
+					"available_cents": max(available, 0),
+				})
+				return
+			}
+		}
+
+		expiresOn := budget.EndsOn
+		if req.AwardExpiresOn != nil && *req.AwardExpiresOn != "" {
+			t, err := time.Parse("2006-01-02", *req.AwardExpiresOn)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "award_expires_on must be YYYY-MM-DD"})
+				return
+			}
+			expiresOn = isoDate(t)
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE scholarship_applications
+			 SET status = 'approved', award_kind = $1, award_percent = $2, award_amount_cents = $3,
+			     award_expires_on = $4, decision_note = $5, decided_by = $6, decided_at = now(), updated_at = now()
+			 WHERE id = $7`,
+			req.AwardKind, req.AwardPercent, req.AwardAmountCents, expiresOn,
+			nullIfEmpty(strings.TrimSpace(req.Note)), claims.UserID, applicationID)
+		award = describeAward(req.AwardKind, req.AwardPercent, req.AwardAmountCents)
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE scholarship_applications
+			 SET status = $1, decision_note = COALESCE($2, decision_note), decided_by = $3, decided_at = now(), updated_at = now()
+			 WHERE id = $4`,
+			req.Status, nullIfEmpty(strings.TrimSpace(req.Note)), claims.UserID, applicationID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	subject, body := mail.ScholarshipDecisionEmail(fund.Name, req.Status, award, req.Note)
+	if err := enqueueEmail(ctx, tx, tenantID, email, subject, body,
+		fmt.Sprintf("scholarship:%s:%s", applicationID, req.Status)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue notification"})
+		return
+	}
+
+	id, _ := uuid.Parse(applicationID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID,
+		"scholarship_decided", "scholarship_application", &id,
+		gin.H{"status": from},
+		gin.H{"status": req.Status, "award_kind": req.AwardKind, "award_percent": req.AwardPercent,
+			"award_amount_cents": req.AwardAmountCents, "note": req.Note})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": req.Status})
+}
+
+// ApplyForScholarship submits the caller's application. Supporting documents
+// are uploaded through the media endpoints first and referenced by id.
+func (h *Handler) ApplyForScholarship(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ScholarshipApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+
+	fund, err := loadScholarshipFund(ctx, h.DB, tenantID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if fund == nil || !fund.AcceptingApplications {
+		c.JSON(http.StatusForbidden, gin.H{"error": "scholarship applications are not being accepted"})
+		return
+	}
+
+	documentIDs := []string{}
+	for _, id := range req.DocumentIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid document id %q", id)})
+			return
+		}
+		var found bool
+		err := h.DB.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM media_assets WHERE id = $1 AND tenant_id = $2)`,
+			id, tenantID).Scan(&found)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("document %s not found", id)})
+			return
+		}
+		documentIDs = append(documentIDs, id)
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// One open application or current award per household
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	var open string
+	err = tx.QueryRow(ctx,
+		`SELECT status FROM scholarship_applications
+		 WHERE tenant_id = $1 AND user_id = $2
+		   AND (status = 'submitted' OR (status = 'approved' AND (award_expires_on IS NULL OR award_expires_on >= $3)))
+		 LIMIT 1`,
+		tenantID, claims.UserID, isoDate(time.Now())).Scan(&open)
+	if err == nil {
+		message := "you already have an application under review"
+		if open == "approved" {
+			message = "you already have a current award"
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+		return
+	}
+	if err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	applicationID := uuid.New()
+	_, err = tx.Exec(ctx,
+		`INSERT INTO scholarship_applications (id, tenant_id, user_id, household_size, annual_income_cents, statement, document_ids)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7::uuid[])`,
+		applicationID, tenantID, claims.UserID, req.HouseholdSize, req.AnnualIncomeCents,
+		nullIfEmpty(strings.TrimSpace(req.Statement)), documentIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit application"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": applicationID.String(), "status": "submitted"})
+}
+
+// ListMyScholarshipApplications returns the caller's applications and awards
+func (h *Handler) ListMyScholarshipApplications(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	fund, err := loadScholarshipFund(ctx, h.DB, claims.TenantID.String(), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+scholarshipApplicationColumns+`
+		 FROM scholarship_applications a
+		 JOIN users u ON a.user_id = u.id
+		 WHERE a.tenant_id = $1 AND a.user_id = $2
+		 ORDER BY a.submitted_at DESC`,
+		claims.TenantID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	applications := []gin.H{}
+	for rows.Next() {
+		app, err := scanScholarshipApplication(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		applications = append(applications, app)
+	}
+
+	var fundInfo gin.H
+	if fund != nil {
+		fundInfo = gin.H{
+			"name":                   fund.Name,
+			"description":            fund.Description,
+			"accepting_applications": fund.AcceptingApplications,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fund": fundInfo, "applications": applications})
+}
+
+// WithdrawMyScholarshipApplication withdraws an application still under review
+func (h *Handler) WithdrawMyScholarshipApplication(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx,
+		`SELECT status FROM scholarship_applications
+		 WHERE id = $1 AND tenant_id = $2 AND user_id = $3
+		 FOR UPDATE`,
+		c.Param("id"), claims.TenantID, claims.UserID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err := scholarshipMachine.Check(status, "withdrawn"); err != nil {
+		respondTransitionError(c, err, "update failed")
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE scholarship_applications SET status = 'withdrawn', updated_at = now() WHERE id = $1`,
+		c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "withdrawn"})
+}
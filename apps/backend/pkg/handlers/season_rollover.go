@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/schedule"
+)
+
+// ============ Season Rollover ============
+
+type RolloverRequest struct {
+	SourceSeasonID *string  `json:"source_season_id"`
+	ProgramIDs     []string `json:"program_ids"`
+	EventIDs       []string `json:"event_ids"`
+
+	// OffsetDays overrides the computed offset; it must be a whole number
+	// of weeks
+	OffsetDays *int `json:"offset_days"`
+
+	// Status is given to the copies, "inactive" unless set to "active", so
+	// they are not published before they have been reviewed
+	Status *string `json:"status"`
+
+	IncludeSessions bool `json:"include_sessions"`
+	IncludePricing  bool `json:"include_pricing"`
+	IncludeForms    bool `json:"include_forms"`
+	DryRun          bool `json:"dry_run"`
+}
+
+// rolloverConflict is a session that could not reserve its facility
+type rolloverConflict struct {
+	ProgramID string `json:"program_id"`
+	Title     string `json:"title"`
+	SlotConflict
+}
+
+type rolledProgram struct {
+	SourceID      string  `json:"source_id"`
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Slug          string  `json:"slug"`
+	StartDate     *string `json:"start_date"`
+	EndDate       *string `json:"end_date"`
+	Schedules     int     `json:"schedules"`
+	Sessions      int     `json:"sessions"`
+	Conflicts     int     `json:"conflicts"`
+	PriceRules    int     `json:"price_rules"`
+	Form          bool    `json:"form"`
+	SkippedReason string  `json:"skipped_reason,omitempty"`
+}
+
+type rolledEvent struct {
+	SourceID      string    `json:"source_id"`
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	SkippedReason string    `json:"skipped_reason,omitempty"`
+}
+
+// uniqueSlug returns base, or base with a numeric suffix, unused by the
+// tenant's rows in table
+func uniqueSlug(ctx context.Context, q dbtx, table, tenantID, base string) (string, error) {
+	slug := base
+	for n := 2; ; n++ {
+		var taken bool
+		err := q.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE tenant_id = $1 AND slug = $2)`,
+			tenantID, slug).Scan(&taken)
+		if err != nil || !taken {
+			return slug, err
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// RolloverSeason copies programs and events into the season in the URL.
+// Dates move by a whole number of weeks so every session keeps its weekday.
+// Schedules are cloned and their sessions regenerated, reserving facility
+// slots; sessions that would double-book a facility are skipped and
+// reported. With dry_run everything is rolled back after the preview.
+func (h *Handler) RolloverSeason(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req RolloverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.OffsetDays != nil && *req.OffsetDays%7 != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset_days must be a whole number of weeks"})
+		return
+	}
+	status := "inactive"
+	if req.Status != nil {
+		status = *req.Status
+	}
+	if status != "active" && status != "inactive" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or inactive"})
+		return
+	}
+	req.Status = &status
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+
+	target, err := scanSeason(h.DB.QueryRow(ctx,
+		`SELECT `+seasonColumns+` FROM seasons WHERE id = $1 AND tenant_id = $2`,
+		c.Param("id"), tenantID))
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "season not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var source *Season
+	if req.SourceSeasonID != nil {
+		source, err = scanSeason(h.DB.QueryRow(ctx,
+			`SELECT `+seasonColumns+` FROM seasons WHERE id = $1 AND tenant_id = $2`,
+			*req.SourceSeasonID, tenantID))
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source season not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if source.ID == target.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source and target season are the same"})
+			return
+		}
+	}
+
+	// Without an explicit list, every program in the source season is copied
+	programIDs := req.ProgramIDs
+	if len(programIDs) == 0 && source != nil {
+		rows, err := h.DB.Query(ctx,
+			`SELECT id::text FROM programs WHERE tenant_id = $1 AND season_id = $2 AND status <> 'archived' ORDER BY title`,
+			tenantID, source.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err == nil {
+				programIDs = append(programIDs, id)
+			}
+		}
+		rows.Close()
+	}
+	if len(programIDs) == 0 && len(req.EventIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "choose programs, events or a source season"})
+		return
+	}
+
+	// Offset from the source season's start to the target's, falling back
+	// to the earliest selected program start
+	offset := 0
+	if req.OffsetDays != nil {
+		offset = *req.OffsetDays
+	} else {
+		var from *time.Time
+		if source != nil && source.StartsOn != nil {
+			from = source.StartsOn
+		} else if len(programIDs) > 0 {
+			err := h.DB.QueryRow(ctx,
+				`SELECT min(start_date) FROM programs WHERE tenant_id = $1 AND id::text = ANY($2)`,
+				tenantID, programIDs).Scan(&from)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+		}
+		if from == nil || target.StartsOn == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset_days is required when the seasons or programs have no start date"})
+			return
+		}
+		offset = schedule.AlignedOffset(*from, *target.StartsOn)
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	programs := []rolledProgram{}
+	conflicts := []rolloverConflict{}
+	for _, sourceID := range programIDs {
+		p, pc, err := h.rolloverProgram(ctx, tx, tenantID, sourceID, target, offset, req, loc, now)
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "program not found: " + sourceID})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to copy program"})
+			return
+		}
+		programs = append(programs, *p)
+		conflicts = append(conflicts, pc...)
+	}
+
+	events := []rolledEvent{}
+	for _, sourceID := range req.EventIDs {
+		e, err := rolloverEvent(ctx, tx, tenantID, sourceID, offset, status, loc)
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event not found: " + sourceID})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to copy event"})
+			return
+		}
+		events = append(events, *e)
+	}
+
+	seasonID := uuid.MustParse(target.ID)
+	err = writeAuditLog(ctx, tx, claims.TenantID, claims.UserID, "season_rollover", "season", &seasonID, nil,
+		gin.H{"offset_days": offset, "programs": programs, "events": events, "conflicts": len(conflicts)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if !req.DryRun {
+		if err := tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":     req.DryRun,
+		"season_id":   target.ID,
+		"offset_days": offset,
+		"programs":    programs,
+		"events":      events,
+		"conflicts":   conflicts,
+	})
+}
+
+// rolloverProgram copies one program into the target season. A program
+// already rolled over into the season is skipped.
+func (h *Handler) rolloverProgram(ctx context.Context, q dbtx, tenantID, sourceID string, target *Season, offset int, req RolloverRequest, loc *time.Location, now time.Time) (*rolledProgram, []rolloverConflict, error) {
+	var title string
+	err := q.QueryRow(ctx,
+		`SELECT title FROM programs WHERE id = $1 AND tenant_id = $2`,
+		sourceID, tenantID).Scan(&title)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &rolledProgram{SourceID: sourceID, Title: title}
+
+	var existingID string
+	err = q.QueryRow(ctx,
+		`SELECT id::text FROM programs WHERE tenant_id = $1 AND rolled_over_from = $2 AND season_id = $3`,
+		tenantID, sourceID, target.ID).Scan(&existingID)
+	if err == nil {
+		p.ID = existingID
+		p.SkippedReason = "already rolled over into this season"
+		return p, nil, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, nil, err
+	}
+
+	p.Slug, err = uniqueSlug(ctx, q, "programs", tenantID, slugify(title+" "+target.Slug))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = q.QueryRow(ctx,
+		`INSERT INTO programs (tenant_id, title, description, season, season_id, category, start_date, end_date,
+		                       price_cents, capacity, status, image_url, slug, required_certifications, rolled_over_from)
+		 SELECT tenant_id, title, description, $3::text, $4::uuid, category, start_date + $5::int, end_date + $5::int,
+		        price_cents, capacity, $7::text, image_url, $6::text, required_certifications, id
+		 FROM programs WHERE id = $1 AND tenant_id = $2
+		 RETURNING id::text, start_date::text, end_date::text`,
+		sourceID, tenantID, target.Name, target.ID, offset, p.Slug, *req.Status).Scan(&p.ID, &p.StartDate, &p.EndDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conflicts := []rolloverConflict{}
+	if req.IncludeSessions {
+		rows, err := q.Query(ctx,
+			`INSERT INTO program_schedules (tenant_id, program_id, facility_id, weekdays, start_time, end_time,
+			                                starts_on, ends_on, interval_weeks, exception_dates, location_note)
+			 SELECT tenant_id, $3::uuid, facility_id, weekdays, start_time, end_time,
+			        starts_on + $4::int, ends_on + $4::int, interval_weeks,
+			        ARRAY(SELECT d + $4::int FROM unnest(exception_dates) d), location_note
+			 FROM program_schedules WHERE program_id = $1 AND tenant_id = $2
+			 RETURNING id::text`,
+			sourceID, tenantID, p.ID, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		var scheduleIDs []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			scheduleIDs = append(scheduleIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		for _, id := range scheduleIDs {
+			s, err := h.loadSchedule(ctx, q, tenantID, p.ID, id)
+			if err != nil {
+				return nil, nil, err
+			}
+			result, err := h.syncScheduleSessions(ctx, q, tenantID, s, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			p.Schedules++
+			p.Sessions += result.Created
+			for _, sc := range result.Conflicts {
+				conflicts = append(conflicts, rolloverConflict{ProgramID: p.ID, Title: title, SlotConflict: sc})
+			}
+		}
+
+		// One-off sessions outside any schedule
+		created, oneOffConflicts, err := rolloverOneOffSessions(ctx, q, tenantID, sourceID, p.ID, offset, loc, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.Sessions += created
+		for _, sc := range oneOffConflicts {
+			conflicts = append(conflicts, rolloverConflict{ProgramID: p.ID, Title: title, SlotConflict: sc})
+		}
+	}
+	p.Conflicts = len(conflicts)
+
+	if req.IncludePricing {
+		tag, err := q.Exec(ctx,
+			`INSERT INTO program_price_rules (tenant_id, program_id, kind, label, amount_cents, percent, ends_at, min_sibling_index)
+			 SELECT tenant_id, $3::uuid, kind, label, amount_cents, percent, ends_at + make_interval(days => $4), min_sibling_index
+			 FROM program_price_rules WHERE program_id = $1 AND tenant_id = $2`,
+			sourceID, tenantID, p.ID, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.PriceRules = int(tag.RowsAffected())
+	}
+
+	if req.IncludeForms {
+		tag, err := q.Exec(ctx,
+			`INSERT INTO program_forms (program_id, tenant_id, fields)
+			 SELECT $3::uuid, tenant_id, fields FROM program_forms WHERE program_id = $1 AND tenant_id = $2`,
+			sourceID, tenantID, p.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.Form = tag.RowsAffected() > 0
+	}
+
+	return p, conflicts, nil
+}
+
+// rolloverOneOffSessions copies a program's scheduled sessions that are not
+// part of a weekly schedule, reserving their facilities
+func rolloverOneOffSessions(ctx context.Context, q dbtx, tenantID, sourceID, programID string, offset int, loc *time.Location, now time.Time) (int, []SlotConflict, error) {
+	rows, err := q.Query(ctx,
+		`SELECT facility_id::text, starts_at, ends_at FROM program_sessions
+		 WHERE program_id = $1 AND tenant_id = $2 AND schedule_id IS NULL AND status = 'scheduled'
+		 ORDER BY starts_at`,
+		sourceID, tenantID)
+	if err != nil {
+		return 0, nil, err
+	}
+	type session struct {
+		facilityID       *string
+		startsAt, endsAt time.Time
+	}
+	var sessions []session
+	for rows.Next() {
+		var s session
+		if err := rows.Scan(&s.facilityID, &s.startsAt, &s.endsAt); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	created := 0
+	var conflicts []SlotConflict
+	for _, s := range sessions {
+		start := schedule.ShiftWallClock(s.startsAt, offset, loc)
+		end := schedule.ShiftWallClock(s.endsAt, offset, loc)
+		if !start.After(now) {
+			continue
+		}
+
+		var slotID *string
+		ownsSlot := true
+		if s.facilityID != nil {
+			id, owns, conflict, err := reserveFacilityRange(ctx, q, *s.facilityID, start, end)
+			if err != nil {
+				return 0, nil, err
+			}
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+				continue
+			}
+			slotID, ownsSlot = &id, owns
+		}
+
+		_, err := q.Exec(ctx,
+			`INSERT INTO program_sessions (tenant_id, program_id, facility_id, facility_slot_id, owns_slot, starts_at, ends_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			tenantID, programID, s.facilityID, slotID, ownsSlot, start, end)
+		if err != nil {
+			return 0, nil, err
+		}
+		created++
+	}
+	return created, conflicts, nil
+}
+
+// rolloverEvent copies an event forward by offset days with the given
+// status. An event already copied to the same start is skipped.
+func rolloverEvent(ctx context.Context, q dbtx, tenantID, sourceID string, offset int, status string, loc *time.Location) (*rolledEvent, error) {
+	var e rolledEvent
+	var slug, rrule *string
+	var exdates []time.Time
+	err := q.QueryRow(ctx,
+		`SELECT title, starts_at, ends_at, slug, rrule, exdates FROM events WHERE id = $1 AND tenant_id = $2`,
+		sourceID, tenantID).Scan(&e.Title, &e.StartsAt, &e.EndsAt, &slug, &rrule, &exdates)
+	if err != nil {
+		return nil, err
+	}
+	e.SourceID = sourceID
+	e.StartsAt = schedule.ShiftWallClock(e.StartsAt, offset, loc)
+	e.EndsAt = schedule.ShiftWallClock(e.EndsAt, offset, loc)
+
+	// A recurring event keeps its pattern, with its end and skipped dates
+	// moved along with it
+	if rrule != nil {
+		if rule, err := schedule.ParseRule(*rrule); err == nil {
+			rule = rule.InLocation(loc)
+			if !rule.Until.IsZero() {
+				rule.Until = schedule.ShiftWallClock(rule.Until, offset, loc)
+			}
+			shifted := rule.String()
+			rrule = &shifted
+		}
+	}
+	for i, ex := range exdates {
+		exdates[i] = schedule.ShiftWallClock(ex, offset, loc)
+	}
+	if exdates == nil {
+		exdates = []time.Time{}
+	}
+
+	err = q.QueryRow(ctx,
+		`SELECT id::text FROM events WHERE tenant_id = $1 AND rolled_over_from = $2 AND starts_at = $3`,
+		tenantID, sourceID, e.StartsAt).Scan(&e.ID)
+	if err == nil {
+		e.SkippedReason = "already rolled over to this date"
+		return &e, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	var newSlug *string
+	if slug != nil {
+		base := slugify(e.Title + " " + e.StartsAt.In(loc).Format("2006-01-02"))
+		s, err := uniqueSlug(ctx, q, "events", tenantID, base)
+		if err != nil {
+			return nil, err
+		}
+		newSlug = &s
+	}
+
+	err = q.QueryRow(ctx,
+		`INSERT INTO events (tenant_id, title, description, starts_at, ends_at, location, capacity,
+		                     category, status, visibility, image_url, slug, rolled_over_from, rrule, exdates)
+		 SELECT tenant_id, title, description, $3::timestamptz, $4::timestamptz, location, capacity,
+		        category, $8::text, visibility, image_url, $5::text, id, $6::text, $7::timestamptz[]
+		 FROM events WHERE id = $1 AND tenant_id = $2
+		 RETURNING id::text`,
+		sourceID, tenantID, e.StartsAt, e.EndsAt, newSlug, rrule, exdates, status).Scan(&e.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+)
+
+// ============ Seasons ============
+
+// Priority audiences
+const (
+	PriorityResidents          = "residents"
+	PriorityMembers            = "members"
+	PriorityResidentsOrMembers = "residents_or_members"
+)
+
+type SeasonRequest struct {
+	Name                 string  `json:"name" binding:"required"`
+	Slug                 *string `json:"slug"`
+	StartsOn             *string `json:"starts_on"`
+	EndsOn               *string `json:"ends_on"`
+	RegistrationOpensAt  *string `json:"registration_opens_at"`
+	RegistrationClosesAt *string `json:"registration_closes_at"`
+	PriorityOpensAt      *string `json:"priority_opens_at"`
+	PriorityAudience     *string `json:"priority_audience"`
+}
+
+// Season is a registration season and its windows
+type Season struct {
+	ID                   string
+	Name                 string
+	Slug                 string
+	StartsOn             *time.Time
+	EndsOn               *time.Time
+	RegistrationOpensAt  *time.Time
+	RegistrationClosesAt *time.Time
+	PriorityOpensAt      *time.Time
+	PriorityAudience     string
+}
+
+// RegistrationWindow reports whether registration is open at the given time.
+// hasPriority is whether the registrant is in the season's priority audience.
+// When closed, the returned message explains why.
+func (s *Season) RegistrationWindow(now time.Time, hasPriority bool, loc *time.Location) (bool, string) {
+	if s.RegistrationClosesAt != nil && !now.Before(*s.RegistrationClosesAt) {
+		return false, fmt.Sprintf("registration for %s closed %s", s.Name, formatLocal(*s.RegistrationClosesAt, loc))
+	}
+	if s.RegistrationOpensAt == nil && s.PriorityOpensAt == nil {
+		return true, ""
+	}
+	if s.RegistrationOpensAt != nil && !now.Before(*s.RegistrationOpensAt) {
+		return true, ""
+	}
+	if s.PriorityOpensAt != nil && !now.Before(*s.PriorityOpensAt) {
+		if hasPriority {
+			return true, ""
+		}
+		if s.RegistrationOpensAt == nil {
+			return false, fmt.Sprintf("registration for %s is limited to %s", s.Name, priorityAudienceLabel(s.PriorityAudience))
+		}
+		return false, fmt.Sprintf("priority registration for %s is limited to %s; general registration opens %s",
+			s.Name, priorityAudienceLabel(s.PriorityAudience), formatLocal(*s.RegistrationOpensAt, loc))
+	}
+	if hasPriority && s.PriorityOpensAt != nil {
+		return false, fmt.Sprintf("priority registration for %s opens %s", s.Name, formatLocal(*s.PriorityOpensAt, loc))
+	}
+	if s.RegistrationOpensAt == nil {
+		return false, fmt.Sprintf("registration for %s is limited to %s", s.Name, priorityAudienceLabel(s.PriorityAudience))
+	}
+	return false, fmt.Sprintf("registration for %s opens %s", s.Name, formatLocal(*s.RegistrationOpensAt, loc))
+}
+
+// Phase names the current registration window for display
+func (s *Season) Phase(now time.Time) string {
+	switch {
+	case s.RegistrationClosesAt != nil && !now.Before(*s.RegistrationClosesAt):
+		return "closed"
+	case s.RegistrationOpensAt == nil && s.PriorityOpensAt == nil:
+		return "open"
+	case s.RegistrationOpensAt != nil && !now.Before(*s.RegistrationOpensAt):
+		return "open"
+	case s.PriorityOpensAt != nil && !now.Before(*s.PriorityOpensAt):
+		return "priority"
+	default:
+		return "upcoming"
+	}
+}
+
+func priorityAudienceLabel(audience string) string {
+	switch audience {
+	case PriorityResidents:
+		return "residents"
+	case PriorityMembers:
+		return "members"
+	default:
+		return "residents and members"
+	}
+}
+
+func formatLocal(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("Jan 2, 2006 3:04 PM MST")
+}
+
+// tenantLocation returns the tenant's configured timezone, or UTC
+func (h *Handler) tenantLocation(ctx context.Context, q dbtx, tenantID string) *time.Location {
+	var name *string
+	err := q.QueryRow(ctx,
+		`SELECT config->>'timezone' FROM tenant_settings WHERE tenant_id = $1`,
+		tenantID).Scan(&name)
+	if err != nil || name == nil || *name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(*name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseTenantTime accepts RFC3339 or a wall-clock time in the tenant's zone
+func parseTenantTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// seasonColumns is the select list scanned by scanSeason
+const seasonColumns = `id::text, name, slug, starts_on, ends_on, registration_opens_at, registration_closes_at,
+	priority_opens_at, priority_audience`
+
+func scanSeason(row pgx.Row) (*Season, error) {
+	var s Season
+	err := row.Scan(&s.ID, &s.Name, &s.Slug, &s.StartsOn, &s.EndsOn, &s.RegistrationOpensAt,
+		&s.RegistrationClosesAt, &s.PriorityOpensAt, &s.PriorityAudience)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// programSeason loads the season a program belongs to, or nil
+func (h *Handler) programSeason(ctx context.Context, q dbtx, tenantID, programID string) (*Season, error) {
+	s, err := scanSeason(q.QueryRow(ctx,
+		`SELECT `+seasonColumns+` FROM seasons
+		 WHERE id = (SELECT season_id FROM programs WHERE id = $1 AND tenant_id = $2)`,
+		programID, tenantID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// hasPriority reports whether the user is in the season's priority audience
+func (h *Handler) hasPriority(ctx context.Context, q dbtx, tenantID, userID string, s *Season) (bool, error) {
+	if s.PriorityAudience != PriorityMembers {
+		resident, err := h.isResident(ctx, q, tenantID, userID)
+		if err != nil || resident {
+			return resident, err
+		}
+	}
+	if s.PriorityAudience != PriorityResidents {
+		return h.isMember(ctx, q, tenantID, userID)
+	}
+	return false, nil
+}
+
+// checkRegistrationWindow returns a message when the program's season is
+// not open to the user
+func (h *Handler) checkRegistrationWindow(ctx context.Context, q dbtx, tenantID, programID, userID string, now time.Time) (string, error) {
+	season, err := h.programSeason(ctx, q, tenantID, programID)
+	if err != nil || season == nil {
+		return "", err
+	}
+
+	priority := false
+	if season.PriorityOpensAt != nil {
+		priority, err = h.hasPriority(ctx, q, tenantID, userID, season)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	open, message := season.RegistrationWindow(now, priority, h.tenantLocation(ctx, q, tenantID))
+	if open {
+		return "", nil
+	}
+	return message, nil
+}
+
+// seasonResponse renders a season with times in the tenant's zone
+func seasonResponse(s *Season, loc *time.Location, now time.Time) gin.H {
+	local := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		v := t.In(loc).Format(time.RFC3339)
+		return &v
+	}
+	day := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		v := t.Format("2006-01-02")
+		return &v
+	}
+	return gin.H{
+		"id":                     s.ID,
+		"name":                   s.Name,
+		"slug":                   s.Slug,
+		"starts_on":              day(s.StartsOn),
+		"ends_on":                day(s.EndsOn),
+		"registration_opens_at":  local(s.RegistrationOpensAt),
+		"registration_closes_at": local(s.RegistrationClosesAt),
+		"priority_opens_at":      local(s.PriorityOpensAt),
+		"priority_audience":      s.PriorityAudience,
+		"registration_phase":     s.Phase(now),
+		"timezone":               loc.String(),
+	}
+}
+
+// seasonFromRequest validates a season request against the tenant's zone
+func seasonFromRequest(req SeasonRequest, loc *time.Location) (*Season, error) {
+	s := &Season{
+		Name:             strings.TrimSpace(req.Name),
+		PriorityAudience: PriorityResidentsOrMembers,
+	}
+	if s.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	s.Slug = slugify(s.Name)
+	if req.Slug != nil && *req.Slug != "" {
+		s.Slug = slugify(*req.Slug)
+	}
+	if s.Slug == "" {
+		return nil, fmt.Errorf("slug must contain letters or numbers")
+	}
+	if req.PriorityAudience != nil {
+		switch *req.PriorityAudience {
+		case PriorityResidents, PriorityMembers, PriorityResidentsOrMembers:
+			s.PriorityAudience = *req.PriorityAudience
+		default:
+			return nil, fmt.Errorf("priority_audience must be residents, members or residents_or_members")
+		}
+	}
+
+	for _, d := range []struct {
+		value *string
+		dest  **time.Time
+		name  string
+	}{
+		{req.StartsOn, &s.StartsOn, "starts_on"},
+		{req.EndsOn, &s.EndsOn, "ends_on"},
+	} {
+		if d.value == nil || *d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", *d.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+		}
+		*d.dest = &t
+	}
+
+	for _, f := range []struct {
+		value *string
+		dest  **time.Time
+		name  string
+	}{
+		{req.RegistrationOpensAt, &s.RegistrationOpensAt, "registration_opens_at"},
+		{req.RegistrationClosesAt, &s.RegistrationClosesAt, "registration_closes_at"},
+		{req.PriorityOpensAt, &s.PriorityOpensAt, "priority_opens_at"},
+	} {
+		if f.value == nil || *f.value == "" {
+			continue
+		}
+		t, err := parseTenantTime(*f.value, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be RFC3339 or YYYY-MM-DDTHH:MM in the tenant timezone", f.name)
+		}
+		*f.dest = &t
+	}
+
+	if s.StartsOn != nil && s.EndsOn != nil && s.EndsOn.Before(*s.StartsOn) {
+		return nil, fmt.Errorf("ends_on must not be before starts_on")
+	}
+	if s.RegistrationOpensAt != nil && s.RegistrationClosesAt != nil && !s.RegistrationClosesAt.After(*s.RegistrationOpensAt) {
+		return nil, fmt.Errorf("registration_closes_at must be after registration_opens_at")
+	}
+	if s.PriorityOpensAt != nil && s.RegistrationOpensAt != nil && !s.PriorityOpensAt.Before(*s.RegistrationOpensAt) {
+		return nil, fmt.Errorf("priority_opens_at must be before registration_opens_at")
+	}
+	if s.PriorityOpensAt != nil && s.RegistrationClosesAt != nil && !s.RegistrationClosesAt.After(*s.PriorityOpensAt) {
+		return nil, fmt.Errorf("registration_closes_at must be after priority_opens_at")
+	}
+	return s, nil
+}
+
+func (h *Handler) ListSeasons(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	seasons, err := h.loadSeasons(ctx, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	now := time.Now()
+	list := []gin.H{}
+	for _, s := range seasons {
+		list = append(list, seasonResponse(s, loc, now))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": list})
+}
+
+// loadSeasons returns the tenant's seasons, most recent first
+func (h *Handler) loadSeasons(ctx context.Context, tenantID string) ([]*Season, error) {
+	rows, err := h.DB.Query(ctx,
+		`SELECT `+seasonColumns+` FROM seasons WHERE tenant_id = $1
+		 ORDER BY starts_on DESC NULLS LAST, created_at DESC`,
+		tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []*Season
+	for rows.Next() {
+		s, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, s)
+	}
+	return seasons, rows.Err()
+}
+
+func (h *Handler) CreateSeason(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req SeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	s, err := seasonFromRequest(req, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seasonID := uuid.New()
+	_, err = h.DB.Exec(ctx,
+		`INSERT INTO seasons (id, tenant_id, name, slug, starts_on, ends_on,
+		                      registration_opens_at, registration_closes_at, priority_opens_at, priority_audience)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		seasonID, tenantID, s.Name, s.Slug, s.StartsOn, s.EndsOn,
+		s.RegistrationOpensAt, s.RegistrationClosesAt, s.PriorityOpensAt, s.PriorityAudience)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a season with this slug already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create season"})
+		return
+	}
+
+	s.ID = seasonID.String()
+	c.JSON(http.StatusCreated, seasonResponse(s, loc, time.Now()))
+}
+
+func (h *Handler) UpdateSeason(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req SeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	seasonID := c.Param("id")
+
+	// Verify ownership
+	var tenantID string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM seasons WHERE id = $1`, seasonID).Scan(&tenantID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if tenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	s, err := seasonFromRequest(req, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE seasons SET name = $1, slug = $2, starts_on = $3, ends_on = $4,
+		                    registration_opens_at = $5, registration_closes_at = $6, priority_opens_at = $7,
+		                    priority_audience = $8, updated_at = now()
+		 WHERE id = $9`,
+		s.Name, s.Slug, s.StartsOn, s.EndsOn,
+		s.RegistrationOpensAt, s.RegistrationClosesAt, s.PriorityOpensAt, s.PriorityAudience, seasonID)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a season with this slug already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	// Keep the legacy season label on programs in step
+	_, err = tx.Exec(ctx, `UPDATE programs SET season = $1 WHERE season_id = $2`, s.Name, seasonID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	s.ID = seasonID
+	c.JSON(http.StatusOK, seasonResponse(s, loc, time.Now()))
+}
+
+func (h *Handler) DeleteSeason(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	seasonID := c.Param("id")
+
+	// Verify ownership
+	var tenantID string
+	err := h.DB.QueryRow(ctx, `SELECT tenant_id FROM seasons WHERE id = $1`, seasonID).Scan(&tenantID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if tenantID != claims.TenantID.String() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Programs keep their season label but are no longer bound to the windows
+	_, err = h.DB.Exec(ctx, `DELETE FROM seasons WHERE id = $1`, seasonID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// GetPublicSeasons lists seasons with their registration windows
+func (h *Handler) GetPublicSeasons(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	seasons, err := h.loadSeasons(ctx, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	loc := h.tenantLocation(ctx, h.DB, tenantID)
+	now := time.Now()
+	list := []gin.H{}
+	for _, s := range seasons {
+		list = append(list, seasonResponse(s, loc, now))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": list})
+}
+
+// resolveProgramSeason links a program request to a season record. A
+// season_id wins; otherwise a free-text season matching an existing season
+// is linked to it. The season label is kept in step with the record.
+func (h *Handler) resolveProgramSeason(ctx context.Context, tenantID string, req *ProgramRequest) error {
+	if req.SeasonID != nil && *req.SeasonID != "" {
+		var name string
+		err := h.DB.QueryRow(ctx,
+			`SELECT name FROM seasons WHERE id = $1 AND tenant_id = $2`,
+			*req.SeasonID, tenantID).Scan(&name)
+		if err != nil {
+			return fmt.Errorf("season not found")
+		}
+		req.Season = &name
+		return nil
+	}
+
+	req.SeasonID = nil
+	if req.Season == nil || strings.TrimSpace(*req.Season) == "" {
+		return nil
+	}
+	var id, name string
+	err := h.DB.QueryRow(ctx,
+		`SELECT id::text, name FROM seasons WHERE tenant_id = $1 AND slug = $2`,
+		tenantID, slugify(*req.Season)).Scan(&id, &name)
+	if err == nil {
+		req.SeasonID = &id
+		req.Season = &name
+	}
+	return nil
+}
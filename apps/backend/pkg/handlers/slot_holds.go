@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ============ Slot Holds ============
+
+// slotHoldTTL is how long a slot is held for a booking awaiting payment.
+// Starting a checkout extends the hold, and the provider's checkout closes
+// when it lapses. Stripe checkouts stay open for at least 30 minutes, so
+// the hold is longer than that.
+const slotHoldTTL = 35 * time.Minute
+
+var errSlotHeld = errors.New("slot is being held for another booking")
+
+// releaseHoldScript deletes a hold only while it still belongs to the
+// booking, so a late release cannot drop someone else's hold
+var releaseHoldScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func slotHoldKey(slotID string) string {
+	return "slot_hold:" + slotID
+}
+
+// acquireSlotHold claims the slot in Redis for the booking. It reports false
+// when another checkout already holds it.
+func (h *Handler) acquireSlotHold(ctx context.Context, slotID, bookingID string) (bool, error) {
+	return h.Redis.SetNX(ctx, slotHoldKey(slotID), bookingID, slotHoldTTL).Result()
+}
+
+// releaseSlotHold drops the booking's Redis hold on the slot. Failures are
+// only logged; the key expires on its own.
+func (h *Handler) releaseSlotHold(ctx context.Context, slotID, bookingID string) {
+	if err := releaseHoldScript.Run(ctx, h.Redis, []string{slotHoldKey(slotID)}, bookingID).Err(); err != nil {
+		log.Printf("Failed to release hold on slot %s: %v\n", slotID, err)
+	}
+}
+
+// bookableSlot is a facility slot a public booking can claim
+type bookableSlot struct {
+	ID           string
+	FacilityType string
+	Status       string
+	StartsAt     time.Time
+	EndsAt       time.Time
+	AmountCents  int
+}
+
+// loadBookableSlot loads a slot in the tenant with its price, locking it
+// when lock is set
+func loadBookableSlot(ctx context.Context, q dbtx, tenantID, slotID string, lock bool) (*bookableSlot, error) {
+	query := `SELECT fs.id::text, f.type, fs.status, fs.starts_at, fs.ends_at, f.hourly_rate_cents
+		 FROM facility_slots fs
+		 JOIN facilities f ON fs.facility_id = f.id
+		 WHERE fs.id = $1 AND f.tenant_id = $2`
+	if lock {
+		query += ` FOR UPDATE OF fs`
+	}
+	var s bookableSlot
+	var rateCents int
+	err := q.QueryRow(ctx, query, slotID, tenantID).Scan(&s.ID, &s.FacilityType, &s.Status, &s.StartsAt, &s.EndsAt, &rateCents)
+	if err != nil {
+		return nil, err
+	}
+	s.AmountCents = slotPriceCents(rateCents, s.StartsAt, s.EndsAt)
+	return &s, nil
+}
+
+// slotPriceCents prices a slot at the facility's hourly rate
+func slotPriceCents(rateCents int, startsAt, endsAt time.Time) int {
+	return int(float64(rateCents)*endsAt.Sub(startsAt).Hours() + 0.5)
+}
+
+// available reports why the slot cannot be booked, or nil
+func (s *bookableSlot) available() error {
+	switch {
+	case s.Status == "held":
+		return errSlotHeld
+	case s.Status != "open":
+		return errSlotUnavailable
+	case !s.StartsAt.After(time.Now()):
+		return errors.New("slot has already started")
+	}
+	return nil
+}
+
+// claimSlot gives an open slot to a new booking. Paid slots are held until
+// the returned time while the requester checks out; free slots are booked
+// straight away and wait on the booking's approval.
+func claimSlot(ctx context.Context, q dbtx, slot *bookableSlot, bookingID string) (*time.Time, error) {
+	if slot.AmountCents == 0 {
+		_, err := q.Exec(ctx,
+			`UPDATE facility_slots SET status = 'booked', booking_id = $1, held_until = NULL, updated_at = now()
+			 WHERE id = $2`,
+			bookingID, slot.ID)
+		return nil, err
+	}
+	var heldUntil time.Time
+	err := q.QueryRow(ctx,
+		`UPDATE facility_slots SET status = 'held', booking_id = $1, held_until = now() + $2 * interval '1 second', updated_at = now()
+		 WHERE id = $3
+		 RETURNING held_until`,
+		bookingID, int(slotHoldTTL.Seconds()), slot.ID).Scan(&heldUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &heldUntil, nil
+}
+
+// holdForCheckout checks a booking still holds its slot before it is paid
+// for and extends the hold for the checkout, returning when the checkout
+// must close. Slots already booked for the booking need no hold, nor do
+// bookings made before slots were claimed.
+func (h *Handler) holdForCheckout(ctx context.Context, tenantID, bookingID string) (*time.Time, error) {
+	var slotID, status string
+	var owner *string
+	err := h.DB.QueryRow(ctx,
+		`SELECT fs.id::text, fs.status, fs.booking_id::text
+		 FROM bookings b
+		 JOIN facility_slots fs ON b.resource_type = 'facility_slot' AND fs.id = b.resource_id
+		 WHERE b.id = $1 AND b.tenant_id = $2`,
+		bookingID, tenantID).Scan(&slotID, &status, &owner)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expired := &checkoutError{http.StatusConflict, "slot hold has expired"}
+	switch {
+	case owner == nil:
+		if status == "open" {
+			return nil, nil
+		}
+		return nil, expired
+	case *owner != bookingID:
+		return nil, expired
+	case status == "booked":
+		return nil, nil
+	case status != "held":
+		return nil, expired
+	}
+
+	var heldUntil time.Time
+	err = h.DB.QueryRow(ctx,
+		`UPDATE facility_slots SET held_until = now() + $1 * interval '1 second', updated_at = now()
+		 WHERE id = $2 AND booking_id = $3 AND status = 'held' AND held_until > now()
+		 RETURNING held_until`,
+		int(slotHoldTTL.Seconds()), slotID, bookingID).Scan(&heldUntil)
+	if err == pgx.ErrNoRows {
+		return nil, expired
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := h.Redis.Set(ctx, slotHoldKey(slotID), bookingID, slotHoldTTL).Err(); err != nil {
+		log.Printf("Failed to extend hold on slot %s: %v\n", slotID, err)
+	}
+	return &heldUntil, nil
+}
+
+// confirmSlotHold books the slot a booking being paid for holds, or an open
+// unclaimed slot for a booking made before slots were claimed. It reports
+// false when the slot has gone to someone else, so the payment can be
+// refunded instead of settled.
+func (h *Handler) confirmSlotHold(ctx context.Context, q dbtx, subjectType, subjectID string) (bool, error) {
+	if subjectType != "booking" {
+		return true, nil
+	}
+	var slotID string
+	err := q.QueryRow(ctx,
+		`UPDATE facility_slots SET status = 'booked', booking_id = $1, held_until = NULL, updated_at = now()
+		 WHERE id = (SELECT resource_id FROM bookings WHERE id = $1 AND resource_type = 'facility_slot')
+		   AND ((booking_id = $1 AND status IN ('held', 'booked')) OR (booking_id IS NULL AND status = 'open'))
+		 RETURNING id::text`,
+		subjectID).Scan(&slotID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	h.releaseSlotHold(ctx, slotID, subjectID)
+	return true, nil
+}
+
+// expireCheckoutSessions closes provider checkouts abandoned in a committed
+// transaction so they can no longer be paid. A payment that gets through
+// anyway is refunded when it arrives.
+func (h *Handler) expireCheckoutSessions(ctx context.Context, sessions []string) {
+	if h.Payments == nil {
+		return
+	}
+	for _, id := range sessions {
+		if err := h.Payments.ExpireCheckoutSession(ctx, id); err != nil {
+			log.Printf("Failed to expire checkout session %s: %v\n", id, err)
+		}
+	}
+}
+
+// ReleaseExpiredHolds cancels bookings whose slot hold lapsed before they
+// were paid for, reopening the slots
+func (h *Handler) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	rows, err := h.DB.Query(ctx,
+		`SELECT fs.booking_id::text, f.tenant_id
+		 FROM facility_slots fs
+		 JOIN facilities f ON fs.facility_id = f.id
+		 WHERE fs.status = 'held' AND fs.held_until <= now()
+		 ORDER BY fs.held_until
+		 LIMIT 100`)
+	if err != nil {
+		return 0, err
+	}
+	type hold struct {
+		bookingID string
+		tenantID  uuid.UUID
+	}
+	var due []hold
+	for rows.Next() {
+		var hd hold
+		if err := rows.Scan(&hd.bookingID, &hd.tenantID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, hd)
+	}
+	rows.Close()
+
+	released := 0
+	for _, hd := range due {
+		ok, err := h.expireHold(ctx, hd.tenantID, hd.bookingID)
+		if err != nil {
+			return released, err
+		}
+		if ok {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// expireHold cancels one booking if its slot is still held and overdue
+func (h *Handler) expireHold(ctx context.Context, tenantID uuid.UUID, bookingID string) (bool, error) {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The booking is locked before its slot, as in transitionBooking
+	var status string
+	err = tx.QueryRow(ctx,
+		`SELECT status FROM bookings WHERE id = $1 FOR UPDATE`,
+		bookingID).Scan(&status)
+	if err != nil {
+		return false, err
+	}
+	var slotID string
+	err = tx.QueryRow(ctx,
+		`SELECT id::text FROM facility_slots
+		 WHERE booking_id = $1 AND status = 'held' AND held_until <= now()
+		 FOR UPDATE`,
+		bookingID).Scan(&slotID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var abandoned []string
+	if status == "pending" {
+		t, err := h.transitionBooking(ctx, tx, tenantID, uuid.Nil, bookingID, "cancelled")
+		if err != nil {
+			return false, err
+		}
+		abandoned = t.AbandonedSessions
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE facility_slots SET status = 'open', booking_id = NULL, held_until = NULL, updated_at = now()
+			 WHERE id = $1`,
+			slotID)
+		if err != nil {
+			return false, err
+		}
+		h.releaseSlotHold(ctx, slotID, bookingID)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	h.expireCheckoutSessions(ctx, abandoned)
+	return true, nil
+}
+
+// RunHoldExpiry releases lapsed slot holds until ctx is cancelled
+func (h *Handler) RunHoldExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := h.ReleaseExpiredHolds(ctx); err != nil {
+			log.Printf("Slot hold expiry: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
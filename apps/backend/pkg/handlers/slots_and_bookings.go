@@ -35,7 +35,7 @@ func (h *Handler) ListFacilitySlots(c *gin.Context) {
 
 	if facilityID != "" {
 		rows, err = h.DB.Query(ctx,
-			`SELECT fs.id, fs.facility_id, fs.starts_at, fs.ends_at, fs.status, fs.created_at, fs.updated_at
+			`SELECT fs.id, fs.facility_id, fs.starts_at, fs.ends_at, fs.status, fs.template_id::text, fs.booking_id::text, fs.held_until, fs.created_at, fs.updated_at
 			 FROM facility_slots fs
 			 JOIN facilities f ON fs.facility_id = f.id
 			 WHERE f.tenant_id = $1 AND fs.facility_id = $2
@@ -43,7 +43,7 @@ func (h *Handler) ListFacilitySlots(c *gin.Context) {
 			claims.TenantID.String(), facilityID)
 	} else {
 		rows, err = h.DB.Query(ctx,
-			`SELECT fs.id, fs.facility_id, fs.starts_at, fs.ends_at, fs.status, fs.created_at, fs.updated_at
+			`SELECT fs.id, fs.facility_id, fs.starts_at, fs.ends_at, fs.status, fs.template_id::text, fs.booking_id::text, fs.held_until, fs.created_at, fs.updated_at
 			 FROM facility_slots fs
 			 JOIN facilities f ON fs.facility_id = f.id
 			 WHERE f.tenant_id = $1
@@ -60,7 +60,9 @@ func (h *Handler) ListFacilitySlots(c *gin.Context) {
 	var slots []interface{}
 	for rows.Next() {
 		var s models.FacilitySlot
-		if err := rows.Scan(&s.ID, &s.FacilityID, &s.StartsAt, &s.EndsAt, &s.Status, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		var templateID, bookingID *string
+		var heldUntil *time.Time
+		if err := rows.Scan(&s.ID, &s.FacilityID, &s.StartsAt, &s.EndsAt, &s.Status, &templateID, &bookingID, &heldUntil, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			continue
 		}
 		slots = append(slots, gin.H{
@@ -69,6 +71,9 @@ func (h *Handler) ListFacilitySlots(c *gin.Context) {
 			"starts_at":   s.StartsAt,
 			"ends_at":     s.EndsAt,
 			"status":      s.Status,
+			"template_id": templateID,
+			"booking_id":  bookingID,
+			"held_until":  heldUntil,
 		})
 	}
 
@@ -104,11 +109,23 @@ func (h *Handler) CreateFacilitySlot(c *gin.Context) {
 		return
 	}
 
+	if !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	if h.respondSlotOverlap(ctx, c, req.FacilityID.String(), "", req.StartsAt, req.EndsAt) {
+		return
+	}
+
 	slotID := uuid.New()
 	_, err = h.DB.Exec(ctx,
 		`INSERT INTO facility_slots (id, facility_id, starts_at, ends_at, status)
 		 VALUES ($1, $2, $3, $4, $5)`,
 		slotID, req.FacilityID, req.StartsAt, req.EndsAt, "open")
+	if isExclusionViolation(err) && h.respondSlotOverlap(ctx, c, req.FacilityID.String(), "", req.StartsAt, req.EndsAt) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create slot"})
 		return
@@ -134,12 +151,12 @@ func (h *Handler) UpdateFacilitySlot(c *gin.Context) {
 	ctx := context.Background()
 
 	// Verify ownership
-	var tenantID string
+	var tenantID, facilityID string
 	err := h.DB.QueryRow(ctx,
-		`SELECT f.tenant_id FROM facility_slots fs
+		`SELECT f.tenant_id, f.id FROM facility_slots fs
 		 JOIN facilities f ON fs.facility_id = f.id
 		 WHERE fs.id = $1`,
-		slotID).Scan(&tenantID)
+		slotID).Scan(&tenantID, &facilityID)
 	if err == pgx.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
@@ -149,14 +166,31 @@ func (h *Handler) UpdateFacilitySlot(c *gin.Context) {
 		return
 	}
 
+	if !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	if h.respondSlotOverlap(ctx, c, facilityID, slotID, req.StartsAt, req.EndsAt) {
+		return
+	}
+
+	// A moved slot is no longer exempt from the overlap constraint
 	_, err = h.DB.Exec(ctx,
-		`UPDATE facility_slots SET starts_at = $1, ends_at = $2, updated_at = now()
+		`UPDATE facility_slots SET starts_at = $1, ends_at = $2, legacy_overlap = false, updated_at = now()
 		 WHERE id = $3`,
 		req.StartsAt, req.EndsAt, slotID)
+	if isExclusionViolation(err) && h.respondSlotOverlap(ctx, c, facilityID, slotID, req.StartsAt, req.EndsAt) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
+	if err := clearResolvedOverlaps(ctx, h.DB, facilityID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
@@ -172,12 +206,12 @@ func (h *Handler) DeleteFacilitySlot(c *gin.Context) {
 	ctx := context.Background()
 
 	// Verify ownership
-	var tenantID string
+	var tenantID, facilityID string
 	err := h.DB.QueryRow(ctx,
-		`SELECT f.tenant_id FROM facility_slots fs
+		`SELECT f.tenant_id, f.id FROM facility_slots fs
 		 JOIN facilities f ON fs.facility_id = f.id
 		 WHERE fs.id = $1`,
-		slotID).Scan(&tenantID)
+		slotID).Scan(&tenantID, &facilityID)
 	if err == pgx.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
@@ -192,18 +226,171 @@ func (h *Handler) DeleteFacilitySlot(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
+	if err := clearResolvedOverlaps(ctx, h.DB, facilityID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
 
 	c.JSON(http.StatusNoContent, gin.H{})
 }
 
+// ConflictingSlot is an existing slot in the way of a new or moved one
+type ConflictingSlot struct {
+	ID       string    `json:"id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason"`
+}
+
+// overlappingSlot returns a slot at the facility overlapping the range,
+// other than excludeID, or nil when there is none
+func overlappingSlot(ctx context.Context, q dbtx, facilityID, excludeID string, startsAt, endsAt time.Time) (*ConflictingSlot, error) {
+	var s ConflictingSlot
+	var booked bool
+	err := q.QueryRow(ctx,
+		`SELECT fs.id::text, fs.starts_at, fs.ends_at, fs.status,
+		        EXISTS(SELECT 1 FROM bookings b
+		               WHERE b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		                 AND b.status NOT IN ('declined', 'cancelled'))
+		 FROM facility_slots fs
+		 WHERE fs.facility_id = $1 AND fs.id::text <> $2
+		   AND fs.starts_at < $4 AND fs.ends_at > $3
+		 ORDER BY fs.starts_at
+		 LIMIT 1`,
+		facilityID, excludeID, startsAt, endsAt).Scan(&s.ID, &s.StartsAt, &s.EndsAt, &s.Status, &booked)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Reason = slotConflictReason(s.Status, booked)
+	return &s, nil
+}
+
+// respondSlotOverlap writes a 409 naming the slot the range overlaps, if
+// any, and reports whether it did
+func (h *Handler) respondSlotOverlap(ctx context.Context, c *gin.Context, facilityID, excludeID string, startsAt, endsAt time.Time) bool {
+	conflict, err := overlappingSlot(ctx, h.DB, facilityID, excludeID, startsAt, endsAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return true
+	}
+	if conflict == nil {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":               "slot overlaps another slot",
+		"conflicting_slot_id": conflict.ID,
+		"conflicting_slot":    conflict,
+	})
+	return true
+}
+
+// clearResolvedOverlaps brings a facility's exempt legacy slots that no
+// longer overlap anything under the overlap constraint
+func clearResolvedOverlaps(ctx context.Context, q dbtx, facilityID string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE facility_slots a SET legacy_overlap = false
+		 WHERE a.facility_id = $1 AND a.legacy_overlap AND a.ends_at > a.starts_at
+		   AND NOT EXISTS (
+		     SELECT 1 FROM facility_slots b
+		     WHERE b.facility_id = a.facility_id AND b.id <> a.id
+		       AND b.starts_at < a.ends_at AND b.ends_at > a.starts_at
+		   )`,
+		facilityID)
+	return err
+}
+
+// SlotOverlapReport lists pairs of overlapping slots, left from before
+// overlaps were prevented, so they can be cleaned up
+func (h *Handler) SlotOverlapReport(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`WITH slots AS (
+		   SELECT fs.id, fs.facility_id, fs.starts_at, fs.ends_at, fs.status, fs.legacy_overlap,
+		          EXISTS(SELECT 1 FROM bookings b
+		                 WHERE b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		                   AND b.status NOT IN ('declined', 'cancelled')) AS booked
+		   FROM facility_slots fs
+		   JOIN facilities f ON fs.facility_id = f.id
+		   WHERE f.tenant_id = $1 AND ($2 = '' OR fs.facility_id::text = $2)
+		 )
+		 SELECT f.id::text, f.name,
+		        a.id::text, a.starts_at, a.ends_at, a.status, a.booked, a.legacy_overlap,
+		        b.id::text, b.starts_at, b.ends_at, b.status, b.booked, b.legacy_overlap
+		 FROM slots a
+		 JOIN slots b ON b.facility_id = a.facility_id AND a.id < b.id
+		   AND b.starts_at < a.ends_at AND b.ends_at > a.starts_at
+		 JOIN facilities f ON a.facility_id = f.id
+		 ORDER BY f.name, a.starts_at, b.starts_at
+		 LIMIT 1000`,
+		claims.TenantID.String(), c.Query("facility_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	type reportSlot struct {
+		ID            string    `json:"id"`
+		StartsAt      time.Time `json:"starts_at"`
+		EndsAt        time.Time `json:"ends_at"`
+		Status        string    `json:"status"`
+		Booked        bool      `json:"booked"`
+		LegacyOverlap bool      `json:"legacy_overlap"`
+	}
+	overlaps := []gin.H{}
+	for rows.Next() {
+		var facilityID, facilityName string
+		var a, b reportSlot
+		if err := rows.Scan(&facilityID, &facilityName,
+			&a.ID, &a.StartsAt, &a.EndsAt, &a.Status, &a.Booked, &a.LegacyOverlap,
+			&b.ID, &b.StartsAt, &b.EndsAt, &b.Status, &b.Booked, &b.LegacyOverlap); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		overlaps = append(overlaps, gin.H{
+			"facility_id":   facilityID,
+			"facility_name": facilityName,
+			"slots":         []reportSlot{a, b},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"overlaps": overlaps, "count": len(overlaps)})
+}
+
 // ============ Bookings ============
 
+// BookingRequest names a slot to book: resource_type facility_slot with
+// the slot's id, or resource_type facility with the facility's id and the
+// slot's exact start and end
 type BookingRequest struct {
 	ResourceType   string `json:"resource_type" binding:"required"`
 	ResourceID     uuid.UUID `json:"resource_id" binding:"required"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
 	RequesterName  *string `json:"requester_name"`
 	RequesterEmail string `json:"requester_email" binding:"required,email"`
 	Notes          *string `json:"notes"`
+	// Signatures for waivers required by the facility's type
+	Waivers []WaiverSignatureRequest `json:"waivers"`
 }
 
 type BookingStatusRequest struct {
@@ -264,33 +451,26 @@ func (h *Handler) UpdateBooking(c *gin.Context) {
 
 	ctx := context.Background()
 
-	// Verify ownership
-	var tenantID string
-	var requesterEmail string
-	var resourceID uuid.UUID
-	err := h.DB.QueryRow(ctx,
-		`SELECT tenant_id, requester_email, resource_id FROM bookings WHERE id = $1`,
-		bookingID).Scan(&tenantID, &requesterEmail, &resourceID)
-	if err == pgx.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
-	if tenantID != claims.TenantID.String() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	_, err = h.DB.Exec(ctx,
-		`UPDATE bookings SET status = $1, updated_at = now() WHERE id = $2`,
-		req.Status, bookingID)
+	t, err := h.transitionBooking(ctx, tx, claims.TenantID, claims.UserID, bookingID, req.Status)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		respondTransitionError(c, err, "update failed")
 		return
 	}
 
-	// TODO: Send email notification to requester about status change
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	h.expireCheckoutSessions(ctx, t.AbandonedSessions)
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"success": true, "transition": t})
 }
 
 func (h *Handler) CreatePublicBooking(c *gin.Context) {
@@ -319,18 +499,144 @@ func (h *Handler) CreatePublicBooking(c *gin.Context) {
 		return
 	}
 
-	// Create booking
+	// Bookings claim a concrete slot, named directly or by its facility and times
+	var slotID string
+	switch req.ResourceType {
+	case "facility_slot":
+		slotID = req.ResourceID.String()
+	case "facility":
+		if req.StartsAt == nil || req.EndsAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "starts_at and ends_at are required to book a facility"})
+			return
+		}
+		err = h.DB.QueryRow(ctx,
+			`SELECT fs.id::text FROM facility_slots fs
+			 JOIN facilities f ON fs.facility_id = f.id
+			 WHERE f.id = $1 AND f.tenant_id = $2 AND fs.starts_at = $3 AND fs.ends_at = $4`,
+			req.ResourceID, tenantID, *req.StartsAt, *req.EndsAt).Scan(&slotID)
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "facility has no slot at that time"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource_type must be facility_slot or facility"})
+		return
+	}
+
+	slot, err := loadBookableSlot(ctx, h.DB, tenantID, slotID, false)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "slot not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err := slot.available(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Facility types may require waivers, signed with the request
+	required, err := requiredWaivers(ctx, h.DB, tenantID, WaiverForFacilityType, slot.FacilityType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load waivers"})
+		return
+	}
+	signedWaivers, issues := matchWaiverSignatures(required, req.Waivers)
+	if len(issues) > 0 {
+		respondWaiverIssues(c, issues)
+		return
+	}
+
+	// Paid slots are held in Redis while the requester checks out, so two
+	// checkouts cannot race for the same slot. The slot row stays the
+	// source of truth and is updated under lock below.
 	bookingID := uuid.New()
-	_, err = h.DB.Exec(ctx,
+	held := slot.AmountCents > 0
+	if held {
+		ok, err := h.acquireSlotHold(ctx, slot.ID, bookingID.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hold slot"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusConflict, gin.H{"error": errSlotHeld.Error()})
+			return
+		}
+	}
+	committed := false
+	defer func() {
+		if held && !committed {
+			h.releaseSlotHold(ctx, slot.ID, bookingID.String())
+		}
+	}()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	slot, err = loadBookableSlot(ctx, tx, tenantID, slotID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err := slot.available(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create booking
+	_, err = tx.Exec(ctx,
 		`INSERT INTO bookings (id, tenant_id, resource_type, resource_id, requester_name, requester_email, notes, status)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		bookingID, tenantID, req.ResourceType, req.ResourceID, req.RequesterName, req.RequesterEmail, req.Notes, "pending")
+		bookingID, tenantID, "facility_slot", slot.ID, req.RequesterName, req.RequesterEmail, req.Notes, "pending")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create booking"})
 		return
 	}
 
+	heldUntil, err := claimSlot(ctx, tx, slot, bookingID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to claim slot"})
+		return
+	}
+
+	if len(signedWaivers) > 0 {
+		signer := signerFromRequest(c, tenantID)
+		signer.Email = req.RequesterEmail
+		signer.Purpose = "Booking of a " + slot.FacilityType
+		if _, err := recordWaiverSignatures(ctx, tx, signer, "booking", bookingID, signedWaivers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record waiver signatures"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	committed = true
+
 	// TODO: Send email notification to admin
 
-	c.JSON(http.StatusCreated, gin.H{"id": bookingID.String()})
+	slotStatus := "booked"
+	if held {
+		slotStatus = "held"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":               bookingID.String(),
+		"slot_id":          slot.ID,
+		"slot_status":      slotStatus,
+		"amount_cents":     slot.AmountCents,
+		"requires_payment": held,
+		"hold_expires_at":  heldUntil,
+	})
 }
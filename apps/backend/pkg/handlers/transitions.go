@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/mail"
+	"github.com/rec-hub/backend/pkg/statemachine"
+)
+
+// ============ Status Transitions ============
+
+var registrationMachine = statemachine.New("registration", map[string][]string{
+	"entered":    {"offered", "waitlisted", "cancelled"},
+	"offered":    {"pending", "approved", "cancelled"},
+	"pending":    {"approved", "waitlisted", "cancelled"},
+	"waitlisted": {"offered", "pending", "approved", "cancelled"},
+	"approved":   {"completed", "cancelled"},
+	"completed":  nil,
+	"cancelled":  nil,
+})
+
+var bookingMachine = statemachine.New("booking", map[string][]string{
+	"pending":   {"approved", "declined", "cancelled"},
+	"approved":  {"cancelled"},
+	"declined":  nil,
+	"cancelled": nil,
+})
+
+// seatStatuses are the registration statuses counted against a program's
+// capacity. An outstanding lottery offer holds its place until it expires.
+var seatStatuses = []string{"offered", "pending", "approved", "completed"}
+
+var (
+	errProgramFull     = errors.New("program is full")
+	errSlotUnavailable = errors.New("slot is not available")
+)
+
+// respondTransitionError maps errors from transitionRegistration and
+// transitionBooking onto responses. Illegal transitions are 409s naming the
+// statuses that are allowed.
+func respondTransitionError(c *gin.Context, err error, fallback string) {
+	var te *statemachine.TransitionError
+	switch {
+	case errors.As(err, &te):
+		status := http.StatusConflict
+		if te.Unknown {
+			status = http.StatusBadRequest
+		}
+		allowed := te.Allowed
+		if allowed == nil {
+			allowed = []string{}
+		}
+		c.JSON(status, gin.H{"error": te.Error(), "from": te.From, "to": te.To, "allowed": allowed})
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errProgramFull), errors.Is(err, errSlotUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// enqueueEmail queues a message in the outbox. It is written with the change
+// that triggers it, so a rolled back change never sends mail. A non-empty
+// dedupeKey makes repeated calls a no-op.
+func enqueueEmail(ctx context.Context, q dbtx, tenantID, to, subject, body, dedupeKey string) error {
+	if strings.TrimSpace(to) == "" {
+		return nil
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO email_outbox (tenant_id, to_email, subject, body_html, dedupe_key)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (dedupe_key) DO NOTHING`,
+		tenantID, to, subject, body, nullIfEmpty(dedupeKey))
+	return err
+}
+
+type registrationTransition struct {
+	ID         string  `json:"id"`
+	ProgramID  string  `json:"program_id"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	PromotedID *string `json:"promoted_registration_id"`
+}
+
+// transitionRegistration moves a registration to a new status and runs the
+// transition's side effects in q: the program's enrolled_count follows
+// registrations entering or leaving a seat, a freed seat promotes the
+// longest-waiting registration, the household is emailed and the change is
+// audited. Taking a seat fails with errProgramFull when the program is at
+// capacity.
+func (h *Handler) transitionRegistration(ctx context.Context, q dbtx, tenantID, actorID uuid.UUID, registrationID, to, reason string) (*registrationTransition, error) {
+	var programID, from, participantName, programTitle string
+	var email *string
+	err := q.QueryRow(ctx,
+		`SELECT pr.program_id::text, pr.status, pr.participant_name, p.title, u.email
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 LEFT JOIN users u ON pr.user_id = u.id
+		 WHERE pr.id = $1 AND pr.tenant_id = $2
+		 FOR UPDATE OF pr`,
+		registrationID, tenantID).Scan(&programID, &from, &participantName, &programTitle, &email)
+	if err != nil {
+		return nil, err
+	}
+	if err := registrationMachine.Check(from, to); err != nil {
+		return nil, err
+	}
+
+	// Capacity counters
+	takesSeat := !containsString(seatStatuses, from) && containsString(seatStatuses, to)
+	freesSeat := containsString(seatStatuses, from) && !containsString(seatStatuses, to)
+	if takesSeat || freesSeat {
+		var capacity *int
+		var enrolled int
+		err := q.QueryRow(ctx,
+			`SELECT capacity, enrolled_count FROM programs WHERE id = $1 FOR UPDATE`,
+			programID).Scan(&capacity, &enrolled)
+		if err != nil {
+			return nil, err
+		}
+		delta := -1
+		if takesSeat {
+			if capacity != nil && enrolled >= *capacity {
+				return nil, errProgramFull
+			}
+			delta = 1
+		}
+		_, err = q.Exec(ctx,
+			`UPDATE programs SET enrolled_count = GREATEST(enrolled_count + $1, 0) WHERE id = $2`,
+			delta, programID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if to == "cancelled" {
+		var cancelledBy *uuid.UUID
+		if actorID != uuid.Nil {
+			cancelledBy = &actorID
+		}
+		_, err = q.Exec(ctx,
+			`UPDATE program_registrations
+			 SET status = $1, cancelled_at = now(), cancelled_by = $2, cancellation_reason = $3, updated_at = now()
+			 WHERE id = $4`,
+			to, cancelledBy, nullIfEmpty(strings.TrimSpace(reason)), registrationID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Checkouts in progress are abandoned
+		_, err = q.Exec(ctx,
+			`UPDATE payments SET status = 'cancelled', updated_at = now()
+			 WHERE subject_type = 'program_registration' AND subject_id = $1 AND status = 'pending'`,
+			registrationID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Scholarship money goes back to the award and the fund
+		_, err = q.Exec(ctx,
+			`UPDATE scholarship_redemptions SET released_at = now()
+			 WHERE registration_id = $1 AND released_at IS NULL`,
+			registrationID)
+	} else if to == "offered" {
+		_, err = q.Exec(ctx,
+			`UPDATE program_registrations
+			 SET status = $1, updated_at = now(),
+			     offer_expires_at = now() + make_interval(hours => (SELECT offer_hours FROM program_lotteries WHERE program_id = $2))
+			 WHERE id = $3`,
+			to, programID, registrationID)
+	} else {
+		_, err = q.Exec(ctx,
+			`UPDATE program_registrations SET status = $1, updated_at = now() WHERE id = $2`,
+			to, registrationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !containsString(reminderStatuses, to) {
+		if err := cancelReminders(ctx, q, tenantID.String(), "program_registration:"+registrationID); err != nil {
+			return nil, err
+		}
+	}
+
+	if email != nil {
+		subject, body := mail.RegistrationStatusEmail(programTitle, participantName, to)
+		if err := enqueueEmail(ctx, q, tenantID.String(), *email, subject, body, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &registrationTransition{ID: registrationID, ProgramID: programID, From: from, To: to}
+
+	// A freed seat goes to the next registration on the waitlist: lottery
+	// rank first, then the longest-waiting. Once a lottery has been drawn
+	// the seat rolls down as a time-limited offer.
+	if freesSeat {
+		var waitingID string
+		var drawn bool
+		err := q.QueryRow(ctx,
+			`SELECT pr.id::text,
+			        EXISTS(SELECT 1 FROM program_lotteries WHERE program_id = pr.program_id AND drawn_at IS NOT NULL)
+			 FROM program_registrations pr
+			 WHERE pr.program_id = $1 AND pr.status = 'waitlisted'
+			 ORDER BY pr.lottery_rank NULLS LAST, pr.registered_at
+			 LIMIT 1
+			 FOR UPDATE OF pr SKIP LOCKED`,
+			programID).Scan(&waitingID, &drawn)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			next := "pending"
+			if drawn {
+				next = "offered"
+			}
+			// A program whose capacity was lowered may still be full
+			_, err := h.transitionRegistration(ctx, q, tenantID, actorID, waitingID, next, "promoted from waitlist")
+			if err != nil && !errors.Is(err, errProgramFull) {
+				return nil, err
+			}
+			if err == nil {
+				t.PromotedID = &waitingID
+			}
+		}
+	}
+
+	regID := uuid.MustParse(registrationID)
+	err = writeAuditLog(ctx, q, tenantID, actorID, "registration_status_changed", "program_registration", &regID,
+		gin.H{"status": from},
+		gin.H{"status": to, "reason": reason, "promoted_registration_id": t.PromotedID})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type bookingTransition struct {
+	ID     string  `json:"id"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	SlotID *string `json:"slot_id"`
+	// Provider checkouts abandoned by the change, to expire once committed
+	AbandonedSessions []string `json:"-"`
+}
+
+// transitionBooking moves a booking to a new status and runs its side
+// effects in q. Approving a facility slot booking marks the slot booked and
+// fails with errSlotUnavailable when the slot is claimed by someone else;
+// declining or cancelling releases a slot the booking held or booked.
+// Declined and cancelled bookings abandon checkouts in progress. The
+// requester is emailed and the change is audited.
+func (h *Handler) transitionBooking(ctx context.Context, q dbtx, tenantID, actorID uuid.UUID, bookingID, to string) (*bookingTransition, error) {
+	var resourceType, resourceID, from, requesterEmail string
+	err := q.QueryRow(ctx,
+		`SELECT resource_type, resource_id::text, status, requester_email
+		 FROM bookings WHERE id = $1 AND tenant_id = $2
+		 FOR UPDATE`,
+		bookingID, tenantID).Scan(&resourceType, &resourceID, &from, &requesterEmail)
+	if err != nil {
+		return nil, err
+	}
+	if err := bookingMachine.Check(from, to); err != nil {
+		return nil, err
+	}
+
+	t := &bookingTransition{ID: bookingID, From: from, To: to}
+	notice := mail.BookingNotification{To: requesterEmail, Status: to, FacilityName: "your booking"}
+
+	if resourceType == "facility_slot" {
+		var slotStatus, facilityName string
+		var slotBooking *string
+		var startsAt, endsAt time.Time
+		err := q.QueryRow(ctx,
+			`SELECT fs.status, fs.booking_id::text, f.name, fs.starts_at, fs.ends_at
+			 FROM facility_slots fs
+			 JOIN facilities f ON fs.facility_id = f.id
+			 WHERE fs.id = $1 AND f.tenant_id = $2
+			 FOR UPDATE OF fs`,
+			resourceID, tenantID).Scan(&slotStatus, &slotBooking, &facilityName, &startsAt, &endsAt)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, err
+		}
+		if err == pgx.ErrNoRows {
+			if to == "approved" {
+				return nil, errSlotUnavailable
+			}
+		} else {
+			t.SlotID = &resourceID
+			loc := h.tenantLocation(ctx, q, tenantID.String())
+			notice.FacilityName = facilityName
+			notice.SlotTime = startsAt.In(loc).Format("Monday, January 2, 2006 3:04 PM") + "–" + endsAt.In(loc).Format("3:04 PM")
+
+			// Slots claimed by this booking are held or booked under its id.
+			// Bookings made before slots were claimed take an open slot on
+			// approval.
+			claimed := slotBooking != nil && *slotBooking == bookingID
+			unclaimed := slotBooking == nil
+			var slotTo string
+			switch {
+			case to == "approved":
+				if !(claimed && (slotStatus == "held" || slotStatus == "booked")) && !(unclaimed && slotStatus == "open") {
+					return nil, errSlotUnavailable
+				}
+				slotTo = "booked"
+			case claimed && (slotStatus == "held" || slotStatus == "booked"):
+				slotTo = "open"
+			case unclaimed && from == "approved" && slotStatus == "booked":
+				slotTo = "open"
+			}
+			if slotTo != "" {
+				var owner *string
+				if slotTo == "booked" {
+					owner = &bookingID
+				}
+				_, err = q.Exec(ctx,
+					`UPDATE facility_slots SET status = $1, booking_id = $2, held_until = NULL, updated_at = now() WHERE id = $3`,
+					slotTo, owner, resourceID)
+				if err != nil {
+					return nil, err
+				}
+				if claimed {
+					h.releaseSlotHold(ctx, resourceID, bookingID)
+				}
+			}
+		}
+	}
+
+	if to == "declined" || to == "cancelled" {
+		t.AbandonedSessions, err = abandonCheckouts(ctx, q, "booking", bookingID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = q.Exec(ctx,
+		`UPDATE bookings SET status = $1, updated_at = now() WHERE id = $2`,
+		to, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if to != "approved" {
+		if err := cancelReminders(ctx, q, tenantID.String(), "booking:"+bookingID); err != nil {
+			return nil, err
+		}
+	}
+
+	subject, body := mail.BookingStatusEmail(notice)
+	if err := enqueueEmail(ctx, q, tenantID.String(), requesterEmail, subject, body, ""); err != nil {
+		return nil, err
+	}
+
+	id := uuid.MustParse(bookingID)
+	err = writeAuditLog(ctx, q, tenantID, actorID, "booking_status_changed", "booking", &id,
+		gin.H{"status": from},
+		gin.H{"status": to, "slot_id": t.SlotID})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
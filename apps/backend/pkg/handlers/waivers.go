@@ -0,0 +1,1222 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rec-hub/backend/pkg/middleware"
+	"github.com/rec-hub/backend/pkg/pdf"
+)
+
+// ============ Waivers ============
+
+// Things a waiver can be attached to
+const (
+	WaiverForProgram      = "program"
+	WaiverForEvent        = "event"
+	WaiverForFacilityType = "facility_type"
+)
+
+type WaiverRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Title  string `json:"title"`
+	Body   string `json:"body" binding:"required"`
+	Active *bool  `json:"active"`
+}
+
+type WaiverUpdateRequest struct {
+	Name   *string `json:"name"`
+	Active *bool   `json:"active"`
+}
+
+type WaiverVersionRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body" binding:"required"`
+}
+
+type WaiverAttachmentsRequest struct {
+	Programs      []string `json:"programs"`
+	Events        []string `json:"events"`
+	FacilityTypes []string `json:"facility_types"`
+}
+
+// WaiverSignatureRequest accepts one waiver version. DocumentHash must match
+// the version's hash so a signer cannot accept text that has since been
+// replaced.
+type WaiverSignatureRequest struct {
+	WaiverVersionID string `json:"waiver_version_id" binding:"required"`
+	SignerName      string `json:"signer_name"`
+	Agree           bool   `json:"agree"`
+	DocumentHash    string `json:"document_hash"`
+}
+
+type SignWaiversRequest struct {
+	Waivers []WaiverSignatureRequest `json:"waivers" binding:"required"`
+}
+
+type WaiverVersion struct {
+	ID        string    `json:"id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	BodyHash  string    `json:"document_hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RequiredWaiver is the current version of an active waiver attached to
+// what is being registered for or booked
+type RequiredWaiver struct {
+	WaiverID string        `json:"waiver_id"`
+	Name     string        `json:"name"`
+	Version  WaiverVersion `json:"version"`
+}
+
+// WaiverIssue explains why a required waiver was not accepted
+type WaiverIssue struct {
+	WaiverID        string `json:"waiver_id"`
+	Name            string `json:"name"`
+	WaiverVersionID string `json:"waiver_version_id"`
+	Reason          string `json:"reason"`
+}
+
+// waiverSigner is who signed and what for, captured with each signature
+type waiverSigner struct {
+	TenantID    string
+	UserID      *string
+	Email       string
+	Participant string
+	Purpose     string
+	IP          string
+	UserAgent   string
+}
+
+type acceptedWaiver struct {
+	Waiver     RequiredWaiver
+	SignerName string
+}
+
+// waiverHash fingerprints a version's text as it is presented to signers
+func waiverHash(title, body string) string {
+	sum := sha256.Sum256([]byte(title + "\n\n" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// requiredWaivers lists the current version of each active waiver attached
+// to a program, event or facility type
+func requiredWaivers(ctx context.Context, q dbtx, tenantID, subjectType, subjectKey string) ([]RequiredWaiver, error) {
+	rows, err := q.Query(ctx,
+		`SELECT DISTINCT ON (w.id) w.id, w.name, v.id, v.version, v.title, v.body, v.body_hash, v.created_at
+		 FROM waiver_attachments a
+		 JOIN waivers w ON a.waiver_id = w.id
+		 JOIN waiver_versions v ON v.waiver_id = w.id
+		 WHERE a.tenant_id = $1 AND a.subject_type = $2 AND a.subject_key = $3 AND w.active
+		 ORDER BY w.id, v.version DESC`,
+		tenantID, subjectType, subjectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	required := []RequiredWaiver{}
+	for rows.Next() {
+		var r RequiredWaiver
+		v := &r.Version
+		if err := rows.Scan(&r.WaiverID, &r.Name, &v.ID, &v.Version, &v.Title, &v.Body, &v.BodyHash, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		required = append(required, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(required, func(i, j int) bool { return required[i].Name < required[j].Name })
+	return required, nil
+}
+
+// bookingFacilityType finds the type of facility a booking is for, or "" when
+// the resource is not a facility
+func bookingFacilityType(ctx context.Context, q dbtx, tenantID, resourceType, resourceID string) (string, error) {
+	var facilityType string
+	var err error
+	switch resourceType {
+	case "facility_slot":
+		err = q.QueryRow(ctx,
+			`SELECT f.type FROM facility_slots fs
+			 JOIN facilities f ON fs.facility_id = f.id
+			 WHERE fs.id = $1 AND f.tenant_id = $2`,
+			resourceID, tenantID).Scan(&facilityType)
+	case "facility":
+		err = q.QueryRow(ctx,
+			`SELECT type FROM facilities WHERE id = $1 AND tenant_id = $2`,
+			resourceID, tenantID).Scan(&facilityType)
+	default:
+		return "", nil
+	}
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return facilityType, err
+}
+
+// matchWaiverSignatures pairs each required waiver with its signature.
+// Signatures for waivers that are not required are ignored.
+func matchWaiverSignatures(required []RequiredWaiver, signatures []WaiverSignatureRequest) ([]acceptedWaiver, []WaiverIssue) {
+	byVersion := map[string]WaiverSignatureRequest{}
+	for _, s := range signatures {
+		byVersion[s.WaiverVersionID] = s
+	}
+
+	var accepted []acceptedWaiver
+	var issues []WaiverIssue
+	for _, r := range required {
+		issue := WaiverIssue{WaiverID: r.WaiverID, Name: r.Name, WaiverVersionID: r.Version.ID}
+		s, ok := byVersion[r.Version.ID]
+		switch {
+		case !ok:
+			issue.Reason = fmt.Sprintf("%s (version %d) must be signed", r.Name, r.Version.Version)
+		case s.DocumentHash == "":
+			issue.Reason = fmt.Sprintf("the document hash of %s is required", r.Name)
+		case s.DocumentHash != r.Version.BodyHash:
+			issue.Reason = fmt.Sprintf("%s has changed since it was shown; review and sign the current version", r.Name)
+		case !s.Agree:
+			issue.Reason = fmt.Sprintf("you must agree to %s", r.Name)
+		case strings.TrimSpace(s.SignerName) == "":
+			issue.Reason = fmt.Sprintf("a signer name is required for %s", r.Name)
+		default:
+			accepted = append(accepted, acceptedWaiver{Waiver: r, SignerName: strings.TrimSpace(s.SignerName)})
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return accepted, issues
+}
+
+// recordWaiverSignatures stores each signature and renders it to a PDF kept
+// as a media asset
+func recordWaiverSignatures(ctx context.Context, q dbtx, signer waiverSigner, subjectType string, subjectID uuid.UUID, accepted []acceptedWaiver) ([]string, error) {
+	var orgName string
+	if err := q.QueryRow(ctx, `SELECT name FROM tenants WHERE id = $1`, signer.TenantID).Scan(&orgName); err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, a := range accepted {
+		signatureID := uuid.New()
+		mediaID := uuid.New()
+		signedAt := time.Now().UTC()
+
+		doc := waiverDocument(orgName, a.Waiver, a.SignerName, signer, signatureID.String(), signedAt)
+		data := doc.Render()
+
+		_, err := q.Exec(ctx,
+			`INSERT INTO media_assets (id, tenant_id, path, mime, size_bytes, data)
+			 VALUES ($1, $2, $3, 'application/pdf', $4, $5)`,
+			mediaID, signer.TenantID,
+			fmt.Sprintf("tenants/%s/waivers/%s.pdf", signer.TenantID, signatureID),
+			len(data), data)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = q.Exec(ctx,
+			`INSERT INTO waiver_signatures (
+				id, tenant_id, waiver_id, waiver_version_id, subject_type, subject_id, user_id,
+				signer_name, signer_email, participant_name, ip_address, user_agent,
+				document_hash, media_id, signed_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			signatureID, signer.TenantID, a.Waiver.WaiverID, a.Waiver.Version.ID, subjectType, subjectID, signer.UserID,
+			a.SignerName, nullIfEmpty(signer.Email), nullIfEmpty(signer.Participant),
+			nullIfEmpty(signer.IP), nullIfEmpty(signer.UserAgent),
+			a.Waiver.Version.BodyHash, mediaID, signedAt)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, signatureID.String())
+	}
+	return ids, nil
+}
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+func waiverDocument(orgName string, w RequiredWaiver, signerName string, signer waiverSigner, signatureID string, signedAt time.Time) pdf.Document {
+	fields := []pdf.Field{{Label: "Signed by", Value: signerName}}
+	if signer.Email != "" {
+		fields = append(fields, pdf.Field{Label: "Email", Value: signer.Email})
+	}
+	if signer.Participant != "" {
+		fields = append(fields, pdf.Field{Label: "Participant", Value: signer.Participant})
+	}
+	if signer.Purpose != "" {
+		fields = append(fields, pdf.Field{Label: "For", Value: signer.Purpose})
+	}
+	fields = append(fields,
+		pdf.Field{Label: "Signed at", Value: signedAt.Format("January 2, 2006 15:04:05 MST")},
+		pdf.Field{Label: "IP address", Value: signer.IP},
+		pdf.Field{Label: "Browser", Value: signer.UserAgent},
+		pdf.Field{Label: "Document SHA-256", Value: w.Version.BodyHash},
+		pdf.Field{Label: "Signature ID", Value: signatureID},
+	)
+
+	return pdf.Document{
+		Title:      w.Version.Title,
+		Subtitle:   []string{orgName, fmt.Sprintf("%s, version %d", w.Name, w.Version.Version)},
+		Paragraphs: blankLines.Split(strings.TrimSpace(w.Version.Body), -1),
+		Heading:    "Electronic signature",
+		Fields:     fields,
+		Footer:     "Signature " + signatureID,
+	}
+}
+
+// signerFromRequest captures the network details of the signing request
+func signerFromRequest(c *gin.Context, tenantID string) waiverSigner {
+	return waiverSigner{
+		TenantID:  tenantID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func respondWaiverIssues(c *gin.Context, issues []WaiverIssue) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "required waivers have not been signed",
+		"waivers": issues,
+	})
+}
+
+// ListWaivers returns the tenant's waivers with their current version
+func (h *Handler) ListWaivers(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT w.id, w.name, w.active, v.id, v.version, v.title, v.body_hash,
+		        (SELECT count(*) FROM waiver_attachments a WHERE a.waiver_id = w.id),
+		        (SELECT count(*) FROM waiver_signatures s WHERE s.waiver_version_id = v.id)
+		 FROM waivers w
+		 JOIN LATERAL (
+		   SELECT id, version, title, body_hash FROM waiver_versions
+		   WHERE waiver_id = w.id ORDER BY version DESC LIMIT 1
+		 ) v ON true
+		 WHERE w.tenant_id = $1
+		 ORDER BY lower(w.name)`,
+		claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	waivers := []gin.H{}
+	for rows.Next() {
+		var id, name, versionID, title, hash string
+		var active bool
+		var version, attachments, signatures int
+		if err := rows.Scan(&id, &name, &active, &versionID, &version, &title, &hash, &attachments, &signatures); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		waivers = append(waivers, gin.H{
+			"id":     id,
+			"name":   name,
+			"active": active,
+			"current_version": gin.H{
+				"id":            versionID,
+				"version":       version,
+				"title":         title,
+				"document_hash": hash,
+				"signatures":    signatures,
+			},
+			"attachment_count": attachments,
+		})
+	}
+
+	c.JSON(http.StatusOK, waivers)
+}
+
+// CreateWaiver creates a waiver with its first version
+func (h *Handler) CreateWaiver(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req WaiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = name
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	ctx := context.Background()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	waiverID := uuid.New()
+	_, err = tx.Exec(ctx,
+		`INSERT INTO waivers (id, tenant_id, name, active) VALUES ($1, $2, $3, $4)`,
+		waiverID, claims.TenantID, name, active)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create waiver"})
+		return
+	}
+	version, err := publishWaiverVersion(ctx, tx, claims.TenantID.String(), waiverID.String(), claims.UserID.String(), title, req.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create waiver"})
+		return
+	}
+
+	if err := writeAuditLog(ctx, tx, claims.TenantID, claims.UserID,
+		"waiver_created", "waiver", &waiverID, nil,
+		gin.H{"name": name, "version": version.Version, "document_hash": version.BodyHash}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": waiverID.String(), "version": version})
+}
+
+// publishWaiverVersion adds the next version of a waiver
+func publishWaiverVersion(ctx context.Context, q dbtx, tenantID, waiverID, actorID, title, body string) (*WaiverVersion, error) {
+	v := WaiverVersion{
+		ID:    uuid.New().String(),
+		Title: strings.TrimSpace(title),
+		Body:  strings.TrimSpace(body),
+	}
+	v.BodyHash = waiverHash(v.Title, v.Body)
+
+	err := q.QueryRow(ctx,
+		`INSERT INTO waiver_versions (id, tenant_id, waiver_id, version, title, body, body_hash, created_by)
+		 VALUES ($1, $2, $3,
+		   (SELECT COALESCE(MAX(version), 0) + 1 FROM waiver_versions WHERE waiver_id = $3),
+		   $4, $5, $6, $7)
+		 RETURNING version, created_at`,
+		v.ID, tenantID, waiverID, v.Title, v.Body, v.BodyHash, actorID).Scan(&v.Version, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// lockWaiver checks the waiver belongs to the tenant and locks it so
+// versions are numbered one at a time
+func lockWaiver(c *gin.Context, ctx context.Context, q dbtx, waiverID, tenantID string) (string, bool) {
+	var name string
+	err := q.QueryRow(ctx,
+		`SELECT name FROM waivers WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		waiverID, tenantID).Scan(&name)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "waiver not found"})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return "", false
+	}
+	return name, true
+}
+
+// GetWaiver returns a waiver with every version and what it is attached to
+func (h *Handler) GetWaiver(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	waiverID := c.Param("id")
+	ctx := context.Background()
+
+	var name string
+	var active bool
+	err := h.DB.QueryRow(ctx,
+		`SELECT name, active FROM waivers WHERE id = $1 AND tenant_id = $2`,
+		waiverID, claims.TenantID).Scan(&name, &active)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "waiver not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT id, version, title, body, body_hash, created_at
+		 FROM waiver_versions WHERE waiver_id = $1
+		 ORDER BY version DESC`,
+		waiverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	versions := []WaiverVersion{}
+	for rows.Next() {
+		var v WaiverVersion
+		if err := rows.Scan(&v.ID, &v.Version, &v.Title, &v.Body, &v.BodyHash, &v.CreatedAt); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		versions = append(versions, v)
+	}
+	rows.Close()
+
+	attachments := WaiverAttachmentsRequest{Programs: []string{}, Events: []string{}, FacilityTypes: []string{}}
+	rows, err = h.DB.Query(ctx,
+		`SELECT subject_type, subject_key FROM waiver_attachments
+		 WHERE waiver_id = $1 ORDER BY subject_type, subject_key`,
+		waiverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subjectType, key string
+		if err := rows.Scan(&subjectType, &key); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		switch subjectType {
+		case WaiverForProgram:
+			attachments.Programs = append(attachments.Programs, key)
+		case WaiverForEvent:
+			attachments.Events = append(attachments.Events, key)
+		case WaiverForFacilityType:
+			attachments.FacilityTypes = append(attachments.FacilityTypes, key)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          waiverID,
+		"name":        name,
+		"active":      active,
+		"versions":    versions,
+		"attachments": attachments,
+	})
+}
+
+// UpdateWaiver renames or deactivates a waiver. Inactive waivers are no
+// longer required anywhere; their signatures are kept.
+func (h *Handler) UpdateWaiver(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req WaiverUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	ctx := context.Background()
+	var name *string
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		name = &trimmed
+	}
+	tag, err := h.DB.Exec(ctx,
+		`UPDATE waivers
+		 SET name = COALESCE($1, name), active = COALESCE($2, active), updated_at = now()
+		 WHERE id = $3 AND tenant_id = $4`,
+		name, req.Active, c.Param("id"), claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "waiver not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// DeleteWaiver removes a waiver nobody has signed; signed waivers can only
+// be deactivated
+func (h *Handler) DeleteWaiver(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	waiverID := c.Param("id")
+	ctx := context.Background()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, ok := lockWaiver(c, ctx, tx, waiverID, claims.TenantID.String()); !ok {
+		return
+	}
+
+	var signed bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM waiver_signatures WHERE waiver_id = $1)`,
+		waiverID).Scan(&signed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if signed {
+		c.JSON(http.StatusConflict, gin.H{"error": "waiver has signatures; deactivate it instead"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM waivers WHERE id = $1`, waiverID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// PublishWaiverVersion replaces the waiver's text with a new version.
+// Existing signatures stay on the version that was signed.
+func (h *Handler) PublishWaiverVersion(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req WaiverVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	waiverID := c.Param("id")
+	ctx := context.Background()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	name, ok := lockWaiver(c, ctx, tx, waiverID, claims.TenantID.String())
+	if !ok {
+		return
+	}
+	title := req.Title
+	if strings.TrimSpace(title) == "" {
+		title = name
+	}
+
+	version, err := publishWaiverVersion(ctx, tx, claims.TenantID.String(), waiverID, claims.UserID.String(), title, req.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish version"})
+		return
+	}
+	if _, err := tx.Exec(ctx, `UPDATE waivers SET updated_at = now() WHERE id = $1`, waiverID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish version"})
+		return
+	}
+
+	id, _ := uuid.Parse(waiverID)
+	if err := writeAuditLog(ctx, tx, claims.TenantID, claims.UserID,
+		"waiver_version_published", "waiver", &id, nil,
+		gin.H{"version": version.Version, "document_hash": version.BodyHash}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write audit log"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// UpdateWaiverAttachments replaces the programs, events and facility types
+// a waiver is required for
+func (h *Handler) UpdateWaiverAttachments(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req WaiverAttachmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	waiverID := c.Param("id")
+	tenantID := claims.TenantID.String()
+	ctx := context.Background()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, ok := lockWaiver(c, ctx, tx, waiverID, tenantID); !ok {
+		return
+	}
+
+	// Programs and events must belong to this tenant
+	for _, check := range []struct {
+		table, label string
+		ids          []string
+	}{
+		{"programs", "program", req.Programs},
+		{"events", "event", req.Events},
+	} {
+		for _, id := range check.ids {
+			if _, err := uuid.Parse(id); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s id %q", check.label, id)})
+				return
+			}
+			var found bool
+			err := tx.QueryRow(ctx,
+				`SELECT EXISTS (SELECT 1 FROM `+check.table+` WHERE id = $1 AND tenant_id = $2)`,
+				id, tenantID).Scan(&found)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s %s not found", check.label, id)})
+				return
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM waiver_attachments WHERE waiver_id = $1`, waiverID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	attach := func(subjectType string, keys []string) error {
+		for _, key := range keys {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			_, err := tx.Exec(ctx,
+				`INSERT INTO waiver_attachments (tenant_id, waiver_id, subject_type, subject_key)
+				 VALUES ($1, $2, $3, $4)
+				 ON CONFLICT (waiver_id, subject_type, subject_key) DO NOTHING`,
+				tenantID, waiverID, subjectType, key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := attach(WaiverForProgram, req.Programs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if err := attach(WaiverForEvent, req.Events); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if err := attach(WaiverForFacilityType, req.FacilityTypes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ListWaiverSignatures returns who has signed a waiver, newest first.
+// ?version_id limits the list to one version.
+func (h *Handler) ListWaiverSignatures(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	ctx := context.Background()
+	var versionID *string
+	if v := c.Query("version_id"); v != "" {
+		versionID = &v
+	}
+
+	rows, err := h.DB.Query(ctx,
+		`SELECT s.id, v.id, v.version, s.subject_type, s.subject_id, s.user_id,
+		        s.signer_name, s.signer_email, s.participant_name, s.ip_address,
+		        s.document_hash, s.media_id, s.signed_at
+		 FROM waiver_signatures s
+		 JOIN waiver_versions v ON s.waiver_version_id = v.id
+		 WHERE s.waiver_id = $1 AND s.tenant_id = $2 AND ($3::uuid IS NULL OR v.id = $3)
+		 ORDER BY s.signed_at DESC`,
+		c.Param("id"), claims.TenantID, versionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	signatures := []gin.H{}
+	for rows.Next() {
+		var id, vID, subjectType, subjectID, signerName, hash string
+		var version int
+		var userID, email, participant, ip, mediaID *string
+		var signedAt time.Time
+		if err := rows.Scan(&id, &vID, &version, &subjectType, &subjectID, &userID,
+			&signerName, &email, &participant, &ip, &hash, &mediaID, &signedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		signatures = append(signatures, gin.H{
+			"id":                id,
+			"waiver_version_id": vID,
+			"version":           version,
+			"subject_type":      subjectType,
+			"subject_id":        subjectID,
+			"user_id":           userID,
+			"signer_name":       signerName,
+			"signer_email":      email,
+			"participant_name":  participant,
+			"ip_address":        ip,
+			"document_hash":     hash,
+			"media_id":          mediaID,
+			"signed_at":         signedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, signatures)
+}
+
+// ListUnsignedWaiver lists active registrations and upcoming bookings the
+// waiver applies to that have no signature on its current version
+func (h *Handler) ListUnsignedWaiver(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	waiverID := c.Param("id")
+	ctx := context.Background()
+
+	var versionID string
+	var version int
+	err := h.DB.QueryRow(ctx,
+		`SELECT v.id, v.version FROM waivers w
+		 JOIN waiver_versions v ON v.waiver_id = w.id
+		 WHERE w.id = $1 AND w.tenant_id = $2
+		 ORDER BY v.version DESC LIMIT 1`,
+		waiverID, claims.TenantID).Scan(&versionID, &version)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "waiver not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	// Each row carries the latest version the subject signed, if any, so
+	// staff can tell "never signed" from "signed an older version"
+	rows, err := h.DB.Query(ctx,
+		`SELECT 'program_registration', pr.id, pr.user_id, u.email, pr.participant_name,
+		        p.title, pr.status, NULL::timestamptz,
+		        (SELECT max(v.version) FROM waiver_signatures s
+		         JOIN waiver_versions v ON s.waiver_version_id = v.id
+		         WHERE s.waiver_id = $1 AND s.subject_type = 'program_registration' AND s.subject_id = pr.id)
+		 FROM program_registrations pr
+		 JOIN programs p ON pr.program_id = p.id
+		 JOIN users u ON pr.user_id = u.id
+		 JOIN waiver_attachments a ON a.waiver_id = $1 AND a.subject_type = 'program' AND a.subject_key = pr.program_id::text
+		 WHERE pr.tenant_id = $3 AND pr.status = ANY($4)
+		   AND NOT EXISTS (SELECT 1 FROM waiver_signatures s
+		                   WHERE s.waiver_version_id = $2 AND s.subject_type = 'program_registration' AND s.subject_id = pr.id)
+		 UNION ALL
+		 SELECT 'event_registration', er.id, er.user_id, COALESCE(u.email, er.guest_email),
+		        COALESCE(NULLIF(concat_ws(' ', u.first_name, u.last_name), ''), er.guest_name),
+		        e.title, er.status, COALESCE(er.occurrence_start, e.starts_at),
+		        (SELECT max(v.version) FROM waiver_signatures s
+		         JOIN waiver_versions v ON s.waiver_version_id = v.id
+		         WHERE s.waiver_id = $1 AND s.subject_type = 'event_registration' AND s.subject_id = er.id)
+		 FROM event_registrations er
+		 JOIN events e ON er.event_id = e.id
+		 LEFT JOIN users u ON er.user_id = u.id
+		 JOIN waiver_attachments a ON a.waiver_id = $1 AND a.subject_type = 'event' AND a.subject_key = er.event_id::text
+		 WHERE er.tenant_id = $3 AND er.status = 'registered' AND COALESCE(er.occurrence_start, e.starts_at) > now()
+		   AND NOT EXISTS (SELECT 1 FROM waiver_signatures s
+		                   WHERE s.waiver_version_id = $2 AND s.subject_type = 'event_registration' AND s.subject_id = er.id)
+		 UNION ALL
+		 SELECT 'booking', b.id, NULL::uuid, b.requester_email, b.requester_name,
+		        f.name, b.status, fs.starts_at,
+		        (SELECT max(v.version) FROM waiver_signatures s
+		         JOIN waiver_versions v ON s.waiver_version_id = v.id
+		         WHERE s.waiver_id = $1 AND s.subject_type = 'booking' AND s.subject_id = b.id)
+		 FROM bookings b
+		 LEFT JOIN facility_slots fs ON b.resource_type = 'facility_slot' AND b.resource_id = fs.id
+		 JOIN facilities f ON f.id = COALESCE(fs.facility_id, CASE WHEN b.resource_type = 'facility' THEN b.resource_id END)
+		 JOIN waiver_attachments a ON a.waiver_id = $1 AND a.subject_type = 'facility_type' AND a.subject_key = f.type
+		 WHERE b.tenant_id = $3 AND b.status IN ('pending', 'approved')
+		   AND (fs.id IS NULL OR fs.ends_at > now())
+		   AND NOT EXISTS (SELECT 1 FROM waiver_signatures s
+		                   WHERE s.waiver_version_id = $2 AND s.subject_type = 'booking' AND s.subject_id = b.id)
+		 ORDER BY 1, 6, 5`,
+		waiverID, versionID, claims.TenantID, activeRegistrationStatuses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	unsigned := []gin.H{}
+	for rows.Next() {
+		var subjectType, subjectID, status string
+		var userID, email, participant, title *string
+		var startsAt *time.Time
+		var signedVersion *int
+		if err := rows.Scan(&subjectType, &subjectID, &userID, &email, &participant,
+			&title, &status, &startsAt, &signedVersion); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		unsigned = append(unsigned, gin.H{
+			"subject_type":         subjectType,
+			"subject_id":           subjectID,
+			"user_id":              userID,
+			"email":                email,
+			"name":                 participant,
+			"for":                  title,
+			"status":               status,
+			"starts_at":            startsAt,
+			"signed_older_version": signedVersion,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"waiver_version_id": versionID,
+		"version":           version,
+		"unsigned":          unsigned,
+	})
+}
+
+// DownloadWaiverSignature returns the signed PDF to staff or the signer
+func (h *Handler) DownloadWaiverSignature(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	var userID *string
+	var path string
+	var data []byte
+	err := h.DB.QueryRow(ctx,
+		`SELECT s.user_id, m.path, m.data
+		 FROM waiver_signatures s
+		 JOIN media_assets m ON s.media_id = m.id
+		 WHERE s.id = $1 AND s.tenant_id = $2`,
+		c.Param("id"), claims.TenantID).Scan(&userID, &path, &data)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "signature not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	if claims.Role != "OWNER" && claims.Role != "ADMIN" && (userID == nil || *userID != claims.UserID.String()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "signature not found"})
+		return
+	}
+
+	filename := path[strings.LastIndex(path, "/")+1:]
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="waiver-%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// ListMyWaivers returns the waivers the caller has signed
+func (h *Handler) ListMyWaivers(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := context.Background()
+	rows, err := h.DB.Query(ctx,
+		`SELECT s.id, w.name, v.version, v.title, s.subject_type, s.subject_id,
+		        s.signer_name, s.participant_name, s.signed_at
+		 FROM waiver_signatures s
+		 JOIN waivers w ON s.waiver_id = w.id
+		 JOIN waiver_versions v ON s.waiver_version_id = v.id
+		 WHERE s.tenant_id = $1 AND s.user_id = $2
+		 ORDER BY s.signed_at DESC`,
+		claims.TenantID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	signatures := []gin.H{}
+	for rows.Next() {
+		var id, name, title, subjectType, subjectID, signerName string
+		var version int
+		var participant *string
+		var signedAt time.Time
+		if err := rows.Scan(&id, &name, &version, &title, &subjectType, &subjectID,
+			&signerName, &participant, &signedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		signatures = append(signatures, gin.H{
+			"id":               id,
+			"waiver":           name,
+			"version":          version,
+			"title":            title,
+			"subject_type":     subjectType,
+			"subject_id":       subjectID,
+			"signer_name":      signerName,
+			"participant_name": participant,
+			"signed_at":        signedAt,
+			"pdf_url":          "/api/waiver-signatures/" + id + "/pdf",
+		})
+	}
+
+	c.JSON(http.StatusOK, signatures)
+}
+
+// SignMyRegistrationWaivers signs waivers for an existing registration, such
+// as a new version published after the resident registered or one staff
+// left unsigned when registering on their behalf
+func (h *Handler) SignMyRegistrationWaivers(c *gin.Context) {
+	claims := middleware.GetClaimsFromContext(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req SignWaiversRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	tenantID := claims.TenantID.String()
+	userID := claims.UserID.String()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	reg, ok := loadMyRegistration(c, ctx, tx, tenantID, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !containsString(activeRegistrationStatuses, reg.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "registration is already " + reg.Status})
+		return
+	}
+
+	required, err := requiredWaivers(ctx, tx, tenantID, WaiverForProgram, reg.ProgramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load waivers"})
+		return
+	}
+
+	// Only waivers the caller has signed in this request are checked; ones
+	// already on file for the current version are skipped
+	var pending []RequiredWaiver
+	for _, r := range required {
+		var signed bool
+		err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM waiver_signatures
+			 WHERE waiver_version_id = $1 AND subject_type = 'program_registration' AND subject_id = $2)`,
+			r.Version.ID, reg.ID).Scan(&signed)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !signed {
+			pending = append(pending, r)
+		}
+	}
+	if len(pending) == 0 {
+		c.JSON(http.StatusOK, gin.H{"signatures": []string{}, "message": "no waivers are awaiting signature"})
+		return
+	}
+
+	accepted, issues := matchWaiverSignatures(pending, req.Waivers)
+	if len(issues) > 0 {
+		respondWaiverIssues(c, issues)
+		return
+	}
+
+	var participant string
+	if err := tx.QueryRow(ctx,
+		`SELECT participant_name FROM program_registrations WHERE id = $1`,
+		reg.ID).Scan(&participant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	signer := signerFromRequest(c, tenantID)
+	signer.UserID = &userID
+	signer.Email = claims.Email
+	signer.Participant = participant
+	signer.Purpose = "Registration for " + reg.ProgramTitle
+	registrationID, _ := uuid.Parse(reg.ID)
+	ids, err := recordWaiverSignatures(ctx, tx, signer, "program_registration", registrationID, accepted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record signatures"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"signatures": ids})
+}
+
+// GetPublicWaivers returns the waivers that must be signed to register for
+// a program or event, or to book a facility or slot
+func (h *Handler) GetPublicWaivers(c *gin.Context) {
+	tenantDomain := middleware.GetTenantIDFromContext(c)
+	ctx := context.Background()
+
+	tenantID, err := middleware.ResolveTenantID(ctx, h.DB, tenantDomain)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		return
+	}
+
+	var subjectType, key string
+	switch {
+	case c.Query("program_id") != "":
+		subjectType, key = WaiverForProgram, c.Query("program_id")
+	case c.Query("event_id") != "":
+		subjectType, key = WaiverForEvent, c.Query("event_id")
+	case c.Query("facility_id") != "":
+		subjectType = WaiverForFacilityType
+		key, err = bookingFacilityType(ctx, h.DB, tenantID, "facility", c.Query("facility_id"))
+	case c.Query("slot_id") != "":
+		subjectType = WaiverForFacilityType
+		key, err = bookingFacilityType(ctx, h.DB, tenantID, "facility_slot", c.Query("slot_id"))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "program_id, event_id, facility_id or slot_id is required"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "facility not found"})
+		return
+	}
+
+	required, err := requiredWaivers(ctx, h.DB, tenantID, subjectType, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load waivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, required)
+}
@@ -0,0 +1,201 @@
+// Package ical writes iCalendar (RFC 5545) feeds that calendar apps can
+// subscribe to.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+
+	// RFC 5545 lines are folded at 75 octets
+	maxLineOctets = 75
+)
+
+// Event status values
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is a VEVENT. An event with RecurrenceID set replaces one instance
+// of the recurring event with the same UID.
+type Event struct {
+	UID          string
+	RecurrenceID *time.Time
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	Categories   []string
+	Status       string
+	RRule        string
+	ExDates      []time.Time
+	LastModified time.Time
+}
+
+// Calendar is a VCALENDAR feed. Event times are written as wall-clock
+// times in Location with a matching VTIMEZONE, or in UTC when Location is
+// nil or UTC.
+type Calendar struct {
+	ProductID   string
+	Name        string
+	Description string
+	Location    *time.Location
+	// Refresh is how often subscribers should poll the feed
+	Refresh time.Duration
+	Events  []Event
+}
+
+// Bytes renders the calendar. now is the DTSTAMP of events without a
+// LastModified time.
+func (c *Calendar) Bytes(now time.Time) []byte {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + c.ProductID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + Escape(c.Name))
+	}
+	if c.Description != "" {
+		w.line("X-WR-CALDESC:" + Escape(c.Description))
+	}
+	loc := c.Location
+	if loc == nil || loc == time.UTC {
+		loc = nil
+	} else {
+		w.line("X-WR-TIMEZONE:" + loc.String())
+	}
+	if c.Refresh > 0 {
+		w.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(c.Refresh))
+		w.line("X-PUBLISHED-TTL:" + duration(c.Refresh))
+	}
+	if loc != nil {
+		writeTimezone(w, loc, now)
+	}
+	for i := range c.Events {
+		c.Events[i].write(w, loc, now)
+	}
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+func (e *Event) write(w *writer, loc *time.Location, now time.Time) {
+	stamp := now
+	if !e.LastModified.IsZero() {
+		stamp = e.LastModified
+	}
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + e.UID)
+	w.line("DTSTAMP:" + stamp.UTC().Format(utcLayout))
+	if e.RecurrenceID != nil {
+		w.line(dateTime("RECURRENCE-ID", *e.RecurrenceID, loc))
+	}
+	w.line(dateTime("DTSTART", e.Start, loc))
+	w.line(dateTime("DTEND", e.End, loc))
+	if e.RRule != "" {
+		w.line("RRULE:" + e.RRule)
+	}
+	for _, ex := range e.ExDates {
+		w.line(dateTime("EXDATE", ex, loc))
+	}
+	w.line("SUMMARY:" + Escape(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION:" + Escape(e.Description))
+	}
+	if e.Location != "" {
+		w.line("LOCATION:" + Escape(e.Location))
+	}
+	if len(e.Categories) > 0 {
+		escaped := make([]string, len(e.Categories))
+		for i, c := range e.Categories {
+			escaped[i] = Escape(c)
+		}
+		w.line("CATEGORIES:" + strings.Join(escaped, ","))
+	}
+	if e.Status != "" {
+		w.line("STATUS:" + e.Status)
+	}
+	if !e.LastModified.IsZero() {
+		w.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(utcLayout))
+	}
+	w.line("END:VEVENT")
+}
+
+// dateTime formats a DATE-TIME property in loc, or in UTC when loc is nil
+func dateTime(name string, t time.Time, loc *time.Location) string {
+	if loc == nil {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+// duration formats d as an RFC 5545 DURATION
+func duration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		d = time.Minute
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	}
+	return fmt.Sprintf("PT%dM", d/time.Minute)
+}
+
+// Escape escapes a TEXT value
+func Escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case ';':
+			b.WriteString(`\;`)
+		case ',':
+			b.WriteString(`\,`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writer emits CRLF-terminated content lines, folding long ones without
+// splitting UTF-8 sequences
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// continuation lines start with a space, which counts
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
@@ -0,0 +1,259 @@
+package ical
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Property is a parsed content line. Parameter names are upper case.
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+	Line   int
+}
+
+// Component is a BEGIN/END block and the blocks nested in it
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+	Line       int
+}
+
+// Parse reads an iCalendar document and returns its VCALENDAR
+func Parse(data string) (*Component, error) {
+	lines := unfold(data)
+	var stack []*Component
+	var root *Component
+	for _, l := range lines {
+		if strings.TrimSpace(l.text) == "" {
+			continue
+		}
+		p, err := parseLine(l.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.number, err)
+		}
+		p.Line = l.number
+		switch p.Name {
+		case "BEGIN":
+			c := &Component{Name: strings.ToUpper(p.Value), Line: l.number}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			} else if root == nil {
+				root = c
+			} else {
+				return nil, fmt.Errorf("line %d: content after END:%s", l.number, root.Name)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", l.number, p.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: %s outside a component", l.number, p.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Properties = append(c.Properties, p)
+		}
+	}
+	if root == nil || root.Name != "VCALENDAR" {
+		return nil, fmt.Errorf("not an iCalendar file: expected BEGIN:VCALENDAR")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// Get returns the component's first property with the given name
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// All returns every property with the given name
+func (c *Component) All(name string) []Property {
+	var out []Property
+	for _, p := range c.Properties {
+		if p.Name == name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Children returns the nested components with the given name
+func (c *Component) Children(name string) []*Component {
+	var out []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Text returns the property's value with TEXT escapes removed
+func (p *Property) Text() string {
+	return Unescape(p.Value)
+}
+
+// Times parses a DATE or DATE-TIME value, or a comma-separated list of
+// them. Times without a zone or with a TZID that is not an IANA name are
+// read in fallback. allDay reports a DATE value.
+func (p *Property) Times(fallback *time.Location) (times []time.Time, allDay bool, err error) {
+	loc := fallback
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	allDay = p.Params["VALUE"] == "DATE"
+	for _, v := range strings.Split(p.Value, ",") {
+		v = strings.TrimSpace(v)
+		var t time.Time
+		switch {
+		case len(v) == 8:
+			allDay = true
+			t, err = time.ParseInLocation("20060102", v, loc)
+		case strings.HasSuffix(v, "Z"):
+			t, err = time.Parse(utcLayout, v)
+		default:
+			t, err = time.ParseInLocation(localLayout, v, loc)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid %s %q", p.Name, v)
+		}
+		times = append(times, t)
+	}
+	return times, allDay, nil
+}
+
+// Time parses a single DATE or DATE-TIME value; see Times
+func (p *Property) Time(fallback *time.Location) (time.Time, bool, error) {
+	times, allDay, err := p.Times(fallback)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return times[0], allDay, nil
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Duration parses a DURATION value such as PT1H30M or P1D
+func (p *Property) Duration() (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.TrimSpace(p.Value))
+	if m == nil || p.Value == "P" || strings.HasSuffix(p.Value, "T") {
+		return 0, fmt.Errorf("invalid %s %q", p.Name, p.Value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(m[i+2])
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// Unescape reverses Escape
+func Unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+type contentLine struct {
+	text   string
+	number int
+}
+
+// unfold joins continuation lines, which start with a space or tab, onto
+// the line before them
+func unfold(data string) []contentLine {
+	data = strings.TrimPrefix(data, "\ufeff")
+	raw := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var out []contentLine
+	for i, l := range raw {
+		l = strings.TrimSuffix(l, "\r")
+		if len(out) > 0 && len(l) > 0 && (l[0] == ' ' || l[0] == '\t') {
+			out[len(out)-1].text += l[1:]
+			continue
+		}
+		out = append(out, contentLine{text: l, number: i + 1})
+	}
+	return out
+}
+
+// parseLine splits "NAME;PARAM=value;PARAM="quoted":value"
+func parseLine(line string) (Property, error) {
+	p := Property{Params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, fmt.Errorf("malformed content line")
+	}
+	p.Name = strings.ToUpper(line[:i])
+	rest := line[i:]
+	for len(rest) > 0 && rest[0] == ';' {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return p, fmt.Errorf("malformed parameter in %s", p.Name)
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return p, fmt.Errorf("unterminated quote in %s", p.Name)
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return p, fmt.Errorf("malformed content line")
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		p.Params[name] = value
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return p, fmt.Errorf("malformed content line")
+	}
+	p.Value = rest[1:]
+	return p, nil
+}
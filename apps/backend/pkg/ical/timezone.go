@@ -0,0 +1,116 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// transition is a change of UTC offset within a zone
+type transition struct {
+	at         time.Time
+	fromOffset int
+	toOffset   int
+	name       string
+	dst        bool
+}
+
+// writeTimezone writes a VTIMEZONE for loc. The zone's transitions in the
+// year of now become yearly rules so clients can place events in any
+// year; a zone without transitions gets a single fixed offset.
+func writeTimezone(w *writer, loc *time.Location, now time.Time) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+	ts := transitions(loc, now.In(loc).Year())
+	if len(ts) == 0 {
+		name, offset := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc).Zone()
+		w.line("BEGIN:STANDARD")
+		w.line("DTSTART:19700101T000000")
+		w.line("TZOFFSETFROM:" + formatOffset(offset))
+		w.line("TZOFFSETTO:" + formatOffset(offset))
+		w.line("TZNAME:" + Escape(name))
+		w.line("END:STANDARD")
+	}
+	for _, t := range ts {
+		kind := "STANDARD"
+		if t.dst {
+			kind = "DAYLIGHT"
+		}
+		// the onset is expressed in the offset in force before it
+		onset := t.at.In(time.FixedZone("", t.fromOffset))
+		n, weekday := weekdayOfMonth(onset)
+		first := nthWeekday(1970, onset.Month(), n, weekday)
+		w.line("BEGIN:" + kind)
+		w.line(fmt.Sprintf("DTSTART:1970%02d%02dT%02d%02d%02d",
+			onset.Month(), first, onset.Hour(), onset.Minute(), onset.Second()))
+		w.line(fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", onset.Month(), n, weekdayCode(weekday)))
+		w.line("TZOFFSETFROM:" + formatOffset(t.fromOffset))
+		w.line("TZOFFSETTO:" + formatOffset(t.toOffset))
+		w.line("TZNAME:" + Escape(t.name))
+		w.line("END:" + kind)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+// transitions returns loc's offset changes during year, found day by day
+// and then narrowed to the second
+func transitions(loc *time.Location, year int) []transition {
+	var out []transition
+	day := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(1, 0, 0)
+	_, prev := day.In(loc).Zone()
+	for day.Before(end) {
+		next := day.Add(24 * time.Hour)
+		_, offset := next.In(loc).Zone()
+		if offset != prev {
+			lo, hi := day, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(loc).Zone(); o == prev {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			name, _ := hi.In(loc).Zone()
+			out = append(out, transition{at: hi, fromOffset: prev, toOffset: offset, name: name, dst: hi.In(loc).IsDST()})
+			prev = offset
+		}
+		day = next
+	}
+	return out
+}
+
+// weekdayOfMonth describes t's date as the nth weekday of its month,
+// counting from the end (-1) when it is the last one
+func weekdayOfMonth(t time.Time) (int, time.Weekday) {
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if t.Day()+7 > daysInMonth {
+		return -1, t.Weekday()
+	}
+	return (t.Day()-1)/7 + 1, t.Weekday()
+}
+
+// nthWeekday returns the day of month of the nth (or last, for -1)
+// weekday in the given month
+func nthWeekday(year int, month time.Month, n int, weekday time.Weekday) int {
+	if n < 0 {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.Day() - (int(last.Weekday())-int(weekday)+7)%7
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return 1 + (int(weekday)-int(first.Weekday())+7)%7 + (n-1)*7
+}
+
+func weekdayCode(d time.Weekday) string {
+	return [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}[d]
+}
+
+// formatOffset formats seconds east of UTC as +HHMM
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
@@ -0,0 +1,125 @@
+// Package ledger builds balanced double-entry transactions for household
+// accounts. Amounts are signed cents: positive debits, negative credits.
+package ledger
+
+import "errors"
+
+// Accounts
+const (
+	Receivable = "receivable"
+	Cash       = "cash"
+	Revenue    = "revenue"
+	Credit     = "credit"
+)
+
+// Transaction kinds
+const (
+	KindCharge     = "charge"
+	KindPayment    = "payment"
+	KindRefund     = "refund"
+	KindCredit     = "credit"
+	KindAdjustment = "adjustment"
+)
+
+var (
+	ErrUnbalanced = errors.New("ledger transaction is not balanced")
+	ErrEmpty      = errors.New("ledger transaction has no entries")
+)
+
+// Entry is one side of a transaction
+type Entry struct {
+	Account     string `json:"account"`
+	AmountCents int    `json:"amount_cents"`
+}
+
+// Transaction is a set of entries that must sum to zero
+type Transaction struct {
+	Kind        string
+	Description string
+	Entries     []Entry
+}
+
+// Validate checks that the transaction has non-zero entries that balance
+func (t Transaction) Validate() error {
+	sum, nonZero := 0, false
+	for _, e := range t.Entries {
+		sum += e.AmountCents
+		if e.AmountCents != 0 {
+			nonZero = true
+		}
+	}
+	if !nonZero {
+		return ErrEmpty
+	}
+	if sum != 0 {
+		return ErrUnbalanced
+	}
+	return nil
+}
+
+func pair(kind, description, debit, credit string, amount int) Transaction {
+	return Transaction{
+		Kind:        kind,
+		Description: description,
+		Entries: []Entry{
+			{Account: debit, AmountCents: amount},
+			{Account: credit, AmountCents: -amount},
+		},
+	}
+}
+
+// Charge bills the household for a registration or booking
+func Charge(description string, amount int) Transaction {
+	return pair(KindCharge, description, Receivable, Revenue, amount)
+}
+
+// Payment records money received against what the household owes
+func Payment(description string, amount int) Transaction {
+	return pair(KindPayment, description, Cash, Receivable, amount)
+}
+
+// CreditApplied spends account credit against what the household owes
+func CreditApplied(description string, amount int) Transaction {
+	return pair(KindPayment, description, Credit, Receivable, amount)
+}
+
+// RefundToPayment returns money to the original payment method
+func RefundToPayment(description string, amount int) Transaction {
+	return pair(KindRefund, description, Revenue, Cash, amount)
+}
+
+// RefundToCredit returns money to the household as account credit
+func RefundToCredit(description string, amount int) Transaction {
+	return pair(KindRefund, description, Revenue, Credit, amount)
+}
+
+// GrantCredit gives the household account credit outside of a refund
+func GrantCredit(description string, amount int) Transaction {
+	return pair(KindCredit, description, Revenue, Credit, amount)
+}
+
+// Adjustment changes what the household owes. Negative amounts write off
+// part of the balance.
+func Adjustment(description string, amount int) Transaction {
+	return pair(KindAdjustment, description, Receivable, Revenue, amount)
+}
+
+// Balance summarises a household account
+type Balance struct {
+	// OwedCents is what the household still owes
+	OwedCents int `json:"owed_cents"`
+	// CreditCents is account credit available to spend
+	CreditCents int `json:"credit_cents"`
+	// NetCents is owed minus credit; negative means the tenant owes the household
+	NetCents int `json:"net_cents"`
+}
+
+// BalanceFromTotals builds a Balance from per-account entry sums
+func BalanceFromTotals(totals map[string]int) Balance {
+	b := Balance{
+		OwedCents:   totals[Receivable],
+		CreditCents: -totals[Credit],
+	}
+	b.NetCents = b.OwedCents - b.CreditCents
+	return b
+}
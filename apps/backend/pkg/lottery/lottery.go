@@ -0,0 +1,93 @@
+// Package lottery runs seeded, weighted random draws. Every entry's draw
+// value depends only on the seed and the entry's id, so anyone holding the
+// seed and the entry list can reproduce a draw.
+package lottery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+)
+
+// Algorithm names the draw method recorded with each draw
+const Algorithm = "hmac-sha256-weighted-v1"
+
+// Entry is one ticket in the draw. Weight must be positive; entries with a
+// higher weight are proportionally more likely to rank early.
+type Entry struct {
+	ID     string  `json:"id"`
+	Weight float64 `json:"weight"`
+}
+
+// Result is an entry's place in the draw. Rank starts at 1.
+type Result struct {
+	ID     string  `json:"id"`
+	Weight float64 `json:"weight"`
+	Draw   float64 `json:"draw"`
+	Key    float64 `json:"key"`
+	Rank   int     `json:"rank"`
+}
+
+// EntriesDigest is the hex SHA-256 of the entry ids, sorted and each ended
+// by a newline. It fixes which entries a draw was seeded for.
+func EntriesDigest(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, id := range sorted {
+		h.Write([]byte(id))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DeriveSeed returns the seed for a draw: the hex HMAC-SHA256, keyed by a
+// server secret, of the draw's name and the digest of its closed entry set.
+// Without the secret the seed cannot be known before the draw, so entries
+// cannot be picked to suit it, and any change to the entries changes it.
+func DeriveSeed(secret, name, entriesDigest string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(name))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(entriesDigest))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Uniform maps the seed and an entry id to a number in (0, 1): the first 53
+// bits of HMAC-SHA256(seed, id), offset by half a step so 0 never occurs.
+func Uniform(seed, id string) float64 {
+	mac := hmac.New(sha256.New, []byte(seed))
+	mac.Write([]byte(id))
+	sum := mac.Sum(nil)
+	x := binary.BigEndian.Uint64(sum[:8]) >> 11
+	return (float64(x) + 0.5) / (1 << 53)
+}
+
+// Draw ranks the entries. Each entry's key is ln(u)/weight, the log of the
+// Efraimidis-Spirakis key u^(1/weight); entries are ranked by key, highest
+// first, with ties broken by id.
+func Draw(seed string, entries []Entry) []Result {
+	results := make([]Result, 0, len(entries))
+	for _, e := range entries {
+		w := e.Weight
+		if w <= 0 {
+			w = 1
+		}
+		u := Uniform(seed, e.ID)
+		results = append(results, Result{ID: e.ID, Weight: w, Draw: u, Key: math.Log(u) / w})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Key != results[j].Key {
+			return results[i].Key > results[j].Key
+		}
+		return results[i].ID < results[j].ID
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
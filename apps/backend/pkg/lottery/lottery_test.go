@@ -0,0 +1,147 @@
+package lottery
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func entryIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("00000000-0000-0000-0000-%012d", i)
+	}
+	return ids
+}
+
+func TestDrawReproducible(t *testing.T) {
+	entries := []Entry{
+		{ID: "a1f0c3e2-0001-4000-8000-000000000001", Weight: 1},
+		{ID: "a1f0c3e2-0002-4000-8000-000000000002", Weight: 2},
+		{ID: "a1f0c3e2-0003-4000-8000-000000000003", Weight: 1.5},
+		{ID: "a1f0c3e2-0004-4000-8000-000000000004", Weight: 1},
+	}
+	first := Draw("fixed-seed", entries)
+
+	// Same seed and entries in another order give the same draw
+	reversed := make([]Entry, len(entries))
+	for i, e := range entries {
+		reversed[len(entries)-1-i] = e
+	}
+	if again := Draw("fixed-seed", reversed); !reflect.DeepEqual(first, again) {
+		t.Errorf("Draw is not reproducible:\n%+v\n%+v", first, again)
+	}
+
+	for i, r := range first {
+		if r.Rank != i+1 {
+			t.Errorf("result %d has rank %d", i, r.Rank)
+		}
+		if r.Draw != Uniform("fixed-seed", r.ID) {
+			t.Errorf("%s: draw %v does not match Uniform", r.ID, r.Draw)
+		}
+		if want := math.Log(r.Draw) / r.Weight; r.Key != want {
+			t.Errorf("%s: key %v, want %v", r.ID, r.Key, want)
+		}
+		if i > 0 && first[i-1].Key < r.Key {
+			t.Errorf("rank %d has a higher key than rank %d", r.Rank, first[i-1].Rank)
+		}
+	}
+
+	if other := Draw("other-seed", entries); reflect.DeepEqual(first, other) {
+		t.Errorf("different seeds gave the same draw")
+	}
+}
+
+func TestDrawWeights(t *testing.T) {
+	tests := []struct {
+		name   string
+		weight float64
+		want   float64
+	}{
+		{"zero weight counts as one", 0, 1},
+		{"negative weight counts as one", -3, 1},
+		{"weight kept", 2.5, 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Draw("seed", []Entry{{ID: "x", Weight: tt.weight}})
+			if r[0].Weight != tt.want {
+				t.Errorf("weight = %v, want %v", r[0].Weight, tt.want)
+			}
+		})
+	}
+
+	// Heavier entries rank earlier: a tenth of the entries at weight 10
+	// take about half of the first 100 places, far above their share
+	ids := entryIDs(1000)
+	entries := make([]Entry, len(ids))
+	heavy := map[string]bool{}
+	for i, id := range ids {
+		entries[i] = Entry{ID: id, Weight: 1}
+		if i%10 == 0 {
+			entries[i].Weight = 10
+			heavy[id] = true
+		}
+	}
+	results := Draw("weights", entries)
+	top := 0
+	for _, r := range results[:100] {
+		if heavy[r.ID] {
+			top++
+		}
+	}
+	if top < 30 {
+		t.Errorf("weight-10 entries took %d of the first 100 places, want at least 30", top)
+	}
+
+	// The same entry gets the same draw at any weight, and a higher key
+	light := Draw("seed", []Entry{{ID: "x", Weight: 1}})[0]
+	heavier := Draw("seed", []Entry{{ID: "x", Weight: 2}})[0]
+	if light.Draw != heavier.Draw || heavier.Key <= light.Key {
+		t.Errorf("weight 2 gave %+v, weight 1 gave %+v", heavier, light)
+	}
+}
+
+func TestUniformRange(t *testing.T) {
+	for _, seed := range []string{"", "seed", "4f1c9a0b7e6d5c4b3a2918f7e6d5c4b3"} {
+		for _, id := range entryIDs(5000) {
+			u := Uniform(seed, id)
+			if u <= 0 || u >= 1 {
+				t.Fatalf("Uniform(%q, %q) = %v, want in (0, 1)", seed, id, u)
+			}
+			if math.IsInf(math.Log(u), 0) {
+				t.Fatalf("Uniform(%q, %q) = %v has no finite log", seed, id, u)
+			}
+		}
+	}
+}
+
+func TestDeriveSeed(t *testing.T) {
+	ids := entryIDs(3)
+	digest := EntriesDigest(ids)
+
+	tests := []struct {
+		name   string
+		secret string
+		draw   string
+		ids    []string
+		same   bool
+	}{
+		{"same inputs", "secret", "program-1", ids, true},
+		{"entries reordered", "secret", "program-1", []string{ids[2], ids[0], ids[1]}, true},
+		{"entry added", "secret", "program-1", append(entryIDs(3), "extra"), false},
+		{"entry removed", "secret", "program-1", ids[:2], false},
+		{"other secret", "other", "program-1", ids, false},
+		{"other program", "secret", "program-2", ids, false},
+	}
+	want := DeriveSeed("secret", "program-1", digest)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeriveSeed(tt.secret, tt.draw, EntriesDigest(tt.ids))
+			if (got == want) != tt.same {
+				t.Errorf("DeriveSeed = %s, base %s, want same = %v", got, want, tt.same)
+			}
+		})
+	}
+}
@@ -91,6 +91,17 @@ func (m *Mailer) SendBookingDeclinedNotification(to string, bn BookingNotificati
 	return m.send(msg)
 }
 
+// Send delivers a prepared HTML message
+func (m *Mailer) Send(to, subject, body string) error {
+	msg := mail.NewMessage()
+	msg.SetHeader("From", m.FromAddr)
+	msg.SetHeader("To", to)
+	msg.SetHeader("Subject", subject)
+	msg.SetBody("text/html", body)
+
+	return m.send(msg)
+}
+
 func (m *Mailer) send(msg *mail.Message) error {
 	dialer := mail.NewDialer(m.Host, m.Port, "", "")
 	if err := dialer.DialAndSend(msg); err != nil {
@@ -0,0 +1,320 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxAttempts is how many times an outbox message is tried before it is
+// marked failed
+const maxAttempts = 5
+
+// Outbox delivers messages queued in the email_outbox table
+type Outbox struct {
+	DB     *pgxpool.Pool
+	Mailer *Mailer
+}
+
+// Run polls the outbox until ctx is cancelled
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := o.Flush(ctx, 50); err != nil {
+			log.Printf("Email outbox: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush sends up to limit due messages. Rows are claimed with SKIP LOCKED so
+// several workers can share the table.
+func (o *Outbox) Flush(ctx context.Context, limit int) error {
+	tx, err := o.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT id::text, to_email, subject, body_html, attempts FROM email_outbox
+		 WHERE status = 'pending' AND send_after <= now()
+		 ORDER BY send_after
+		 LIMIT $1
+		 FOR UPDATE SKIP LOCKED`,
+		limit)
+	if err != nil {
+		return err
+	}
+	type message struct {
+		id, to, subject, body string
+		attempts              int
+	}
+	var due []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.to, &m.subject, &m.body, &m.attempts); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range due {
+		var err error
+		if sendErr := o.Mailer.Send(m.to, m.subject, m.body); sendErr != nil {
+			// Back off a minute per attempt
+			status := "pending"
+			if m.attempts+1 >= maxAttempts {
+				status = "failed"
+			}
+			_, err = tx.Exec(ctx,
+				`UPDATE email_outbox
+				 SET attempts = attempts + 1, last_error = $1, status = $2,
+				     send_after = now() + make_interval(mins => attempts + 1)
+				 WHERE id = $3`,
+				sendErr.Error(), status, m.id)
+		} else {
+			_, err = tx.Exec(ctx,
+				`UPDATE email_outbox SET attempts = attempts + 1, status = 'sent', sent_at = now(), last_error = NULL
+				 WHERE id = $1`,
+				m.id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RegistrationStatusEmail renders the notice sent to a household when a
+// program registration changes status
+func RegistrationStatusEmail(programTitle, participantName, status string) (subject, body string) {
+	program := html.EscapeString(programTitle)
+	participant := html.EscapeString(participantName)
+
+	var heading, text string
+	switch status {
+	case "entered":
+		heading = "Lottery Entry Received"
+		text = "%s has been entered in the lottery for %s. We'll email you after the draw."
+	case "offered":
+		heading = "A Place Is Available"
+		text = "A place is available for %s in %s. Sign in to accept it before the offer expires."
+	case "pending":
+		heading = "Registration Received"
+		text = "A place has been held for %s in %s. We'll let you know once it is confirmed."
+	case "approved":
+		heading = "Registration Approved"
+		text = "Great news! %s is confirmed for %s."
+	case "waitlisted":
+		heading = "Added to Waitlist"
+		text = "%s has been added to the waitlist for %s. We'll email you if a place opens up."
+	case "completed":
+		heading = "Program Completed"
+		text = "%s has completed %s. Thanks for taking part!"
+	case "cancelled":
+		heading = "Registration Cancelled"
+		text = "The registration for %s in %s has been cancelled."
+	default:
+		heading = "Registration Update"
+		text = "The registration for %s in %s is now " + html.EscapeString(status) + "."
+	}
+
+	subject = fmt.Sprintf("%s - %s", heading, programTitle)
+	body = fmt.Sprintf(`
+<h2>%s</h2>
+<p>%s</p>
+`, heading, fmt.Sprintf(text, participant, program))
+	return subject, body
+}
+
+// BookingStatusEmail renders the notice sent to a requester when a booking
+// changes status
+func BookingStatusEmail(bn BookingNotification) (subject, body string) {
+	facility := html.EscapeString(bn.FacilityName)
+	slot := html.EscapeString(bn.SlotTime)
+
+	var heading, text string
+	switch bn.Status {
+	case "approved":
+		heading = "Booking Approved"
+		text = "Great news! Your booking request has been approved."
+	case "declined":
+		heading = "Booking Status Update"
+		text = "Unfortunately, your booking request has been declined. Please try another time or contact us for more information."
+	case "cancelled":
+		heading = "Booking Cancelled"
+		text = "Your booking has been cancelled."
+	default:
+		heading = "Booking Status Update"
+		text = "Your booking is now " + html.EscapeString(bn.Status) + "."
+	}
+
+	subject = fmt.Sprintf("%s - %s", heading, bn.FacilityName)
+	body = fmt.Sprintf(`
+<h2>%s</h2>
+<p>%s</p>
+<p><strong>Facility:</strong> %s</p>
+<p><strong>Time Slot:</strong> %s</p>
+`, heading, text, facility, slot)
+	return subject, body
+}
+
+// ScholarshipDecisionEmail renders the notice sent to a household when its
+// financial assistance application is decided. award describes an approved
+// award, such as "50% off program fees".
+func ScholarshipDecisionEmail(fundName, status, award, note string) (subject, body string) {
+	fund := html.EscapeString(fundName)
+
+	var heading, text string
+	switch status {
+	case "approved":
+		heading = "Application Approved"
+		text = fmt.Sprintf("Your %s application has been approved: %s. It will be applied automatically when you register for programs.", fund, html.EscapeString(award))
+	case "denied":
+		heading = "Application Update"
+		text = fmt.Sprintf("We were unable to approve your %s application.", fund)
+	case "revoked":
+		heading = "Award Withdrawn"
+		text = fmt.Sprintf("Your %s award has been withdrawn and will no longer be applied to new registrations.", fund)
+	default:
+		heading = "Application Update"
+		text = fmt.Sprintf("Your %s application is now %s.", fund, html.EscapeString(status))
+	}
+
+	subject = fmt.Sprintf("%s - %s", heading, fundName)
+	body = fmt.Sprintf(`
+<h2>%s</h2>
+<p>%s</p>
+`, heading, text)
+	if strings.TrimSpace(note) != "" {
+		body += fmt.Sprintf("<p>%s</p>\n", html.EscapeString(note))
+	}
+	return subject, body
+}
+
+// EventNotification describes an event RSVP for notices
+type EventNotification struct {
+	EventTitle string
+	When       string
+	Location   string
+	Status     string
+	PartySize  int
+	// CancelURL lets guests without an account cancel
+	CancelURL string
+	// TicketCode is scanned at check-in; TicketImageURL is its QR code
+	TicketCode     string
+	TicketImageURL string
+}
+
+// EventRegistrationEmail renders the notice sent when an RSVP is made,
+// changed or cancelled
+func EventRegistrationEmail(en EventNotification) (subject, body string) {
+	event := html.EscapeString(en.EventTitle)
+
+	var heading, text string
+	switch en.Status {
+	case "registered":
+		heading = "You're Registered"
+		people := "1 person"
+		if en.PartySize != 1 {
+			people = fmt.Sprintf("%d people", en.PartySize)
+		}
+		text = fmt.Sprintf("Your RSVP for %s is confirmed for %s.", event, people)
+	case "cancelled":
+		heading = "RSVP Cancelled"
+		text = fmt.Sprintf("Your RSVP for %s has been cancelled.", event)
+	default:
+		heading = "RSVP Update"
+		text = fmt.Sprintf("Your RSVP for %s is now %s.", event, html.EscapeString(en.Status))
+	}
+
+	subject = fmt.Sprintf("%s - %s", heading, en.EventTitle)
+	body = fmt.Sprintf(`
+<h2>%s</h2>
+<p>%s</p>
+<p><strong>When:</strong> %s</p>
+`, heading, text, html.EscapeString(en.When))
+	if en.Location != "" {
+		body += fmt.Sprintf("<p><strong>Where:</strong> %s</p>\n", html.EscapeString(en.Location))
+	}
+	if en.TicketCode != "" && en.Status == "registered" {
+		body += "<p>Show this ticket at check-in:</p>\n"
+		if en.TicketImageURL != "" {
+			body += fmt.Sprintf("<p><img src=\"%s\" alt=\"Ticket QR code\" width=\"240\" height=\"240\"></p>\n", html.EscapeString(en.TicketImageURL))
+		}
+		body += fmt.Sprintf("<p><code>%s</code></p>\n", html.EscapeString(en.TicketCode))
+	}
+	if en.CancelURL != "" && en.Status == "registered" {
+		body += fmt.Sprintf("<p>Can't make it? <a href=\"%s\">Cancel your RSVP</a>.</p>\n", html.EscapeString(en.CancelURL))
+	}
+	return subject, body
+}
+
+// ReminderNotification describes an upcoming event, program or booking for
+// a reminder. Kind is "event", "program" or "booking".
+type ReminderNotification struct {
+	Kind        string
+	Title       string
+	Participant string
+	When        string
+	Location    string
+	// TicketCode is shown for event reminders; TicketImageURL is its QR code
+	TicketCode     string
+	TicketImageURL string
+}
+
+// ReminderEmail renders a reminder sent ahead of an event, a program's
+// first session or a booking
+func ReminderEmail(rn ReminderNotification) (subject, body string) {
+	title := html.EscapeString(rn.Title)
+
+	var text string
+	switch rn.Kind {
+	case "event":
+		text = fmt.Sprintf("This is a reminder that %s is coming up.", title)
+	case "program":
+		text = fmt.Sprintf("This is a reminder that %s starts soon.", title)
+		if rn.Participant != "" {
+			text = fmt.Sprintf("This is a reminder that %s starts soon for %s.", title, html.EscapeString(rn.Participant))
+		}
+	case "booking":
+		text = fmt.Sprintf("This is a reminder of your booking at %s.", title)
+	default:
+		text = fmt.Sprintf("This is a reminder about %s.", title)
+	}
+
+	subject = fmt.Sprintf("Reminder - %s", rn.Title)
+	body = fmt.Sprintf(`
+<h2>Reminder</h2>
+<p>%s</p>
+<p><strong>When:</strong> %s</p>
+`, text, html.EscapeString(rn.When))
+	if rn.Location != "" {
+		body += fmt.Sprintf("<p><strong>Where:</strong> %s</p>\n", html.EscapeString(rn.Location))
+	}
+	if rn.TicketCode != "" {
+		body += "<p>Show this ticket at check-in:</p>\n"
+		if rn.TicketImageURL != "" {
+			body += fmt.Sprintf("<p><img src=\"%s\" alt=\"Ticket QR code\" width=\"240\" height=\"240\"></p>\n", html.EscapeString(rn.TicketImageURL))
+		}
+		body += fmt.Sprintf("<p><code>%s</code></p>\n", html.EscapeString(rn.TicketCode))
+	}
+	return subject, body
+}
@@ -112,15 +112,16 @@ type Event struct {
 
 // Facility represents a recreation facility
 type Facility struct {
-	ID        uuid.UUID  `json:"id"`
-	TenantID  uuid.UUID  `json:"tenant_id"`
-	Name      string     `json:"name"`
-	Type      string     `json:"type"`
-	Address   *string    `json:"address"`
-	Rules     *string    `json:"rules"`
-	PhotoID   *uuid.UUID `json:"photo_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id"`
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	Address         *string    `json:"address"`
+	Rules           *string    `json:"rules"`
+	PhotoID         *uuid.UUID `json:"photo_id"`
+	HourlyRateCents int        `json:"hourly_rate_cents"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // FacilitySlot represents an available time slot for a facility
@@ -0,0 +1,96 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider is a local stand-in for development and tests. Its checkout
+// URL points back at the API, which completes the payment by sending itself a
+// signed webhook in the same format a real provider would.
+type FakeProvider struct {
+	Secret  string
+	BaseURL string
+}
+
+func NewFakeProvider(secret, baseURL string) *FakeProvider {
+	return &FakeProvider{Secret: secret, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) SignatureHeader() string { return "X-Fake-Signature" }
+
+type fakeEvent struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	SessionID   string `json:"session_id"`
+	PaymentID   string `json:"payment_id"`
+	PaymentRef  string `json:"payment_ref"`
+	AmountCents int    `json:"amount_cents"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+func (p *FakeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	id := "fake_cs_" + uuid.NewString()
+	return &CheckoutSession{
+		ID:  id,
+		URL: fmt.Sprintf("%s/api/payments/fake/checkout/%s", p.BaseURL, id),
+	}, nil
+}
+
+// ExpireCheckoutSession is a no-op; fake checkouts are completed by hand
+func (p *FakeProvider) ExpireCheckoutSession(ctx context.Context, sessionID string) error {
+	return nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if req.PaymentRef == "" {
+		return nil, fmt.Errorf("fake refund: missing payment reference")
+	}
+	return &RefundResult{ID: "fake_re_" + uuid.NewString()}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if err := VerifySignature(p.Secret, payload, signature, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var evt fakeEvent
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("unable to decode fake event: %w", err)
+	}
+
+	return &WebhookEvent{
+		ID:          evt.ID,
+		Type:        evt.Type,
+		SessionID:   evt.SessionID,
+		PaymentID:   evt.PaymentID,
+		PaymentRef:  evt.PaymentRef,
+		AmountCents: evt.AmountCents,
+		Reason:      evt.Reason,
+	}, nil
+}
+
+// BuildEvent returns a signed webhook payload completing a fake checkout
+func (p *FakeProvider) BuildEvent(sessionID, paymentID string, amountCents int, succeeded bool) ([]byte, string) {
+	evt := fakeEvent{
+		ID:          "evt_" + uuid.NewString(),
+		Type:        EventPaymentSucceeded,
+		SessionID:   sessionID,
+		PaymentID:   paymentID,
+		PaymentRef:  "fake_pi_" + uuid.NewString(),
+		AmountCents: amountCents,
+	}
+	if !succeeded {
+		evt.Type = EventPaymentFailed
+		evt.Reason = "card declined"
+	}
+	payload, _ := json.Marshal(evt)
+	return payload, SignPayload(p.Secret, payload, time.Now())
+}
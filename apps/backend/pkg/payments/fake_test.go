@@ -0,0 +1,47 @@
+package payments
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeProviderParseWebhook(t *testing.T) {
+	p := NewFakeProvider("fake-secret", "http://localhost:8000")
+	paid, paidSig := p.BuildEvent("fake_cs_1", "payment-1", 4500, true)
+	failed, failedSig := p.BuildEvent("fake_cs_2", "payment-2", 4500, false)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		wantType  string
+		wantErr   error
+	}{
+		{"paid", paid, paidSig, EventPaymentSucceeded, nil},
+		{"failed", failed, failedSig, EventPaymentFailed, nil},
+		{"tampered amount", bytes.Replace(paid, []byte(`4500`), []byte(`1`), 1), paidSig, "", ErrInvalidSignature},
+		{"signature for another event", paid, failedSig, "", ErrInvalidSignature},
+		{"signed with another secret", paid, SignPayload("other-secret", paid, time.Now()), "", ErrInvalidSignature},
+		{"expired", paid, SignPayload("fake-secret", paid, time.Now().Add(-SignatureTolerance-time.Minute)), "", ErrInvalidSignature},
+		{"unsigned", paid, "", "", ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := p.ParseWebhook(tt.payload, tt.signature)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ParseWebhook() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if evt.Type != tt.wantType || evt.AmountCents != 4500 {
+				t.Errorf("ParseWebhook() = %+v, want type %s for 4500 cents", evt, tt.wantType)
+			}
+		})
+	}
+}
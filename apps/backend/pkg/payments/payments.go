@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Webhook event types normalised across providers
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+)
+
+// ErrInvalidSignature is returned when a webhook payload fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// CheckoutRequest describes a single hosted checkout for one payment
+type CheckoutRequest struct {
+	PaymentID     string
+	AmountCents   int
+	Currency      string
+	Description   string
+	CustomerEmail string
+	SuccessURL    string
+	CancelURL     string
+	// ExpiresAt closes the checkout early; zero keeps the provider default
+	ExpiresAt time.Time
+}
+
+// CheckoutSession is the provider's hosted checkout the payer is sent to
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// RefundRequest returns part or all of a completed payment to the payer
+type RefundRequest struct {
+	RefundID    string
+	PaymentRef  string
+	AmountCents int
+}
+
+// RefundResult is the provider's record of a refund
+type RefundResult struct {
+	ID string
+}
+
+// WebhookEvent is a provider notification reduced to what the app needs
+type WebhookEvent struct {
+	ID          string
+	Type        string
+	SessionID   string
+	PaymentID   string
+	PaymentRef  string
+	AmountCents int
+	Reason      string
+}
+
+// Provider is implemented by each payment processor
+type Provider interface {
+	// Name identifies the provider in URLs and the payments table
+	Name() string
+	// SignatureHeader is the HTTP header carrying the webhook signature
+	SignatureHeader() string
+	CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error)
+	// ExpireCheckoutSession stops an unfinished checkout from being paid
+	ExpireCheckoutSession(ctx context.Context, sessionID string) error
+	// Refund sends money back to the original payment method
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+	// ParseWebhook verifies the signature and decodes the event. Events the
+	// app does not act on are returned with an empty Type.
+	ParseWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
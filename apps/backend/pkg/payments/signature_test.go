@@ -0,0 +1,56 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","amount_cents":4500}`)
+	now := time.Unix(1_800_000_000, 0)
+	signed := SignPayload(secret, payload, now)
+	v1 := signed[len(fmt.Sprintf("t=%d,v1=", now.Unix())):]
+	flipped := "0" + v1[1:]
+	if v1[0] == '0' {
+		flipped = "1" + v1[1:]
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		payload []byte
+		header  string
+		now     time.Time
+		valid   bool
+	}{
+		{"valid", secret, payload, signed, now, true},
+		{"valid with spaces", secret, payload, fmt.Sprintf("t=%d, v1=%s", now.Unix(), v1), now, true},
+		{"one of several signatures valid", secret, payload, fmt.Sprintf("t=%d,v1=deadbeef,v1=%s", now.Unix(), v1), now, true},
+		{"tampered payload", secret, []byte(`{"id":"evt_1","type":"payment.succeeded","amount_cents":1}`), signed, now, false},
+		{"wrong secret", "whsec_other", payload, signed, now, false},
+		{"tampered signature", secret, payload, fmt.Sprintf("t=%d,v1=%s", now.Unix(), flipped), now, false},
+		{"timestamp changed", secret, payload, fmt.Sprintf("t=%d,v1=%s", now.Unix()+1, v1), now.Add(time.Second), false},
+		{"at the tolerance", secret, payload, signed, now.Add(SignatureTolerance), true},
+		{"expired", secret, payload, signed, now.Add(SignatureTolerance + time.Second), false},
+		{"early within the tolerance", secret, payload, signed, now.Add(-SignatureTolerance), true},
+		{"too far in the future", secret, payload, signed, now.Add(-SignatureTolerance - time.Second), false},
+		{"missing timestamp", secret, payload, "v1=" + v1, now, false},
+		{"missing signature", secret, payload, fmt.Sprintf("t=%d", now.Unix()), now, false},
+		{"bad timestamp", secret, payload, "t=soon,v1=" + v1, now, false},
+		{"empty header", secret, payload, "", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.payload, tt.header, tt.now)
+			if tt.valid && err != nil {
+				t.Errorf("VerifySignature() = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifySignature() = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
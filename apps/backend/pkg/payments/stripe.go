@@ -0,0 +1,176 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stripeAPIBase = "https://api.stripe.com"
+
+// StripeProvider creates Stripe Checkout sessions and verifies Stripe webhooks
+type StripeProvider struct {
+	SecretKey     string
+	WebhookSecret string
+	BaseURL       string
+	HTTPClient    *http.Client
+}
+
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		SecretKey:     secretKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       stripeAPIBase,
+		HTTPClient:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *StripeProvider) Name() string { return "stripe" }
+
+func (p *StripeProvider) SignatureHeader() string { return "Stripe-Signature" }
+
+func (p *StripeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("client_reference_id", req.PaymentID)
+	form.Set("metadata[payment_id]", req.PaymentID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", req.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(req.AmountCents))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+	if req.CustomerEmail != "" {
+		form.Set("customer_email", req.CustomerEmail)
+	}
+	if !req.ExpiresAt.IsZero() {
+		// Stripe requires at least 30 minutes from now
+		form.Set("expires_at", strconv.FormatInt(req.ExpiresAt.Unix(), 10))
+	}
+
+	var body struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := p.post(ctx, "/v1/checkout/sessions", req.PaymentID, form, &body); err != nil {
+		return nil, err
+	}
+
+	return &CheckoutSession{ID: body.ID, URL: body.URL}, nil
+}
+
+func (p *StripeProvider) ExpireCheckoutSession(ctx context.Context, sessionID string) error {
+	var body struct {
+		ID string `json:"id"`
+	}
+	return p.post(ctx, "/v1/checkout/sessions/"+url.PathEscape(sessionID)+"/expire", "expire_"+sessionID, url.Values{}, &body)
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.PaymentRef)
+	form.Set("amount", strconv.Itoa(req.AmountCents))
+	form.Set("metadata[refund_id]", req.RefundID)
+
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := p.post(ctx, "/v1/refunds", req.RefundID, form, &body); err != nil {
+		return nil, err
+	}
+
+	return &RefundResult{ID: body.ID}, nil
+}
+
+// post sends a form-encoded request to the Stripe API and decodes the reply
+func (p *StripeProvider) post(ctx context.Context, path, idempotencyKey string, form url.Values, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.SetBasicAuth(p.SecretKey, "")
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read stripe response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(payload, &apiErr) == nil && apiErr.Error != nil {
+			return fmt.Errorf("stripe error: %s", apiErr.Error.Message)
+		}
+		return fmt.Errorf("stripe error: status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("unable to decode stripe response: %w", err)
+	}
+	return nil
+}
+
+func (p *StripeProvider) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if err := VerifySignature(p.WebhookSecret, payload, signature, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var evt struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID                string `json:"id"`
+				ClientReferenceID string `json:"client_reference_id"`
+				PaymentIntent     string `json:"payment_intent"`
+				PaymentStatus     string `json:"payment_status"`
+				AmountTotal       int    `json:"amount_total"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("unable to decode stripe event: %w", err)
+	}
+
+	obj := evt.Data.Object
+	out := &WebhookEvent{
+		ID:          evt.ID,
+		SessionID:   obj.ID,
+		PaymentID:   obj.ClientReferenceID,
+		PaymentRef:  obj.PaymentIntent,
+		AmountCents: obj.AmountTotal,
+	}
+
+	switch evt.Type {
+	case "checkout.session.completed":
+		// Delayed payment methods complete the session before funds arrive
+		if obj.PaymentStatus == "paid" {
+			out.Type = EventPaymentSucceeded
+		}
+	case "checkout.session.async_payment_succeeded":
+		out.Type = EventPaymentSucceeded
+	case "checkout.session.async_payment_failed":
+		out.Type = EventPaymentFailed
+		out.Reason = "payment failed"
+	case "checkout.session.expired":
+		out.Type = EventPaymentFailed
+		out.Reason = "checkout session expired"
+	}
+
+	return out, nil
+}
@@ -0,0 +1,140 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// US Letter, portrait, in points
+const (
+	portraitWidth  = 612.0
+	portraitHeight = 792.0
+
+	bodySize   = 10.0
+	lineHeight = 14.0
+)
+
+// Field is a labelled value printed after a document's body, such as the
+// details of a signature
+type Field struct {
+	Label string
+	Value string
+}
+
+// Document is a titled run of paragraphs followed by labelled fields,
+// flowed across as many portrait pages as needed
+type Document struct {
+	Title      string
+	Subtitle   []string
+	Paragraphs []string
+	Heading    string
+	Fields     []Field
+	Footer     string
+}
+
+type line struct {
+	font string
+	text string
+	gap  float64
+}
+
+// Render returns the PDF bytes
+func (d Document) Render() []byte {
+	width := portraitWidth - 2*margin
+
+	var lines []line
+	for _, s := range d.Subtitle {
+		lines = append(lines, line{font: "F1", text: s})
+	}
+	for _, p := range d.Paragraphs {
+		for i, l := range wrap(p, width, bodySize) {
+			gap := 0.0
+			if i == 0 {
+				gap = lineHeight / 2
+			}
+			lines = append(lines, line{font: "F1", text: l, gap: gap})
+		}
+	}
+	if d.Heading != "" {
+		lines = append(lines, line{font: "F2", text: d.Heading, gap: lineHeight})
+	}
+	for _, f := range d.Fields {
+		for i, l := range wrap(f.Label+": "+f.Value, width, bodySize) {
+			gap := 0.0
+			if i == 0 {
+				gap = 2
+			}
+			lines = append(lines, line{font: "F1", text: l, gap: gap})
+		}
+	}
+
+	var pages []string
+	for first := true; first || len(lines) > 0; first = false {
+		var page strings.Builder
+		y := portraitHeight - margin
+
+		if first {
+			y -= titleSize
+			writeText(&page, "F2", titleSize, margin, y, d.Title)
+			y -= 6
+		}
+
+		for len(lines) > 0 && y-lines[0].gap-lineHeight > margin+lineHeight {
+			y -= lines[0].gap + lineHeight
+			writeText(&page, lines[0].font, bodySize, margin, y, lines[0].text)
+			lines = lines[1:]
+		}
+
+		pages = append(pages, page.String())
+	}
+
+	for i := range pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		if d.Footer != "" {
+			footer = d.Footer + "  ·  " + footer
+		}
+		var page strings.Builder
+		page.WriteString(pages[i])
+		writeText(&page, "F1", 8, margin, margin/2, footer)
+		pages[i] = page.String()
+	}
+
+	return assemble(pages, portraitWidth, portraitHeight)
+}
+
+// wrap breaks s into lines of roughly width points at the given size, using
+// an average Helvetica glyph width. Single newlines are kept as line breaks.
+func wrap(s string, width, size float64) []string {
+	max := int(width / (size * 0.5))
+	var out []string
+	for _, para := range strings.Split(s, "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			out = append(out, "")
+			continue
+		}
+		cur := ""
+		for _, w := range words {
+			for len([]rune(w)) > max {
+				if cur != "" {
+					out = append(out, cur)
+					cur = ""
+				}
+				r := []rune(w)
+				out = append(out, string(r[:max]))
+				w = string(r[max:])
+			}
+			switch {
+			case cur == "":
+				cur = w
+			case len([]rune(cur))+1+len([]rune(w)) <= max:
+				cur += " " + w
+			default:
+				out = append(out, cur)
+				cur = w
+			}
+		}
+		out = append(out, cur)
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+// Package pdf renders simple text documents, such as printable rosters and
+// signed waivers, as PDF without external dependencies. Only the standard
+// Helvetica fonts are used, so text outside Latin-1 is replaced with "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// US Letter, landscape, in points
+const (
+	pageWidth  = 792.0
+	pageHeight = 612.0
+	margin     = 36.0
+
+	titleSize = 14.0
+	textSize  = 9.0
+	rowHeight = 16.0
+)
+
+// Column is a table column. Width is in points; cells that do not fit are
+// truncated with an ellipsis.
+type Column struct {
+	Header string
+	Width  float64
+}
+
+// Table is a titled table split across as many pages as needed. The column
+// headers repeat on every page.
+type Table struct {
+	Title    string
+	Subtitle []string
+	Columns  []Column
+	Rows     [][]string
+	Footer   string
+}
+
+// Render returns the PDF bytes
+func (t Table) Render() []byte {
+	var pages []string
+	rows := t.Rows
+	for first := true; first || len(rows) > 0; first = false {
+		var page strings.Builder
+		y := pageHeight - margin
+
+		if first {
+			y -= titleSize
+			writeText(&page, "F2", titleSize, margin, y, t.Title)
+			y -= 6
+			for _, line := range t.Subtitle {
+				y -= rowHeight
+				writeText(&page, "F1", textSize, margin, y, line)
+			}
+			y -= rowHeight / 2
+		}
+
+		y -= rowHeight
+		x := margin
+		for _, col := range t.Columns {
+			writeText(&page, "F2", textSize, x, y, fit(col.Header, col.Width))
+			x += col.Width
+		}
+		fmt.Fprintf(&page, "%.2f %.2f m %.2f %.2f l S\n", margin, y-4, pageWidth-margin, y-4)
+
+		for len(rows) > 0 && y-rowHeight > margin+rowHeight {
+			y -= rowHeight
+			x = margin
+			for i, col := range t.Columns {
+				cell := ""
+				if i < len(rows[0]) {
+					cell = rows[0][i]
+				}
+				writeText(&page, "F1", textSize, x, y, fit(cell, col.Width))
+				x += col.Width
+			}
+			fmt.Fprintf(&page, "0.85 G %.2f %.2f m %.2f %.2f l S 0 G\n", margin, y-4, pageWidth-margin, y-4)
+			rows = rows[1:]
+		}
+
+		pages = append(pages, page.String())
+	}
+
+	for i := range pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		if t.Footer != "" {
+			footer = t.Footer + "  ·  " + footer
+		}
+		var page strings.Builder
+		page.WriteString(pages[i])
+		writeText(&page, "F1", 8, margin, margin/2, footer)
+		pages[i] = page.String()
+	}
+
+	return assemble(pages, pageWidth, pageHeight)
+}
+
+// assemble writes the catalog, fonts and one page object plus content
+// stream per page, followed by the cross-reference table
+func assemble(pages []string, width, height float64) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page then takes two objects
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			width, height, 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+func writeText(w *strings.Builder, font string, size, x, y float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(w, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, encode(s))
+}
+
+// fit truncates s to roughly fit width points at the body text size, using
+// an average Helvetica glyph width
+func fit(s string, width float64) string {
+	max := int((width - 6) / (textSize * 0.5))
+	r := []rune(s)
+	if max < 1 || len(r) <= max {
+		return s
+	}
+	if max <= 3 {
+		return string(r[:max])
+	}
+	return string(r[:max-3]) + "..."
+}
+
+// encode converts to WinAnsi and escapes string delimiters
+func encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '–':
+			b.WriteByte(0x96)
+		case r == '—':
+			b.WriteByte(0x97)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package pricing
+
+import (
+	"math"
+	"time"
+)
+
+// Rule kinds that can be attached to a program
+const (
+	KindNonResidentSurcharge = "non_resident_surcharge"
+	KindEarlyBird            = "early_bird"
+	KindSiblingDiscount      = "sibling_discount"
+)
+
+// Line item kinds that appear on a quote
+const (
+	LineBase        = "base"
+	LineCoupon      = "coupon"
+	LineScholarship = "scholarship"
+)
+
+// Rule adjusts a program's base price. Exactly one of AmountCents or Percent
+// is used; Percent wins when both are set.
+type Rule struct {
+	ID              string     `json:"id"`
+	Kind            string     `json:"kind"`
+	Label           string     `json:"label"`
+	AmountCents     int        `json:"amount_cents"`
+	Percent         float64    `json:"percent"`
+	EndsAt          *time.Time `json:"ends_at,omitempty"`
+	MinSiblingIndex int        `json:"min_sibling_index,omitempty"`
+}
+
+// Coupon is a tenant-level discount code
+type Coupon struct {
+	Code           string  `json:"code"`
+	PercentOff     float64 `json:"percent_off"`
+	AmountOffCents int     `json:"amount_off_cents"`
+}
+
+// Scholarship is financial assistance awarded to the household. A percentage
+// award reduces the running total; a fixed award applies in full. Either way
+// the discount never exceeds MaxCents, what is left of the award and the
+// fund's budget.
+type Scholarship struct {
+	Label      string  `json:"label"`
+	PercentOff float64 `json:"percent_off"`
+	MaxCents   int     `json:"max_cents"`
+}
+
+// Input describes the registration being priced
+type Input struct {
+	BasePriceCents int
+	IsResident     bool
+	// SiblingIndex is 1 for the household's first participant in the
+	// program, 2 for the second and so on
+	SiblingIndex int
+	At           time.Time
+	Coupon       *Coupon
+	Scholarship  *Scholarship
+}
+
+// Line is a single entry on a quote; discounts have negative amounts
+type Line struct {
+	Kind        string `json:"kind"`
+	Label       string `json:"label"`
+	AmountCents int    `json:"amount_cents"`
+	RuleID      string `json:"rule_id,omitempty"`
+}
+
+// Quote is the itemised price for a registration
+type Quote struct {
+	Lines      []Line `json:"lines"`
+	TotalCents int    `json:"total_cents"`
+}
+
+// Add appends a line and keeps the total from going below zero. A discount
+// larger than the remaining total is clamped to it.
+func (q *Quote) Add(l Line) {
+	if q.TotalCents+l.AmountCents < 0 {
+		l.AmountCents = -q.TotalCents
+	}
+	q.Lines = append(q.Lines, l)
+	q.TotalCents += l.AmountCents
+}
+
+// Compute applies the rules to the input in a fixed order: residency
+// surcharge, early-bird, sibling discount, coupon, then scholarship.
+// Percentages apply to the running total at the point they are evaluated.
+func Compute(rules []Rule, in Input) Quote {
+	q := Quote{Lines: []Line{}}
+	q.Add(Line{Kind: LineBase, Label: "Base price", AmountCents: in.BasePriceCents})
+
+	for _, kind := range []string{KindNonResidentSurcharge, KindEarlyBird, KindSiblingDiscount} {
+		for _, r := range rules {
+			if r.Kind != kind || !applies(r, in) {
+				continue
+			}
+			amount := ruleAmount(r, q.TotalCents)
+			if kind != KindNonResidentSurcharge {
+				amount = -amount
+			}
+			q.Add(Line{Kind: r.Kind, Label: labelFor(r), AmountCents: amount, RuleID: r.ID})
+		}
+	}
+
+	if in.Coupon != nil {
+		amount := in.Coupon.AmountOffCents
+		if in.Coupon.PercentOff > 0 {
+			amount = percentOf(q.TotalCents, in.Coupon.PercentOff)
+		}
+		q.Add(Line{Kind: LineCoupon, Label: "Coupon " + in.Coupon.Code, AmountCents: -amount})
+	}
+
+	if in.Scholarship != nil {
+		q.ApplyScholarship(*in.Scholarship)
+	}
+
+	return q
+}
+
+// ApplyScholarship adds a scholarship line for what the award covers of the
+// current total and returns the amount discounted
+func (q *Quote) ApplyScholarship(s Scholarship) int {
+	amount := s.MaxCents
+	if s.PercentOff > 0 {
+		amount = min(percentOf(q.TotalCents, s.PercentOff), s.MaxCents)
+	}
+	amount = min(amount, q.TotalCents)
+	if amount <= 0 {
+		return 0
+	}
+	label := s.Label
+	if label == "" {
+		label = "Scholarship"
+	}
+	q.Add(Line{Kind: LineScholarship, Label: label, AmountCents: -amount})
+	return amount
+}
+
+func applies(r Rule, in Input) bool {
+	switch r.Kind {
+	case KindNonResidentSurcharge:
+		return !in.IsResident
+	case KindEarlyBird:
+		return r.EndsAt != nil && in.At.Before(*r.EndsAt)
+	case KindSiblingDiscount:
+		min := r.MinSiblingIndex
+		if min < 2 {
+			min = 2
+		}
+		return in.SiblingIndex >= min
+	}
+	return false
+}
+
+func ruleAmount(r Rule, runningTotal int) int {
+	if r.Percent > 0 {
+		return percentOf(runningTotal, r.Percent)
+	}
+	return r.AmountCents
+}
+
+func percentOf(cents int, pct float64) int {
+	return int(math.Round(float64(cents) * pct / 100))
+}
+
+func labelFor(r Rule) string {
+	if r.Label != "" {
+		return r.Label
+	}
+	switch r.Kind {
+	case KindNonResidentSurcharge:
+		return "Non-resident fee"
+	case KindEarlyBird:
+		return "Early-bird discount"
+	case KindSiblingDiscount:
+		return "Sibling discount"
+	}
+	return r.Kind
+}
+
+// ValidKind reports whether kind is a supported rule kind
+func ValidKind(kind string) bool {
+	return kind == KindNonResidentSurcharge || kind == KindEarlyBird || kind == KindSiblingDiscount
+}
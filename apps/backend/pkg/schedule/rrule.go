@@ -0,0 +1,601 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is an RRULE FREQ value
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how many days, weeks, months or years an expansion
+// walks from the first start, whatever the rule says
+const maxPeriods = 20000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func weekdayCode(d time.Weekday) string {
+	return strings.ToUpper(d.String()[:2])
+}
+
+// WeekdayNum is a BYDAY entry such as "TU", "2TU" (second Tuesday) or
+// "-1FR" (last Friday). N is zero when every matching weekday counts.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayCode(w.Weekday)
+	}
+	return strconv.Itoa(w.N) + weekdayCode(w.Weekday)
+}
+
+// Rule is the subset of an RFC 5545 RRULE the platform supports: FREQ,
+// INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	UntilLocal bool // Until is a date-only or floating wall-clock time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// ParseRule reads an RRULE value, with or without the "RRULE:" prefix
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("rrule is empty")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid rrule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(value)); f {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = f
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q, expected DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, local, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until, r.UntilLocal = t, local
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(v)
+				if err != nil {
+					return r, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("invalid BYMONTHDAY %q", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return r, fmt.Errorf("invalid BYMONTH %q", v)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			d, ok := weekdayCodes[strings.ToUpper(value)]
+			if !ok {
+				return r, fmt.Errorf("invalid WKST %q", value)
+			}
+			r.WeekStart = d
+		default:
+			return r, fmt.Errorf("unsupported rrule part %s", strings.ToUpper(key))
+		}
+	}
+
+	return r, r.Validate()
+}
+
+// parseUntil reads an UNTIL value. Only the UTC form names an instant;
+// date-only and floating values are wall-clock times, reported as local.
+func parseUntil(v string) (time.Time, bool, error) {
+	v = strings.ToUpper(v)
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", v); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102", v); err == nil {
+		// A date-only UNTIL includes the whole day
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid UNTIL %q, expected YYYYMMDD or YYYYMMDDTHHMMSSZ", v)
+}
+
+// UntilIn returns the instant UNTIL names for a series in loc
+func (r Rule) UntilIn(loc *time.Location) time.Time {
+	if !r.UntilLocal || r.Until.IsZero() {
+		return r.Until
+	}
+	u := r.Until
+	return time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, loc)
+}
+
+// InLocation returns the rule with a local UNTIL resolved in loc, so it
+// renders as an instant
+func (r Rule) InLocation(loc *time.Location) Rule {
+	r.Until, r.UntilLocal = r.UntilIn(loc), false
+	return r
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	d, ok := weekdayCodes[v[len(v)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	wd := WeekdayNum{Weekday: d}
+	if prefix := v[:len(v)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// Validate checks the rule is well formed and within the supported subset
+func (r Rule) Validate() error {
+	if r.Freq == "" {
+		return errors.New("rrule needs a FREQ")
+	}
+	if r.Interval < 1 {
+		return errors.New("rrule INTERVAL must be at least 1")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("rrule cannot have both COUNT and UNTIL")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N == 0 {
+			continue
+		}
+		if r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return errors.New("numbered BYDAY entries need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+		if r.Freq == FreqYearly && len(r.ByMonth) == 0 {
+			return errors.New("numbered BYDAY entries with FREQ=YEARLY need BYMONTH")
+		}
+		if wd.N < -5 || wd.N > 5 {
+			return fmt.Errorf("BYDAY %s is out of range for a month", wd)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == FreqWeekly {
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return nil
+}
+
+// String renders the rule in a canonical order, without the "RRULE:" prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	switch {
+	case r.Until.IsZero():
+	case r.UntilLocal:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	default:
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Recurrence is a rule anchored at the series' first start. Starts keep
+// the first start's wall-clock time in Location across daylight saving
+// changes. Exceptions are EXDATEs: starts that are skipped.
+type Recurrence struct {
+	Rule       Rule
+	Start      time.Time
+	Location   *time.Location
+	Exceptions []time.Time
+}
+
+// Between returns the starts in [from, to), skipping exceptions
+func (rec Recurrence) Between(from, to time.Time) []time.Time {
+	var out []time.Time
+	rec.walk(func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) && !rec.excluded(t) {
+			out = append(out, t)
+		}
+		return len(out) < MaxOccurrences
+	})
+	return out
+}
+
+// Includes reports whether t is one of the series' starts
+func (rec Recurrence) Includes(t time.Time) bool {
+	found := false
+	rec.walk(func(s time.Time) bool {
+		if s.Equal(t) {
+			found = !rec.excluded(s)
+		}
+		return s.Before(t)
+	})
+	return found
+}
+
+// CountBefore returns how many starts the rule generates before t,
+// exceptions included, which is what COUNT is measured in
+func (rec Recurrence) CountBefore(t time.Time) int {
+	n := 0
+	rec.walk(func(s time.Time) bool {
+		if !s.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// Last returns the final start of a bounded series, or false when the
+// rule repeats forever
+func (rec Recurrence) Last() (time.Time, bool) {
+	if rec.Rule.Count == 0 && rec.Rule.Until.IsZero() {
+		return time.Time{}, false
+	}
+	var last time.Time
+	found := false
+	rec.walk(func(s time.Time) bool {
+		if !rec.excluded(s) {
+			last, found = s, true
+		}
+		return true
+	})
+	return last, found
+}
+
+func (rec Recurrence) excluded(t time.Time) bool {
+	for _, ex := range rec.Exceptions {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn with each start the rule generates, in order, until fn
+// returns false or the rule runs out
+func (rec Recurrence) walk(fn func(time.Time) bool) {
+	r := rec.Rule
+	if r.Validate() != nil {
+		return
+	}
+	loc := rec.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	start := rec.Start.In(loc)
+	until := r.UntilIn(loc)
+	count := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.candidates(start, period)
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if !until.IsZero() && t.After(until) {
+				return
+			}
+			if !fn(t) {
+				return
+			}
+			count++
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the sorted starts in the nth period after the one
+// containing start
+func (r Rule) candidates(start time.Time, n int) []time.Time {
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		day := at(start.Year(), start.Month(), start.Day()+n*r.Interval)
+		if r.matchesMonth(day.Month()) && r.matchesMonthDay(day) && r.matchesWeekday(day.Weekday()) {
+			days = append(days, day)
+		}
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := at(start.Year(), start.Month(), start.Day()-offset+n*7*r.Interval)
+		for i := 0; i < 7; i++ {
+			day := at(weekStart.Year(), weekStart.Month(), weekStart.Day()+i)
+			if !r.matchesMonth(day.Month()) {
+				continue
+			}
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if len(r.ByDay) > 0 && !r.matchesWeekday(day.Weekday()) {
+				continue
+			}
+			days = append(days, day)
+		}
+	case FreqMonthly:
+		first := at(start.Year(), start.Month()+time.Month(n*r.Interval), 1)
+		if r.matchesMonth(first.Month()) {
+			days = r.monthDays(first, start, at)
+		}
+	case FreqYearly:
+		year := start.Year() + n*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			days = append(days, r.monthDays(at(year, m, 1), start, at)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupe(days)
+}
+
+// monthDays expands BYMONTHDAY and BYDAY within the month starting at
+// first. With neither, the series' own day of the month is used and
+// months too short for it are skipped.
+func (r Rule) monthDays(first, start time.Time, at func(int, time.Month, int) time.Time) []time.Time {
+	y, m := first.Year(), first.Month()
+	length := at(y, m+1, 0).Day()
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+		if start.Day() <= length {
+			days = append(days, at(y, m, start.Day()))
+		}
+	case len(r.ByDay) == 0:
+		for _, d := range r.ByMonthDay {
+			if day, ok := resolveMonthDay(d, length); ok {
+				days = append(days, at(y, m, day))
+			}
+		}
+	default:
+		for d := 1; d <= length; d++ {
+			day := at(y, m, d)
+			if r.matchesMonthDay(day) && r.matchesNthWeekday(day, length) {
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
+
+func resolveMonthDay(d, length int) (int, bool) {
+	if d < 0 {
+		d = length + 1 + d
+	}
+	return d, d >= 1 && d <= length
+}
+
+func (r Rule) matchesMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, d := range r.ByMonthDay {
+		if day, ok := resolveMonthDay(d, length); ok && day == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesWeekday(d time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNthWeekday checks a day against BYDAY entries, where numbered
+// entries count from the start (or, when negative, the end) of the month
+func (r Rule) matchesNthWeekday(t time.Time, length int) bool {
+	for _, wd := range r.ByDay {
+		if wd.Weekday != t.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && (t.Day()-1)/7+1 == wd.N:
+			return true
+		case wd.N < 0 && (length-t.Day())/7+1 == -wd.N:
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Describe summarises the rule for people, e.g. "Weekly on Sat" or
+// "Monthly on the last Fri"
+func (r Rule) Describe() string {
+	unit := map[Frequency]string{FreqDaily: "day", FreqWeekly: "week", FreqMonthly: "month", FreqYearly: "year"}[r.Freq]
+	desc := string(r.Freq[:1]) + strings.ToLower(string(r.Freq[1:]))
+	if r.Interval > 1 {
+		desc = fmt.Sprintf("Every %d %ss", r.Interval, unit)
+	}
+
+	if len(r.ByDay) > 0 {
+		names := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			day := wd.Weekday.String()[:3]
+			switch {
+			case wd.N == -1:
+				names[i] = "the last " + day
+			case wd.N > 0:
+				names[i] = "the " + ordinal(wd.N) + " " + day
+			case wd.N < 0:
+				names[i] = "the " + ordinal(-wd.N) + " to last " + day
+			default:
+				names[i] = day
+			}
+		}
+		desc += " on " + strings.Join(names, ", ")
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			if d == -1 {
+				days[i] = "the last day"
+			} else {
+				days[i] = "the " + ordinal(d)
+			}
+		}
+		desc += " on " + strings.Join(days, ", ")
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = m.String()[:3]
+		}
+		desc += " in " + strings.Join(months, ", ")
+	}
+	if r.Count > 0 {
+		desc += fmt.Sprintf(", %d times", r.Count)
+	}
+	if !r.Until.IsZero() {
+		desc += ", until " + r.Until.Format("Jan 2, 2006")
+	}
+	return desc
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceUntil(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		rule  string
+		start string
+		last  string
+	}{
+		{"date-only, New York", "America/New_York", "FREQ=WEEKLY;UNTIL=20261231", "2026-12-03 19:30", "2026-12-31 19:30"},
+		{"date-only, Los Angeles", "America/Los_Angeles", "FREQ=WEEKLY;UNTIL=20261231", "2026-12-03 20:00", "2026-12-31 20:00"},
+		{"date-only, Honolulu", "Pacific/Honolulu", "FREQ=DAILY;UNTIL=20260615", "2026-06-10 18:00", "2026-06-15 18:00"},
+		{"date-only, Sao Paulo", "America/Sao_Paulo", "FREQ=WEEKLY;UNTIL=20261231", "2026-12-03 22:00", "2026-12-31 22:00"},
+		{"date-only across DST, New York", "America/New_York", "FREQ=WEEKLY;UNTIL=20261105", "2026-10-22 21:00", "2026-11-05 21:00"},
+		{"floating, New York", "America/New_York", "FREQ=WEEKLY;UNTIL=20261231T193000", "2026-12-03 19:30", "2026-12-31 19:30"},
+		{"floating before start time, New York", "America/New_York", "FREQ=WEEKLY;UNTIL=20261231T190000", "2026-12-03 19:30", "2026-12-24 19:30"},
+		{"UTC, New York", "America/New_York", "FREQ=WEEKLY;UNTIL=20261231T235959Z", "2026-12-03 19:30", "2026-12-24 19:30"},
+		{"date-only, UTC", "UTC", "FREQ=WEEKLY;UNTIL=20261231", "2026-12-03 19:30", "2026-12-31 19:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Skipf("time zone %s unavailable: %v", tt.zone, err)
+			}
+			rule, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.rule, err)
+			}
+			start := mustLocal(t, tt.start, loc)
+			want := mustLocal(t, tt.last, loc)
+
+			rec := Recurrence{Rule: rule, Start: start, Location: loc}
+			got, ok := rec.Last()
+			if !ok {
+				t.Fatalf("Last() found no occurrence")
+			}
+			if !got.Equal(want) {
+				t.Errorf("Last() = %s, want %s", got.In(loc), want)
+			}
+
+			// A rule stored as an instant keeps the same last occurrence
+			stored, err := ParseRule(rule.InLocation(loc).String())
+			if err != nil {
+				t.Fatalf("ParseRule(stored): %v", err)
+			}
+			rec.Rule = stored
+			if got, _ := rec.Last(); !got.Equal(want) {
+				t.Errorf("stored Last() = %s, want %s", got.In(loc), want)
+			}
+		})
+	}
+}
+
+func mustLocal(t *testing.T, v string, loc *time.Location) time.Time {
+	t.Helper()
+	tm, err := time.ParseInLocation("2006-01-02 15:04", v, loc)
+	if err != nil {
+		t.Fatalf("parse %q: %v", v, err)
+	}
+	return tm
+}
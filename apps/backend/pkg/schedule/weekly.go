@@ -0,0 +1,176 @@
+// Package schedule expands weekly meeting patterns into concrete occurrences.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxOccurrences bounds a single expansion so a bad date range cannot
+// generate an unbounded number of rows
+const MaxOccurrences = 1000
+
+// Clock is a time of day in minutes after midnight
+type Clock int
+
+// ParseClock reads "HH:MM" (24-hour)
+func ParseClock(s string) (Clock, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return Clock(t.Hour()*60 + t.Minute()), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+// Label formats the clock as "6:00 PM"
+func (c Clock) Label() string {
+	return time.Date(2000, 1, 1, int(c)/60, int(c)%60, 0, 0, time.UTC).Format("3:04 PM")
+}
+
+// Weekly meets on the given weekdays every IntervalWeeks weeks between
+// StartsOn and EndsOn (inclusive, calendar dates), skipping Exceptions
+type Weekly struct {
+	Weekdays      []time.Weekday
+	StartTime     Clock
+	EndTime       Clock
+	StartsOn      time.Time
+	EndsOn        time.Time
+	IntervalWeeks int
+	Exceptions    []time.Time
+}
+
+// Occurrence is one meeting
+type Occurrence struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// Validate checks the pattern is well formed
+func (w Weekly) Validate() error {
+	if len(w.Weekdays) == 0 {
+		return errors.New("at least one weekday is required")
+	}
+	for _, d := range w.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid weekday %d, expected 0 (Sunday) to 6 (Saturday)", d)
+		}
+	}
+	if w.StartTime < 0 || w.EndTime > 24*60 || w.EndTime <= w.StartTime {
+		return errors.New("end_time must be after start_time")
+	}
+	if w.EndsOn.Before(w.StartsOn) {
+		return errors.New("ends_on must not be before starts_on")
+	}
+	if w.IntervalWeeks < 1 {
+		return errors.New("interval_weeks must be at least 1")
+	}
+	return nil
+}
+
+func dateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// Occurrences expands the pattern in loc. Wall-clock times are kept across
+// daylight saving changes.
+func (w Weekly) Occurrences(loc *time.Location) []Occurrence {
+	if w.Validate() != nil {
+		return nil
+	}
+
+	skip := make(map[string]bool, len(w.Exceptions))
+	for _, d := range w.Exceptions {
+		skip[dateKey(d)] = true
+	}
+	days := make(map[time.Weekday]bool, len(w.Weekdays))
+	for _, d := range w.Weekdays {
+		days[d] = true
+	}
+
+	start := time.Date(w.StartsOn.Year(), w.StartsOn.Month(), w.StartsOn.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(w.EndsOn.Year(), w.EndsOn.Month(), w.EndsOn.Day(), 0, 0, 0, 0, loc)
+	// Weeks are counted from the Sunday on or before StartsOn
+	weekZero := start.AddDate(0, 0, -int(start.Weekday()))
+
+	var out []Occurrence
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !days[day.Weekday()] || skip[dateKey(day)] {
+			continue
+		}
+		week := int(day.Sub(weekZero).Hours()+12) / (24 * 7)
+		if week%w.IntervalWeeks != 0 {
+			continue
+		}
+		out = append(out, Occurrence{
+			StartsAt: time.Date(day.Year(), day.Month(), day.Day(), int(w.StartTime)/60, int(w.StartTime)%60, 0, 0, loc),
+			EndsAt:   time.Date(day.Year(), day.Month(), day.Day(), int(w.EndTime)/60, int(w.EndTime)%60, 0, 0, loc),
+		})
+		if len(out) >= MaxOccurrences {
+			break
+		}
+	}
+	return out
+}
+
+// Slots splits each occurrence into slots of slotMinutes with
+// bufferMinutes between them, by wall-clock time in loc. A slot that would
+// run past the occurrence's end is dropped.
+func (w Weekly) Slots(loc *time.Location, slotMinutes, bufferMinutes int) []Occurrence {
+	if slotMinutes <= 0 || bufferMinutes < 0 {
+		return nil
+	}
+	var out []Occurrence
+	for _, o := range w.Occurrences(loc) {
+		day := o.StartsAt.In(loc)
+		at := func(m int) time.Time {
+			return time.Date(day.Year(), day.Month(), day.Day(), m/60, m%60, 0, 0, loc)
+		}
+		for m := int(w.StartTime); m+slotMinutes <= int(w.EndTime); m += slotMinutes + bufferMinutes {
+			out = append(out, Occurrence{StartsAt: at(m), EndsAt: at(m + slotMinutes)})
+		}
+	}
+	return out
+}
+
+// Summary describes the pattern, e.g. "Tue, Thu 6:00 PM–7:00 PM"
+func (w Weekly) Summary() string {
+	days := append([]time.Weekday(nil), w.Weekdays...)
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	names := make([]string, len(days))
+	for i, d := range days {
+		names[i] = d.String()[:3]
+	}
+	summary := strings.Join(names, ", ") + " " + w.StartTime.Label() + "–" + w.EndTime.Label()
+	switch {
+	case w.IntervalWeeks == 2:
+		summary += ", every other week"
+	case w.IntervalWeeks > 2:
+		summary += fmt.Sprintf(", every %d weeks", w.IntervalWeeks)
+	}
+	return summary
+}
+
+// AlignedOffset returns the whole number of weeks, in days, closest to the
+// gap between two dates, so shifted dates keep their weekday. Ties round
+// forward.
+func AlignedOffset(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(to.Sub(from).Hours() / 24)
+	weeks := int(math.Floor(float64(days)/7 + 0.5))
+	return weeks * 7
+}
+
+// ShiftWallClock moves t by days in loc, keeping its local time of day
+// across daylight saving changes
+func ShiftWallClock(t time.Time, days int, loc *time.Location) time.Time {
+	return t.In(loc).AddDate(0, 0, days)
+}
@@ -0,0 +1,81 @@
+// Package statemachine describes the legal status transitions of an entity
+// such as a program registration or a facility booking.
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Machine maps each status to the statuses it may move to. Statuses with no
+// outgoing transitions are terminal.
+type Machine struct {
+	Entity      string
+	transitions map[string][]string
+}
+
+// New builds a machine from a status -> next statuses table
+func New(entity string, transitions map[string][]string) *Machine {
+	return &Machine{Entity: entity, transitions: transitions}
+}
+
+// Known reports whether status is part of the machine
+func (m *Machine) Known(status string) bool {
+	if _, ok := m.transitions[status]; ok {
+		return true
+	}
+	for _, next := range m.transitions {
+		for _, s := range next {
+			if s == status {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allowed returns the statuses reachable from the given one
+func (m *Machine) Allowed(from string) []string {
+	next := append([]string(nil), m.transitions[from]...)
+	sort.Strings(next)
+	return next
+}
+
+// Can reports whether from -> to is a legal transition
+func (m *Machine) Can(from, to string) bool {
+	for _, s := range m.transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a *TransitionError when from -> to is not legal
+func (m *Machine) Check(from, to string) error {
+	if m.Can(from, to) {
+		return nil
+	}
+	return &TransitionError{Entity: m.Entity, From: from, To: to, Allowed: m.Allowed(from), Unknown: !m.Known(to)}
+}
+
+// TransitionError describes a rejected transition
+type TransitionError struct {
+	Entity  string
+	From    string
+	To      string
+	Allowed []string
+
+	// Unknown is set when To is not a status of the machine at all
+	Unknown bool
+}
+
+func (e *TransitionError) Error() string {
+	if e.Unknown {
+		return fmt.Sprintf("unknown %s status %q", e.Entity, e.To)
+	}
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("%s is %s and can no longer change status", e.Entity, e.From)
+	}
+	return fmt.Sprintf("%s cannot move from %s to %s", e.Entity, e.From, e.To)
+}